@@ -1,19 +1,38 @@
 package main
 
 import (
+	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"boxmagic/internal/config"
 	"boxmagic/internal/handlers"
+	"boxmagic/internal/jwtkeys"
 	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
 	"boxmagic/internal/repository"
 	"boxmagic/internal/services"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	// Firma asimétrica con kid si hay directorio de claves; se relee para tomar rotaciones
+	if cfg.JWTKeysDir != "" {
+		keys, err := jwtkeys.Load(cfg.JWTKeysDir)
+		if err != nil {
+			log.Fatal("Failed to load JWT keys:", err)
+		}
+		cfg.JWTKeys = keys
+		go keys.Watch(cfg.JWTKeysReload)
+	}
 
 	db, err := repository.NewDB(cfg.DatabaseURL)
 	if err != nil {
@@ -21,14 +40,98 @@ func main() {
 	}
 	defer db.Close()
 
-	if err := repository.Migrate(db); err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	// Rate limiting: en memoria por defecto; con varias réplicas RATE_LIMIT_STORE=postgres
+	if cfg.RateLimitStore == "postgres" {
+		middleware.SetRateLimitStore(repository.NewRateLimitRepository(db))
+	}
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	apiLimiter := middleware.NewLimiter("api", cfg.RateLimitAPI)
+
+	// Ensure upload directory exists
+	if err := os.MkdirAll(cfg.UploadDir, 0755); err != nil {
+		log.Fatal("Failed to create upload directory:", err)
+	}
+
+	root := http.NewServeMux()
+
+	// Health check
+	root.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	root.HandleFunc("GET /.well-known/jwks.json", handlers.NewJWKSHandler(cfg.JWTKeys).JWKS)
+
+	if cfg.MultiTenant {
+		// Un schema por tenant; el schema public solo guarda el registro de tenants
+		if err := repository.MigratePlatform(db); err != nil {
+			log.Fatal("Failed to run platform migrations:", err)
+		}
+		tenantService := services.NewTenantService(repository.NewTenantRepository(db), db, cfg)
+		if err := tenantService.MigrateAll(); err != nil {
+			log.Fatal("Failed to run tenant migrations:", err)
+		}
+		tenants := newTenantRouter(cfg, tenantService)
+		tenantService.SetOnChange(tenants.Forget)
+		tenants.StartAll()
+
+		platformHandler := handlers.NewPlatformHandler(tenantService)
+		platformAdmin := middleware.PlatformAdmin(cfg.PlatformAdminToken)
+		root.Handle("GET /api/v1/platform/tenants", platformAdmin(http.HandlerFunc(platformHandler.ListTenants)))
+		root.Handle("POST /api/v1/platform/tenants", platformAdmin(http.HandlerFunc(platformHandler.CreateTenant)))
+		root.Handle("GET /api/v1/platform/tenants/{slug}", platformAdmin(http.HandlerFunc(platformHandler.GetTenant)))
+		root.Handle("PUT /api/v1/platform/tenants/{slug}", platformAdmin(http.HandlerFunc(platformHandler.UpdateTenant)))
+
+		// Los archivos subidos tienen nombre aleatorio y se piden desde <img>, sin tenant
+		root.Handle("GET /uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.UploadDir))))
+		root.Handle("/", tenants)
+	} else {
+		if err := repository.Migrate(db); err != nil {
+			log.Fatal("Failed to run migrations:", err)
+		}
+
+		if err := repository.Seed(db); err != nil {
+			log.Fatal("Failed to seed database:", err)
+		}
+		root.Handle("/", buildAPI(db, cfg))
+	}
+
+	handler := middleware.CORS(middleware.Logger(apiLimiter.PerIP(root)))
+
+	log.Printf("Server starting on port %s", cfg.Port)
+	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
+		log.Fatal("Server failed:", err)
 	}
+}
+
+// tenantWorker repite una tarea de mantenimiento (anonimizar cuentas vencidas, purgar auditoría). Hay uno por
+// tarea y tenant; cuando la API del tenant se reconstruye, el worker pasa a usar los servicios nuevos.
+type tenantWorker struct {
+	task atomic.Pointer[func()]
+}
+
+var tenantWorkers sync.Map
 
-	if err := repository.Seed(db); err != nil {
-		log.Fatal("Failed to seed database:", err)
+func startTenantWorker(name, tenant string, every time.Duration, task func()) {
+	w := &tenantWorker{}
+	w.task.Store(&task)
+	if prev, loaded := tenantWorkers.LoadOrStore(name+":"+tenant, w); loaded {
+		prev.(*tenantWorker).task.Store(&task)
+		return
 	}
+	go func() {
+		for {
+			(*w.task.Load())()
+			time.Sleep(every)
+		}
+	}()
+}
 
+// buildAPI arma repositorios, servicios y rutas sobre la base y la config recibidas. En multi-tenant se
+// llama una vez por tenant con el pool de su schema y su config propia.
+func buildAPI(db *sql.DB, cfg *config.Config) http.Handler {
 	mux := http.NewServeMux()
 
 	userRepo := repository.NewUserRepository(db)
@@ -52,26 +155,86 @@ func main() {
 	tagRepo := repository.NewTagRepository(db)
 	nutritionRepo := repository.NewNutritionRepository(db)
 
-	authService := services.NewAuthService(userRepo, cfg)
+	sessionRepo := repository.NewSessionRepository(db)
+	authService := services.NewAuthService(userRepo, sessionRepo, cfg)
+	roleService := services.NewRoleService(repository.NewRoleRepository(db))
+	auditRepo := repository.NewAuditRepository(db)
+	auditService := services.NewAuditService(auditRepo, cfg.AuditRetentionDays)
+	startTenantWorker("audit-purge", cfg.TenantSlug, 24*time.Hour, auditService.PurgeExpired)
+	startTenantWorker("payment-expiry", cfg.TenantSlug, time.Hour, func() {
+		if _, err := paymentRepo.ExpirePendingPayments(); err != nil {
+			log.Printf("[PAYMENTS] expire pending: %v", err)
+		}
+	})
+	scope := &middleware.Scope{AccessChecker: authService, PermissionResolver: roleService, Auditor: auditService}
 	emailService := services.NewEmailService(cfg)
+	authService.SetEmailService(emailService)
+	authService.SetLockoutRepo(repository.NewLoginLockoutRepository(db))
+	authService.SetTwoFactor(repository.NewTwoFactorRepository(db), repository.NewSettingsRepository(db))
 
-	// Ensure upload directory exists
-	if err := os.MkdirAll(cfg.UploadDir, 0755); err != nil {
-		log.Fatal("Failed to create upload directory:", err)
-	}
+	authLimiter := middleware.NewLimiter("auth", cfg.RateLimitAuth)
+	publicLimiter := middleware.NewLimiter("public", cfg.RateLimitPublic)
 
 	configHandler := handlers.NewConfigHandler(cfg)
+	configHandler.SetSecuritySettings(authService)
 	authHandler := handlers.NewAuthHandler(authService)
+	accountLimiter := middleware.NewLimiter("login:"+cfg.TenantSlug, cfg.RateLimitAccount)
+	authHandler.SetAccountLimiter(accountLimiter)
+	magicLinkHandler := handlers.NewMagicLinkHandler(authService)
+	magicLinkHandler.SetAccountLimiter(accountLimiter)
+	twoFactorHandler := handlers.NewTwoFactorHandler(authService)
+	twoFactorHandler.SetUserRepo(userRepo)
 	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler.SetSessionRepo(sessionRepo)
+	privacyRepo := repository.NewPrivacyRepository(db)
+	privacyService := services.NewPrivacyService(privacyRepo, userRepo, cfg.UploadDir, cfg.ErasureGraceDays)
+	startTenantWorker("erasure", cfg.TenantSlug, time.Hour, privacyService.ProcessDue)
+	userHandler.SetEraser(privacyService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, privacyRepo)
+	impersonationRepo := repository.NewImpersonationRepository(db)
+	impersonationService := services.NewImpersonationService(impersonationRepo, userRepo, cfg)
+	impersonationService.SetEmailService(emailService)
+	scope.Impersonations = impersonationService
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationService, impersonationRepo)
+	sessionHandler := handlers.NewSessionHandler(sessionRepo)
+	sessionHandler.SetUserRepo(userRepo)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	auditHandler := handlers.NewAuditHandler(auditRepo, auditService)
 	planHandler := handlers.NewPlanHandler(planRepo)
 	paymentHandler := handlers.NewPaymentHandler(paymentRepo, planRepo, userRepo)
 	paymentHandler.SetDiscountRepo(discountRepo)
+	paymentHandler.SetEmailService(emailService)
+	paymentHandler.SetConfig(cfg)
+	bankHandler := handlers.NewBankHandler(repository.NewBankRepository(db), paymentHandler)
 	classHandler := handlers.NewClassHandler(classRepo, paymentRepo, instructorRepo, userRepo, emailService)
 	classHandler.SetConfig(cfg)
+	waiverRepo := repository.NewWaiverRepository(db)
+	waiverService := services.NewWaiverService(waiverRepo, userRepo)
+	classHandler.SetWaiverChecker(waiverService)
+	waiverHandler := handlers.NewWaiverHandler(waiverService, waiverRepo, services.BrandingFromConfig(cfg))
 	routineHandler := handlers.NewRoutineHandler(routineRepo, feedRepo)
 	routineHandler.SetBadgeRepo(badgeRepo)
+	routineHandler.SetWorkoutService(services.NewWorkoutService(movementRepo))
+	benchmarkRepo := repository.NewBenchmarkRepository(db)
+	benchmarkService := services.NewBenchmarkService(benchmarkRepo)
+	routineHandler.SetBenchmarkService(benchmarkService)
+	benchmarkHandler := handlers.NewBenchmarkHandler(benchmarkService, benchmarkRepo)
+	customProgramRepo := repository.NewCustomProgramRepository(db)
+	customProgramService := services.NewCustomProgramService(customProgramRepo, repository.NewSettingsRepository(db))
+	routineHandler.SetCustomProgramService(customProgramService)
+	customProgramHandler := handlers.NewCustomProgramHandler(customProgramService, customProgramRepo)
+	programmingRepo := repository.NewProgrammingRepository(db)
+	programmingService := services.NewProgrammingService(programmingRepo, routineRepo)
+	classHandler.SetScheduleSyncer(programmingService)
+	programmingHandler := handlers.NewProgrammingHandler(programmingService, programmingRepo)
+	strengthRepo := repository.NewStrengthRepository(db)
+	strengthHandler := handlers.NewStrengthHandler(services.NewStrengthService(strengthRepo, movementRepo, routineRepo), strengthRepo)
 	feedHandler := handlers.NewFeedHandler(feedRepo)
 	instructorHandler := handlers.NewInstructorHandler(instructorRepo)
+	instructorHandler.SetUserRepo(userRepo)
+	memberNoteRepo := repository.NewMemberNoteRepository(db)
+	memberNoteHandler := handlers.NewMemberNoteHandler(memberNoteRepo, userRepo)
+	coachHandler := handlers.NewCoachHandler(instructorRepo, classRepo, routineRepo, memberNoteRepo)
 	statsHandler := handlers.NewStatsHandler(statsRepo)
 	uploadHandler := handlers.NewUploadHandler(cfg)
 	tvHandler := handlers.NewTVHandler(classRepo, routineRepo)
@@ -84,15 +247,61 @@ func main() {
 	onrampHandler := handlers.NewOnrampHandler(onrampRepo)
 	movementHandler := handlers.NewMovementHandler(movementRepo)
 	eventHandler := handlers.NewEventHandler(eventRepo)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerRepo, userRepo)
 	productHandler := handlers.NewProductHandler(productRepo)
+	productHandler.SetLedgerRepo(ledgerRepo)
+	userHandler.SetLedgerRepo(ledgerRepo)
+	cashHandler := handlers.NewCashHandler(repository.NewCashRepository(db))
+	receiptHandler := handlers.NewReceiptHandler(repository.NewReceiptRepository(db), userRepo, emailService, services.BrandingFromConfig(cfg))
+	taxRepo := repository.NewTaxRepository(db)
+	taxService := services.NewTaxService(taxRepo, cfg)
+	taxHandler := handlers.NewTaxHandler(taxService, taxRepo)
+	paymentHandler.SetTaxService(taxService)
+	productHandler.SetTaxService(taxService)
 	tagHandler := handlers.NewTagHandler(tagRepo)
 	nutritionHandler := handlers.NewNutritionHandler(nutritionRepo)
 
 	// Public routes
 	mux.HandleFunc("GET /api/v1/config", configHandler.Get)
-	mux.HandleFunc("POST /api/v1/auth/register", authHandler.Register)
-	mux.HandleFunc("POST /api/v1/auth/login", authHandler.Login)
-	mux.HandleFunc("POST /api/v1/auth/refresh", authHandler.Refresh)
+	mux.Handle("POST /api/v1/auth/register", authLimiter.PerIP(http.HandlerFunc(authHandler.Register)))
+	mux.Handle("POST /api/v1/auth/login", authLimiter.PerIP(http.HandlerFunc(authHandler.Login)))
+	mux.Handle("POST /api/v1/auth/refresh", authLimiter.PerIP(http.HandlerFunc(authHandler.Refresh)))
+	mux.Handle("POST /api/v1/auth/forgot-password", authLimiter.PerIP(http.HandlerFunc(authHandler.ForgotPassword)))
+	mux.Handle("POST /api/v1/auth/reset-password", authLimiter.PerIP(http.HandlerFunc(authHandler.ResetPassword)))
+	mux.Handle("POST /api/v1/auth/verify-email", authLimiter.PerIP(http.HandlerFunc(authHandler.VerifyEmail)))
+	mux.Handle("POST /api/v1/auth/resend-verification", middleware.Auth(cfg)(http.HandlerFunc(authHandler.ResendVerification)))
+	mux.Handle("POST /api/v1/auth/change-password", middleware.Auth(cfg)(http.HandlerFunc(authHandler.ChangePassword)))
+	mux.Handle("POST /api/v1/auth/logout", middleware.Auth(cfg)(http.HandlerFunc(authHandler.Logout)))
+	mux.Handle("POST /api/v1/auth/logout-all", middleware.Auth(cfg)(http.HandlerFunc(authHandler.LogoutAll)))
+	mux.Handle("GET /api/v1/auth/sessions", middleware.Auth(cfg)(http.HandlerFunc(sessionHandler.MySessions)))
+	mux.Handle("DELETE /api/v1/auth/sessions/{id}", middleware.Auth(cfg)(http.HandlerFunc(sessionHandler.RevokeMine)))
+	mux.Handle("POST /api/v1/auth/magic-link", authLimiter.PerIP(http.HandlerFunc(magicLinkHandler.Request)))
+	mux.Handle("POST /api/v1/auth/magic-link/verify", authLimiter.PerIP(http.HandlerFunc(magicLinkHandler.Verify)))
+	mux.Handle("POST /api/v1/auth/login/2fa", authLimiter.PerIP(http.HandlerFunc(twoFactorHandler.LoginVerify)))
+	mux.Handle("POST /api/v1/auth/login/2fa/setup", authLimiter.PerIP(http.HandlerFunc(twoFactorHandler.LoginSetup)))
+	mux.Handle("GET /api/v1/auth/2fa", middleware.Auth(cfg)(http.HandlerFunc(twoFactorHandler.Status)))
+	mux.Handle("POST /api/v1/auth/2fa/setup", middleware.Auth(cfg)(http.HandlerFunc(twoFactorHandler.Setup)))
+	mux.Handle("POST /api/v1/auth/2fa/enable", middleware.Auth(cfg)(http.HandlerFunc(twoFactorHandler.Enable)))
+	mux.Handle("POST /api/v1/auth/2fa/disable", middleware.Auth(cfg)(http.HandlerFunc(twoFactorHandler.Disable)))
+	mux.Handle("POST /api/v1/auth/2fa/recovery-codes", middleware.Auth(cfg)(http.HandlerFunc(twoFactorHandler.RecoveryCodes)))
+	mux.Handle("GET /api/v1/auth/permissions", middleware.Auth(cfg)(http.HandlerFunc(roleHandler.MyPermissions)))
+
+	// Roles y permisos del staff
+	mux.Handle("GET /api/v1/permissions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage)(http.HandlerFunc(roleHandler.Permissions))))
+	mux.Handle("GET /api/v1/roles", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage, models.PermUsersManage)(http.HandlerFunc(roleHandler.List))))
+	mux.Handle("GET /api/v1/roles/{name}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage)(http.HandlerFunc(roleHandler.Get))))
+	mux.Handle("POST /api/v1/roles", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage)(middleware.Audit(models.AuditEntityRole)(http.HandlerFunc(roleHandler.Create)))))
+	mux.Handle("PUT /api/v1/roles/{name}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage)(middleware.Audit(models.AuditEntityRole)(http.HandlerFunc(roleHandler.Update)))))
+	mux.Handle("DELETE /api/v1/roles/{name}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRolesManage)(middleware.Audit(models.AuditEntityRole)(http.HandlerFunc(roleHandler.Delete)))))
+
+	// Log de auditoría
+	mux.Handle("GET /api/v1/audit", middleware.Auth(cfg)(middleware.RequirePermission(models.PermAuditView)(http.HandlerFunc(auditHandler.List))))
+	mux.Handle("GET /api/v1/audit/export", middleware.Auth(cfg)(middleware.RequirePermission(models.PermAuditView)(http.HandlerFunc(auditHandler.Export))))
+
+	// Políticas de seguridad
+	mux.Handle("GET /api/v1/security/settings", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSecurityManage)(http.HandlerFunc(twoFactorHandler.GetSettings))))
+	mux.Handle("PUT /api/v1/security/settings", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSecurityManage)(middleware.Audit(models.AuditEntitySettings)(http.HandlerFunc(twoFactorHandler.UpdateSettings)))))
 
 	// Dev only: seed test data (requires API_ENV=development)
 	mux.HandleFunc("POST /api/v1/dev/seed-users", handlers.SeedTestUsers(db, cfg))
@@ -100,69 +309,140 @@ func main() {
 	// Plans (public read, admin write)
 	mux.HandleFunc("GET /api/v1/plans", planHandler.List)
 	mux.HandleFunc("GET /api/v1/plans/{id}", planHandler.GetByID)
-	mux.Handle("POST /api/v1/plans", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(planHandler.Create))))
-	mux.Handle("PUT /api/v1/plans/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(planHandler.Update))))
-	mux.Handle("DELETE /api/v1/plans/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(planHandler.Delete))))
+	mux.Handle("POST /api/v1/plans", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPlansManage)(middleware.Audit(models.AuditEntityPlan)(http.HandlerFunc(planHandler.Create)))))
+	mux.Handle("PUT /api/v1/plans/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPlansManage)(middleware.Audit(models.AuditEntityPlan)(http.HandlerFunc(planHandler.Update)))))
+	mux.Handle("DELETE /api/v1/plans/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPlansManage)(middleware.Audit(models.AuditEntityPlan)(http.HandlerFunc(planHandler.Delete)))))
 
 	// Payments (solo admin registra)
-	mux.Handle("POST /api/v1/payments", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(paymentHandler.Create))))
+	mux.Handle("POST /api/v1/payments", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPaymentsCreate)(middleware.Audit(models.AuditEntityPayment)(http.HandlerFunc(paymentHandler.Create)))))
 	mux.Handle("GET /api/v1/payments/me", middleware.Auth(cfg)(http.HandlerFunc(paymentHandler.MyPayments)))
 	mux.Handle("GET /api/v1/subscriptions/me", middleware.Auth(cfg)(http.HandlerFunc(paymentHandler.MySubscription)))
 	mux.Handle("POST /api/v1/subscriptions/me/freeze", middleware.Auth(cfg)(http.HandlerFunc(paymentHandler.FreezeSubscription)))
 	mux.Handle("POST /api/v1/subscriptions/me/unfreeze", middleware.Auth(cfg)(http.HandlerFunc(paymentHandler.UnfreezeSubscription)))
-	mux.Handle("GET /api/v1/payments", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(paymentHandler.ListAll))))
+	mux.Handle("GET /api/v1/payments", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPaymentsView)(http.HandlerFunc(paymentHandler.ListAll))))
+
+	// Transferencias informadas por miembros (cola de aprobación)
+	mux.Handle("POST /api/v1/payments/transfer", middleware.Auth(cfg)(http.HandlerFunc(paymentHandler.SubmitTransfer)))
+	mux.Handle("GET /api/v1/payments/pending", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPaymentsView)(http.HandlerFunc(paymentHandler.ListPending))))
+	mux.Handle("POST /api/v1/payments/{id}/approve", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPaymentsApprove)(middleware.Audit(models.AuditEntityPayment)(http.HandlerFunc(paymentHandler.Approve)))))
+	mux.Handle("POST /api/v1/payments/{id}/reject", middleware.Auth(cfg)(middleware.RequirePermission(models.PermPaymentsApprove)(middleware.Audit(models.AuditEntityPayment)(http.HandlerFunc(paymentHandler.Reject)))))
+	mux.Handle("GET /api/v1/payments/me/statement", middleware.Auth(cfg)(middleware.NoImpersonation(http.HandlerFunc(receiptHandler.MyStatement))))
+	mux.Handle("GET /api/v1/payments/{id}/receipt", middleware.Auth(cfg)(middleware.NoImpersonation(http.HandlerFunc(receiptHandler.PaymentReceipt))))
+	mux.Handle("POST /api/v1/payments/{id}/receipt/email", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReceiptsSend)(http.HandlerFunc(receiptHandler.EmailPaymentReceipt))))
+
+	// Conciliación bancaria (admin)
+	mux.Handle("POST /api/v1/bank/imports", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(middleware.Audit(models.AuditEntityBankImport)(http.HandlerFunc(bankHandler.Import)))))
+	mux.Handle("GET /api/v1/bank/imports", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(http.HandlerFunc(bankHandler.ListImports))))
+	mux.Handle("GET /api/v1/bank/transactions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(http.HandlerFunc(bankHandler.ListTransactions))))
+	mux.Handle("POST /api/v1/bank/transactions/{id}/confirm", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(middleware.Audit(models.AuditEntityBankTx)(http.HandlerFunc(bankHandler.Confirm)))))
+	mux.Handle("POST /api/v1/bank/transactions/{id}/reassign", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(middleware.Audit(models.AuditEntityBankTx)(http.HandlerFunc(bankHandler.Reassign)))))
+	mux.Handle("POST /api/v1/bank/transactions/{id}/ignore", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBankReconcile)(middleware.Audit(models.AuditEntityBankTx)(http.HandlerFunc(bankHandler.Ignore)))))
 
 	// Instructors (admin only)
-	mux.Handle("GET /api/v1/instructors", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(instructorHandler.List))))
-	mux.Handle("GET /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(instructorHandler.GetByID))))
-	mux.Handle("POST /api/v1/instructors", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(instructorHandler.Create))))
-	mux.Handle("PUT /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(instructorHandler.Update))))
-	mux.Handle("DELETE /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(instructorHandler.Delete))))
+	mux.Handle("GET /api/v1/instructors", middleware.Auth(cfg)(middleware.RequirePermission(models.PermInstructorsManage)(http.HandlerFunc(instructorHandler.List))))
+	mux.Handle("GET /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermInstructorsManage)(http.HandlerFunc(instructorHandler.GetByID))))
+	mux.Handle("POST /api/v1/instructors", middleware.Auth(cfg)(middleware.RequirePermission(models.PermInstructorsManage)(middleware.Audit(models.AuditEntityInstructor)(http.HandlerFunc(instructorHandler.Create)))))
+	mux.Handle("PUT /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermInstructorsManage)(middleware.Audit(models.AuditEntityInstructor)(http.HandlerFunc(instructorHandler.Update)))))
+	mux.Handle("DELETE /api/v1/instructors/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermInstructorsManage)(middleware.Audit(models.AuditEntityInstructor)(http.HandlerFunc(instructorHandler.Delete)))))
+
+	// Portal del coach (limitado a las clases que dicta; classes.manage ve todo)
+	mux.Handle("GET /api/v1/coach/me", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.Me)))
+	mux.Handle("GET /api/v1/coach/schedules", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.MySchedules)))
+	mux.Handle("GET /api/v1/coach/schedules/{id}/attendance", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.Attendance)))
+	mux.Handle("POST /api/v1/coach/schedules/{id}/routine", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.AssignRoutine)))
+	mux.Handle("DELETE /api/v1/coach/schedules/{id}/routine", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.RemoveRoutine)))
+	mux.Handle("POST /api/v1/coach/bookings/{id}/checkin", middleware.Auth(cfg)(middleware.Audit(models.AuditEntityBooking)(http.HandlerFunc(coachHandler.CheckIn))))
+	mux.Handle("GET /api/v1/coach/members/{userId}/notes", middleware.Auth(cfg)(http.HandlerFunc(coachHandler.MemberNotes)))
+	mux.Handle("POST /api/v1/coach/members/{userId}/notes", middleware.Auth(cfg)(middleware.Audit(models.AuditEntityMemberNote)(http.HandlerFunc(coachHandler.AddMemberNote))))
 
 	// Disciplines (public read, admin write)
 	mux.HandleFunc("GET /api/v1/disciplines", classHandler.ListDisciplines)
-	mux.Handle("POST /api/v1/disciplines", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.CreateDiscipline))))
-	mux.Handle("PUT /api/v1/disciplines/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.UpdateDiscipline))))
-	mux.Handle("DELETE /api/v1/disciplines/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.DeleteDiscipline))))
+	mux.Handle("POST /api/v1/disciplines", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityDiscipline)(http.HandlerFunc(classHandler.CreateDiscipline)))))
+	mux.Handle("PUT /api/v1/disciplines/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityDiscipline)(http.HandlerFunc(classHandler.UpdateDiscipline)))))
+	mux.Handle("DELETE /api/v1/disciplines/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityDiscipline)(http.HandlerFunc(classHandler.DeleteDiscipline)))))
 
 	// Classes (public read, admin write)
 	mux.HandleFunc("GET /api/v1/classes", classHandler.ListClasses)
 	mux.HandleFunc("GET /api/v1/classes/{id}", classHandler.GetClass)
-	mux.Handle("POST /api/v1/classes", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.CreateClass))))
-	mux.Handle("PUT /api/v1/classes/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.UpdateClass))))
-	mux.Handle("DELETE /api/v1/classes/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.DeleteClass))))
+	mux.Handle("POST /api/v1/classes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityClass)(http.HandlerFunc(classHandler.CreateClass)))))
+	mux.Handle("PUT /api/v1/classes/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityClass)(http.HandlerFunc(classHandler.UpdateClass)))))
+	mux.Handle("DELETE /api/v1/classes/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntityClass)(http.HandlerFunc(classHandler.DeleteClass)))))
 
 	// Schedules
 	mux.HandleFunc("GET /api/v1/schedules", classHandler.ListSchedules)
-	mux.Handle("POST /api/v1/schedules/generate", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.GenerateSchedules))))
-	mux.Handle("GET /api/v1/schedules/{id}/attendance", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.GetScheduleAttendance))))
-	mux.Handle("POST /api/v1/schedules/{id}/cancel", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.CancelSchedule))))
+	mux.Handle("POST /api/v1/schedules/generate", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntitySchedule)(http.HandlerFunc(classHandler.GenerateSchedules)))))
+	mux.Handle("GET /api/v1/schedules/{id}/attendance", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBookingsView)(http.HandlerFunc(classHandler.GetScheduleAttendance))))
+	mux.Handle("POST /api/v1/schedules/{id}/cancel", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntitySchedule)(http.HandlerFunc(classHandler.CancelSchedule)))))
 
 	// Waitlist
 	mux.Handle("POST /api/v1/schedules/{id}/waitlist", middleware.Auth(cfg)(http.HandlerFunc(classHandler.JoinWaitlist)))
 	mux.Handle("DELETE /api/v1/schedules/{id}/waitlist", middleware.Auth(cfg)(http.HandlerFunc(classHandler.LeaveWaitlist)))
-	mux.Handle("GET /api/v1/schedules/{id}/waitlist", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.GetWaitlist))))
+	mux.Handle("GET /api/v1/schedules/{id}/waitlist", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBookingsView)(http.HandlerFunc(classHandler.GetWaitlist))))
 
 	// Bookings
 	mux.Handle("POST /api/v1/schedules/{scheduleId}/book", middleware.Auth(cfg)(http.HandlerFunc(classHandler.CreateBooking)))
 	mux.Handle("GET /api/v1/bookings/me", middleware.Auth(cfg)(http.HandlerFunc(classHandler.MyBookings)))
 	mux.Handle("DELETE /api/v1/bookings/{id}", middleware.Auth(cfg)(http.HandlerFunc(classHandler.CancelBooking)))
-	mux.Handle("POST /api/v1/bookings/{id}/checkin", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(classHandler.CheckIn))))
+	mux.Handle("POST /api/v1/bookings/{id}/checkin", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBookingsCheckin)(middleware.Audit(models.AuditEntityBooking)(http.HandlerFunc(classHandler.CheckIn)))))
 	mux.Handle("POST /api/v1/bookings/{id}/before-photo", middleware.Auth(cfg)(http.HandlerFunc(classHandler.SetBookingBeforePhoto)))
 
+	// Documentos de exención y autorizaciones de apoderados
+	mux.Handle("GET /api/v1/waivers/me", middleware.Auth(cfg)(http.HandlerFunc(waiverHandler.MyWaivers)))
+	mux.Handle("POST /api/v1/waivers/documents/{id}/sign", middleware.Auth(cfg)(http.HandlerFunc(waiverHandler.Sign)))
+	mux.Handle("GET /api/v1/waivers/signatures/{id}/pdf", middleware.Auth(cfg)(http.HandlerFunc(waiverHandler.SignedPDF)))
+	mux.Handle("GET /api/v1/waivers/documents", middleware.Auth(cfg)(middleware.RequirePermission(models.PermWaiversView, models.PermWaiversManage)(http.HandlerFunc(waiverHandler.ListDocuments))))
+	mux.Handle("POST /api/v1/waivers/documents", middleware.Auth(cfg)(middleware.RequirePermission(models.PermWaiversManage)(middleware.Audit(models.AuditEntityWaiver)(http.HandlerFunc(waiverHandler.Publish)))))
+	mux.Handle("DELETE /api/v1/waivers/documents/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermWaiversManage)(middleware.Audit(models.AuditEntityWaiver)(http.HandlerFunc(waiverHandler.Retire)))))
+	mux.Handle("GET /api/v1/waivers/pending", middleware.Auth(cfg)(middleware.RequirePermission(models.PermWaiversView)(http.HandlerFunc(waiverHandler.Pending))))
+	mux.Handle("GET /api/v1/users/{id}/waivers", middleware.Auth(cfg)(middleware.RequirePermission(models.PermWaiversView)(http.HandlerFunc(waiverHandler.UserWaivers))))
+
 	// Routines
-	mux.Handle("GET /api/v1/routines/custom", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.ListCustom))))
+	mux.Handle("GET /api/v1/routines/custom", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(http.HandlerFunc(routineHandler.ListCustom))))
 	mux.Handle("GET /api/v1/routines", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.List)))
 	mux.Handle("GET /api/v1/routines/{id}", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.GetByID)))
-	mux.Handle("POST /api/v1/routines", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.Create))))
-	mux.Handle("PUT /api/v1/routines/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.Update))))
-	mux.Handle("DELETE /api/v1/routines/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.Delete))))
+	mux.Handle("POST /api/v1/routines", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityRoutine)(http.HandlerFunc(routineHandler.Create)))))
+	mux.Handle("PUT /api/v1/routines/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityRoutine)(http.HandlerFunc(routineHandler.Update)))))
+	mux.Handle("DELETE /api/v1/routines/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityRoutine)(http.HandlerFunc(routineHandler.Delete)))))
 	mux.Handle("GET /api/v1/routines/{id}/history", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.GetRoutineHistory)))
+	mux.Handle("GET /api/v1/routines/{id}/versions", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.ListVersions)))
+	mux.Handle("GET /api/v1/routines/{id}/versions/diff", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.DiffVersions)))
+	mux.Handle("GET /api/v1/routines/{id}/versions/{version}", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.GetVersion)))
+	mux.Handle("POST /api/v1/routines/{id}/versions/{version}/rollback", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityRoutine)(http.HandlerFunc(routineHandler.Rollback)))))
+	mux.Handle("GET /api/v1/routines/{id}/targets", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.RoutineTargets)))
+
+	// Benchmarks (catálogo, historial y PRs del miembro)
+	mux.Handle("GET /api/v1/benchmarks", middleware.Auth(cfg)(http.HandlerFunc(benchmarkHandler.List)))
+	mux.Handle("GET /api/v1/benchmarks/prs", middleware.Auth(cfg)(http.HandlerFunc(benchmarkHandler.PRs)))
+	mux.Handle("GET /api/v1/benchmarks/{id}", middleware.Auth(cfg)(http.HandlerFunc(benchmarkHandler.Get)))
+	mux.Handle("GET /api/v1/benchmarks/{id}/history", middleware.Auth(cfg)(http.HandlerFunc(benchmarkHandler.History)))
+	mux.Handle("POST /api/v1/benchmarks", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBenchmarksManage)(middleware.Audit(models.AuditEntityBenchmark)(http.HandlerFunc(benchmarkHandler.Create)))))
+	mux.Handle("PUT /api/v1/benchmarks/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBenchmarksManage)(middleware.Audit(models.AuditEntityBenchmark)(http.HandlerFunc(benchmarkHandler.Update)))))
+	mux.Handle("DELETE /api/v1/benchmarks/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermBenchmarksManage)(middleware.Audit(models.AuditEntityBenchmark)(http.HandlerFunc(benchmarkHandler.Delete)))))
+
+	// Tracks de programación (calendario de rutinas asignado a los horarios)
+	mux.Handle("GET /api/v1/tracks", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(http.HandlerFunc(programmingHandler.List))))
+	mux.Handle("POST /api/v1/tracks", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.Create)))))
+	mux.Handle("GET /api/v1/tracks/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(http.HandlerFunc(programmingHandler.Get))))
+	mux.Handle("PUT /api/v1/tracks/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.Update)))))
+	mux.Handle("DELETE /api/v1/tracks/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.Delete)))))
+	mux.Handle("PUT /api/v1/tracks/{id}/targets", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.SetTargets)))))
+	mux.Handle("GET /api/v1/tracks/{id}/days", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(http.HandlerFunc(programmingHandler.Days))))
+	mux.Handle("PUT /api/v1/tracks/{id}/days/{date}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.SetDay)))))
+	mux.Handle("DELETE /api/v1/tracks/{id}/days/{date}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.DeleteDay)))))
+	mux.Handle("POST /api/v1/tracks/{id}/copy-week", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.CopyWeek)))))
+	mux.Handle("POST /api/v1/tracks/{id}/shift", middleware.Auth(cfg)(middleware.RequirePermission(models.PermRoutinesManage)(middleware.Audit(models.AuditEntityTrack)(http.HandlerFunc(programmingHandler.ShiftDays)))))
+
+	// Registro de fuerza y calculadora de porcentajes
+	mux.Handle("GET /api/v1/lifts", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.List)))
+	mux.Handle("POST /api/v1/lifts", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.Log)))
+	mux.Handle("DELETE /api/v1/lifts/{id}", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.Delete)))
+	mux.Handle("GET /api/v1/lifts/summary", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.Summary)))
+	mux.Handle("GET /api/v1/lifts/percentages", middleware.Auth(cfg)(http.HandlerFunc(strengthHandler.Percentages)))
 
 	// Schedule Routines
 	mux.Handle("GET /api/v1/schedules/{scheduleId}/routine", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.GetScheduleRoutine)))
-	mux.Handle("POST /api/v1/schedules/{scheduleId}/routine", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.AssignToSchedule))))
-	mux.Handle("DELETE /api/v1/schedules/{scheduleId}/routine", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.RemoveScheduleRoutine))))
+	mux.Handle("POST /api/v1/schedules/{scheduleId}/routine", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntitySchedule)(http.HandlerFunc(routineHandler.AssignToSchedule)))))
+	mux.Handle("DELETE /api/v1/schedules/{scheduleId}/routine", middleware.Auth(cfg)(middleware.RequirePermission(models.PermClassesManage)(middleware.Audit(models.AuditEntitySchedule)(http.HandlerFunc(routineHandler.RemoveScheduleRoutine)))))
 
 	// Feed
 	mux.Handle("GET /api/v1/feed", middleware.Auth(cfg)(http.HandlerFunc(feedHandler.GetFeed)))
@@ -178,23 +458,49 @@ func main() {
 	// Results
 	mux.Handle("POST /api/v1/results", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.LogResult)))
 	mux.Handle("GET /api/v1/results/me", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.MyResults)))
-	mux.Handle("GET /api/v1/users/{userId}/results", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(routineHandler.UserResults))))
+	mux.Handle("GET /api/v1/users/{userId}/results", middleware.Auth(cfg)(middleware.RequirePermission(models.PermResultsView)(http.HandlerFunc(routineHandler.UserResults))))
 	mux.Handle("PUT /api/v1/results/{id}", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.UpdateResult)))
 	mux.Handle("DELETE /api/v1/results/{id}", middleware.Auth(cfg)(http.HandlerFunc(routineHandler.DeleteResult)))
 
 	// Users
 	mux.Handle("GET /api/v1/users/me", middleware.Auth(cfg)(http.HandlerFunc(userHandler.GetMe)))
 	mux.Handle("PUT /api/v1/users/me", middleware.Auth(cfg)(http.HandlerFunc(userHandler.UpdateMe)))
-	mux.Handle("GET /api/v1/users", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(userHandler.List))))
-	mux.Handle("GET /api/v1/users/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(userHandler.GetByID))))
-	mux.Handle("PUT /api/v1/users/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(userHandler.Update))))
-	mux.Handle("POST /api/v1/users/{id}/invitation", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(userHandler.AddInvitation))))
-	mux.Handle("DELETE /api/v1/users/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(userHandler.Delete))))
+	mux.Handle("GET /api/v1/users/me/export", middleware.Auth(cfg)(middleware.NoImpersonation(http.HandlerFunc(privacyHandler.ExportMe))))
+	mux.Handle("GET /api/v1/users/me/erasure", middleware.Auth(cfg)(http.HandlerFunc(privacyHandler.MyErasure)))
+	mux.Handle("POST /api/v1/users/me/erasure", middleware.Auth(cfg)(http.HandlerFunc(privacyHandler.RequestErasure)))
+	mux.Handle("DELETE /api/v1/users/me/erasure", middleware.Auth(cfg)(http.HandlerFunc(privacyHandler.CancelErasure)))
+	mux.Handle("GET /api/v1/users/me/injuries", middleware.Auth(cfg)(http.HandlerFunc(memberNoteHandler.MyInjuries)))
+	mux.Handle("POST /api/v1/users/me/injuries", middleware.Auth(cfg)(http.HandlerFunc(memberNoteHandler.ReportInjury)))
+	mux.Handle("POST /api/v1/users/me/injuries/{noteId}/resolve", middleware.Auth(cfg)(http.HandlerFunc(memberNoteHandler.ResolveMyInjury)))
+	mux.Handle("GET /api/v1/users/{id}/notes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersView)(http.HandlerFunc(memberNoteHandler.List))))
+	mux.Handle("POST /api/v1/users/{id}/notes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersManage)(middleware.Audit(models.AuditEntityMemberNote)(http.HandlerFunc(memberNoteHandler.Create)))))
+	mux.Handle("POST /api/v1/users/{id}/notes/{noteId}/resolve", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersManage)(middleware.Audit(models.AuditEntityMemberNote)(http.HandlerFunc(memberNoteHandler.Resolve)))))
+	mux.Handle("GET /api/v1/users", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersView)(http.HandlerFunc(userHandler.List))))
+	mux.Handle("GET /api/v1/users/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersView)(http.HandlerFunc(userHandler.GetByID))))
+	mux.Handle("PUT /api/v1/users/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(userHandler.Update)))))
+	mux.Handle("POST /api/v1/users/{id}/invitation", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(userHandler.AddInvitation)))))
+	mux.Handle("DELETE /api/v1/users/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersDelete)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(userHandler.Delete)))))
+	mux.Handle("DELETE /api/v1/users/{id}/2fa", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSessionsManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(twoFactorHandler.Reset)))))
+	mux.Handle("GET /api/v1/users/{id}/sessions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSessionsManage)(http.HandlerFunc(sessionHandler.UserSessions))))
+	mux.Handle("DELETE /api/v1/users/{id}/sessions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSessionsManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(sessionHandler.RevokeAllUserSessions)))))
+	mux.Handle("DELETE /api/v1/users/{id}/sessions/{sessionId}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSessionsManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(sessionHandler.RevokeUserSession)))))
+	mux.Handle("GET /api/v1/users/{id}/statement", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReceiptsSend)(http.HandlerFunc(receiptHandler.UserStatement))))
+	mux.Handle("POST /api/v1/users/{id}/statement/email", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReceiptsSend)(http.HandlerFunc(receiptHandler.EmailUserStatement))))
+
+	// Solicitudes de eliminación de cuenta (anonimización con periodo de gracia)
+	mux.Handle("GET /api/v1/erasure-requests", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersDelete)(http.HandlerFunc(privacyHandler.ListErasures))))
+	mux.Handle("POST /api/v1/erasure-requests/{id}/approve", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersDelete)(middleware.Audit(models.AuditEntityErasure)(http.HandlerFunc(privacyHandler.ApproveErasure)))))
+	mux.Handle("POST /api/v1/erasure-requests/{id}/reject", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersDelete)(middleware.Audit(models.AuditEntityErasure)(http.HandlerFunc(privacyHandler.RejectErasure)))))
+
+	// Impersonación de soporte
+	mux.Handle("GET /api/v1/impersonations", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersImpersonate)(http.HandlerFunc(impersonationHandler.List))))
+	mux.Handle("POST /api/v1/impersonations", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersImpersonate)(middleware.Audit(models.AuditEntityImpersonation)(http.HandlerFunc(impersonationHandler.Start)))))
+	mux.Handle("POST /api/v1/impersonations/{id}/end", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersImpersonate)(middleware.Audit(models.AuditEntityImpersonation)(http.HandlerFunc(impersonationHandler.End)))))
 
 	// Discount codes (admin CRUD + authenticated validate)
-	mux.Handle("GET /api/v1/discount-codes", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(discountHandler.List))))
-	mux.Handle("POST /api/v1/discount-codes", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(discountHandler.Create))))
-	mux.Handle("DELETE /api/v1/discount-codes/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(discountHandler.Delete))))
+	mux.Handle("GET /api/v1/discount-codes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermDiscountsManage)(http.HandlerFunc(discountHandler.List))))
+	mux.Handle("POST /api/v1/discount-codes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermDiscountsManage)(middleware.Audit(models.AuditEntityDiscountCode)(http.HandlerFunc(discountHandler.Create)))))
+	mux.Handle("DELETE /api/v1/discount-codes/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermDiscountsManage)(middleware.Audit(models.AuditEntityDiscountCode)(http.HandlerFunc(discountHandler.Delete)))))
 	mux.Handle("GET /api/v1/discount-codes/validate", middleware.Auth(cfg)(http.HandlerFunc(discountHandler.Validate)))
 
 	// Badges
@@ -204,26 +510,26 @@ func main() {
 	mux.Handle("GET /api/v1/challenges", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.List)))
 	mux.Handle("GET /api/v1/challenges/mine", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.MyChallenges)))
 	mux.Handle("GET /api/v1/challenges/{id}", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.GetByID)))
-	mux.Handle("POST /api/v1/challenges", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(challengeHandler.Create))))
-	mux.Handle("PUT /api/v1/challenges/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(challengeHandler.Update))))
-	mux.Handle("DELETE /api/v1/challenges/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(challengeHandler.Delete))))
+	mux.Handle("POST /api/v1/challenges", middleware.Auth(cfg)(middleware.RequirePermission(models.PermChallengesManage)(middleware.Audit(models.AuditEntityChallenge)(http.HandlerFunc(challengeHandler.Create)))))
+	mux.Handle("PUT /api/v1/challenges/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermChallengesManage)(middleware.Audit(models.AuditEntityChallenge)(http.HandlerFunc(challengeHandler.Update)))))
+	mux.Handle("DELETE /api/v1/challenges/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermChallengesManage)(middleware.Audit(models.AuditEntityChallenge)(http.HandlerFunc(challengeHandler.Delete)))))
 	mux.Handle("POST /api/v1/challenges/{id}/join", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.Join)))
 	mux.Handle("DELETE /api/v1/challenges/{id}/join", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.Leave)))
 	mux.Handle("POST /api/v1/challenges/{id}/progress", middleware.Auth(cfg)(http.HandlerFunc(challengeHandler.SubmitProgress)))
 
 	// Stats (admin only)
-	mux.Handle("GET /api/v1/stats/dashboard", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Dashboard))))
-	mux.Handle("GET /api/v1/stats/attendance", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Attendance))))
-	mux.Handle("GET /api/v1/stats/revenue", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Revenue))))
-	mux.Handle("GET /api/v1/stats/plans", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Plans))))
-	mux.Handle("GET /api/v1/stats/users", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Users))))
-	mux.Handle("GET /api/v1/stats/classes", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Classes))))
-	mux.Handle("GET /api/v1/stats/report", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.MonthlyReport))))
-	mux.Handle("GET /api/v1/stats/retention", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.Retention))))
+	mux.Handle("GET /api/v1/stats/dashboard", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Dashboard))))
+	mux.Handle("GET /api/v1/stats/attendance", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Attendance))))
+	mux.Handle("GET /api/v1/stats/revenue", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Revenue))))
+	mux.Handle("GET /api/v1/stats/plans", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Plans))))
+	mux.Handle("GET /api/v1/stats/users", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Users))))
+	mux.Handle("GET /api/v1/stats/classes", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Classes))))
+	mux.Handle("GET /api/v1/stats/report", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.MonthlyReport))))
+	mux.Handle("GET /api/v1/stats/retention", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(statsHandler.Retention))))
 
 	// Exports (admin only)
-	mux.Handle("GET /api/v1/export/users", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.ExportUsers))))
-	mux.Handle("GET /api/v1/export/revenue", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(statsHandler.ExportRevenue))))
+	mux.Handle("GET /api/v1/export/users", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReportsExport)(http.HandlerFunc(statsHandler.ExportUsers))))
+	mux.Handle("GET /api/v1/export/revenue", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReportsExport)(http.HandlerFunc(statsHandler.ExportRevenue))))
 
 	// Upload
 	mux.Handle("POST /api/v1/upload", middleware.Auth(cfg)(http.HandlerFunc(uploadHandler.Upload)))
@@ -232,10 +538,10 @@ func main() {
 	mux.Handle("GET /uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.UploadDir))))
 
 	// Leads / pipeline (admin only)
-	mux.Handle("GET /api/v1/leads", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(leadHandler.List))))
-	mux.Handle("POST /api/v1/leads", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(leadHandler.Create))))
-	mux.Handle("PUT /api/v1/leads/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(leadHandler.Update))))
-	mux.Handle("DELETE /api/v1/leads/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(leadHandler.Delete))))
+	mux.Handle("GET /api/v1/leads", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLeadsManage)(http.HandlerFunc(leadHandler.List))))
+	mux.Handle("POST /api/v1/leads", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLeadsManage)(middleware.Audit(models.AuditEntityLead)(http.HandlerFunc(leadHandler.Create)))))
+	mux.Handle("PUT /api/v1/leads/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLeadsManage)(middleware.Audit(models.AuditEntityLead)(http.HandlerFunc(leadHandler.Update)))))
+	mux.Handle("DELETE /api/v1/leads/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLeadsManage)(middleware.Audit(models.AuditEntityLead)(http.HandlerFunc(leadHandler.Delete)))))
 
 	// Body tracking (authenticated user)
 	mux.Handle("GET /api/v1/body-tracking", middleware.Auth(cfg)(http.HandlerFunc(bodyHandler.List)))
@@ -249,49 +555,92 @@ func main() {
 
 	// On-ramp programs
 	mux.Handle("GET /api/v1/onramp/programs", middleware.Auth(cfg)(http.HandlerFunc(onrampHandler.ListPrograms)))
-	mux.Handle("POST /api/v1/onramp/programs", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.CreateProgram))))
-	mux.Handle("PUT /api/v1/onramp/programs/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.UpdateProgram))))
-	mux.Handle("DELETE /api/v1/onramp/programs/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.DeleteProgram))))
-	mux.Handle("POST /api/v1/onramp/enroll", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.Enroll))))
-	mux.Handle("PUT /api/v1/onramp/users/{userId}/programs/{programId}/sessions", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.UpdateSessions))))
-	mux.Handle("GET /api/v1/onramp/programs/{id}/enrollments", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(onrampHandler.ListEnrollments))))
+	mux.Handle("POST /api/v1/onramp/programs", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(middleware.Audit(models.AuditEntityOnrampProgram)(http.HandlerFunc(onrampHandler.CreateProgram)))))
+	mux.Handle("PUT /api/v1/onramp/programs/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(middleware.Audit(models.AuditEntityOnrampProgram)(http.HandlerFunc(onrampHandler.UpdateProgram)))))
+	mux.Handle("DELETE /api/v1/onramp/programs/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(middleware.Audit(models.AuditEntityOnrampProgram)(http.HandlerFunc(onrampHandler.DeleteProgram)))))
+	mux.Handle("POST /api/v1/onramp/enroll", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(middleware.Audit(models.AuditEntityOnramp)(http.HandlerFunc(onrampHandler.Enroll)))))
+	mux.Handle("PUT /api/v1/onramp/users/{userId}/programs/{programId}/sessions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(middleware.Audit(models.AuditEntityOnramp)(http.HandlerFunc(onrampHandler.UpdateSessions)))))
+	mux.Handle("GET /api/v1/onramp/programs/{id}/enrollments", middleware.Auth(cfg)(middleware.RequirePermission(models.PermOnrampManage)(http.HandlerFunc(onrampHandler.ListEnrollments))))
 	mux.Handle("GET /api/v1/onramp/me", middleware.Auth(cfg)(http.HandlerFunc(onrampHandler.MyEnrollments)))
 
 	// Movements / biblioteca (2.4 — public read, admin write)
 	mux.Handle("GET /api/v1/movements", middleware.Auth(cfg)(http.HandlerFunc(movementHandler.List)))
 	mux.Handle("GET /api/v1/movements/{id}", middleware.Auth(cfg)(http.HandlerFunc(movementHandler.GetByID)))
-	mux.Handle("POST /api/v1/movements", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(movementHandler.Create))))
-	mux.Handle("PUT /api/v1/movements/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(movementHandler.Update))))
-	mux.Handle("DELETE /api/v1/movements/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(movementHandler.Delete))))
+	mux.Handle("POST /api/v1/movements", middleware.Auth(cfg)(middleware.RequirePermission(models.PermMovementsManage)(middleware.Audit(models.AuditEntityMovement)(http.HandlerFunc(movementHandler.Create)))))
+	mux.Handle("PUT /api/v1/movements/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermMovementsManage)(middleware.Audit(models.AuditEntityMovement)(http.HandlerFunc(movementHandler.Update)))))
+	mux.Handle("DELETE /api/v1/movements/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermMovementsManage)(middleware.Audit(models.AuditEntityMovement)(http.HandlerFunc(movementHandler.Delete)))))
 
 	// Events / competencias (16.1-16.4)
 	mux.Handle("GET /api/v1/events", middleware.Auth(cfg)(http.HandlerFunc(eventHandler.List)))
 	mux.Handle("GET /api/v1/events/me", middleware.Auth(cfg)(http.HandlerFunc(eventHandler.MyEvents)))
 	mux.Handle("GET /api/v1/events/{id}", middleware.Auth(cfg)(http.HandlerFunc(eventHandler.GetByID)))
-	mux.Handle("POST /api/v1/events", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(eventHandler.Create))))
-	mux.Handle("PUT /api/v1/events/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(eventHandler.Update))))
-	mux.Handle("DELETE /api/v1/events/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(eventHandler.Delete))))
+	mux.Handle("POST /api/v1/events", middleware.Auth(cfg)(middleware.RequirePermission(models.PermEventsManage)(middleware.Audit(models.AuditEntityEvent)(http.HandlerFunc(eventHandler.Create)))))
+	mux.Handle("PUT /api/v1/events/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermEventsManage)(middleware.Audit(models.AuditEntityEvent)(http.HandlerFunc(eventHandler.Update)))))
+	mux.Handle("DELETE /api/v1/events/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermEventsManage)(middleware.Audit(models.AuditEntityEvent)(http.HandlerFunc(eventHandler.Delete)))))
 	mux.Handle("POST /api/v1/events/{id}/register", middleware.Auth(cfg)(http.HandlerFunc(eventHandler.Register)))
 	mux.Handle("DELETE /api/v1/events/{id}/register", middleware.Auth(cfg)(http.HandlerFunc(eventHandler.Unregister)))
-	mux.Handle("GET /api/v1/events/{id}/registrations", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(eventHandler.ListRegistrations))))
-	mux.Handle("PUT /api/v1/events/{id}/registrations", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(eventHandler.UpdateRegistration))))
+	mux.Handle("GET /api/v1/events/{id}/registrations", middleware.Auth(cfg)(middleware.RequirePermission(models.PermEventsManage)(http.HandlerFunc(eventHandler.ListRegistrations))))
+	mux.Handle("PUT /api/v1/events/{id}/registrations", middleware.Auth(cfg)(middleware.RequirePermission(models.PermEventsManage)(middleware.Audit(models.AuditEntityEvent)(http.HandlerFunc(eventHandler.UpdateRegistration)))))
 
 	// Products / Retail POS (5.6)
 	mux.Handle("GET /api/v1/products", middleware.Auth(cfg)(http.HandlerFunc(productHandler.ListProducts)))
-	mux.Handle("POST /api/v1/products", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(productHandler.CreateProduct))))
-	mux.Handle("PUT /api/v1/products/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(productHandler.UpdateProduct))))
-	mux.Handle("DELETE /api/v1/products/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(productHandler.DeleteProduct))))
-	mux.Handle("GET /api/v1/sales", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(productHandler.ListSales))))
-	mux.Handle("POST /api/v1/sales", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(productHandler.CreateSale))))
+	mux.Handle("POST /api/v1/products", middleware.Auth(cfg)(middleware.RequirePermission(models.PermProductsManage)(middleware.Audit(models.AuditEntityProduct)(http.HandlerFunc(productHandler.CreateProduct)))))
+	mux.Handle("PUT /api/v1/products/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermProductsManage)(middleware.Audit(models.AuditEntityProduct)(http.HandlerFunc(productHandler.UpdateProduct)))))
+	mux.Handle("DELETE /api/v1/products/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermProductsManage)(middleware.Audit(models.AuditEntityProduct)(http.HandlerFunc(productHandler.DeleteProduct)))))
+	mux.Handle("GET /api/v1/sales", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSalesView)(http.HandlerFunc(productHandler.ListSales))))
+	mux.Handle("POST /api/v1/sales", middleware.Auth(cfg)(middleware.RequirePermission(models.PermSalesCreate)(middleware.Audit(models.AuditEntitySale)(http.HandlerFunc(productHandler.CreateSale)))))
+	mux.Handle("GET /api/v1/sales/{id}/receipt", middleware.Auth(cfg)(middleware.NoImpersonation(http.HandlerFunc(receiptHandler.SaleReceipt))))
+	mux.Handle("POST /api/v1/sales/{id}/receipt/email", middleware.Auth(cfg)(middleware.RequirePermission(models.PermReceiptsSend)(http.HandlerFunc(receiptHandler.EmailSaleReceipt))))
+
+	// Cuadratura de caja (admin)
+	mux.Handle("POST /api/v1/cash/sessions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashManage)(middleware.Audit(models.AuditEntityCashSession)(http.HandlerFunc(cashHandler.Open)))))
+	mux.Handle("GET /api/v1/cash/sessions", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashView)(http.HandlerFunc(cashHandler.List))))
+	mux.Handle("GET /api/v1/cash/sessions/current", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashView)(http.HandlerFunc(cashHandler.Current))))
+	mux.Handle("GET /api/v1/cash/sessions/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashView)(http.HandlerFunc(cashHandler.Get))))
+	mux.Handle("GET /api/v1/cash/sessions/{id}/print", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashView)(http.HandlerFunc(cashHandler.Print))))
+	mux.Handle("POST /api/v1/cash/sessions/{id}/movements", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashManage)(middleware.Audit(models.AuditEntityCashSession)(http.HandlerFunc(cashHandler.AddMovement)))))
+	mux.Handle("POST /api/v1/cash/sessions/{id}/close", middleware.Auth(cfg)(middleware.RequirePermission(models.PermCashManage)(middleware.Audit(models.AuditEntityCashSession)(http.HandlerFunc(cashHandler.Close)))))
+
+	// Cuenta del miembro y gift cards
+	mux.Handle("GET /api/v1/ledger/me", middleware.Auth(cfg)(http.HandlerFunc(ledgerHandler.MyStatement)))
+	mux.Handle("GET /api/v1/ledger/balances", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerView)(http.HandlerFunc(ledgerHandler.Balances))))
+	mux.Handle("GET /api/v1/ledger/users/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerView)(http.HandlerFunc(ledgerHandler.UserStatement))))
+	mux.Handle("POST /api/v1/ledger/users/{id}/entries", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerManage)(middleware.Audit(models.AuditEntityLedger)(http.HandlerFunc(ledgerHandler.CreateEntry)))))
+	mux.Handle("POST /api/v1/ledger/users/{id}/settle", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerManage)(middleware.Audit(models.AuditEntityLedger)(http.HandlerFunc(ledgerHandler.Settle)))))
+	mux.Handle("GET /api/v1/gift-cards", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerView)(http.HandlerFunc(ledgerHandler.ListGiftCards))))
+	mux.Handle("POST /api/v1/gift-cards/redeem", middleware.Auth(cfg)(middleware.Audit(models.AuditEntityGiftCard)(http.HandlerFunc(ledgerHandler.RedeemGiftCard))))
+
+	// Programación personalizada: entregas, cierre mensual e ingresos por coach
+	mux.Handle("GET /api/v1/custom-programs", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerView)(http.HandlerFunc(customProgramHandler.List))))
+	mux.Handle("GET /api/v1/custom-programs/settings", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerView)(http.HandlerFunc(customProgramHandler.GetSettings))))
+	mux.Handle("PUT /api/v1/custom-programs/settings", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerManage)(middleware.Audit(models.AuditEntitySettings)(http.HandlerFunc(customProgramHandler.UpdateSettings)))))
+	mux.Handle("GET /api/v1/custom-programs/revenue", middleware.Auth(cfg)(middleware.RequirePermission(models.PermStatsView)(http.HandlerFunc(customProgramHandler.Revenue))))
+	mux.Handle("POST /api/v1/custom-programs/bill", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerManage)(middleware.Audit(models.AuditEntityCustomProgram)(http.HandlerFunc(customProgramHandler.Bill)))))
+	mux.Handle("POST /api/v1/custom-programs/{id}/void", middleware.Auth(cfg)(middleware.RequirePermission(models.PermLedgerManage)(middleware.Audit(models.AuditEntityCustomProgram)(http.HandlerFunc(customProgramHandler.Void)))))
+	mux.Handle("GET /api/v1/users/me/programs", middleware.Auth(cfg)(http.HandlerFunc(customProgramHandler.MyPrograms)))
+	mux.Handle("POST /api/v1/users/me/programs/{id}/acknowledge", middleware.Auth(cfg)(http.HandlerFunc(customProgramHandler.Acknowledge)))
+
+	// Boletas y facturas electrónicas
+	mux.Handle("POST /api/v1/tax/cafs", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxManage)(middleware.Audit(models.AuditEntityTaxCAF)(http.HandlerFunc(taxHandler.ImportCAF)))))
+	mux.Handle("GET /api/v1/tax/cafs", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxManage)(http.HandlerFunc(taxHandler.ListCAFs))))
+	mux.Handle("POST /api/v1/tax/documents", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxManage)(middleware.Audit(models.AuditEntityTaxDocument)(http.HandlerFunc(taxHandler.Issue)))))
+	mux.Handle("GET /api/v1/tax/documents", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxView)(http.HandlerFunc(taxHandler.List))))
+	mux.Handle("GET /api/v1/tax/documents/me", middleware.Auth(cfg)(http.HandlerFunc(taxHandler.MyDocuments)))
+	mux.Handle("GET /api/v1/tax/documents/{id}", middleware.Auth(cfg)(http.HandlerFunc(taxHandler.Get)))
+	mux.Handle("GET /api/v1/tax/documents/{id}/pdf", middleware.Auth(cfg)(http.HandlerFunc(taxHandler.PDF)))
+	mux.Handle("GET /api/v1/tax/documents/{id}/xml", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxView)(http.HandlerFunc(taxHandler.XML))))
+	mux.Handle("POST /api/v1/tax/documents/{id}/send", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTaxManage)(middleware.Audit(models.AuditEntityTaxDocument)(http.HandlerFunc(taxHandler.Send)))))
+	mux.Handle("GET /api/v1/users/me/billing", middleware.Auth(cfg)(http.HandlerFunc(taxHandler.GetBillingProfile)))
+	mux.Handle("PUT /api/v1/users/me/billing", middleware.Auth(cfg)(http.HandlerFunc(taxHandler.UpdateBillingProfile)))
 
 	// Tags de miembros (6.8)
-	mux.Handle("GET /api/v1/tags", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.ListTags))))
-	mux.Handle("POST /api/v1/tags", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.CreateTag))))
-	mux.Handle("PUT /api/v1/tags/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.UpdateTag))))
-	mux.Handle("DELETE /api/v1/tags/{id}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.DeleteTag))))
-	mux.Handle("GET /api/v1/users/{userId}/tags", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.GetUserTags))))
-	mux.Handle("POST /api/v1/users/{userId}/tags", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.AddUserTag))))
-	mux.Handle("DELETE /api/v1/users/{userId}/tags/{tagId}", middleware.Auth(cfg)(middleware.AdminOnly(http.HandlerFunc(tagHandler.RemoveUserTag))))
+	mux.Handle("GET /api/v1/tags", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(http.HandlerFunc(tagHandler.ListTags))))
+	mux.Handle("POST /api/v1/tags", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(middleware.Audit(models.AuditEntityTag)(http.HandlerFunc(tagHandler.CreateTag)))))
+	mux.Handle("PUT /api/v1/tags/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(middleware.Audit(models.AuditEntityTag)(http.HandlerFunc(tagHandler.UpdateTag)))))
+	mux.Handle("DELETE /api/v1/tags/{id}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(middleware.Audit(models.AuditEntityTag)(http.HandlerFunc(tagHandler.DeleteTag)))))
+	mux.Handle("GET /api/v1/users/{userId}/tags", middleware.Auth(cfg)(middleware.RequirePermission(models.PermUsersView)(http.HandlerFunc(tagHandler.GetUserTags))))
+	mux.Handle("POST /api/v1/users/{userId}/tags", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(tagHandler.AddUserTag)))))
+	mux.Handle("DELETE /api/v1/users/{userId}/tags/{tagId}", middleware.Auth(cfg)(middleware.RequirePermission(models.PermTagsManage)(middleware.Audit(models.AuditEntityUser)(http.HandlerFunc(tagHandler.RemoveUserTag)))))
 
 	// Nutrition tracking (9.4-9.7)
 	mux.Handle("GET /api/v1/nutrition", middleware.Auth(cfg)(http.HandlerFunc(nutritionHandler.GetDay)))
@@ -301,21 +650,10 @@ func main() {
 	mux.Handle("DELETE /api/v1/nutrition/water/{id}", middleware.Auth(cfg)(http.HandlerFunc(nutritionHandler.DeleteWater)))
 
 	// Lead capture público (6.2 — no auth)
-	mux.HandleFunc("POST /api/v1/public/leads", leadHandler.PublicCapture)
+	mux.Handle("POST /api/v1/public/leads", publicLimiter.PerIP(http.HandlerFunc(leadHandler.PublicCapture)))
 
 	// TV Display (public)
 	mux.HandleFunc("GET /api/v1/tv/today", tvHandler.GetToday)
 
-	// Health check
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
-
-	handler := middleware.CORS(middleware.Logger(mux))
-
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
-		log.Fatal("Server failed:", err)
-	}
+	return middleware.WithScope(scope)(mux)
 }
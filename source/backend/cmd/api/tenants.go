@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"sync"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// tenantSource - Registro de tenants y apertura de sus pools (services.TenantService)
+type tenantSource interface {
+	List() ([]*models.Tenant, error)
+	Get(slug string) (*models.Tenant, error)
+	Open(t *models.Tenant) (*sql.DB, error)
+}
+
+// tenantRouter resuelve el tenant de cada request y lo atiende con la API construida sobre su schema.
+// Las APIs se construyen al primer uso y se descartan cuando cambia la configuración del tenant.
+type tenantRouter struct {
+	cfg     *config.Config
+	tenants tenantSource
+	build   func(db *sql.DB, cfg *config.Config) http.Handler
+
+	mu   sync.Mutex
+	dbs  map[string]*sql.DB
+	apis map[string]http.Handler
+}
+
+func newTenantRouter(cfg *config.Config, tenants tenantSource) *tenantRouter {
+	return &tenantRouter{cfg: cfg, tenants: tenants, build: buildAPI, dbs: map[string]*sql.DB{}, apis: map[string]http.Handler{}}
+}
+
+// StartAll construye la API de cada tenant activo al iniciar, para que sus workers (purga de auditoría,
+// anonimización, expiración de pagos) corran aunque el tenant no reciba tráfico
+func (t *tenantRouter) StartAll() {
+	list, err := t.tenants.List()
+	if err != nil {
+		log.Printf("[TENANT] list: %v", err)
+		return
+	}
+	for _, tenant := range list {
+		if !tenant.Active {
+			continue
+		}
+		if _, err := t.api(tenant.Slug); err != nil {
+			log.Printf("[TENANT] %s: %v", tenant.Slug, err)
+		}
+	}
+}
+
+func (t *tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	slug, err := middleware.TenantSlug(r, t.cfg.TenantBaseDomain)
+	if err != nil {
+		http.Error(w, `{"error":"Tenant header does not match the host"}`, http.StatusBadRequest)
+		return
+	}
+	if slug == "" {
+		http.Error(w, `{"error":"Tenant not specified"}`, http.StatusNotFound)
+		return
+	}
+	api, err := t.api(slug)
+	switch err {
+	case nil:
+		api.ServeHTTP(w, r)
+	case services.ErrTenantNotFound:
+		http.Error(w, `{"error":"Unknown tenant"}`, http.StatusNotFound)
+	case services.ErrTenantSuspended:
+		http.Error(w, `{"error":"Tenant suspended"}`, http.StatusForbidden)
+	default:
+		log.Printf("[TENANT] %s: %v", slug, err)
+		http.Error(w, `{"error":"Tenant unavailable"}`, http.StatusServiceUnavailable)
+	}
+}
+
+func (t *tenantRouter) api(slug string) (http.Handler, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if api, ok := t.apis[slug]; ok {
+		return api, nil
+	}
+	tenant, err := t.tenants.Get(slug)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.Active {
+		return nil, services.ErrTenantSuspended
+	}
+	db, ok := t.dbs[tenant.Slug]
+	if !ok {
+		if db, err = t.tenants.Open(tenant); err != nil {
+			return nil, err
+		}
+		t.dbs[tenant.Slug] = db
+	}
+	api := t.build(db, services.TenantConfig(t.cfg, tenant))
+	t.apis[tenant.Slug] = api
+	return api, nil
+}
+
+// Forget descarta la API del tenant para reconstruirla con su configuración nueva (el pool se reutiliza)
+func (t *tenantRouter) Forget(slug string) {
+	t.mu.Lock()
+	delete(t.apis, slug)
+	t.mu.Unlock()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// fakeTenants - Registro de tenants en memoria; Open no abre conexiones reales
+type fakeTenants map[string]*models.Tenant
+
+func (f fakeTenants) List() ([]*models.Tenant, error) {
+	var list []*models.Tenant
+	for _, t := range f {
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+func (f fakeTenants) Get(slug string) (*models.Tenant, error) {
+	if t, ok := f[slug]; ok {
+		return t, nil
+	}
+	return nil, services.ErrTenantNotFound
+}
+
+func (f fakeTenants) Open(t *models.Tenant) (*sql.DB, error) { return nil, nil }
+
+func newTestRouter(built *[]string) *tenantRouter {
+	tenants := fakeTenants{
+		"boxnorte": {Slug: "boxnorte", Schema: "tenant_boxnorte", Active: true},
+		"boxsur":   {Slug: "boxsur", Schema: "tenant_boxsur", Active: true},
+		"cerrado":  {Slug: "cerrado", Schema: "tenant_cerrado", Active: false},
+	}
+	router := newTenantRouter(&config.Config{TenantBaseDomain: "example.com"}, tenants)
+	router.build = func(db *sql.DB, cfg *config.Config) http.Handler {
+		*built = append(*built, cfg.TenantSlug)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(cfg.TenantSlug))
+		})
+	}
+	return router
+}
+
+func TestTenantRouter_Resolution(t *testing.T) {
+	var built []string
+	router := newTestRouter(&built)
+
+	cases := []struct {
+		name   string
+		host   string
+		header string
+		status int
+		body   string
+	}{
+		{"subdomain", "boxnorte.example.com", "", http.StatusOK, "boxnorte"},
+		{"header", "api.example.org", "boxsur", http.StatusOK, "boxsur"},
+		{"header conflicts with subdomain", "boxnorte.example.com", "boxsur", http.StatusBadRequest, ""},
+		{"unknown tenant", "nadie.example.com", "", http.StatusNotFound, ""},
+		{"suspended tenant", "cerrado.example.com", "", http.StatusForbidden, ""},
+		{"no tenant", "api.example.org", "", http.StatusNotFound, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/v1/plans", nil)
+		req.Host = c.host
+		if c.header != "" {
+			req.Header.Set("X-Tenant", c.header)
+		}
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, rr.Code)
+		}
+		if c.body != "" && rr.Body.String() != c.body {
+			t.Errorf("%s: served by %q, want %q", c.name, rr.Body.String(), c.body)
+		}
+	}
+	for _, slug := range built {
+		if slug == "cerrado" || slug == "nadie" {
+			t.Errorf("API built for rejected tenant %q", slug)
+		}
+	}
+}
+
+func TestTenantRouter_StartAllBuildsActiveTenants(t *testing.T) {
+	var built []string
+	router := newTestRouter(&built)
+
+	router.StartAll()
+
+	if len(built) != 2 {
+		t.Fatalf("expected APIs for the 2 active tenants, got %v", built)
+	}
+	for _, slug := range built {
+		if slug == "cerrado" {
+			t.Fatal("suspended tenant must not start workers")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"boxmagic/internal/jwtkeys"
+)
+
+func usage() {
+	fmt.Println("Uso: go run ./cmd/jwtkeys [-dir DIR] [-alg RS256|EdDSA] <comando> [kid]")
+	fmt.Println("Comandos:")
+	fmt.Println("  generate        crea una clave nueva sin activarla (las réplicas ya la aceptan al recargar)")
+	fmt.Println("  rotate          crea una clave nueva y la activa para firmar")
+	fmt.Println("  activate <kid>  firma con una clave existente")
+	fmt.Println("  remove <kid>    retira una clave; los tokens firmados con ella dejan de ser válidos")
+	fmt.Println("  list            muestra las claves y cuál está activa")
+	fmt.Println("Ejemplo: go run ./cmd/jwtkeys -dir ./keys rotate")
+}
+
+func main() {
+	dir := flag.String("dir", envOr("JWT_KEYS_DIR", "./keys"), "directorio de claves")
+	alg := flag.String("alg", jwtkeys.AlgEdDSA, "algoritmo de las claves nuevas (RS256 o EdDSA)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch cmd := flag.Arg(0); cmd {
+	case "generate", "rotate":
+		var kid string
+		kid, err = jwtkeys.Generate(*dir, *alg)
+		if err != nil {
+			break
+		}
+		fmt.Printf("Clave generada: %s (%s)\n", kid, *alg)
+		if _, readErr := jwtkeys.ReadActive(*dir); cmd == "rotate" || readErr != nil {
+			// La primera clave del directorio se activa siempre
+			if err = jwtkeys.Activate(*dir, kid); err == nil {
+				fmt.Printf("Clave activa: %s\n", kid)
+			}
+		}
+	case "activate":
+		if err = requireKid(); err == nil {
+			if err = jwtkeys.Activate(*dir, flag.Arg(1)); err == nil {
+				fmt.Printf("Clave activa: %s\n", flag.Arg(1))
+			}
+		}
+	case "remove":
+		if err = requireKid(); err == nil {
+			if err = jwtkeys.Remove(*dir, flag.Arg(1)); err == nil {
+				fmt.Printf("Clave eliminada: %s\n", flag.Arg(1))
+			}
+		}
+	case "list":
+		var keys *jwtkeys.KeySet
+		if keys, err = jwtkeys.Load(*dir); err == nil {
+			for i, k := range keys.JWKS() {
+				mark := ""
+				if i == 0 {
+					mark = " (activa)"
+				}
+				fmt.Printf("%s  %s%s\n", k.Kid, k.Alg, mark)
+			}
+		}
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func requireKid() error {
+	if flag.NArg() < 2 {
+		return fmt.Errorf("missing kid")
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
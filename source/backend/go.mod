@@ -5,8 +5,8 @@ go 1.24.0
 toolchain go1.24.12
 
 require (
-	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.11.1 // indirect
-	golang.org/x/crypto v0.47.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.11.1
+	golang.org/x/crypto v0.47.0
 )
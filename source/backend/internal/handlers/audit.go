@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// AuditHandler - Consulta y exportación del log de auditoría
+type AuditHandler struct {
+	auditRepo    *repository.AuditRepository
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditRepo *repository.AuditRepository, auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditRepo: auditRepo, auditService: auditService}
+}
+
+// parseAuditFilter lee ?actor_id=&entity_type=&entity_id=&action=&from=YYYY-MM-DD&to=YYYY-MM-DD
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	f := models.AuditFilter{
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		Action:     q.Get("action"),
+	}
+	if a := q.Get("actor_id"); a != "" {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("invalid actor_id")
+		}
+		f.ActorID = &id
+	}
+	if from := q.Get("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, fmt.Errorf("invalid from date (YYYY-MM-DD)")
+		}
+		f.From = &t
+	}
+	if to := q.Get("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, fmt.Errorf("invalid to date (YYYY-MM-DD)")
+		}
+		t = t.AddDate(0, 0, 1) // inclusivo
+		f.To = &t
+	}
+	return f, nil
+}
+
+// List - Log de auditoría filtrable
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	// La retención se aplica al consultar, igual que la expiración de transferencias
+	h.auditService.PurgeExpired()
+
+	entries, err := h.auditRepo.List(filter, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":        entries,
+		"limit":          limit,
+		"offset":         offset,
+		"retention_days": h.auditService.RetentionDays(),
+	})
+}
+
+// Export - CSV con los mismos filtros que List
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entries, err := h.auditRepo.List(filter, 0, 0)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit_%s.csv", time.Now().Format("20060102")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"ID", "Date", "Actor ID", "Actor", "Role", "Action", "Entity", "Entity ID", "Changes", "IP", "Path", "On Behalf Of"})
+	for _, e := range entries {
+		onBehalfOf := ""
+		if e.OnBehalfOf != nil {
+			onBehalfOf = strconv.FormatInt(*e.OnBehalfOf, 10)
+		}
+		writer.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.FormatInt(e.ActorID, 10),
+			e.ActorName,
+			string(e.ActorRole),
+			e.Action,
+			e.EntityType,
+			e.EntityID,
+			formatAuditChanges(e.Changes),
+			e.IP,
+			e.Method + " " + e.Path,
+			onBehalfOf,
+		})
+	}
+	writer.Flush()
+}
+
+// formatAuditChanges resume el diff como "campo: antes -> después; ..."
+func formatAuditChanges(changes map[string]models.AuditChange) string {
+	fields := make([]string, 0, len(changes))
+	for k := range changes {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, k := range fields {
+		c := changes[k]
+		parts = append(parts, fmt.Sprintf("%s: %s -> %s", k, auditValue(c.Before), auditValue(c.After)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func auditValue(v json.RawMessage) string {
+	if len(v) == 0 {
+		return "∅"
+	}
+	return string(v)
+}
@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"boxmagic/internal/middleware"
 	"boxmagic/internal/models"
 	"boxmagic/internal/services"
 )
@@ -12,17 +17,30 @@ import (
 type AuthServicer interface {
 	Register(req *models.RegisterRequest) (*models.AuthResponse, error)
 	Login(req *models.LoginRequest) (*models.AuthResponse, error)
-	Refresh(refreshToken string) (*models.AuthResponse, error)
+	Refresh(req *models.RefreshRequest) (*models.AuthResponse, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(token, password string) error
+	VerifyEmail(token string) error
+	ResendVerification(userID int64) error
+	ChangePassword(userID int64, req *models.ChangePasswordRequest) (*models.AuthResponse, error)
+	Logout(userID, sessionID int64) error
+	LogoutAll(userID int64) error
 }
 
 type AuthHandler struct {
-	authService AuthServicer
+	authService    AuthServicer
+	accountLimiter *middleware.Limiter
 }
 
 func NewAuthHandler(authService AuthServicer) *AuthHandler {
 	return &AuthHandler{authService: authService}
 }
 
+// SetAccountLimiter limita los intentos de login por cuenta (email), además del límite por IP
+func (h *AuthHandler) SetAccountLimiter(l *middleware.Limiter) {
+	h.accountLimiter = l
+}
+
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -35,12 +53,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Client = clientInfo(r)
 	resp, err := h.authService.Register(&req)
 	if err != nil {
 		if err == services.ErrUserExists {
 			respondError(w, http.StatusConflict, "User already exists")
 			return
 		}
+		if err == services.ErrWeakPassword {
+			respondError(w, http.StatusBadRequest, "Password must be at least 8 characters")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "Failed to register user")
 		return
 	}
@@ -55,7 +78,16 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.accountLimiter.Allow(w, "account:"+strings.ToLower(strings.TrimSpace(req.Email))) {
+		return
+	}
+
+	req.Client = clientInfo(r)
+	req.Client.Remember = req.RememberDevice
 	resp, err := h.authService.Login(&req)
+	if respondAccountLocked(w, err) {
+		return
+	}
 	if err != nil {
 		respondError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
@@ -64,6 +96,17 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, resp)
 }
 
+// respondAccountLocked responde 429 con Retry-After si la cuenta está bloqueada por intentos fallidos
+func respondAccountLocked(w http.ResponseWriter, err error) bool {
+	var locked *services.AccountLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(locked.Until).Seconds())+1))
+	respondError(w, http.StatusTooManyRequests, "Account temporarily locked due to failed login attempts")
+	return true
+}
+
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var req models.RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -71,7 +114,8 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.authService.Refresh(req.RefreshToken)
+	req.Client = clientInfo(r)
+	resp, err := h.authService.Refresh(&req)
 	if err != nil {
 		respondError(w, http.StatusUnauthorized, "Invalid refresh token")
 		return
@@ -80,6 +124,124 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, resp)
 }
 
+// ForgotPassword - Envía el link de recuperación; responde igual exista o no el email
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		respondError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(req.Email); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to request password reset")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "If the email exists, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		respondError(w, http.StatusBadRequest, "Token and password are required")
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.Password); err != nil {
+		switch err {
+		case services.ErrWeakPassword:
+			respondError(w, http.StatusBadRequest, "Password must be at least 8 characters")
+		case services.ErrInvalidToken:
+			respondError(w, http.StatusBadRequest, "Invalid or expired token")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to reset password")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Password updated"})
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		respondError(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		if err == services.ErrInvalidToken {
+			respondError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Email verified"})
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.ResendVerification(middleware.GetUserID(r.Context())); err != nil {
+		if err == services.ErrEmailVerified {
+			respondError(w, http.StatusConflict, "Email already verified")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Verification email sent"})
+}
+
+// ChangePassword - Cambia la contraseña; las demás sesiones quedan cerradas y se entregan tokens nuevos
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Client = clientInfo(r)
+	resp, err := h.authService.ChangePassword(middleware.GetUserID(r.Context()), &req)
+	if err != nil {
+		switch err {
+		case services.ErrWeakPassword:
+			respondError(w, http.StatusBadRequest, "Password must be at least 8 characters")
+		case services.ErrInvalidCredentials:
+			respondError(w, http.StatusBadRequest, "Current password is incorrect")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to change password")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// Logout - Cierra la sesión actual (revoca su familia de refresh tokens)
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.Logout(middleware.GetUserID(r.Context()), middleware.GetSessionID(r.Context())); err != nil {
+		respondError(w, http.StatusBadRequest, "No active session")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// LogoutAll - Cierra todas las sesiones del usuario en todos sus dispositivos
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.LogoutAll(middleware.GetUserID(r.Context())); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged out from all devices"})
+}
+
+// clientInfo extrae IP (considerando proxy) y User-Agent del request
+func clientInfo(r *http.Request) models.ClientInfo {
+	return middleware.ClientInfo(r)
+}
+
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -18,6 +18,10 @@ type mockAuthService struct {
 	loginErr     error
 	refreshResp  *models.AuthResponse
 	refreshErr   error
+	resetErr     error
+	verifyErr    error
+	changeResp   *models.AuthResponse
+	changeErr    error
 }
 
 func (m *mockAuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
@@ -28,10 +32,26 @@ func (m *mockAuthService) Login(req *models.LoginRequest) (*models.AuthResponse,
 	return m.loginResp, m.loginErr
 }
 
-func (m *mockAuthService) Refresh(refreshToken string) (*models.AuthResponse, error) {
+func (m *mockAuthService) Refresh(req *models.RefreshRequest) (*models.AuthResponse, error) {
 	return m.refreshResp, m.refreshErr
 }
 
+func (m *mockAuthService) RequestPasswordReset(email string) error { return nil }
+
+func (m *mockAuthService) ResetPassword(token, password string) error { return m.resetErr }
+
+func (m *mockAuthService) VerifyEmail(token string) error { return m.verifyErr }
+
+func (m *mockAuthService) ResendVerification(userID int64) error { return m.verifyErr }
+
+func (m *mockAuthService) ChangePassword(userID int64, req *models.ChangePasswordRequest) (*models.AuthResponse, error) {
+	return m.changeResp, m.changeErr
+}
+
+func (m *mockAuthService) Logout(userID, sessionID int64) error { return nil }
+
+func (m *mockAuthService) LogoutAll(userID int64) error { return nil }
+
 func TestAuthHandler_Register_Success(t *testing.T) {
 	user := &models.User{ID: 1, Email: "test@test.com", Name: "Test", Role: models.RoleUser}
 	resp := &models.AuthResponse{AccessToken: "token", RefreshToken: "refresh", User: user}
@@ -201,3 +221,46 @@ func TestAuthHandler_Refresh_InvalidToken(t *testing.T) {
 		t.Fatalf("expected 401, got %d", rr.Code)
 	}
 }
+
+func TestAuthHandler_ForgotPassword_AlwaysOK(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{})
+
+	body := `{"email":"unknown@test.com"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/forgot-password", bytes.NewReader([]byte(body)))
+	rr := httptest.NewRecorder()
+
+	handler.ForgotPassword(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestAuthHandler_ResetPassword_InvalidToken(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{resetErr: services.ErrInvalidToken})
+
+	body := `{"token":"used-token","password":"newsecret123"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/reset-password", bytes.NewReader([]byte(body)))
+	rr := httptest.NewRecorder()
+
+	handler.ResetPassword(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestAuthHandler_ChangePassword_WrongCurrent(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{changeErr: services.ErrInvalidCredentials})
+
+	body := `{"current_password":"wrong","new_password":"newsecret123"}`
+	req := httptest.NewRequest("POST", "/api/v1/auth/change-password", bytes.NewReader([]byte(body)))
+	req = classRequestWithAuth(req, 1)
+	rr := httptest.NewRecorder()
+
+	handler.ChangePassword(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
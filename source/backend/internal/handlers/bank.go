@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// BankHandler - Importación de cartolas y conciliación de transferencias (admin)
+type BankHandler struct {
+	bankRepo *repository.BankRepository
+	payments *PaymentHandler
+}
+
+func NewBankHandler(bankRepo *repository.BankRepository, payments *PaymentHandler) *BankHandler {
+	return &BankHandler{bankRepo: bankRepo, payments: payments}
+}
+
+// Import - Sube una cartola (multipart "file", "format" opcional: auto, csv, bancochile) y cruza los abonos con pagos pendientes
+func (h *BankHandler) Import(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		respondError(w, http.StatusBadRequest, "File too large (max 10MB)")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	txs, format, err := services.ParseBankStatement(file, r.FormValue("format"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptyStatement):
+			respondError(w, http.StatusBadRequest, "No credit lines found in statement")
+		case errors.Is(err, services.ErrStatementAmount):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusBadRequest, "Unrecognized bank statement format")
+		}
+		return
+	}
+
+	_, _ = h.payments.paymentRepo.ExpirePendingPayments()
+	pending, err := h.payments.paymentRepo.ListPending(1000, 0)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch pending payments")
+		return
+	}
+	members, err := h.bankRepo.ListMembersForMatching()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch members")
+		return
+	}
+
+	imp := &models.BankStatementImport{Filename: header.Filename, Format: format, CreatedBy: adminID}
+	if err := h.bankRepo.CreateImport(imp); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save import")
+		return
+	}
+
+	used := map[int64]bool{}
+	for _, tx := range txs {
+		tx.ImportID = imp.ID
+		if m := services.MatchBankTransaction(tx, pending, members, used); m != nil {
+			tx.Status = models.BankTxSuggested
+			tx.MatchedPaymentID = m.PaymentID
+			tx.MatchedUserID = m.UserID
+			tx.MatchScore = m.Score
+			tx.MatchReason = m.Reason
+		}
+		inserted, err := h.bankRepo.InsertTransaction(tx)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to save bank transactions")
+			return
+		}
+		if !inserted {
+			imp.Duplicates++
+			continue
+		}
+		imp.TotalLines++
+		if tx.Status == models.BankTxSuggested {
+			imp.Matched++
+			if tx.MatchedPaymentID != nil {
+				used[*tx.MatchedPaymentID] = true
+			}
+		}
+	}
+	_ = h.bankRepo.UpdateImportCounts(imp)
+
+	respondJSON(w, http.StatusCreated, imp)
+}
+
+func (h *BankHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	imports, err := h.bankRepo.ListImports(limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch imports")
+		return
+	}
+	if imports == nil {
+		imports = []*models.BankStatementImport{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"imports": imports,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// ListTransactions - Bandeja de conciliación (?status=unmatched|suggested|confirmed|ignored, ?import_id=)
+func (h *BankHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	status := r.URL.Query().Get("status")
+	importID, _ := strconv.ParseInt(r.URL.Query().Get("import_id"), 10, 64)
+
+	txs, err := h.bankRepo.ListTransactions(status, importID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch bank transactions")
+		return
+	}
+	if txs == nil {
+		txs = []*models.BankTransactionWithDetails{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"transactions": txs,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// openTransaction obtiene una línea de cartola que aún no fue confirmada ni ignorada
+func (h *BankHandler) openTransaction(w http.ResponseWriter, r *http.Request) *models.BankTransactionWithDetails {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid transaction ID")
+		return nil
+	}
+	tx, err := h.bankRepo.GetTransaction(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Bank transaction not found")
+		return nil
+	}
+	if tx.Status == models.BankTxConfirmed || tx.Status == models.BankTxIgnored {
+		respondError(w, http.StatusConflict, "Bank transaction already resolved")
+		return nil
+	}
+	return tx
+}
+
+// Confirm - Confirma la coincidencia: completa el pago pendiente (o crea uno con user_id + plan_id) y activa la suscripción
+func (h *BankHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	tx := h.openTransaction(w, r)
+	if tx == nil {
+		return
+	}
+
+	var req models.ConfirmBankTransactionRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	paymentID := tx.MatchedPaymentID
+	if req.PaymentID != nil {
+		paymentID = req.PaymentID
+	}
+	userID := tx.MatchedUserID
+	if req.UserID != nil {
+		userID = req.UserID
+	}
+
+	var payment *models.Payment
+	var subscription *models.Subscription
+
+	switch {
+	case paymentID != nil:
+		p, err := h.payments.paymentRepo.GetByID(*paymentID)
+		if err != nil || p == nil {
+			respondError(w, http.StatusNotFound, "Payment not found")
+			return
+		}
+		if confirmed, _ := h.bankRepo.IsPaymentConfirmed(p.ID); confirmed {
+			respondError(w, http.StatusConflict, "Payment already reconciled with another bank transaction")
+			return
+		}
+		payment = p
+		// Si un admin ya aprobó el comprobante, solo se vincula la línea
+		if p.Status != models.PaymentCompleted {
+			subscription, err = h.payments.completePending(p, adminID)
+			if err != nil {
+				switch {
+				case errors.Is(err, errPaymentNotPending):
+					respondError(w, http.StatusConflict, "Payment is not pending")
+				case errors.Is(err, errPlanNotFound):
+					respondError(w, http.StatusNotFound, "Plan not found")
+				default:
+					respondError(w, http.StatusInternalServerError, "Failed to complete payment")
+				}
+				return
+			}
+		}
+	case userID != nil && req.PlanID != nil:
+		if _, err := h.payments.userRepo.GetByID(*userID); err != nil {
+			respondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		p, sub, err := h.payments.recordTransfer(*userID, *req.PlanID, tx.Amount)
+		if err != nil {
+			if errors.Is(err, errPlanNotFound) {
+				respondError(w, http.StatusNotFound, "Plan not found")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "Failed to create payment")
+			return
+		}
+		payment, subscription = p, sub
+	default:
+		respondError(w, http.StatusBadRequest, "payment_id, or user_id and plan_id, are required")
+		return
+	}
+
+	if err := h.bankRepo.Resolve(tx.ID, models.BankTxConfirmed, &payment.ID, &payment.UserID, adminID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusConflict, "Bank transaction already resolved")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to confirm bank transaction")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"payment":      payment,
+		"subscription": subscription,
+	})
+}
+
+// Reassign - Admin corrige la coincidencia sugerida (otro pago pendiente u otro miembro)
+func (h *BankHandler) Reassign(w http.ResponseWriter, r *http.Request) {
+	tx := h.openTransaction(w, r)
+	if tx == nil {
+		return
+	}
+
+	var req models.ReassignBankTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PaymentID == nil && req.UserID == nil {
+		respondError(w, http.StatusBadRequest, "payment_id or user_id is required")
+		return
+	}
+
+	userID := req.UserID
+	if req.PaymentID != nil {
+		p, err := h.payments.paymentRepo.GetByID(*req.PaymentID)
+		if err != nil || p == nil {
+			respondError(w, http.StatusNotFound, "Payment not found")
+			return
+		}
+		userID = &p.UserID
+	} else if _, err := h.payments.userRepo.GetByID(*userID); err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.bankRepo.SetMatch(tx.ID, req.PaymentID, userID, "manual"); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to reassign bank transaction")
+		return
+	}
+
+	updated, err := h.bankRepo.GetTransaction(tx.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch bank transaction")
+		return
+	}
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// Ignore - Descarta un abono que no corresponde a una mensualidad
+func (h *BankHandler) Ignore(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	tx := h.openTransaction(w, r)
+	if tx == nil {
+		return
+	}
+
+	if err := h.bankRepo.Resolve(tx.ID, models.BankTxIgnored, nil, nil, adminID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusConflict, "Bank transaction already resolved")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to ignore bank transaction")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Bank transaction ignored"})
+}
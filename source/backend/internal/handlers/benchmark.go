@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// BenchmarkHandler - Catálogo de benchmarks (Girls, Heroes, Open) e historial del miembro
+type BenchmarkHandler struct {
+	benchmarkService *services.BenchmarkService
+	benchmarkRepo    *repository.BenchmarkRepository
+}
+
+func NewBenchmarkHandler(benchmarkService *services.BenchmarkService, benchmarkRepo *repository.BenchmarkRepository) *BenchmarkHandler {
+	return &BenchmarkHandler{benchmarkService: benchmarkService, benchmarkRepo: benchmarkRepo}
+}
+
+func respondBenchmarkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBenchmarkNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrBenchmarkNameConflict):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrBenchmarkInvalid), errors.Is(err, services.ErrBenchmarkCategory),
+		errors.Is(err, services.ErrBenchmarkScoreType):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process benchmark")
+	}
+}
+
+func benchmarkID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid benchmark ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// List - Catálogo (?category=girl|hero|open|other, ?all=true incluye inactivos para el staff)
+func (h *BenchmarkHandler) List(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "true" && middleware.HasPermission(r.Context(), models.PermBenchmarksManage)
+	list, err := h.benchmarkRepo.List(r.URL.Query().Get("category"), all)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch benchmarks")
+		return
+	}
+	if list == nil {
+		list = []*models.Benchmark{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+func (h *BenchmarkHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := benchmarkID(w, r)
+	if !ok {
+		return
+	}
+	b, err := h.benchmarkService.Get(id)
+	if err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, b)
+}
+
+func (h *BenchmarkHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.BenchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	b, err := h.benchmarkService.Create(&req)
+	if err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, b)
+}
+
+func (h *BenchmarkHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := benchmarkID(w, r)
+	if !ok {
+		return
+	}
+	var req models.BenchmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	b, err := h.benchmarkService.Update(id, &req)
+	if err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, b)
+}
+
+// Delete - Desactiva el benchmark; las rutinas vinculadas y su historial se conservan
+func (h *BenchmarkHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := benchmarkID(w, r)
+	if !ok {
+		return
+	}
+	b, err := h.benchmarkService.Get(id)
+	if err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	b.Active = false
+	if err := h.benchmarkRepo.Update(b); err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// History - Progresión del miembro en todas las rutinas del benchmark y su percentil Rx (?user_id= para el staff)
+func (h *BenchmarkHandler) History(w http.ResponseWriter, r *http.Request) {
+	id, ok := benchmarkID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	hist, err := h.benchmarkService.History(id, userID)
+	if err != nil {
+		respondBenchmarkError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, hist)
+}
+
+// PRs - Mejor marca del miembro en cada benchmark (?user_id= para el staff)
+func (h *BenchmarkHandler) PRs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.benchmarkService.PRs(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch benchmark PRs")
+		return
+	}
+	respondJSON(w, http.StatusOK, list)
+}
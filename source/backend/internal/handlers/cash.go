@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// CashHandler - Cuadratura de caja diaria: apertura con sencillo, movimientos manuales y cierre con arqueo
+type CashHandler struct {
+	cashRepo repository.CashRepo
+}
+
+func NewCashHandler(cashRepo repository.CashRepo) *CashHandler {
+	return &CashHandler{cashRepo: cashRepo}
+}
+
+// Open - Abre la caja con el sencillo inicial
+func (h *CashHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var req models.OpenCashSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.OpeningFloat < 0 {
+		respondError(w, http.StatusBadRequest, "opening_float cannot be negative")
+		return
+	}
+
+	session := &models.CashSession{
+		OpeningFloat: req.OpeningFloat,
+		OpenedBy:     middleware.GetUserID(r.Context()),
+		Notes:        req.Notes,
+	}
+	if err := h.cashRepo.Open(session); err != nil {
+		if errors.Is(err, repository.ErrCashSessionAlreadyOpen) {
+			respondError(w, http.StatusConflict, "A cash session is already open")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to open cash session")
+		return
+	}
+	respondJSON(w, http.StatusCreated, session)
+}
+
+// Current - Sesión abierta con el arqueo esperado en vivo
+func (h *CashHandler) Current(w http.ResponseWriter, r *http.Request) {
+	session, err := h.cashRepo.GetOpen()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "No open cash session")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash session")
+		return
+	}
+	if err := h.loadDetails(session); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash session")
+		return
+	}
+	respondJSON(w, http.StatusOK, session)
+}
+
+func (h *CashHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit := 30
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	var from, to *time.Time
+	if f, err := time.Parse("2006-01-02", r.URL.Query().Get("from")); err == nil {
+		from = &f
+	}
+	if t, err := time.Parse("2006-01-02", r.URL.Query().Get("to")); err == nil {
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+
+	sessions, err := h.cashRepo.List(from, to, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.CashSession{}
+	}
+	for _, s := range sessions {
+		if s.Status == models.CashSessionClosed {
+			s.Totals, _ = h.cashRepo.GetTotals(s.ID)
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func (h *CashHandler) Get(w http.ResponseWriter, r *http.Request) {
+	session := h.sessionFromPath(w, r)
+	if session == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, session)
+}
+
+// AddMovement - Ingreso o egreso manual de efectivo (caja chica) en una sesión abierta
+func (h *CashHandler) AddMovement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req models.CreateCashMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Type != models.CashMovementIn && req.Type != models.CashMovementOut {
+		respondError(w, http.StatusBadRequest, "type must be in or out")
+		return
+	}
+	if req.Amount <= 0 {
+		respondError(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	movement := &models.CashMovement{
+		SessionID: id,
+		Type:      req.Type,
+		Amount:    req.Amount,
+		Reason:    strings.TrimSpace(req.Reason),
+		CreatedBy: middleware.GetUserID(r.Context()),
+	}
+	if err := h.cashRepo.AddMovement(movement); err != nil {
+		if errors.Is(err, repository.ErrCashSessionNotOpen) {
+			respondError(w, http.StatusConflict, "Cash session is not open")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to record cash movement")
+		return
+	}
+	respondJSON(w, http.StatusCreated, movement)
+}
+
+// Close - Cierra la caja con los montos contados y devuelve la cuadratura por medio de pago
+func (h *CashHandler) Close(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	var req models.CloseCashSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, ok := req.Counted[models.PaymentMethodEfectivo]; !ok {
+		respondError(w, http.StatusBadRequest, "counted.efectivo is required")
+		return
+	}
+	counted := make(map[string]int64, len(req.Counted))
+	for method, amount := range req.Counted {
+		if amount < 0 {
+			respondError(w, http.StatusBadRequest, "counted amounts cannot be negative")
+			return
+		}
+		counted[models.NormalizePaymentMethod(method)] += amount
+	}
+
+	if _, err := h.cashRepo.Close(id, middleware.GetUserID(r.Context()), counted, req.Notes); err != nil {
+		if errors.Is(err, repository.ErrCashSessionNotOpen) {
+			respondError(w, http.StatusConflict, "Cash session is not open")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to close cash session")
+		return
+	}
+
+	session, err := h.cashRepo.GetByID(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash session")
+		return
+	}
+	if err := h.loadDetails(session); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash session")
+		return
+	}
+	respondJSON(w, http.StatusOK, session)
+}
+
+// Print - Resumen imprimible (HTML) de la sesión
+func (h *CashHandler) Print(w http.ResponseWriter, r *http.Request) {
+	session := h.sessionFromPath(w, r)
+	if session == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(renderCashSummary(session)))
+}
+
+func (h *CashHandler) sessionFromPath(w http.ResponseWriter, r *http.Request) *models.CashSession {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return nil
+	}
+	session, err := h.cashRepo.GetByID(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Cash session not found")
+		return nil
+	}
+	if err := h.loadDetails(session); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch cash session")
+		return nil
+	}
+	return session
+}
+
+// loadDetails agrega movimientos y cuadratura: la guardada al cierre o la esperada si sigue abierta
+func (h *CashHandler) loadDetails(session *models.CashSession) error {
+	movements, err := h.cashRepo.ListMovements(session.ID)
+	if err != nil {
+		return err
+	}
+	session.Movements = movements
+
+	if session.Status == models.CashSessionClosed {
+		session.Totals, err = h.cashRepo.GetTotals(session.ID)
+		return err
+	}
+	sums, err := h.cashRepo.Sums(session.ID)
+	if err != nil {
+		return err
+	}
+	session.Totals = models.BuildCashTotals(session.OpeningFloat, sums, nil)
+	return nil
+}
+
+func renderCashSummary(s *models.CashSession) string {
+	var b strings.Builder
+	closed := "Abierta"
+	if s.ClosedAt != nil {
+		closed = s.ClosedAt.Format("02/01/2006 15:04") + " por " + html.EscapeString(s.ClosedByName)
+	}
+
+	fmt.Fprintf(&b, `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Cierre de caja #%d</title></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+<h2 style="color: #333;">Cierre de caja #%d</h2>
+<p>Apertura: %s por %s<br>Cierre: %s<br>Sencillo inicial: %s</p>
+<table style="width: 100%%; border-collapse: collapse;" border="1" cellpadding="6">
+<tr><th>Medio</th><th>Pagos</th><th>Ventas</th><th>Esperado</th><th>Contado</th><th>Diferencia</th></tr>`,
+		s.ID, s.ID, s.OpenedAt.Format("02/01/2006 15:04"), html.EscapeString(s.OpenedByName), closed, services.FormatCLP(s.OpeningFloat))
+
+	for _, t := range s.Totals {
+		fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			html.EscapeString(t.Method), services.FormatCLP(t.Payments), services.FormatCLP(t.Sales), services.FormatCLP(t.Expected), services.FormatCLP(t.Counted), services.FormatCLP(t.Difference))
+	}
+	b.WriteString(`</table>`)
+
+	if len(s.Movements) > 0 {
+		b.WriteString(`<h3>Movimientos de caja</h3><table style="width: 100%; border-collapse: collapse;" border="1" cellpadding="6">
+<tr><th>Hora</th><th>Tipo</th><th>Monto</th><th>Motivo</th></tr>`)
+		for _, m := range s.Movements {
+			kind := "Ingreso"
+			if m.Type == models.CashMovementOut {
+				kind = "Egreso"
+			}
+			fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+				m.CreatedAt.Format("15:04"), kind, services.FormatCLP(m.Amount), html.EscapeString(m.Reason))
+		}
+		b.WriteString(`</table>`)
+	}
+	if s.Notes != "" {
+		fmt.Fprintf(&b, `<p><strong>Notas:</strong> %s</p>`, html.EscapeString(s.Notes))
+	}
+	b.WriteString(`<p style="color: #999; font-size: 12px;">Box Magic</p></body></html>`)
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// mockCashRepo simula una sesión ya cerrada: no admite movimientos ni otro cierre
+type mockCashRepo struct {
+	repository.CashRepo
+	closed  bool
+	counted map[string]int64
+}
+
+func (m *mockCashRepo) AddMovement(mv *models.CashMovement) error {
+	if m.closed {
+		return repository.ErrCashSessionNotOpen
+	}
+	return nil
+}
+
+func (m *mockCashRepo) Close(sessionID, closedBy int64, counted map[string]int64, notes string) ([]models.CashSessionTotal, error) {
+	if m.closed {
+		return nil, repository.ErrCashSessionNotOpen
+	}
+	m.counted = counted
+	m.closed = true
+	return nil, nil
+}
+
+func (m *mockCashRepo) GetByID(id int64) (*models.CashSession, error) {
+	return &models.CashSession{ID: id, Status: models.CashSessionClosed}, nil
+}
+func (m *mockCashRepo) ListMovements(sessionID int64) ([]models.CashMovement, error) { return nil, nil }
+func (m *mockCashRepo) GetTotals(sessionID int64) ([]models.CashSessionTotal, error) { return nil, nil }
+
+func cashRequest(target, body string) *http.Request {
+	req := httptest.NewRequest("POST", target, bytes.NewReader([]byte(body)))
+	req.SetPathValue("id", "3")
+	return adminRequestWithAuth(req)
+}
+
+func TestCashHandler_AddMovement(t *testing.T) {
+	cases := []struct {
+		name   string
+		closed bool
+		body   string
+		want   int
+	}{
+		{"open session", false, `{"type":"out","amount":2500,"reason":"Insumos"}`, http.StatusCreated},
+		{"closed session", true, `{"type":"out","amount":2500,"reason":"Insumos"}`, http.StatusConflict},
+		{"invalid type", false, `{"type":"transfer","amount":2500,"reason":"Insumos"}`, http.StatusBadRequest},
+		{"non positive amount", false, `{"type":"in","amount":0,"reason":"Sencillo"}`, http.StatusBadRequest},
+		{"missing reason", false, `{"type":"in","amount":1000,"reason":"  "}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		handler := NewCashHandler(&mockCashRepo{closed: c.closed})
+		rr := httptest.NewRecorder()
+
+		handler.AddMovement(rr, cashRequest("/api/v1/cash/sessions/3/movements", c.body))
+
+		if rr.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, rr.Code)
+		}
+	}
+}
+
+func TestCashHandler_Close_NormalizesCountedMethods(t *testing.T) {
+	repo := &mockCashRepo{}
+	handler := NewCashHandler(repo)
+	body := `{"counted":{"efectivo":40000,"cash":500,"card":12000,"transfer":8000}}`
+
+	rr := httptest.NewRecorder()
+	handler.Close(rr, cashRequest("/api/v1/cash/sessions/3/close", body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := map[string]int64{models.PaymentMethodEfectivo: 40500, models.PaymentMethodDebito: 12000, models.PaymentMethodTransferencia: 8000}
+	for method, amount := range want {
+		if repo.counted[method] != amount {
+			t.Errorf("counted[%s] = %d, want %d (%v)", method, repo.counted[method], amount, repo.counted)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handler.Close(rr, cashRequest("/api/v1/cash/sessions/3/close", body))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("closing twice: expected 409, got %d", rr.Code)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,6 +21,18 @@ type ClassHandler struct {
 	userRepo       repository.UserRepo
 	emailService   *services.EmailService
 	cfg            *config.Config
+	waivers        waiverChecker
+	programming    scheduleSyncer
+}
+
+// waiverChecker bloquea reservas mientras falten documentos obligatorios por firmar
+type waiverChecker interface {
+	CanBook(userID int64) (bool, error)
+}
+
+// scheduleSyncer asigna la rutina de los tracks de programación a los horarios recién generados
+type scheduleSyncer interface {
+	SyncSchedules(from time.Time) error
 }
 
 func NewClassHandler(classRepo repository.ClassRepo, paymentRepo repository.PaymentRepo, instructorRepo repository.InstructorRepo, userRepo repository.UserRepo, emailService *services.EmailService) *ClassHandler {
@@ -36,6 +49,31 @@ func (h *ClassHandler) SetConfig(cfg *config.Config) {
 	h.cfg = cfg
 }
 
+func (h *ClassHandler) SetWaiverChecker(waivers waiverChecker) {
+	h.waivers = waivers
+}
+
+func (h *ClassHandler) SetScheduleSyncer(programming scheduleSyncer) {
+	h.programming = programming
+}
+
+// requireWaivers responde 403 si el miembro tiene documentos obligatorios sin firmar
+func (h *ClassHandler) requireWaivers(w http.ResponseWriter, userID int64) bool {
+	if h.waivers == nil {
+		return true
+	}
+	ok, err := h.waivers.CanBook(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check waivers")
+		return false
+	}
+	if !ok {
+		respondError(w, http.StatusForbidden, "Required waivers must be signed before booking")
+		return false
+	}
+	return true
+}
+
 // Disciplines
 
 func (h *ClassHandler) CreateDiscipline(w http.ResponseWriter, r *http.Request) {
@@ -361,6 +399,11 @@ func (h *ClassHandler) GenerateSchedules(w http.ResponseWriter, r *http.Request)
 		respondError(w, http.StatusInternalServerError, "Failed to generate schedules")
 		return
 	}
+	if h.programming != nil {
+		if err := h.programming.SyncSchedules(startDate); err != nil {
+			log.Printf("[WARN] track sync after generating schedules: %v", err)
+		}
+	}
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Schedules generated"})
 }
@@ -376,6 +419,10 @@ func (h *ClassHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.requireWaivers(w, userID) {
+		return
+	}
+
 	// Validate booking window
 	if h.cfg != nil {
 		sched, sErr := h.classRepo.GetScheduleByID(scheduleID)
@@ -607,6 +654,10 @@ func (h *ClassHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.requireWaivers(w, userID) {
+		return
+	}
+
 	entry, err := h.classRepo.JoinWaitlist(userID, scheduleID)
 	if err != nil {
 		respondError(w, http.StatusConflict, "Already on waitlist or failed to join")
@@ -100,6 +100,21 @@ func (m *mockInstructorRepo) AssignToClass(classID int64, instructorIDs []int64)
 func (m *mockInstructorRepo) GetClassInstructors(classID int64) ([]*models.Instructor, error) {
 	return nil, nil
 }
+func (m *mockInstructorRepo) GetByUserID(userID int64) (*models.Instructor, error) {
+	return nil, sql.ErrNoRows
+}
+func (m *mockInstructorRepo) ListSchedules(instructorID int64, from, to time.Time) ([]*models.CoachSchedule, error) {
+	return nil, nil
+}
+func (m *mockInstructorRepo) TeachesSchedule(instructorID, scheduleID int64) (bool, error) {
+	return false, nil
+}
+func (m *mockInstructorRepo) TeachesMember(instructorID, userID int64) (bool, error) {
+	return false, nil
+}
+func (m *mockInstructorRepo) BookingSchedule(bookingID int64) (int64, error) {
+	return 0, sql.ErrNoRows
+}
 
 type mockUserRepo struct {
 	user *models.User
@@ -112,13 +127,14 @@ func (m *mockUserRepo) GetByID(id int64) (*models.User, error)         { return
 func (m *mockUserRepo) Update(user *models.User) error                 { return nil }
 func (m *mockUserRepo) Delete(id int64) error                          { return nil }
 func (m *mockUserRepo) List(limit, offset int) ([]*models.User, error) { return nil, nil }
-func (m *mockUserRepo) SaveRefreshToken(userID int64, token string, expiresAt time.Time) error {
-	return nil
-}
-func (m *mockUserRepo) GetRefreshToken(token string) (int64, error)        { return 0, nil }
-func (m *mockUserRepo) DeleteRefreshToken(token string) error              { return nil }
 func (m *mockUserRepo) AddInvitationClasses(userID int64, count int) error { return nil }
 func (m *mockUserRepo) UseInvitationClass(userID int64) (bool, error)      { return true, nil }
+func (m *mockUserRepo) UpdatePassword(userID int64, passwordHash string) error { return nil }
+func (m *mockUserRepo) SetEmailVerified(userID int64) error                  { return nil }
+func (m *mockUserRepo) CreateAuthToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+func (m *mockUserRepo) ConsumeAuthToken(purpose, tokenHash string) (int64, error) { return 0, nil }
 
 func classRequestWithAuth(r *http.Request, userID int64) *http.Request {
 	ctx := middleware.WithAuth(r.Context(), userID, models.RoleUser)
@@ -362,6 +378,35 @@ func TestClassHandler_CreateBooking_Success(t *testing.T) {
 	}
 }
 
+type mockWaiverChecker struct {
+	canBook bool
+}
+
+func (m *mockWaiverChecker) CanBook(userID int64) (bool, error) { return m.canBook, nil }
+
+func TestClassHandler_CreateBooking_WaiversPending(t *testing.T) {
+	sub := &models.SubscriptionWithPlan{
+		Subscription: models.Subscription{ID: 1, UserID: 1, PlanID: 1, Active: true},
+	}
+	classRepo := &mockClassRepo{}
+	paymentRepo := &mockPaymentRepo{getActiveSubscription: sub}
+	handler := NewClassHandler(classRepo, paymentRepo, &mockInstructorRepo{}, &mockUserRepo{}, nil)
+	handler.SetWaiverChecker(&mockWaiverChecker{canBook: false})
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/schedules/{scheduleId}/book", http.HandlerFunc(handler.CreateBooking))
+
+	req := httptest.NewRequest("POST", "/api/v1/schedules/1/book", nil)
+	req = classRequestWithAuth(req, 1)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
 func TestClassHandler_CancelBooking_InvalidID(t *testing.T) {
 	classRepo := &mockClassRepo{}
 	paymentRepo := &mockPaymentRepo{}
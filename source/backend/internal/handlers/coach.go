@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// CoachHandler - Portal del coach: sus clases, asistencia, rutinas y notas de los miembros.
+// El acceso se limita a los horarios que dicta; el staff con classes.manage ve todo.
+type CoachHandler struct {
+	instructorRepo repository.InstructorRepo
+	classRepo      repository.ClassRepo
+	routineRepo    repository.RoutineRepo
+	noteRepo       *repository.MemberNoteRepository
+}
+
+func NewCoachHandler(instructorRepo repository.InstructorRepo, classRepo repository.ClassRepo, routineRepo repository.RoutineRepo, noteRepo *repository.MemberNoteRepository) *CoachHandler {
+	return &CoachHandler{instructorRepo: instructorRepo, classRepo: classRepo, routineRepo: routineRepo, noteRepo: noteRepo}
+}
+
+func isScheduleAdmin(r *http.Request) bool {
+	return middleware.HasPermission(r.Context(), models.PermClassesManage)
+}
+
+// instructor resuelve el instructor del usuario; el staff puede indicar ?instructor_id=
+func (h *CoachHandler) instructor(w http.ResponseWriter, r *http.Request) (*models.Instructor, bool) {
+	if idStr := r.URL.Query().Get("instructor_id"); idStr != "" && isScheduleAdmin(r) {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid instructor ID")
+			return nil, false
+		}
+		instructor, err := h.instructorRepo.GetByID(id)
+		if err != nil {
+			respondError(w, http.StatusNotFound, "Instructor not found")
+			return nil, false
+		}
+		return instructor, true
+	}
+
+	instructor, err := h.instructorRepo.GetByUserID(middleware.GetUserID(r.Context()))
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusForbidden, "Your account is not linked to an instructor")
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch instructor")
+		return nil, false
+	}
+	return instructor, true
+}
+
+// authorizeSchedule deja pasar al staff o al instructor que dicta el horario
+func (h *CoachHandler) authorizeSchedule(w http.ResponseWriter, r *http.Request, scheduleID int64) bool {
+	if isScheduleAdmin(r) {
+		return true
+	}
+	instructor, ok := h.instructor(w, r)
+	if !ok {
+		return false
+	}
+	teaches, err := h.instructorRepo.TeachesSchedule(instructor.ID, scheduleID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check schedule")
+		return false
+	}
+	if !teaches {
+		respondError(w, http.StatusForbidden, "You do not teach this class")
+		return false
+	}
+	return true
+}
+
+// authorizeMember deja pasar al staff o al coach que tiene al miembro en sus clases
+func (h *CoachHandler) authorizeMember(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	if isScheduleAdmin(r) {
+		return true
+	}
+	instructor, ok := h.instructor(w, r)
+	if !ok {
+		return false
+	}
+	teaches, err := h.instructorRepo.TeachesMember(instructor.ID, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check member")
+		return false
+	}
+	if !teaches {
+		respondError(w, http.StatusForbidden, "Member is not booked in your classes")
+		return false
+	}
+	return true
+}
+
+// Me - Perfil de instructor vinculado a la cuenta
+func (h *CoachHandler) Me(w http.ResponseWriter, r *http.Request) {
+	instructor, ok := h.instructor(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, instructor)
+}
+
+// MySchedules - Clases del coach hoy y en la semana en curso (lunes a domingo)
+func (h *CoachHandler) MySchedules(w http.ResponseWriter, r *http.Request) {
+	instructor, ok := h.instructor(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekday := int(today.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	weekStart := today.AddDate(0, 0, 1-weekday)
+	weekEnd := weekStart.AddDate(0, 0, 6)
+
+	schedules, err := h.instructorRepo.ListSchedules(instructor.ID, weekStart, weekEnd)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch schedules")
+		return
+	}
+	todays := []*models.CoachSchedule{}
+	for _, s := range schedules {
+		if s.Date.Format("2006-01-02") == today.Format("2006-01-02") {
+			todays = append(todays, s)
+		}
+	}
+	if schedules == nil {
+		schedules = []*models.CoachSchedule{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"instructor": instructor,
+		"today":      todays,
+		"week":       schedules,
+		"week_start": weekStart.Format("2006-01-02"),
+		"week_end":   weekEnd.Format("2006-01-02"),
+	})
+}
+
+func scheduleIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid schedule ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// Attendance - Reservas del horario con notas y lesiones vigentes de cada miembro
+func (h *CoachHandler) Attendance(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDFromPath(w, r)
+	if !ok || !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	schedule, err := h.classRepo.GetScheduleByID(scheduleID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Schedule not found")
+		return
+	}
+	bookings, err := h.classRepo.GetScheduleBookings(scheduleID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch bookings")
+		return
+	}
+	notes, err := h.noteRepo.ListForSchedule(scheduleID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch member notes")
+		return
+	}
+
+	attendees := []*models.CoachAttendee{}
+	for _, b := range bookings {
+		a := &models.CoachAttendee{BookingWithUser: *b, Notes: notes[b.UserID]}
+		if a.Notes == nil {
+			a.Notes = []*models.MemberNote{}
+		}
+		attendees = append(attendees, a)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"schedule":  schedule,
+		"attendees": attendees,
+	})
+}
+
+// CheckIn - Marca asistencia de una reserva en una clase del coach
+func (h *CoachHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+	scheduleID, err := h.instructorRepo.BookingSchedule(bookingID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Booking not found")
+		return
+	}
+	if !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	if err := h.classRepo.CheckIn(bookingID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check in")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Checked in"})
+}
+
+// AssignRoutine - Asigna la rutina del día a una clase del coach
+func (h *CoachHandler) AssignRoutine(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDFromPath(w, r)
+	if !ok || !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	var req models.AssignRoutineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RoutineID <= 0 {
+		respondError(w, http.StatusBadRequest, "Routine ID is required")
+		return
+	}
+
+	sr := &models.ScheduleRoutine{
+		ClassScheduleID: scheduleID,
+		RoutineID:       req.RoutineID,
+		Notes:           req.Notes,
+		PublishAt:       req.PublishAt,
+	}
+	if err := h.routineRepo.AssignToSchedule(sr); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to assign routine")
+		return
+	}
+	respondJSON(w, http.StatusOK, sr)
+}
+
+func (h *CoachHandler) RemoveRoutine(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := scheduleIDFromPath(w, r)
+	if !ok || !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+	if err := h.routineRepo.RemoveScheduleRoutine(scheduleID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to remove routine assignment")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MemberNotes - Notas y lesiones vigentes de un miembro de las clases del coach
+func (h *CoachHandler) MemberNotes(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("userId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !h.authorizeMember(w, r, userID) {
+		return
+	}
+	notes, err := h.noteRepo.ListByUser(userID, "", false)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch member notes")
+		return
+	}
+	if notes == nil {
+		notes = []*models.MemberNote{}
+	}
+	respondJSON(w, http.StatusOK, notes)
+}
+
+// AddMemberNote - El coach deja una nota o lesión observada en clase
+func (h *CoachHandler) AddMemberNote(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("userId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !h.authorizeMember(w, r, userID) {
+		return
+	}
+	createMemberNote(w, r, h.noteRepo, userID, "")
+}
+
+// createMemberNote valida y guarda la nota; forcedKind fija el tipo (p. ej. lesión informada por el miembro)
+func createMemberNote(w http.ResponseWriter, r *http.Request, noteRepo *repository.MemberNoteRepository, userID int64, forcedKind string) {
+	var req models.CreateMemberNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		respondError(w, http.StatusBadRequest, "body is required")
+		return
+	}
+	kind := forcedKind
+	if kind == "" {
+		kind = req.Kind
+		if kind == "" {
+			kind = models.MemberNoteGeneral
+		}
+		if kind != models.MemberNoteGeneral && kind != models.MemberNoteInjury {
+			respondError(w, http.StatusBadRequest, "kind must be note or injury")
+			return
+		}
+	}
+
+	authorID := middleware.GetUserID(r.Context())
+	note := &models.MemberNote{UserID: userID, AuthorID: &authorID, Kind: kind, Body: req.Body}
+	if err := noteRepo.Create(note); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save note")
+		return
+	}
+	respondJSON(w, http.StatusCreated, note)
+}
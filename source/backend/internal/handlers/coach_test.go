@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// coachInstructors - El usuario 10 es el instructor 1 y dicta solo el horario 100; la reserva 7 es del 100 y la 8 del 200
+type coachInstructors struct {
+	repository.InstructorRepo
+}
+
+func (coachInstructors) GetByUserID(userID int64) (*models.Instructor, error) {
+	if userID == 10 {
+		return &models.Instructor{ID: 1, Name: "Coach"}, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (coachInstructors) TeachesSchedule(instructorID, scheduleID int64) (bool, error) {
+	return instructorID == 1 && scheduleID == 100, nil
+}
+
+func (coachInstructors) BookingSchedule(bookingID int64) (int64, error) {
+	switch bookingID {
+	case 7:
+		return 100, nil
+	case 8:
+		return 200, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+type coachClasses struct {
+	repository.ClassRepo
+	checkedIn []int64
+}
+
+func (c *coachClasses) CheckIn(bookingID int64) error {
+	c.checkedIn = append(c.checkedIn, bookingID)
+	return nil
+}
+
+func coachRequest(method, path string, userID int64, role models.Role) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	return req.WithContext(middleware.WithAuth(req.Context(), userID, role))
+}
+
+func TestCoachHandler_CheckIn_Scoping(t *testing.T) {
+	cases := []struct {
+		name    string
+		booking string
+		userID  int64
+		role    models.Role
+		want    int
+	}{
+		{"own class", "7", 10, models.RoleCoach, http.StatusOK},
+		{"class not assigned", "8", 10, models.RoleCoach, http.StatusForbidden},
+		{"account without instructor", "7", 11, models.RoleCoach, http.StatusForbidden},
+		{"unknown booking", "9", 10, models.RoleCoach, http.StatusNotFound},
+		{"staff with classes.manage", "8", 1, models.RoleManager, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			classes := &coachClasses{}
+			h := NewCoachHandler(coachInstructors{}, classes, nil, nil)
+
+			req := coachRequest("POST", "/api/v1/coach/bookings/"+c.booking+"/checkin", c.userID, c.role)
+			req.SetPathValue("id", c.booking)
+			rr := httptest.NewRecorder()
+			h.CheckIn(rr, req)
+
+			if rr.Code != c.want {
+				t.Fatalf("expected %d, got %d: %s", c.want, rr.Code, rr.Body.String())
+			}
+			if checked := len(classes.checkedIn) == 1; checked != (c.want == http.StatusOK) {
+				t.Errorf("checked in = %v, want only on success", classes.checkedIn)
+			}
+		})
+	}
+}
+
+func TestCoachHandler_Attendance_OtherCoachClassForbidden(t *testing.T) {
+	h := NewCoachHandler(coachInstructors{}, &coachClasses{}, nil, nil)
+
+	req := coachRequest("GET", "/api/v1/coach/schedules/200/attendance", 10, models.RoleCoach)
+	req.SetPathValue("id", "200")
+	rr := httptest.NewRecorder()
+	h.Attendance(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -4,22 +4,41 @@ import (
 	"net/http"
 
 	"boxmagic/internal/config"
+	"boxmagic/internal/models"
 )
 
+// SecuritySettingsSource entrega las políticas de seguridad configuradas por el gimnasio
+type SecuritySettingsSource interface {
+	SecuritySettings() (*models.SecuritySettings, error)
+}
+
 // ConfigHandler returns public config values for the frontend
 type ConfigHandler struct {
-	cfg *config.Config
+	cfg      *config.Config
+	security SecuritySettingsSource
 }
 
 func NewConfigHandler(cfg *config.Config) *ConfigHandler {
 	return &ConfigHandler{cfg: cfg}
 }
 
+// SetSecuritySettings expone en la config pública las opciones de login habilitadas
+func (h *ConfigHandler) SetSecuritySettings(src SecuritySettingsSource) {
+	h.security = src
+}
+
 func (h *ConfigHandler) Get(w http.ResponseWriter, r *http.Request) {
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"invitation_class_price":   h.cfg.InvitationClassPrice,
 		"before_class_photo_price": h.cfg.BeforeClassPhotoPrice,
 		"booking_window_days":      h.cfg.BookingWindowDays,
 		"booking_cutoff_hours":     h.cfg.BookingCutoffHours,
-	})
+		"magic_link_enabled":       false,
+	}
+	if h.security != nil {
+		if settings, err := h.security.SecuritySettings(); err == nil {
+			resp["magic_link_enabled"] = settings.MagicLinkEnabled
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
 }
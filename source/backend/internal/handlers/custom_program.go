@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// CustomProgramHandler - Programación personalizada: entregas, cobro mensual e ingresos por coach
+type CustomProgramHandler struct {
+	programService *services.CustomProgramService
+	programRepo    *repository.CustomProgramRepository
+}
+
+func NewCustomProgramHandler(programService *services.CustomProgramService, programRepo *repository.CustomProgramRepository) *CustomProgramHandler {
+	return &CustomProgramHandler{programService: programService, programRepo: programRepo}
+}
+
+func respondCustomProgramError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomProgramNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCustomProgramBilled):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrCustomProgramPrice), errors.Is(err, services.ErrMonthInvalid),
+		errors.Is(err, services.ErrBillingMonthOpen):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process custom program")
+	}
+}
+
+func customProgramID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid custom program ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *CustomProgramHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.programService.Settings()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch custom programming settings")
+		return
+	}
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings - Precio por defecto de cada rutina personalizada facturable
+func (h *CustomProgramHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.CustomProgrammingSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	settings, err := h.programService.UpdateSettings(&req, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondCustomProgramError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// List - Programas entregados (?user_id=, ?status=included|pending|billed|void)
+func (h *CustomProgramHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	status := r.URL.Query().Get("status")
+
+	programs, err := h.programRepo.List(userID, status, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch custom programs")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"programs": programs,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// Void - Anula el cobro de un programa que aún no se factura
+func (h *CustomProgramHandler) Void(w http.ResponseWriter, r *http.Request) {
+	id, ok := customProgramID(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.programService.Void(id)
+	if err != nil {
+		respondCustomProgramError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, p)
+}
+
+// Bill - Cierre mensual: carga los programas pendientes a la cuenta de cada miembro
+func (h *CustomProgramHandler) Bill(w http.ResponseWriter, r *http.Request) {
+	var req models.BillCustomProgramsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	run, err := h.programService.BillMonth(req.Month, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondCustomProgramError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, run)
+}
+
+// Revenue - Ingresos por coach (?from=YYYY-MM&to=YYYY-MM; por defecto el mes en curso)
+func (h *CustomProgramHandler) Revenue(w http.ResponseWriter, r *http.Request) {
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = time.Now().Format("2006-01")
+	}
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = to
+	}
+	list, err := h.programService.RevenueByCoach(from, to)
+	if err != nil {
+		respondCustomProgramError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"from": from, "to": to, "coaches": list})
+}
+
+// MyPrograms - Programas personalizados entregados al miembro
+func (h *CustomProgramHandler) MyPrograms(w http.ResponseWriter, r *http.Request) {
+	programs, err := h.programRepo.ListForMember(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch custom programs")
+		return
+	}
+	respondJSON(w, http.StatusOK, programs)
+}
+
+// Acknowledge - El miembro confirma que recibió el programa
+func (h *CustomProgramHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	id, ok := customProgramID(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.programService.Acknowledge(id, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondCustomProgramError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, p)
+}
@@ -86,6 +86,7 @@ func SeedAllDevData(db *sql.DB, cfg *config.Config) http.HandlerFunc {
 
 		// 1. Clean in order (avoid FK violations)
 		_, _ = db.Exec("DELETE FROM schedule_routines")
+		_, _ = db.Exec("DELETE FROM programming_track_days")
 		_, _ = db.Exec("DELETE FROM user_routine_results")
 		_, _ = db.Exec("DELETE FROM bookings")
 		_, _ = db.Exec("DELETE FROM class_instructors")
@@ -323,7 +324,7 @@ func SeedAllDevData(db *sql.DB, cfg *config.Config) http.HandlerFunc {
 
 		// 11. Asignar rutinas a algunos horarios (entrenamiento del día)
 		schedules, _ := classRepo.ListSchedules(weekStart, weekStart.AddDate(0, 0, 14))
-		routineList, _ := routineRepo.List("", nil, 10, 0)
+		routineList, _ := routineRepo.List("", nil, false, 10, 0)
 		for i, s := range schedules {
 			if i >= len(routineList) {
 				break
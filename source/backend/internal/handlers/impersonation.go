@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// ImpersonationHandler - Sesiones de soporte en que un admin entra como un miembro
+type ImpersonationHandler struct {
+	impersonationService *services.ImpersonationService
+	impersonationRepo    *repository.ImpersonationRepository
+}
+
+func NewImpersonationHandler(impersonationService *services.ImpersonationService, impersonationRepo *repository.ImpersonationRepository) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonationService: impersonationService, impersonationRepo: impersonationRepo}
+}
+
+func respondImpersonationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrImpersonationNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrImpersonationReason):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrImpersonationTarget):
+		respondError(w, http.StatusForbidden, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process impersonation")
+	}
+}
+
+// Start - Devuelve un access token que actúa como el miembro; el miembro recibe un aviso por email
+func (h *ImpersonationHandler) Start(w http.ResponseWriter, r *http.Request) {
+	if middleware.GetImpersonator(r.Context()) != nil {
+		respondError(w, http.StatusForbidden, "Cannot impersonate while impersonating")
+		return
+	}
+	var req models.StartImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	resp, err := h.impersonationService.Start(middleware.GetUserID(r.Context()), &req, middleware.ClientInfo(r))
+	if err != nil {
+		respondImpersonationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, resp)
+}
+
+// End - El admin cierra la sesión antes de que venza; el token de impersonación deja de aceptarse
+func (h *ImpersonationHandler) End(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid impersonation ID")
+		return
+	}
+	imp, err := h.impersonationService.End(id, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondImpersonationError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, imp)
+}
+
+// List - Historial de impersonaciones (?user_id= filtra por miembro)
+func (h *ImpersonationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	list, err := h.impersonationRepo.List(userID, 200)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch impersonations")
+		return
+	}
+	if list == nil {
+		list = []*models.Impersonation{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
@@ -11,6 +11,7 @@ import (
 
 type InstructorHandler struct {
 	instructorRepo repository.InstructorRepo
+	userRepo       repository.UserRepo
 }
 
 func NewInstructorHandler(instructorRepo repository.InstructorRepo) *InstructorHandler {
@@ -19,6 +20,25 @@ func NewInstructorHandler(instructorRepo repository.InstructorRepo) *InstructorH
 	}
 }
 
+func (h *InstructorHandler) SetUserRepo(repo repository.UserRepo) {
+	h.userRepo = repo
+}
+
+// validateUserLink verifica que la cuenta exista y no esté vinculada a otro instructor
+func (h *InstructorHandler) validateUserLink(w http.ResponseWriter, userID, instructorID int64) bool {
+	if h.userRepo != nil {
+		if _, err := h.userRepo.GetByID(userID); err != nil {
+			respondError(w, http.StatusBadRequest, "User not found")
+			return false
+		}
+	}
+	if other, err := h.instructorRepo.GetByUserID(userID); err == nil && other.ID != instructorID {
+		respondError(w, http.StatusConflict, "User is already linked to another instructor")
+		return false
+	}
+	return true
+}
+
 func (h *InstructorHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateInstructorRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -39,6 +59,12 @@ func (h *InstructorHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Bio:       req.Bio,
 		Active:    true,
 	}
+	if req.UserID != nil && *req.UserID > 0 {
+		if !h.validateUserLink(w, *req.UserID, 0) {
+			return
+		}
+		instructor.UserID = req.UserID
+	}
 
 	if err := h.instructorRepo.Create(instructor); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create instructor")
@@ -116,6 +142,16 @@ func (h *InstructorHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Active != nil {
 		instructor.Active = *req.Active
 	}
+	if req.UserID != nil {
+		if *req.UserID == 0 {
+			instructor.UserID = nil
+		} else {
+			if !h.validateUserLink(w, *req.UserID, instructor.ID) {
+				return
+			}
+			instructor.UserID = req.UserID
+		}
+	}
 
 	if err := h.instructorRepo.Update(instructor); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update instructor")
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"boxmagic/internal/jwtkeys"
+)
+
+// JWKSHandler - Publica las claves públicas de verificación de los JWT
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS - /.well-known/jwks.json; vacío si se firma con HS256 (el secreto no se publica)
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys := []jwtkeys.JWK{}
+	if h.keys != nil {
+		keys = h.keys.JWKS()
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// LedgerHandler - Cuenta corriente del miembro: saldo a favor, deudas (fiado) y gift cards
+type LedgerHandler struct {
+	ledgerRepo repository.LedgerRepo
+	userRepo   repository.UserRepo
+}
+
+func NewLedgerHandler(ledgerRepo repository.LedgerRepo, userRepo repository.UserRepo) *LedgerHandler {
+	return &LedgerHandler{ledgerRepo: ledgerRepo, userRepo: userRepo}
+}
+
+func (h *LedgerHandler) statement(w http.ResponseWriter, r *http.Request, userID int64) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	balance, err := h.ledgerRepo.Balance(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch balance")
+		return
+	}
+	entries, err := h.ledgerRepo.ListEntries(userID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch statement")
+		return
+	}
+	if entries == nil {
+		entries = []*models.LedgerEntry{}
+	}
+	respondJSON(w, http.StatusOK, models.MemberStatement{UserID: userID, Balance: balance, Entries: entries})
+}
+
+// MyStatement - Estado de cuenta del miembro autenticado
+func (h *LedgerHandler) MyStatement(w http.ResponseWriter, r *http.Request) {
+	h.statement(w, r, middleware.GetUserID(r.Context()))
+}
+
+// UserStatement - Estado de cuenta de un miembro (admin)
+func (h *LedgerHandler) UserStatement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if _, err := h.userRepo.GetByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	h.statement(w, r, id)
+}
+
+// Balances - Miembros con saldo (?debt=true solo cuentas por cobrar)
+func (h *LedgerHandler) Balances(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.ledgerRepo.ListBalances(r.URL.Query().Get("debt") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch balances")
+		return
+	}
+	if balances == nil {
+		balances = []*models.MemberBalance{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"balances": balances})
+}
+
+// CreateEntry - Admin registra crédito, devolución o ajuste manual
+func (h *LedgerHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreateLedgerEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !models.ManualLedgerTypes[req.Type] {
+		respondError(w, http.StatusBadRequest, "type must be refund, store_credit or adjustment")
+		return
+	}
+	if req.Amount == 0 || (req.Type != models.LedgerAdjustment && req.Amount < 0) {
+		respondError(w, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		respondError(w, http.StatusBadRequest, "description is required")
+		return
+	}
+	if _, err := h.userRepo.GetByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	entry := &models.LedgerEntry{
+		UserID:      id,
+		Type:        req.Type,
+		Amount:      req.Amount,
+		Description: strings.TrimSpace(req.Description),
+		CreatedBy:   &adminID,
+	}
+	if err := h.ledgerRepo.AddEntry(entry); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create ledger entry")
+		return
+	}
+	respondJSON(w, http.StatusCreated, entry)
+}
+
+// Settle - Admin registra el pago de la deuda del miembro (total si amount = 0)
+func (h *LedgerHandler) Settle(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.SettleAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !models.ValidPaymentMethods[req.PaymentMethod] {
+		respondError(w, http.StatusBadRequest, "payment_method must be efectivo, debito or transferencia")
+		return
+	}
+	if req.Amount < 0 {
+		respondError(w, http.StatusBadRequest, "Invalid amount")
+		return
+	}
+	if _, err := h.userRepo.GetByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	balance, err := h.ledgerRepo.Balance(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch balance")
+		return
+	}
+	if balance >= 0 {
+		respondError(w, http.StatusConflict, "Member has no outstanding balance")
+		return
+	}
+	amount := req.Amount
+	if amount == 0 || amount > -balance {
+		amount = -balance
+	}
+
+	entry := &models.LedgerEntry{
+		UserID:        id,
+		Type:          models.LedgerPayment,
+		Amount:        amount,
+		Description:   "Pago de cuenta",
+		PaymentMethod: req.PaymentMethod,
+		CreatedBy:     &adminID,
+	}
+	if err := h.ledgerRepo.AddEntry(entry); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to settle account")
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"entry":   entry,
+		"balance": balance + amount,
+	})
+}
+
+// RedeemGiftCard - El miembro canjea una gift card; un admin puede indicar user_id para canjearla a nombre de otro
+func (h *LedgerHandler) RedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	callerID := middleware.GetUserID(r.Context())
+
+	var req models.RedeemGiftCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		respondError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	userID := callerID
+	if req.UserID != nil && *req.UserID != callerID {
+		if !middleware.HasPermission(r.Context(), models.PermLedgerManage) {
+			respondError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if _, err := h.userRepo.GetByID(*req.UserID); err != nil {
+			respondError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		userID = *req.UserID
+	}
+
+	entry, err := h.ledgerRepo.RedeemGiftCard(req.Code, userID, callerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrGiftCardNotFound):
+			respondError(w, http.StatusNotFound, "Gift card not found")
+		case errors.Is(err, repository.ErrGiftCardRedeemed):
+			respondError(w, http.StatusConflict, "Gift card already redeemed")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to redeem gift card")
+		}
+		return
+	}
+
+	balance, _ := h.ledgerRepo.Balance(userID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entry":   entry,
+		"balance": balance,
+	})
+}
+
+func (h *LedgerHandler) ListGiftCards(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	cards, err := h.ledgerRepo.ListGiftCards(limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch gift cards")
+		return
+	}
+	if cards == nil {
+		cards = []*models.GiftCard{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"gift_cards": cards,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// memLedger - Cuenta corriente en memoria: el saldo se deriva de los movimientos, como en la base
+type memLedger struct {
+	repository.LedgerRepo
+	entries   []*models.LedgerEntry
+	giftCards map[string]*models.GiftCard
+}
+
+func (m *memLedger) AddEntry(e *models.LedgerEntry) error {
+	e.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func (m *memLedger) Balance(userID int64) (int64, error) {
+	var balance int64
+	for _, e := range m.entries {
+		if e.UserID == userID {
+			balance += e.Amount
+		}
+	}
+	return balance, nil
+}
+
+func (m *memLedger) ListEntries(userID int64, limit, offset int) ([]*models.LedgerEntry, error) {
+	var out []*models.LedgerEntry
+	for _, e := range m.entries {
+		if e.UserID == userID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memLedger) RedeemGiftCard(code string, userID int64, createdBy int64) (*models.LedgerEntry, error) {
+	gc, ok := m.giftCards[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, repository.ErrGiftCardNotFound
+	}
+	if gc.RedeemedBy != nil {
+		return nil, repository.ErrGiftCardRedeemed
+	}
+	gc.RedeemedBy = &userID
+	entry := &models.LedgerEntry{UserID: userID, Type: models.LedgerGiftCardLoad, Amount: gc.Amount, GiftCardID: &gc.ID}
+	return entry, m.AddEntry(entry)
+}
+
+func ledgerRequest(method, target, body string, userID int64, role models.Role) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	req.SetPathValue("id", "7")
+	return req.WithContext(middleware.WithAuth(req.Context(), userID, role))
+}
+
+func decodeBalance(t *testing.T, rr *httptest.ResponseRecorder) int64 {
+	t.Helper()
+	var resp struct {
+		Balance int64 `json:"balance"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Balance
+}
+
+func TestLedgerHandler_StatementDerivesBalance(t *testing.T) {
+	ledger := &memLedger{entries: []*models.LedgerEntry{
+		{UserID: 7, Type: models.LedgerStoreCredit, Amount: 10000},
+		{UserID: 7, Type: models.LedgerPOSCharge, Amount: -15500},
+		{UserID: 8, Type: models.LedgerPOSCharge, Amount: -9000},
+		{UserID: 7, Type: models.LedgerInvitationGrant, Amount: 0},
+	}}
+	handler := NewLedgerHandler(ledger, &mockUserRepo{user: &models.User{ID: 7}})
+	rr := httptest.NewRecorder()
+
+	handler.UserStatement(rr, ledgerRequest("GET", "/api/v1/users/7/ledger", "", 1, models.RoleAdmin))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := decodeBalance(t, rr); got != -5500 {
+		t.Fatalf("expected balance -5500, got %d", got)
+	}
+}
+
+func TestLedgerHandler_Settle(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantPaid    int64
+		wantBalance int64
+	}{
+		{"full debt", `{"payment_method":"efectivo"}`, http.StatusCreated, 12000, 0},
+		{"partial", `{"amount":5000,"payment_method":"debito"}`, http.StatusCreated, 5000, -7000},
+		{"overpayment capped at the debt", `{"amount":20000,"payment_method":"transferencia"}`, http.StatusCreated, 12000, 0},
+		{"invalid method", `{"payment_method":"account"}`, http.StatusBadRequest, 0, -12000},
+		{"negative amount", `{"amount":-100,"payment_method":"efectivo"}`, http.StatusBadRequest, 0, -12000},
+	}
+	for _, c := range cases {
+		ledger := &memLedger{entries: []*models.LedgerEntry{{UserID: 7, Type: models.LedgerPOSCharge, Amount: -12000}}}
+		handler := NewLedgerHandler(ledger, &mockUserRepo{user: &models.User{ID: 7}})
+		rr := httptest.NewRecorder()
+
+		handler.Settle(rr, ledgerRequest("POST", "/api/v1/users/7/ledger/settle", c.body, 1, models.RoleAdmin))
+
+		if rr.Code != c.wantStatus {
+			t.Errorf("%s: expected %d, got %d", c.name, c.wantStatus, rr.Code)
+			continue
+		}
+		if c.wantPaid > 0 {
+			last := ledger.entries[len(ledger.entries)-1]
+			if last.Type != models.LedgerPayment || last.Amount != c.wantPaid {
+				t.Errorf("%s: expected payment of %d, got %+v", c.name, c.wantPaid, last)
+			}
+		}
+		if balance, _ := ledger.Balance(7); balance != c.wantBalance {
+			t.Errorf("%s: expected balance %d, got %d", c.name, c.wantBalance, balance)
+		}
+	}
+}
+
+func TestLedgerHandler_Settle_NoDebt(t *testing.T) {
+	ledger := &memLedger{entries: []*models.LedgerEntry{{UserID: 7, Type: models.LedgerStoreCredit, Amount: 3000}}}
+	handler := NewLedgerHandler(ledger, &mockUserRepo{user: &models.User{ID: 7}})
+	rr := httptest.NewRecorder()
+
+	handler.Settle(rr, ledgerRequest("POST", "/api/v1/users/7/ledger/settle", `{"payment_method":"efectivo"}`, 1, models.RoleAdmin))
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestLedgerHandler_RedeemGiftCard(t *testing.T) {
+	ledger := &memLedger{giftCards: map[string]*models.GiftCard{"GC-ABCD-EFGH": {ID: 1, Code: "GC-ABCD-EFGH", Amount: 25000}}}
+	handler := NewLedgerHandler(ledger, &mockUserRepo{user: &models.User{ID: 9}})
+	redeem := func(body string, role models.Role) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.RedeemGiftCard(rr, ledgerRequest("POST", "/api/v1/gift-cards/redeem", body, 7, role))
+		return rr
+	}
+
+	if rr := redeem(`{"code":"GC-ZZZZ-ZZZZ"}`, models.RoleUser); rr.Code != http.StatusNotFound {
+		t.Fatalf("wrong code: expected 404, got %d", rr.Code)
+	}
+	if rr := redeem(`{"code":"GC-ABCD-EFGH","user_id":9}`, models.RoleUser); rr.Code != http.StatusForbidden {
+		t.Fatalf("member redeeming for someone else: expected 403, got %d", rr.Code)
+	}
+	rr := redeem(`{"code":" gc-abcd-efgh "}`, models.RoleUser)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first redemption: expected 200, got %d", rr.Code)
+	}
+	if got := decodeBalance(t, rr); got != 25000 {
+		t.Fatalf("expected balance 25000 after redemption, got %d", got)
+	}
+	if rr := redeem(`{"code":"GC-ABCD-EFGH"}`, models.RoleUser); rr.Code != http.StatusConflict {
+		t.Fatalf("double redemption: expected 409, got %d", rr.Code)
+	}
+	if balance, _ := ledger.Balance(7); balance != 25000 {
+		t.Fatalf("double redemption must not load the card twice, balance %d", balance)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// MagicLinkHandler - Login sin contraseña por link enviado al email
+type MagicLinkHandler struct {
+	authService    *services.AuthService
+	accountLimiter *middleware.Limiter
+}
+
+func NewMagicLinkHandler(authService *services.AuthService) *MagicLinkHandler {
+	return &MagicLinkHandler{authService: authService}
+}
+
+// SetAccountLimiter limita los envíos por email (comparte el límite con el login por contraseña)
+func (h *MagicLinkHandler) SetAccountLimiter(l *middleware.Limiter) {
+	h.accountLimiter = l
+}
+
+// Request - Envía el link; responde igual exista o no el email
+func (h *MagicLinkHandler) Request(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		respondError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+	if !h.accountLimiter.Allow(w, "account:"+strings.ToLower(strings.TrimSpace(req.Email))) {
+		return
+	}
+
+	err := h.authService.RequestMagicLink(req.Email)
+	if err == services.ErrMagicLinkDisabled {
+		respondError(w, http.StatusForbidden, "Magic link login is disabled")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to send magic link")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "If the email is registered, a login link has been sent"})
+}
+
+// Verify - Canjea el link por tokens (misma respuesta que el login)
+func (h *MagicLinkHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		respondError(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	req.Client = clientInfo(r)
+	req.Client.Remember = req.RememberDevice
+	resp, err := h.authService.VerifyMagicLink(&req)
+	if err == services.ErrMagicLinkDisabled {
+		respondError(w, http.StatusForbidden, "Magic link login is disabled")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired link")
+		return
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// MemberNoteHandler - Notas del staff sobre un miembro y lesiones informadas por el propio miembro
+type MemberNoteHandler struct {
+	noteRepo *repository.MemberNoteRepository
+	userRepo repository.UserRepo
+}
+
+func NewMemberNoteHandler(noteRepo *repository.MemberNoteRepository, userRepo repository.UserRepo) *MemberNoteHandler {
+	return &MemberNoteHandler{noteRepo: noteRepo, userRepo: userRepo}
+}
+
+func (h *MemberNoteHandler) list(w http.ResponseWriter, r *http.Request, userID int64, kind string) {
+	notes, err := h.noteRepo.ListByUser(userID, kind, r.URL.Query().Get("resolved") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch notes")
+		return
+	}
+	if notes == nil {
+		notes = []*models.MemberNote{}
+	}
+	respondJSON(w, http.StatusOK, notes)
+}
+
+func (h *MemberNoteHandler) resolve(w http.ResponseWriter, r *http.Request, userID int64, kind string) {
+	noteID, err := strconv.ParseInt(r.PathValue("noteId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+	if err := h.noteRepo.Resolve(userID, noteID, kind); err != nil {
+		respondError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Note resolved"})
+}
+
+// List - Notas de un miembro (?resolved=true incluye las resueltas)
+func (h *MemberNoteHandler) List(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, id, "")
+}
+
+func (h *MemberNoteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.userRepo.GetByID(id); err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	createMemberNote(w, r, h.noteRepo, id, "")
+}
+
+// Resolve - Marca una nota o lesión como resuelta
+func (h *MemberNoteHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	h.resolve(w, r, id, "")
+}
+
+// MyInjuries - Lesiones informadas por el miembro autenticado
+func (h *MemberNoteHandler) MyInjuries(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, middleware.GetUserID(r.Context()), models.MemberNoteInjury)
+}
+
+// ReportInjury - El miembro informa una lesión para que la vean sus coaches
+func (h *MemberNoteHandler) ReportInjury(w http.ResponseWriter, r *http.Request) {
+	createMemberNote(w, r, h.noteRepo, middleware.GetUserID(r.Context()), models.MemberNoteInjury)
+}
+
+// ResolveMyInjury - El miembro da de alta una lesión propia
+func (h *MemberNoteHandler) ResolveMyInjury(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, middleware.GetUserID(r.Context()), models.MemberNoteInjury)
+}
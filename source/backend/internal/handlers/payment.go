@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"boxmagic/internal/config"
 	"boxmagic/internal/middleware"
 	"boxmagic/internal/models"
 	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
 )
 
 type PaymentHandler struct {
@@ -16,6 +21,9 @@ type PaymentHandler struct {
 	planRepo     repository.PlanRepo
 	userRepo     repository.UserRepo
 	discountRepo *repository.DiscountCodeRepository
+	emailService *services.EmailService
+	taxService   *services.TaxService
+	cfg          *config.Config
 }
 
 func NewPaymentHandler(paymentRepo repository.PaymentRepo, planRepo repository.PlanRepo, userRepo repository.UserRepo) *PaymentHandler {
@@ -30,6 +38,158 @@ func (h *PaymentHandler) SetDiscountRepo(repo *repository.DiscountCodeRepository
 	h.discountRepo = repo
 }
 
+func (h *PaymentHandler) SetEmailService(svc *services.EmailService) {
+	h.emailService = svc
+}
+
+func (h *PaymentHandler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+}
+
+func (h *PaymentHandler) SetTaxService(svc *services.TaxService) {
+	h.taxService = svc
+}
+
+// issueTaxDocument emite la boleta/factura de un pago completado. Sin tipo explícito solo emite si AutoIssue está activo.
+// Un fallo (ej. sin folios) no revierte el pago: el admin puede emitirlo luego desde /tax/documents.
+func (h *PaymentHandler) issueTaxDocument(paymentID int64, docType int, receptor *models.TaxReceptor) *models.TaxDocument {
+	if h.taxService == nil || !h.taxService.Enabled() {
+		return nil
+	}
+	if docType == 0 && !h.taxService.AutoIssue() {
+		return nil
+	}
+	doc, err := h.taxService.IssueForPayment(paymentID, docType, receptor)
+	if err != nil {
+		log.Printf("[WARN] tax document for payment %d: %v", paymentID, err)
+		return nil
+	}
+	return doc
+}
+
+var errInvalidDiscountCode = errors.New("invalid discount code")
+
+// effectivePrice calcula el precio a cobrar: precio de prueba si aplica y luego el código de descuento.
+func (h *PaymentHandler) effectivePrice(user *models.User, plan *models.Plan, discountCode string) (int64, *int64, error) {
+	price := plan.Price
+	if plan.TrialPrice > 0 && plan.TrialDays > 0 {
+		// Eligible if user registered within TrialDays and has no prior subscriptions
+		daysSinceReg := int(time.Since(user.CreatedAt).Hours() / 24)
+		if daysSinceReg <= plan.TrialDays {
+			existing, _ := h.paymentRepo.GetActiveSubscription(user.ID)
+			if existing == nil {
+				price = plan.TrialPrice
+			}
+		}
+	}
+
+	if discountCode == "" || h.discountRepo == nil {
+		return price, nil, nil
+	}
+	dc, err := h.discountRepo.Validate(discountCode)
+	if err != nil {
+		return 0, nil, errInvalidDiscountCode
+	}
+	return dc.ApplyDiscount(price), &dc.ID, nil
+}
+
+// newSubscription arma la suscripción asociada a un pago completado
+func newSubscription(payment *models.Payment, plan *models.Plan) *models.Subscription {
+	startDate := time.Now()
+	return &models.Subscription{
+		UserID:         payment.UserID,
+		PlanID:         plan.ID,
+		PaymentID:      payment.ID,
+		StartDate:      startDate,
+		EndDate:        startDate.AddDate(0, 0, plan.Duration),
+		ClassesUsed:    0,
+		ClassesAllowed: plan.MaxClasses,
+		Active:         true,
+	}
+}
+
+// activateSubscription crea la suscripción asociada a un pago completado
+func (h *PaymentHandler) activateSubscription(payment *models.Payment, plan *models.Plan) (*models.Subscription, error) {
+	subscription := newSubscription(payment, plan)
+	if err := h.paymentRepo.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+var (
+	errPaymentNotPending = errors.New("payment is not pending")
+	errPlanNotFound      = errors.New("plan not found")
+)
+
+// completePending completa un pago pendiente, activa la suscripción y avisa al miembro.
+// Lo usan la aprobación manual y la conciliación bancaria.
+func (h *PaymentHandler) completePending(payment *models.Payment, adminID int64) (*models.Subscription, error) {
+	if payment.Status != models.PaymentPending {
+		return nil, errPaymentNotPending
+	}
+
+	plan, err := h.planRepo.GetByID(payment.PlanID)
+	if err != nil {
+		return nil, errPlanNotFound
+	}
+
+	// Aprobación y suscripción van juntas: si falla la suscripción el pago sigue pendiente
+	subscription := newSubscription(payment, plan)
+	if err := h.paymentRepo.CompleteWithSubscription(payment.ID, adminID, subscription); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errPaymentNotPending
+		}
+		return nil, err
+	}
+	payment.Status = models.PaymentCompleted
+
+	if payment.DiscountCodeID != nil && h.discountRepo != nil {
+		_ = h.discountRepo.IncrementUses(*payment.DiscountCodeID)
+	}
+
+	h.issueTaxDocument(payment.ID, payment.TaxDocumentType, nil)
+
+	if h.emailService != nil {
+		if u, err := h.userRepo.GetByID(payment.UserID); err == nil {
+			go h.emailService.SendTransferApproved(u.Email, u.Name, plan.Name, subscription.EndDate.Format("02/01/2006"))
+		}
+	}
+	return subscription, nil
+}
+
+// recordTransfer registra una transferencia ya recibida (sin pago pendiente previo) y activa la suscripción
+func (h *PaymentHandler) recordTransfer(userID, planID, amount int64) (*models.Payment, *models.Subscription, error) {
+	plan, err := h.planRepo.GetByID(planID)
+	if err != nil {
+		return nil, nil, errPlanNotFound
+	}
+	payment := &models.Payment{
+		UserID:        userID,
+		PlanID:        plan.ID,
+		Amount:        amount,
+		Currency:      plan.Currency,
+		Status:        models.PaymentCompleted,
+		PaymentMethod: models.PaymentMethodTransferencia,
+	}
+	if err := h.paymentRepo.Create(payment); err != nil {
+		return nil, nil, err
+	}
+	subscription, err := h.activateSubscription(payment, plan)
+	if err != nil {
+		return payment, nil, err
+	}
+	h.issueTaxDocument(payment.ID, 0, nil)
+	return payment, subscription, nil
+}
+
+func (h *PaymentHandler) pendingExpiryDays() int {
+	if h.cfg != nil && h.cfg.PendingPaymentExpiryDays > 0 {
+		return h.cfg.PendingPaymentExpiryDays
+	}
+	return 3
+}
+
 // Create - Solo admin registra pagos (efectivo, débito, transferencia). Transferencia requiere proof_image_url.
 func (h *PaymentHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePaymentRequest
@@ -69,41 +229,23 @@ func (h *PaymentHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine effective price: trial or regular
-	effectivePrice := plan.Price
-	if plan.TrialPrice > 0 && plan.TrialDays > 0 {
-		// Eligible if user registered within TrialDays and has no prior subscriptions
-		daysSinceReg := int(time.Since(user.CreatedAt).Hours() / 24)
-		if daysSinceReg <= plan.TrialDays {
-			existing, _ := h.paymentRepo.GetActiveSubscription(req.UserID)
-			if existing == nil {
-				effectivePrice = plan.TrialPrice
-			}
-		}
-	}
-
-	// Apply discount code if provided
-	var discountCodeID int64
-	if req.DiscountCode != "" && h.discountRepo != nil {
-		dc, dcErr := h.discountRepo.Validate(req.DiscountCode)
-		if dcErr != nil {
-			respondError(w, http.StatusBadRequest, "Invalid or expired discount code")
-			return
-		}
-		effectivePrice = dc.ApplyDiscount(effectivePrice)
-		discountCodeID = dc.ID
+	effectivePrice, discountCodeID, err := h.effectivePrice(user, plan, req.DiscountCode)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid or expired discount code")
+		return
 	}
 
 	payment := &models.Payment{
-		UserID:        req.UserID,
-		PlanID:        plan.ID,
-		Amount:        effectivePrice,
-		Currency:      plan.Currency,
-		Status:        models.PaymentCompleted,
-		PaymentMethod: req.PaymentMethod,
-		ProofImageURL: req.ProofImageURL,
+		UserID:          req.UserID,
+		PlanID:          plan.ID,
+		Amount:          effectivePrice,
+		Currency:        plan.Currency,
+		Status:          models.PaymentCompleted,
+		PaymentMethod:   req.PaymentMethod,
+		ProofImageURL:   req.ProofImageURL,
+		DiscountCodeID:  discountCodeID,
+		TaxDocumentType: req.DocumentType,
 	}
-	_ = discountCodeID
 
 	if err := h.paymentRepo.Create(payment); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create payment")
@@ -111,35 +253,212 @@ func (h *PaymentHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Increment discount code uses
-	if discountCodeID > 0 && h.discountRepo != nil {
-		_ = h.discountRepo.IncrementUses(discountCodeID)
+	if discountCodeID != nil && h.discountRepo != nil {
+		_ = h.discountRepo.IncrementUses(*discountCodeID)
 	}
 
-	startDate := time.Now()
-	endDate := startDate.AddDate(0, 0, plan.Duration)
+	subscription, err := h.activateSubscription(payment, plan)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create subscription")
+		return
+	}
 
-	subscription := &models.Subscription{
-		UserID:         req.UserID,
-		PlanID:         plan.ID,
-		PaymentID:      payment.ID,
-		StartDate:      startDate,
-		EndDate:        endDate,
-		ClassesUsed:    0,
-		ClassesAllowed: plan.MaxClasses,
-		Active:         true,
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"payment":      payment,
+		"subscription": subscription,
+		"tax_document": h.issueTaxDocument(payment.ID, req.DocumentType, req.Receptor),
+	})
+}
+
+// SubmitTransfer - Miembro informa una transferencia con comprobante. Queda pendiente hasta que un admin la revise.
+func (h *PaymentHandler) SubmitTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req models.SubmitTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.PlanID <= 0 {
+		respondError(w, http.StatusBadRequest, "plan_id is required")
+		return
+	}
+	if req.ProofImageURL == "" {
+		respondError(w, http.StatusBadRequest, "proof_image_url is required")
+		return
 	}
 
-	if err := h.paymentRepo.CreateSubscription(subscription); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create subscription")
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
+	plan, err := h.planRepo.GetByID(req.PlanID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Plan not found")
+		return
+	}
+	if !plan.Active {
+		respondError(w, http.StatusBadRequest, "Plan is not active")
+		return
+	}
+
+	amount, discountCodeID, err := h.effectivePrice(user, plan, req.DiscountCode)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid or expired discount code")
+		return
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, h.pendingExpiryDays())
+	payment := &models.Payment{
+		UserID:          userID,
+		PlanID:          plan.ID,
+		Amount:          amount,
+		Currency:        plan.Currency,
+		Status:          models.PaymentPending,
+		PaymentMethod:   models.PaymentMethodTransferencia,
+		ProofImageURL:   req.ProofImageURL,
+		DiscountCodeID:  discountCodeID,
+		ExpiresAt:       &expiresAt,
+		TaxDocumentType: req.DocumentType,
+	}
+
+	if err := h.paymentRepo.Create(payment); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to submit payment")
+		return
+	}
+
+	// Datos de facturación capturados al pagar; el documento se emite al aprobar
+	if req.Receptor != nil && h.taxService != nil {
+		_ = h.taxService.SaveBillingProfile(userID, req.Receptor)
+	}
+
+	respondJSON(w, http.StatusCreated, payment)
+}
+
+// ListPending - Cola de aprobación de transferencias (admin)
+func (h *PaymentHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	_, _ = h.paymentRepo.ExpirePendingPayments()
+
+	payments, err := h.paymentRepo.ListPending(limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch pending payments")
+		return
+	}
+
+	if payments == nil {
+		payments = []*models.PaymentWithDetails{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"payments": payments,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// Approve - Admin valida el comprobante: el pago queda completado y se crea la suscripción
+func (h *PaymentHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	_, _ = h.paymentRepo.ExpirePendingPayments()
+
+	payment, err := h.paymentRepo.GetByID(id)
+	if err != nil || payment == nil {
+		respondError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	subscription, err := h.completePending(payment, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errPaymentNotPending):
+			respondError(w, http.StatusConflict, "Payment is not pending")
+		case errors.Is(err, errPlanNotFound):
+			respondError(w, http.StatusNotFound, "Plan not found")
+		default:
+			respondError(w, http.StatusInternalServerError, "Failed to approve payment")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"payment":      payment,
 		"subscription": subscription,
 	})
 }
 
+// Reject - Admin rechaza el comprobante indicando el motivo; se notifica al miembro
+func (h *PaymentHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserID(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return
+	}
+
+	var req models.RejectPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Reason == "" {
+		respondError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	payment, err := h.paymentRepo.GetByID(id)
+	if err != nil || payment == nil {
+		respondError(w, http.StatusNotFound, "Payment not found")
+		return
+	}
+
+	if err := h.paymentRepo.Review(payment.ID, models.PaymentRejected, adminID, req.Reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusConflict, "Payment is not pending")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to reject payment")
+		return
+	}
+	payment.Status = models.PaymentRejected
+	payment.RejectionReason = req.Reason
+
+	if h.emailService != nil {
+		if u, err := h.userRepo.GetByID(payment.UserID); err == nil {
+			planName := ""
+			if plan, err := h.planRepo.GetByID(payment.PlanID); err == nil {
+				planName = plan.Name
+			}
+			go h.emailService.SendTransferRejected(u.Email, u.Name, planName, req.Reason)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, payment)
+}
+
 func (h *PaymentHandler) MyPayments(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 
@@ -157,6 +476,8 @@ func (h *PaymentHandler) MyPayments(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	_, _ = h.paymentRepo.ExpirePendingPayments()
+
 	payments, err := h.paymentRepo.ListByUser(userID, limit, offset)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch payments")
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -18,21 +19,30 @@ type mockPaymentUserRepo struct {
 	err  error
 }
 
-func (m *mockPaymentUserRepo) Create(user *models.User) error                 { return nil }
-func (m *mockPaymentUserRepo) GetByEmail(email string) (*models.User, error)  { return m.user, m.err }
-func (m *mockPaymentUserRepo) GetByID(id int64) (*models.User, error)         { return m.user, m.err }
-func (m *mockPaymentUserRepo) Update(user *models.User) error                 { return nil }
-func (m *mockPaymentUserRepo) Delete(id int64) error                          { return nil }
-func (m *mockPaymentUserRepo) List(limit, offset int) ([]*models.User, error) { return nil, nil }
-func (m *mockPaymentUserRepo) SaveRefreshToken(userID int64, token string, expiresAt time.Time) error {
-	return nil
-}
-func (m *mockPaymentUserRepo) GetRefreshToken(token string) (int64, error)        { return 0, nil }
-func (m *mockPaymentUserRepo) DeleteRefreshToken(token string) error              { return nil }
+func (m *mockPaymentUserRepo) Create(user *models.User) error                     { return nil }
+func (m *mockPaymentUserRepo) GetByEmail(email string) (*models.User, error)      { return m.user, m.err }
+func (m *mockPaymentUserRepo) GetByID(id int64) (*models.User, error)             { return m.user, m.err }
+func (m *mockPaymentUserRepo) Update(user *models.User) error                     { return nil }
+func (m *mockPaymentUserRepo) Delete(id int64) error                              { return nil }
+func (m *mockPaymentUserRepo) List(limit, offset int) ([]*models.User, error)     { return nil, nil }
 func (m *mockPaymentUserRepo) AddInvitationClasses(userID int64, count int) error { return nil }
 func (m *mockPaymentUserRepo) UseInvitationClass(userID int64) (bool, error)      { return true, nil }
+func (m *mockPaymentUserRepo) UpdatePassword(userID int64, passwordHash string) error {
+	return nil
+}
+func (m *mockPaymentUserRepo) SetEmailVerified(userID int64) error { return nil }
+func (m *mockPaymentUserRepo) CreateAuthToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+func (m *mockPaymentUserRepo) ConsumeAuthToken(purpose, tokenHash string) (int64, error) {
+	return 0, nil
+}
 
 type mockPaymentRepo struct {
+	created                  *models.Payment
+	getByID                  *models.Payment
+	reviewErr                error
+	reviewedStatus           models.PaymentStatus
 	createErr                error
 	updateStatusErr          error
 	createSubscriptionErr    error
@@ -42,8 +52,16 @@ type mockPaymentRepo struct {
 	getActiveSubscriptionErr error
 }
 
-func (m *mockPaymentRepo) Create(payment *models.Payment) error      { return m.createErr }
-func (m *mockPaymentRepo) GetByID(id int64) (*models.Payment, error) { return nil, nil }
+func (m *mockPaymentRepo) Create(payment *models.Payment) error {
+	m.created = payment
+	return m.createErr
+}
+func (m *mockPaymentRepo) GetByID(id int64) (*models.Payment, error) {
+	if m.getByID == nil {
+		return nil, sql.ErrNoRows
+	}
+	return m.getByID, nil
+}
 func (m *mockPaymentRepo) UpdateStatus(id int64, status models.PaymentStatus) error {
 	return m.updateStatusErr
 }
@@ -68,6 +86,29 @@ func (m *mockPaymentRepo) GetActiveSubscription(userID int64) (*models.Subscript
 func (m *mockPaymentRepo) IncrementClassesUsed(subscriptionID int64) error { return nil }
 func (m *mockPaymentRepo) DecrementClassesUsed(subscriptionID int64) error { return nil }
 func (m *mockPaymentRepo) DeactivateExpiredSubscriptions() error           { return nil }
+func (m *mockPaymentRepo) FreezeSubscription(userID int64, frozenUntil time.Time) error {
+	return nil
+}
+func (m *mockPaymentRepo) UnfreezeSubscription(userID int64) error { return nil }
+func (m *mockPaymentRepo) ListPending(limit, offset int) ([]*models.PaymentWithDetails, error) {
+	return []*models.PaymentWithDetails{}, nil
+}
+func (m *mockPaymentRepo) Review(id int64, status models.PaymentStatus, reviewedBy int64, reason string) error {
+	m.reviewedStatus = status
+	return m.reviewErr
+}
+func (m *mockPaymentRepo) CompleteWithSubscription(id, reviewedBy int64, sub *models.Subscription) error {
+	// Simula la transacción: sin suscripción el pago no cambia de estado
+	if m.reviewErr != nil {
+		return m.reviewErr
+	}
+	if m.createSubscriptionErr != nil {
+		return m.createSubscriptionErr
+	}
+	m.reviewedStatus = models.PaymentCompleted
+	return nil
+}
+func (m *mockPaymentRepo) ExpirePendingPayments() (int64, error) { return 0, nil }
 
 type mockPlanRepo struct {
 	getByIDPlan *models.Plan
@@ -267,3 +308,156 @@ func TestPaymentHandler_ListAll_Success(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestPaymentHandler_SubmitTransfer_ProofRequired(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{}
+	planRepo := &mockPlanRepo{}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	body := `{"plan_id":1}`
+	req := httptest.NewRequest("POST", "/api/v1/payments/transfer", bytes.NewReader([]byte(body)))
+	req = paymentRequestWithAuth(req, 1)
+	rr := httptest.NewRecorder()
+
+	handler.SubmitTransfer(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestPaymentHandler_SubmitTransfer_CreatesPending(t *testing.T) {
+	plan := &models.Plan{ID: 1, Name: "Test", Price: 10000, Currency: "CLP", Duration: 30, Active: true}
+	paymentRepo := &mockPaymentRepo{}
+	planRepo := &mockPlanRepo{getByIDPlan: plan}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	body := `{"plan_id":1,"proof_image_url":"http://localhost/uploads/proof.jpg"}`
+	req := httptest.NewRequest("POST", "/api/v1/payments/transfer", bytes.NewReader([]byte(body)))
+	req = paymentRequestWithAuth(req, 1)
+	rr := httptest.NewRecorder()
+
+	handler.SubmitTransfer(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	p := paymentRepo.created
+	if p == nil || p.Status != models.PaymentPending || p.PaymentMethod != models.PaymentMethodTransferencia {
+		t.Fatalf("expected pending transferencia payment, got %+v", p)
+	}
+	if p.ExpiresAt == nil || !p.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expected future expires_at, got %v", p.ExpiresAt)
+	}
+}
+
+func TestPaymentHandler_Approve_NotPending(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{getByID: &models.Payment{ID: 5, UserID: 1, PlanID: 1, Status: models.PaymentRejected}}
+	planRepo := &mockPlanRepo{getByIDPlan: &models.Plan{ID: 1, Duration: 30, Active: true}}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/5/approve", nil)
+	req.SetPathValue("id", "5")
+	req = adminRequestWithAuth(req)
+	rr := httptest.NewRecorder()
+
+	handler.Approve(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+func TestPaymentHandler_Approve_SubscriptionFailureKeepsPending(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{
+		getByID:               &models.Payment{ID: 5, UserID: 1, PlanID: 1, Status: models.PaymentPending},
+		createSubscriptionErr: errors.New("insert failed"),
+	}
+	planRepo := &mockPlanRepo{getByIDPlan: &models.Plan{ID: 1, Duration: 30, Active: true}}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/5/approve", nil)
+	req.SetPathValue("id", "5")
+	req = adminRequestWithAuth(req)
+	rr := httptest.NewRecorder()
+
+	handler.Approve(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if paymentRepo.reviewedStatus != "" || paymentRepo.getByID.Status != models.PaymentPending {
+		t.Fatalf("payment must stay pending, got %q / %q", paymentRepo.reviewedStatus, paymentRepo.getByID.Status)
+	}
+}
+
+func TestPaymentHandler_Approve_CreatesSubscription(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{getByID: &models.Payment{ID: 5, UserID: 1, PlanID: 1, Status: models.PaymentPending}}
+	planRepo := &mockPlanRepo{getByIDPlan: &models.Plan{ID: 1, Duration: 30, Active: true}}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/5/approve", nil)
+	req.SetPathValue("id", "5")
+	req = adminRequestWithAuth(req)
+	rr := httptest.NewRecorder()
+
+	handler.Approve(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if paymentRepo.reviewedStatus != models.PaymentCompleted {
+		t.Fatalf("expected payment marked completed, got %q", paymentRepo.reviewedStatus)
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result["subscription"] == nil {
+		t.Fatalf("expected subscription in response")
+	}
+}
+
+func TestPaymentHandler_Reject_ReasonRequired(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{getByID: &models.Payment{ID: 5, UserID: 1, PlanID: 1, Status: models.PaymentPending}}
+	planRepo := &mockPlanRepo{}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/5/reject", bytes.NewReader([]byte(`{}`)))
+	req.SetPathValue("id", "5")
+	req = adminRequestWithAuth(req)
+	rr := httptest.NewRecorder()
+
+	handler.Reject(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestPaymentHandler_Reject_Success(t *testing.T) {
+	paymentRepo := &mockPaymentRepo{getByID: &models.Payment{ID: 5, UserID: 1, PlanID: 1, Status: models.PaymentPending}}
+	planRepo := &mockPlanRepo{}
+	userRepo := &mockPaymentUserRepo{user: &models.User{ID: 1}}
+	handler := NewPaymentHandler(paymentRepo, planRepo, userRepo)
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/5/reject", bytes.NewReader([]byte(`{"reason":"Monto no coincide"}`)))
+	req.SetPathValue("id", "5")
+	req = adminRequestWithAuth(req)
+	rr := httptest.NewRecorder()
+
+	handler.Reject(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if paymentRepo.reviewedStatus != models.PaymentRejected {
+		t.Fatalf("expected payment marked rejected, got %q", paymentRepo.reviewedStatus)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// PlatformHandler - API de super-admin para aprovisionar y administrar tenants
+type PlatformHandler struct {
+	tenantService *services.TenantService
+}
+
+func NewPlatformHandler(tenantService *services.TenantService) *PlatformHandler {
+	return &PlatformHandler{tenantService: tenantService}
+}
+
+func respondTenantError(w http.ResponseWriter, err error) {
+	switch err {
+	case services.ErrTenantInvalidSlug, services.ErrTenantNameMissing, services.ErrTenantOwner:
+		respondError(w, http.StatusBadRequest, err.Error())
+	case services.ErrTenantExists:
+		respondError(w, http.StatusConflict, err.Error())
+	case services.ErrTenantNotFound:
+		respondError(w, http.StatusNotFound, "Tenant not found")
+	default:
+		respondError(w, http.StatusInternalServerError, "Tenant operation failed")
+	}
+}
+
+func (h *PlatformHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.tenantService.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tenants")
+		return
+	}
+	if tenants == nil {
+		tenants = []*models.Tenant{}
+	}
+	respondJSON(w, http.StatusOK, tenants)
+}
+
+func (h *PlatformHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, err := h.tenantService.Get(r.PathValue("slug"))
+	if err != nil {
+		respondTenantError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
+
+// CreateTenant - Aprovisiona el tenant (schema, migraciones y cuenta del dueño)
+func (h *PlatformHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	t, err := h.tenantService.Provision(&req)
+	if err != nil {
+		respondTenantError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, t)
+}
+
+// UpdateTenant - Nombre, configuración propia o suspensión (active=false)
+func (h *PlatformHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	t, err := h.tenantService.Update(r.PathValue("slug"), &req)
+	if err != nil {
+		respondTenantError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
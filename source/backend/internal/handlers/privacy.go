@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// PrivacyHandler - Descarga de datos personales y solicitudes de eliminación de cuenta
+type PrivacyHandler struct {
+	privacyService *services.PrivacyService
+	privacyRepo    *repository.PrivacyRepository
+}
+
+func NewPrivacyHandler(privacyService *services.PrivacyService, privacyRepo *repository.PrivacyRepository) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService, privacyRepo: privacyRepo}
+}
+
+func respondErasureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrErasureNotFound), errors.Is(err, sql.ErrNoRows):
+		respondError(w, http.StatusNotFound, services.ErrErasureNotFound.Error())
+	case errors.Is(err, services.ErrErasureOpen):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrErasureStaff):
+		respondError(w, http.StatusForbidden, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process erasure request")
+	}
+}
+
+// ExportMe - ZIP con los datos personales del miembro autenticado
+func (h *PrivacyHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	var buf bytes.Buffer
+	if err := h.privacyService.Export(userID, &buf); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to export data")
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mis-datos-%s.zip"`, time.Now().Format("20060102")))
+	w.Write(buf.Bytes())
+}
+
+// RequestErasure - El miembro pide eliminar su cuenta; queda pendiente de aprobación
+func (h *PrivacyHandler) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	var req models.ErasureRequestInput
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	e, err := h.privacyService.RequestErasure(middleware.GetUserID(r.Context()), req.Reason)
+	if err != nil {
+		respondErasureError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, e)
+}
+
+// MyErasure - Solicitud de eliminación en curso del miembro
+func (h *PrivacyHandler) MyErasure(w http.ResponseWriter, r *http.Request) {
+	e, err := h.privacyService.OpenErasure(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondErasureError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, e)
+}
+
+// CancelErasure - El miembro retira su solicitud durante la revisión o el periodo de gracia
+func (h *PrivacyHandler) CancelErasure(w http.ResponseWriter, r *http.Request) {
+	if err := h.privacyService.CancelErasure(middleware.GetUserID(r.Context())); err != nil {
+		respondErasureError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Erasure request cancelled"})
+}
+
+// ListErasures - Solicitudes de eliminación (?status=pending)
+func (h *PrivacyHandler) ListErasures(w http.ResponseWriter, r *http.Request) {
+	list, err := h.privacyRepo.ListErasures(r.URL.Query().Get("status"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch erasure requests")
+		return
+	}
+	if list == nil {
+		list = []*models.ErasureRequest{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+func (h *PrivacyHandler) review(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request ID")
+		return
+	}
+	var req models.ReviewErasureRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	adminID := middleware.GetUserID(r.Context())
+	var e *models.ErasureRequest
+	if approve {
+		e, err = h.privacyService.Approve(id, adminID, req.Notes)
+	} else {
+		e, err = h.privacyService.Reject(id, adminID, req.Notes)
+	}
+	if err != nil {
+		respondErasureError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, e)
+}
+
+// ApproveErasure - Aprueba la solicitud; la cuenta se anonimiza al terminar el periodo de gracia
+func (h *PrivacyHandler) ApproveErasure(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, true)
+}
+
+// RejectErasure - Rechaza la solicitud (p. ej. deuda pendiente)
+func (h *PrivacyHandler) RejectErasure(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, false)
+}
@@ -2,22 +2,35 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"boxmagic/internal/middleware"
 	"boxmagic/internal/models"
 	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
 )
 
 type ProductHandler struct {
-	repo *repository.ProductRepository
+	repo       *repository.ProductRepository
+	ledgerRepo *repository.LedgerRepository
+	taxService *services.TaxService
 }
 
 func NewProductHandler(repo *repository.ProductRepository) *ProductHandler {
 	return &ProductHandler{repo: repo}
 }
 
+func (h *ProductHandler) SetLedgerRepo(repo *repository.LedgerRepository) {
+	h.ledgerRepo = repo
+}
+
+func (h *ProductHandler) SetTaxService(svc *services.TaxService) {
+	h.taxService = svc
+}
+
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	activeOnly := r.URL.Query().Get("active") != "false"
 	products, err := h.repo.ListProducts(activeOnly)
@@ -143,6 +156,37 @@ func (h *ProductHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
 	if req.PaymentMethod == "" {
 		req.PaymentMethod = "cash"
 	}
+	for _, it := range req.Items {
+		if it.GiftCard && (it.UnitPrice <= 0 || it.Quantity <= 0) {
+			respondError(w, http.StatusBadRequest, "Gift card items require a positive unit_price and quantity")
+			return
+		}
+	}
+
+	// Venta cargada a la cuenta del miembro (fiado) o pagada con su saldo a favor
+	chargeToAccount := req.PaymentMethod == models.SalePaymentAccount || req.PaymentMethod == models.SalePaymentStoreCredit
+	if chargeToAccount {
+		if req.UserID == nil {
+			respondError(w, http.StatusBadRequest, "user_id is required to charge the member account")
+			return
+		}
+		if h.ledgerRepo == nil {
+			respondError(w, http.StatusBadRequest, "Member accounts are not enabled")
+			return
+		}
+		if req.PaymentMethod == models.SalePaymentStoreCredit {
+			balance, err := h.ledgerRepo.Balance(*req.UserID)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to fetch balance")
+				return
+			}
+			if balance < total {
+				respondError(w, http.StatusBadRequest, "Insufficient store credit")
+				return
+			}
+		}
+	}
+
 	sale := &models.Sale{
 		UserID: req.UserID, Total: total,
 		PaymentMethod: req.PaymentMethod, Notes: req.Notes,
@@ -152,6 +196,47 @@ func (h *ProductHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to create sale")
 		return
 	}
+
+	if chargeToAccount {
+		entry := &models.LedgerEntry{
+			UserID:      *req.UserID,
+			Type:        models.LedgerPOSCharge,
+			Amount:      -total,
+			Description: "Compra POS #" + strconv.FormatInt(sale.ID, 10),
+			SaleID:      &sale.ID,
+			CreatedBy:   &createdBy,
+		}
+		if err := h.ledgerRepo.AddEntry(entry); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to charge member account")
+			return
+		}
+	}
+
+	// Gift cards vendidas: se emite un código por unidad
+	if h.ledgerRepo != nil {
+		for _, it := range sale.Items {
+			if !it.GiftCard {
+				continue
+			}
+			for i := 0; i < it.Quantity; i++ {
+				gc := &models.GiftCard{Amount: it.UnitPrice, SaleID: &sale.ID}
+				if err := h.ledgerRepo.CreateGiftCard(gc); err != nil {
+					respondError(w, http.StatusInternalServerError, "Failed to issue gift card")
+					return
+				}
+				sale.GiftCards = append(sale.GiftCards, *gc)
+			}
+		}
+	}
+
+	// Boleta/factura de la venta (las gift cards se excluyen del documento)
+	if h.taxService != nil && h.taxService.Enabled() && (req.DocumentType != 0 || h.taxService.AutoIssue()) {
+		doc, err := h.taxService.IssueForSale(sale.ID, req.DocumentType, req.Receptor)
+		if err != nil && !errors.Is(err, services.ErrNothingToInvoice) {
+			log.Printf("[WARN] tax document for sale %d: %v", sale.ID, err)
+		}
+		sale.TaxDocument = doc
+	}
 	respondJSON(w, http.StatusCreated, sale)
 }
 
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// ProgrammingHandler - Tracks de programación y su calendario de rutinas
+type ProgrammingHandler struct {
+	programmingService *services.ProgrammingService
+	trackRepo          *repository.ProgrammingRepository
+}
+
+func NewProgrammingHandler(programmingService *services.ProgrammingService, trackRepo *repository.ProgrammingRepository) *ProgrammingHandler {
+	return &ProgrammingHandler{programmingService: programmingService, trackRepo: trackRepo}
+}
+
+func respondProgrammingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTrackNotFound), errors.Is(err, services.ErrTrackDayNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrTrackNameConflict), errors.Is(err, services.ErrTrackTargetTaken),
+		errors.Is(err, services.ErrTrackShiftConflict):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrTrackInvalid), errors.Is(err, services.ErrTrackPublishTime),
+		errors.Is(err, services.ErrTrackRoutine), errors.Is(err, services.ErrTrackTargetNotFound),
+		errors.Is(err, services.ErrTrackSameWeek), errors.Is(err, services.ErrTrackShiftInvalid):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process programming track")
+	}
+}
+
+func trackID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid track ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func parseDay(w http.ResponseWriter, value, field string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid "+field+" (YYYY-MM-DD)")
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// List - Tracks con sus clases y disciplinas vinculadas (?all=true incluye inactivos)
+func (h *ProgrammingHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.trackRepo.List(r.URL.Query().Get("all") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tracks")
+		return
+	}
+	if list == nil {
+		list = []*models.ProgrammingTrack{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+func (h *ProgrammingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	t, err := h.programmingService.Get(id)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
+
+func (h *ProgrammingHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.TrackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	t, err := h.programmingService.Create(&req, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, t)
+}
+
+func (h *ProgrammingHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	var req models.TrackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	t, err := h.programmingService.Update(id, &req)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
+
+// Delete - Desactiva el track; sus horarios futuros dejan de recibir rutina
+func (h *ProgrammingHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.programmingService.Deactivate(id); err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetTargets - Reemplaza las clases y disciplinas que siguen el track
+func (h *ProgrammingHandler) SetTargets(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	var req models.TrackTargetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	t, err := h.programmingService.SetTargets(id, &req)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, t)
+}
+
+// Days - Calendario del track (?from=&to=, por defecto las próximas 6 semanas)
+func (h *ProgrammingHandler) Days(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, ok = parseDay(w, v, "from"); !ok {
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 41)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, ok = parseDay(w, v, "to"); !ok {
+			return
+		}
+	}
+	days, err := h.programmingService.Days(id, from, to)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"days": days,
+		"from": from.Format("2006-01-02"),
+		"to":   to.Format("2006-01-02"),
+	})
+}
+
+// SetDay - Programa la rutina de una fecha (publish_at opcional adelanta o atrasa su publicación)
+func (h *ProgrammingHandler) SetDay(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	date, ok := parseDay(w, r.PathValue("date"), "date")
+	if !ok {
+		return
+	}
+	var req models.TrackDayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RoutineID <= 0 {
+		respondError(w, http.StatusBadRequest, "Routine ID is required")
+		return
+	}
+	day, err := h.programmingService.SetDay(id, date, &req)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, day)
+}
+
+func (h *ProgrammingHandler) DeleteDay(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	date, ok := parseDay(w, r.PathValue("date"), "date")
+	if !ok {
+		return
+	}
+	if err := h.programmingService.DeleteDay(id, date); err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CopyWeek - Copia una semana del calendario a otra
+func (h *ProgrammingHandler) CopyWeek(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	var req models.CopyWeekRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	from, ok := parseDay(w, req.From, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseDay(w, req.To, "to")
+	if !ok {
+		return
+	}
+	n, err := h.programmingService.CopyWeek(id, from, to, req.Overwrite)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]int64{"copied": n})
+}
+
+// ShiftDays - Corre los días de un rango hacia adelante o atrás
+func (h *ProgrammingHandler) ShiftDays(w http.ResponseWriter, r *http.Request) {
+	id, ok := trackID(w, r)
+	if !ok {
+		return
+	}
+	var req models.ShiftDaysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	from, ok := parseDay(w, req.From, "from")
+	if !ok {
+		return
+	}
+	var to *time.Time
+	if req.To != "" {
+		t, ok := parseDay(w, req.To, "to")
+		if !ok {
+			return
+		}
+		to = &t
+	}
+	n, err := h.programmingService.ShiftDays(id, from, to, req.Days)
+	if err != nil {
+		respondProgrammingError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]int{"shifted": n})
+}
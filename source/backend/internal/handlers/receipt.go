@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// ReceiptHandler - Comprobantes PDF de pagos y ventas, y certificado anual de pagos del miembro
+type ReceiptHandler struct {
+	receiptRepo  *repository.ReceiptRepository
+	userRepo     repository.UserRepo
+	emailService *services.EmailService
+	branding     services.ReceiptBranding
+}
+
+func NewReceiptHandler(receiptRepo *repository.ReceiptRepository, userRepo repository.UserRepo, emailService *services.EmailService, branding services.ReceiptBranding) *ReceiptHandler {
+	return &ReceiptHandler{receiptRepo: receiptRepo, userRepo: userRepo, emailService: emailService, branding: branding}
+}
+
+func writePDF(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	w.Write(data)
+}
+
+// canAccess permite al staff con el permiso ver todo y al miembro solo lo propio
+func canAccess(r *http.Request, perm string, ownerID *int64) bool {
+	if middleware.HasPermission(r.Context(), perm) {
+		return true
+	}
+	return ownerID != nil && *ownerID == middleware.GetUserID(r.Context())
+}
+
+func (h *ReceiptHandler) paymentReceipt(w http.ResponseWriter, r *http.Request) (*models.PaymentReceipt, []byte, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid payment ID")
+		return nil, nil, false
+	}
+	rc, err := h.receiptRepo.PaymentReceipt(id)
+	if err == sql.ErrNoRows || (err == nil && !canAccess(r, models.PermPaymentsView, &rc.UserID)) {
+		respondError(w, http.StatusNotFound, "Payment not found")
+		return nil, nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch payment")
+		return nil, nil, false
+	}
+	if rc.Status != models.PaymentCompleted && rc.Status != models.PaymentRefunded {
+		respondError(w, http.StatusBadRequest, "Receipts are only available for completed payments")
+		return nil, nil, false
+	}
+	return rc, services.RenderPaymentReceipt(rc, h.branding), true
+}
+
+// PaymentReceipt - PDF del comprobante de un pago
+func (h *ReceiptHandler) PaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	rc, pdf, ok := h.paymentReceipt(w, r)
+	if !ok {
+		return
+	}
+	writePDF(w, "comprobante-"+rc.ReferenceCode+".pdf", pdf)
+}
+
+// EmailPaymentReceipt - Admin envía el comprobante al miembro
+func (h *ReceiptHandler) EmailPaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	rc, pdf, ok := h.paymentReceipt(w, r)
+	if !ok {
+		return
+	}
+	h.email(w, rc.UserEmail, rc.UserName, "Comprobante de pago "+rc.ReferenceCode, "comprobante-"+rc.ReferenceCode+".pdf", pdf)
+}
+
+func (h *ReceiptHandler) saleReceipt(w http.ResponseWriter, r *http.Request) (*models.Sale, []byte, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid sale ID")
+		return nil, nil, false
+	}
+	sale, err := h.receiptRepo.SaleReceipt(id)
+	if err == sql.ErrNoRows || (err == nil && !canAccess(r, models.PermSalesView, sale.UserID)) {
+		respondError(w, http.StatusNotFound, "Sale not found")
+		return nil, nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch sale")
+		return nil, nil, false
+	}
+	return sale, services.RenderSaleReceipt(sale, h.branding), true
+}
+
+// SaleReceipt - PDF del comprobante de una venta POS
+func (h *ReceiptHandler) SaleReceipt(w http.ResponseWriter, r *http.Request) {
+	sale, pdf, ok := h.saleReceipt(w, r)
+	if !ok {
+		return
+	}
+	writePDF(w, fmt.Sprintf("venta-%d.pdf", sale.ID), pdf)
+}
+
+// EmailSaleReceipt - Admin envía el comprobante de venta al miembro asociado
+func (h *ReceiptHandler) EmailSaleReceipt(w http.ResponseWriter, r *http.Request) {
+	sale, pdf, ok := h.saleReceipt(w, r)
+	if !ok {
+		return
+	}
+	if sale.UserID == nil {
+		respondError(w, http.StatusBadRequest, "Sale has no member to email")
+		return
+	}
+	user, err := h.userRepo.GetByID(*sale.UserID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	h.email(w, user.Email, user.Name, fmt.Sprintf("Comprobante de venta V-%d", sale.ID), fmt.Sprintf("venta-%d.pdf", sale.ID), pdf)
+}
+
+func (h *ReceiptHandler) statement(w http.ResponseWriter, r *http.Request, userID int64) (*models.YearlyStatement, []byte, bool) {
+	year := time.Now().Year()
+	if y := r.URL.Query().Get("year"); y != "" {
+		parsed, err := strconv.Atoi(y)
+		if err != nil || parsed < 2000 || parsed > year {
+			respondError(w, http.StatusBadRequest, "Invalid year")
+			return nil, nil, false
+		}
+		year = parsed
+	}
+	st, err := h.receiptRepo.YearlyStatement(userID, year)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "User not found")
+		return nil, nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch statement")
+		return nil, nil, false
+	}
+	return st, services.RenderYearlyStatement(st, h.branding), true
+}
+
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// MyStatement - Certificado anual del miembro autenticado (?year=2025)
+func (h *ReceiptHandler) MyStatement(w http.ResponseWriter, r *http.Request) {
+	st, pdf, ok := h.statement(w, r, middleware.GetUserID(r.Context()))
+	if !ok {
+		return
+	}
+	writePDF(w, fmt.Sprintf("certificado-%d.pdf", st.Year), pdf)
+}
+
+// UserStatement - Certificado anual de un miembro (admin)
+func (h *ReceiptHandler) UserStatement(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	st, pdf, ok := h.statement(w, r, id)
+	if !ok {
+		return
+	}
+	writePDF(w, fmt.Sprintf("certificado-%d.pdf", st.Year), pdf)
+}
+
+// EmailUserStatement - Admin envía el certificado anual al miembro
+func (h *ReceiptHandler) EmailUserStatement(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	st, pdf, ok := h.statement(w, r, id)
+	if !ok {
+		return
+	}
+	h.email(w, st.UserEmail, st.UserName, fmt.Sprintf("Certificado anual de pagos %d", st.Year), fmt.Sprintf("certificado-%d.pdf", st.Year), pdf)
+}
+
+func (h *ReceiptHandler) email(w http.ResponseWriter, to, name, title, filename string, pdf []byte) {
+	if h.emailService == nil {
+		respondError(w, http.StatusServiceUnavailable, "Email is not configured")
+		return
+	}
+	if err := h.emailService.SendReceipt(to, name, title, filename, pdf); err != nil {
+		respondError(w, http.StatusBadGateway, "Failed to send email")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Email sent", "to": to})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// RoleHandler - Catálogo de permisos y roles del staff (integrados y personalizados)
+type RoleHandler struct {
+	roleService *services.RoleService
+}
+
+func NewRoleHandler(roleService *services.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+func respondRoleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleInUse), errors.Is(err, services.ErrRoleBuiltIn):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrRoleInvalidName), errors.Is(err, services.ErrUnknownPermission), errors.Is(err, services.ErrRoleLabelRequired):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to save role")
+	}
+}
+
+// Permissions - Catálogo de permisos disponibles
+func (h *RoleHandler) Permissions(w http.ResponseWriter, r *http.Request) {
+	type permission struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	perms := []permission{}
+	for _, name := range models.PermissionNames() {
+		perms = append(perms, permission{Name: name, Description: models.AllPermissions[name]})
+	}
+	respondJSON(w, http.StatusOK, perms)
+}
+
+// MyPermissions - Rol y permisos del usuario autenticado (para armar el menú del backoffice)
+func (h *RoleHandler) MyPermissions(w http.ResponseWriter, r *http.Request) {
+	role := middleware.GetRole(r.Context())
+	granted, _ := middleware.RolePermissions(r.Context(), role)
+	perms := []string{}
+	for p, ok := range granted {
+		if ok {
+			perms = append(perms, p)
+		}
+	}
+	sort.Strings(perms)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"role":        role,
+		"permissions": perms,
+	})
+}
+
+// grantsBeyondCaller rechaza crear o editar roles con permisos que quien lo hace no tiene
+func grantsBeyondCaller(w http.ResponseWriter, r *http.Request, permissions []string) bool {
+	perms := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		perms[p] = true
+	}
+	if exceedsCaller(r, perms) {
+		respondError(w, http.StatusForbidden, "Cannot grant permissions you do not have")
+		return true
+	}
+	return false
+}
+
+// roleBeyondCaller rechaza modificar o eliminar un rol con permisos que quien lo hace no tiene
+func roleBeyondCaller(w http.ResponseWriter, r *http.Request, name models.Role) bool {
+	perms, _ := middleware.RolePermissions(r.Context(), name)
+	if exceedsCaller(r, perms) {
+		respondError(w, http.StatusForbidden, "Permission denied to edit this role")
+		return true
+	}
+	return false
+}
+
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.List()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch roles")
+		return
+	}
+	respondJSON(w, http.StatusOK, roles)
+}
+
+func (h *RoleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	role, err := h.roleService.Get(models.Role(r.PathValue("name")))
+	if err != nil {
+		respondRoleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, role)
+}
+
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if grantsBeyondCaller(w, r, req.Permissions) {
+		return
+	}
+	role, err := h.roleService.Create(&req)
+	if err != nil {
+		respondRoleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, role)
+}
+
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := models.Role(r.PathValue("name"))
+	if roleBeyondCaller(w, r, name) || grantsBeyondCaller(w, r, req.Permissions) {
+		return
+	}
+	role, err := h.roleService.Update(name, &req)
+	if err != nil {
+		respondRoleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, role)
+}
+
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := models.Role(r.PathValue("name"))
+	if roleBeyondCaller(w, r, name) {
+		return
+	}
+	if err := h.roleService.Delete(name); err != nil {
+		respondRoleError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Role deleted"})
+}
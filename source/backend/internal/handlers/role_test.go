@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoleHandler_RejectsPermissionsBeyondCaller(t *testing.T) {
+	// Las solicitudes rechazadas no llegan al servicio
+	handler := NewRoleHandler(nil)
+
+	cases := []struct {
+		name string
+		call http.HandlerFunc
+		req  *http.Request
+	}{
+		{"create with security.manage", handler.Create,
+			editorRequest("POST", "/api/v1/roles", `{"name":"soporte","label":"Soporte","permissions":["users.view","security.manage"]}`, "")},
+		{"create with impersonation", handler.Create,
+			editorRequest("POST", "/api/v1/roles", `{"name":"soporte","label":"Soporte","permissions":["users.impersonate"]}`, "")},
+		{"grant extra permission on update", handler.Update,
+			editorRequest("PUT", "/api/v1/roles/editor", `{"label":"Editor","permissions":["roles.manage","users.delete"]}`, "editor")},
+		{"edit a role above the caller", handler.Update,
+			editorRequest("PUT", "/api/v1/roles/auditor", `{"label":"Auditor","permissions":["users.view"]}`, "auditor")},
+		{"delete a role above the caller", handler.Delete,
+			editorRequest("DELETE", "/api/v1/roles/auditor", "", "auditor")},
+	}
+	for _, c := range cases {
+		rr := httptest.NewRecorder()
+		serveScoped(c.call, rr, c.req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", c.name, rr.Code)
+		}
+	}
+}
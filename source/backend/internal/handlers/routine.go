@@ -2,18 +2,25 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"boxmagic/internal/middleware"
 	"boxmagic/internal/models"
 	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
 )
 
 type RoutineHandler struct {
 	routineRepo repository.RoutineRepo
 	feedRepo    repository.FeedRepo
 	badgeRepo   *repository.BadgeRepository
+	workout     *services.WorkoutService
+	benchmarks  *services.BenchmarkService
+	programs    *services.CustomProgramService
 }
 
 func NewRoutineHandler(routineRepo repository.RoutineRepo, feedRepo ...repository.FeedRepo) *RoutineHandler {
@@ -28,6 +35,64 @@ func (h *RoutineHandler) SetBadgeRepo(repo *repository.BadgeRepository) {
 	h.badgeRepo = repo
 }
 
+// SetWorkoutService habilita las rutinas estructuradas en bloques
+func (h *RoutineHandler) SetWorkoutService(svc *services.WorkoutService) {
+	h.workout = svc
+}
+
+// SetBenchmarkService habilita el vínculo con el catálogo de benchmarks y sus PR entre rutinas
+func (h *RoutineHandler) SetBenchmarkService(svc *services.BenchmarkService) {
+	h.benchmarks = svc
+}
+
+// SetCustomProgramService registra la entrega (y el cobro) de las rutinas personalizadas
+func (h *RoutineHandler) SetCustomProgramService(svc *services.CustomProgramService) {
+	h.programs = svc
+}
+
+// deliverProgram registra la entrega (y el cobro) de la rutina personalizada; un programa pagado no puede
+// quedar entregado sin cobro, así que el error se devuelve al coach
+func (h *RoutineHandler) deliverProgram(routine *models.Routine, price *int64) error {
+	if h.programs == nil {
+		return nil
+	}
+	_, err := h.programs.Deliver(routine, price)
+	return err
+}
+
+func respondDeliverError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, services.ErrCustomProgramPrice) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondError(w, http.StatusInternalServerError, fallback)
+}
+
+// checkBenchmark valida que el benchmark a vincular exista
+func (h *RoutineHandler) checkBenchmark(w http.ResponseWriter, id *int64) bool {
+	if id == nil || h.benchmarks == nil {
+		return true
+	}
+	if _, err := h.benchmarks.Get(*id); errors.Is(err, services.ErrBenchmarkNotFound) {
+		respondError(w, http.StatusBadRequest, "Benchmark not found")
+		return false
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch benchmark")
+		return false
+	}
+	return true
+}
+
+func respondWorkoutError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrWorkoutInvalidKind), errors.Is(err, services.ErrWorkoutInvalidFormat),
+		errors.Is(err, services.ErrWorkoutLineMissing), errors.Is(err, services.ErrMovementNotFound):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to save routine blocks")
+	}
+}
+
 func (h *RoutineHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 
@@ -37,7 +102,8 @@ func (h *RoutineHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Name == "" || req.Content == "" {
+	structured := h.workout != nil && len(req.Blocks) > 0
+	if req.Name == "" || (req.Content == "" && !structured) {
 		respondError(w, http.StatusBadRequest, "Name and content are required")
 		return
 	}
@@ -45,6 +111,13 @@ func (h *RoutineHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if req.Type == "" {
 		req.Type = "wod"
 	}
+	if req.Price != nil && *req.Price < 0 {
+		respondError(w, http.StatusBadRequest, services.ErrCustomProgramPrice.Error())
+		return
+	}
+	if !h.checkBenchmark(w, req.BenchmarkID) {
+		return
+	}
 
 	routine := &models.Routine{
 		Name:            req.Name,
@@ -61,10 +134,26 @@ func (h *RoutineHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Billable:        req.Billable,
 		TargetUserID:    req.TargetUserID,
 		IsCustom:        req.IsCustom,
+		BenchmarkID:     req.BenchmarkID,
+	}
+	if structured {
+		routine.Blocks = req.Blocks
+		if err := h.workout.Prepare(routine); err != nil {
+			respondWorkoutError(w, err)
+			return
+		}
 	}
 
-	if err := h.routineRepo.Create(routine); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create routine")
+	if !h.saveRevision(w, routine, structured, userID, nil) {
+		return
+	}
+	if err := h.deliverProgram(routine, req.Price); err != nil {
+		// Sin entrega registrada la rutina se retira para que el miembro no la reciba sin cobro
+		log.Printf("[WARN] custom program for routine %d: %v", routine.ID, err)
+		if derr := h.routineRepo.Delete(routine.ID); derr != nil {
+			log.Printf("[WARN] deactivate routine %d: %v", routine.ID, derr)
+		}
+		respondDeliverError(w, err, "Failed to record custom program")
 		return
 	}
 
@@ -93,7 +182,21 @@ func (h *RoutineHandler) List(w http.ResponseWriter, r *http.Request) {
 		custom = &val
 	}
 
-	routines, err := h.routineRepo.List(routineType, custom, limit, offset)
+	// Los miembros no ven las rutinas programadas para clases que aún no se publican
+	publishedOnly := !canSeeUnpublished(r)
+
+	var routines []*models.RoutineWithCreator
+	var err error
+	if m := r.URL.Query().Get("movement_id"); m != "" {
+		movementID, perr := strconv.ParseInt(m, 10, 64)
+		if perr != nil {
+			respondError(w, http.StatusBadRequest, "Invalid movement_id")
+			return
+		}
+		routines, err = h.routineRepo.ListByMovement(movementID, publishedOnly, limit, offset)
+	} else {
+		routines, err = h.routineRepo.List(routineType, custom, publishedOnly, limit, offset)
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to fetch routines")
 		return
@@ -121,6 +224,13 @@ func (h *RoutineHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusNotFound, "Routine not found")
 		return
 	}
+	if !h.checkPublished(w, r, id) {
+		return
+	}
+	if routine.Blocks, err = h.routineRepo.GetBlocks(id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch routine blocks")
+		return
+	}
 
 	respondJSON(w, http.StatusOK, routine)
 }
@@ -138,6 +248,11 @@ func (h *RoutineHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Price != nil && *req.Price < 0 {
+		respondError(w, http.StatusBadRequest, services.ErrCustomProgramPrice.Error())
+		return
+	}
+
 	routine, err := h.routineRepo.GetByID(id)
 	if err != nil {
 		respondError(w, http.StatusNotFound, "Routine not found")
@@ -183,9 +298,40 @@ func (h *RoutineHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.ContentBeginner != nil {
 		routine.ContentBeginner = *req.ContentBeginner
 	}
+	if req.BenchmarkID != nil {
+		if *req.BenchmarkID == 0 {
+			routine.BenchmarkID = nil
+		} else if !h.checkBenchmark(w, req.BenchmarkID) {
+			return
+		} else {
+			routine.BenchmarkID = req.BenchmarkID
+		}
+	}
+
+	// Con estructura el texto se regenera desde los bloques; [] la quita y vuelve al texto libre
+	if h.workout != nil {
+		if req.Blocks != nil {
+			routine.Blocks = *req.Blocks
+		} else if routine.Blocks, err = h.routineRepo.GetBlocks(id); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to fetch routine blocks")
+			return
+		}
+		if len(routine.Blocks) > 0 {
+			if err := h.workout.Prepare(&routine.Routine); err != nil {
+				respondWorkoutError(w, err)
+				return
+			}
+		}
+	}
 
-	if err := h.routineRepo.Update(&routine.Routine); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to update routine")
+	replaceBlocks := h.workout != nil && (req.Blocks != nil || len(routine.Blocks) > 0)
+	if !h.saveRevision(w, &routine.Routine, replaceBlocks, middleware.GetUserID(r.Context()), nil) {
+		return
+	}
+	// La entrega es idempotente: si falla, repetir la edición la registra
+	if err := h.deliverProgram(&routine.Routine, req.Price); err != nil {
+		log.Printf("[WARN] custom program for routine %d: %v", routine.ID, err)
+		respondDeliverError(w, err, "Routine saved but failed to record the custom program; retry the update")
 		return
 	}
 
@@ -269,6 +415,7 @@ func (h *RoutineHandler) AssignToSchedule(w http.ResponseWriter, r *http.Request
 		ClassScheduleID: scheduleID,
 		RoutineID:       req.RoutineID,
 		Notes:           req.Notes,
+		PublishAt:       req.PublishAt,
 	}
 
 	if err := h.routineRepo.AssignToSchedule(sr); err != nil {
@@ -291,10 +438,38 @@ func (h *RoutineHandler) GetScheduleRoutine(w http.ResponseWriter, r *http.Reque
 		respondJSON(w, http.StatusOK, map[string]interface{}{"routine": nil})
 		return
 	}
+	// Antes de la hora de publicación los miembros solo ven cuándo se libera
+	if !routine.Published(time.Now()) && !canSeeUnpublished(r) {
+		respondJSON(w, http.StatusOK, map[string]interface{}{"routine": nil, "publish_at": routine.PublishAt})
+		return
+	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{"routine": routine})
 }
 
+// canSeeUnpublished - El staff que programa o administra horarios ve las rutinas antes de publicarse
+func canSeeUnpublished(r *http.Request) bool {
+	return middleware.HasPermission(r.Context(), models.PermRoutinesManage) ||
+		middleware.HasPermission(r.Context(), models.PermClassesManage)
+}
+
+// checkPublished responde 404 a los miembros si la rutina solo está programada en clases aún no publicadas
+func (h *RoutineHandler) checkPublished(w http.ResponseWriter, r *http.Request, id int64) bool {
+	if canSeeUnpublished(r) {
+		return true
+	}
+	releaseAt, err := h.routineRepo.ReleaseAt(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch routine")
+		return false
+	}
+	if releaseAt != nil {
+		respondError(w, http.StatusNotFound, "Routine not found")
+		return false
+	}
+	return true
+}
+
 func (h *RoutineHandler) LogResult(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 
@@ -308,6 +483,13 @@ func (h *RoutineHandler) LogResult(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Routine ID and score are required")
 		return
 	}
+	if req.UserID != nil && *req.UserID != userID {
+		if !middleware.HasPermission(r.Context(), models.PermResultsManage) {
+			respondError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		userID = *req.UserID
+	}
 
 	result := &models.UserRoutineResult{
 		UserID:          userID,
@@ -322,6 +504,14 @@ func (h *RoutineHandler) LogResult(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to log result")
 		return
 	}
+	// En rutinas de un benchmark el PR se compara contra todas las veces que se programó
+	if h.benchmarks != nil {
+		if isPR, ok, err := h.benchmarks.ResultIsPR(result); err != nil {
+			log.Printf("[WARN] benchmark PR for result %d: %v", result.ID, err)
+		} else if ok {
+			result.IsPR = isPR
+		}
+	}
 
 	// Create feed event
 	if h.feedRepo != nil {
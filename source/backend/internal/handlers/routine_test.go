@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// mockRoutineRepo implementa solo lo que usan las lecturas de rutinas; el resto entra en pánico si se llama
+type mockRoutineRepo struct {
+	repository.RoutineRepo
+	routine       *models.RoutineWithCreator
+	releaseAt     *time.Time
+	publishedOnly bool
+
+	versions       map[int]*models.RoutineVersion
+	saveErr        error
+	saved          *models.Routine
+	replacedBlocks bool
+	rolledBackFrom *int
+}
+
+func (m *mockRoutineRepo) GetByID(id int64) (*models.RoutineWithCreator, error) {
+	return m.routine, nil
+}
+func (m *mockRoutineRepo) GetBlocks(routineID int64) ([]*models.WorkoutBlock, error) {
+	return []*models.WorkoutBlock{}, nil
+}
+func (m *mockRoutineRepo) ReleaseAt(routineID int64) (*time.Time, error) { return m.releaseAt, nil }
+func (m *mockRoutineRepo) List(routineType string, custom *bool, publishedOnly bool, limit, offset int) ([]*models.RoutineWithCreator, error) {
+	m.publishedOnly = publishedOnly
+	return nil, nil
+}
+func (m *mockRoutineRepo) GetVersion(routineID int64, version int) (*models.RoutineVersion, error) {
+	if v, ok := m.versions[version]; ok {
+		return v, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// SaveRevision es atómico en el repositorio: si falla no se guarda nada
+func (m *mockRoutineRepo) SaveRevision(routine *models.Routine, replaceBlocks bool, createdBy int64, rolledBackFrom *int) (*models.RoutineVersion, bool, error) {
+	if m.saveErr != nil {
+		return nil, false, m.saveErr
+	}
+	saved := *routine
+	m.saved, m.replacedBlocks, m.rolledBackFrom = &saved, replaceBlocks, rolledBackFrom
+	return &models.RoutineVersion{RoutineID: routine.ID, Version: routine.Version + 1}, true, nil
+}
+func (m *mockRoutineRepo) ListVersions(routineID int64) ([]*models.RoutineVersion, error) {
+	return []*models.RoutineVersion{}, nil
+}
+
+func unpublishedRoutineRepo() *mockRoutineRepo {
+	tomorrow := time.Now().Add(24 * time.Hour)
+	return &mockRoutineRepo{routine: &models.RoutineWithCreator{Routine: models.Routine{ID: 5, Name: "Fran"}}, releaseAt: &tomorrow}
+}
+
+func TestRoutineHandler_UnpublishedHiddenFromMembers(t *testing.T) {
+	handler := NewRoutineHandler(unpublishedRoutineRepo())
+
+	for name, call := range map[string]func(http.ResponseWriter, *http.Request){
+		"get":      handler.GetByID,
+		"versions": handler.ListVersions,
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/routines/5", nil)
+		req.SetPathValue("id", "5")
+		req = req.WithContext(middleware.WithAuth(req.Context(), 9, models.RoleMember))
+		rr := httptest.NewRecorder()
+
+		call(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for member before publish time, got %d", name, rr.Code)
+		}
+	}
+}
+
+func TestRoutineHandler_UnpublishedVisibleToCoach(t *testing.T) {
+	handler := NewRoutineHandler(unpublishedRoutineRepo())
+
+	req := httptest.NewRequest("GET", "/api/v1/routines/5", nil)
+	req.SetPathValue("id", "5")
+	req = req.WithContext(middleware.WithAuth(req.Context(), 2, models.RoleCoach))
+	rr := httptest.NewRecorder()
+
+	handler.GetByID(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for coach, got %d", rr.Code)
+	}
+}
+
+func TestRoutineHandler_List_FiltersUnpublishedForMembers(t *testing.T) {
+	repo := unpublishedRoutineRepo()
+	handler := NewRoutineHandler(repo)
+
+	req := httptest.NewRequest("GET", "/api/v1/routines", nil)
+	req = req.WithContext(middleware.WithAuth(req.Context(), 9, models.RoleMember))
+	handler.List(httptest.NewRecorder(), req)
+	if !repo.publishedOnly {
+		t.Fatal("member listing should exclude unpublished routines")
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/routines", nil)
+	req = req.WithContext(middleware.WithAuth(req.Context(), 2, models.RoleCoach))
+	handler.List(httptest.NewRecorder(), req)
+	if repo.publishedOnly {
+		t.Fatal("coach listing should include unpublished routines")
+	}
+}
+
+func versionedRoutineRepo() *mockRoutineRepo {
+	return &mockRoutineRepo{
+		routine: &models.RoutineWithCreator{Routine: models.Routine{ID: 5, Name: "Fran", Content: "21-15-9 Thrusters 35kg", Version: 3}},
+		versions: map[int]*models.RoutineVersion{
+			1: {RoutineID: 5, Version: 1, Name: "Fran", Type: "wod", Content: "21-15-9 Thrusters 43kg"},
+			3: {RoutineID: 5, Version: 3, Name: "Fran", Type: "wod", Content: "21-15-9 Thrusters 35kg"},
+		},
+	}
+}
+
+func rollbackRequest(version string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/v1/routines/5/versions/"+version+"/rollback", nil)
+	req.SetPathValue("id", "5")
+	req.SetPathValue("version", version)
+	return req.WithContext(middleware.WithAuth(req.Context(), 2, models.RoleCoach))
+}
+
+func TestRoutineHandler_Rollback_RestoresVersionAsNewRevision(t *testing.T) {
+	repo := versionedRoutineRepo()
+	handler := NewRoutineHandler(repo)
+	rr := httptest.NewRecorder()
+
+	handler.Rollback(rr, rollbackRequest("1"))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if repo.saved == nil || repo.saved.Content != "21-15-9 Thrusters 43kg" {
+		t.Fatalf("expected version 1 content to be saved, got %+v", repo.saved)
+	}
+	if !repo.replacedBlocks || repo.rolledBackFrom == nil || *repo.rolledBackFrom != 1 {
+		t.Fatalf("rollback must replace blocks and record the source version: blocks=%v from=%v", repo.replacedBlocks, repo.rolledBackFrom)
+	}
+	var resp models.RoutineWithCreator
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Version != 4 {
+		t.Fatalf("expected new version 4, got %d", resp.Version)
+	}
+}
+
+func TestRoutineHandler_Rollback_CurrentVersionRejected(t *testing.T) {
+	repo := versionedRoutineRepo()
+	rr := httptest.NewRecorder()
+
+	NewRoutineHandler(repo).Rollback(rr, rollbackRequest("3"))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	if repo.saved != nil {
+		t.Fatal("nothing should be saved when rolling back to the current version")
+	}
+}
+
+func TestRoutineHandler_Rollback_UnknownVersion(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	NewRoutineHandler(versionedRoutineRepo()).Rollback(rr, rollbackRequest("2"))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRoutineHandler_Rollback_SaveFailure(t *testing.T) {
+	repo := versionedRoutineRepo()
+	repo.saveErr = errors.New("version insert failed")
+	rr := httptest.NewRecorder()
+
+	NewRoutineHandler(repo).Rollback(rr, rollbackRequest("1"))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if repo.saved != nil {
+		t.Fatal("a failed rollback must not leave a partial save")
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/services"
+)
+
+// Historial de versiones de RoutineHandler
+
+// saveRevision guarda la rutina, sus bloques (ya validados con Prepare) si replaceBlocks y la nueva versión
+// (si cambió el contenido) en una sola transacción, y actualiza routine.Version
+func (h *RoutineHandler) saveRevision(w http.ResponseWriter, routine *models.Routine, replaceBlocks bool, userID int64, rolledBackFrom *int) bool {
+	v, _, err := h.routineRepo.SaveRevision(routine, replaceBlocks, userID, rolledBackFrom)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save routine")
+		return false
+	}
+	routine.Version = v.Version
+	return true
+}
+
+func routineVersionFromPath(w http.ResponseWriter, r *http.Request) (int64, int, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid routine ID")
+		return 0, 0, false
+	}
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid version")
+		return 0, 0, false
+	}
+	return id, version, true
+}
+
+// getVersion responde 404 si la versión no existe
+func (h *RoutineHandler) getVersion(w http.ResponseWriter, id int64, version int) (*models.RoutineVersion, bool) {
+	v, err := h.routineRepo.GetVersion(id, version)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Routine version not found")
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch routine version")
+		return nil, false
+	}
+	return v, true
+}
+
+// ListVersions - Historial de la rutina con cuántas clases y resultados usan cada versión
+func (h *RoutineHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid routine ID")
+		return
+	}
+	if !h.checkPublished(w, r, id) {
+		return
+	}
+	versions, err := h.routineRepo.ListVersions(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch routine versions")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"versions": versions})
+}
+
+func (h *RoutineHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	id, version, ok := routineVersionFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkPublished(w, r, id) {
+		return
+	}
+	v, ok := h.getVersion(w, id, version)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, v)
+}
+
+// DiffVersions - Cambios entre dos versiones (?from=&to=; por defecto la vigente contra la anterior)
+func (h *RoutineHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid routine ID")
+		return
+	}
+	if !h.checkPublished(w, r, id) {
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		routine, err := h.routineRepo.GetByID(id)
+		if err != nil {
+			respondError(w, http.StatusNotFound, "Routine not found")
+			return
+		}
+		to = routine.Version
+	}
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		from = to - 1
+	}
+	if from <= 0 || to <= 0 || from == to {
+		respondError(w, http.StatusBadRequest, "from and to must be two different versions")
+		return
+	}
+
+	fromVersion, ok := h.getVersion(w, id, from)
+	if !ok {
+		return
+	}
+	toVersion, ok := h.getVersion(w, id, to)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, services.DiffRoutineVersions(fromVersion, toVersion))
+}
+
+// Rollback - Restaura el contenido de una versión anterior creando una nueva; el historial no se reescribe
+func (h *RoutineHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	id, version, ok := routineVersionFromPath(w, r)
+	if !ok {
+		return
+	}
+	routine, err := h.routineRepo.GetByID(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Routine not found")
+		return
+	}
+	v, ok := h.getVersion(w, id, version)
+	if !ok {
+		return
+	}
+	if v.Version == routine.Version {
+		respondError(w, http.StatusBadRequest, "Version is already current")
+		return
+	}
+
+	routine.Name, routine.Description, routine.Type = v.Name, v.Description, v.Type
+	routine.Content, routine.ContentScaled, routine.ContentBeginner = v.Content, v.ContentScaled, v.ContentBeginner
+	routine.Duration, routine.Difficulty, routine.BenchmarkID = v.Duration, v.Difficulty, v.BenchmarkID
+	routine.Blocks = v.Blocks
+	if routine.Blocks == nil {
+		routine.Blocks = []*models.WorkoutBlock{}
+	}
+	// Los movimientos de la versión pueden haberse borrado del catálogo desde entonces
+	if h.workout != nil && len(routine.Blocks) > 0 {
+		if err := h.workout.Prepare(&routine.Routine); err != nil {
+			respondWorkoutError(w, err)
+			return
+		}
+	}
+
+	if !h.saveRevision(w, &routine.Routine, true, middleware.GetUserID(r.Context()), &v.Version) {
+		return
+	}
+	respondJSON(w, http.StatusOK, routine)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// SessionHandler - Sesiones activas (dispositivo, IP, último uso) y su revocación
+type SessionHandler struct {
+	sessionRepo *repository.SessionRepository
+	userRepo    repository.UserRepo
+}
+
+func NewSessionHandler(sessionRepo *repository.SessionRepository) *SessionHandler {
+	return &SessionHandler{sessionRepo: sessionRepo}
+}
+
+// SetUserRepo permite comprobar la jerarquía antes de cerrar sesiones de otro usuario
+func (h *SessionHandler) SetUserRepo(repo repository.UserRepo) {
+	h.userRepo = repo
+}
+
+func (h *SessionHandler) list(w http.ResponseWriter, userID, currentID int64) {
+	sessions, err := h.sessionRepo.ListSessions(userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []*models.Session{}
+	}
+	for _, s := range sessions {
+		s.Current = s.ID == currentID
+	}
+	respondJSON(w, http.StatusOK, sessions)
+}
+
+// MySessions - Sesiones activas del usuario; marca la actual
+func (h *SessionHandler) MySessions(w http.ResponseWriter, r *http.Request) {
+	h.list(w, middleware.GetUserID(r.Context()), middleware.GetSessionID(r.Context()))
+}
+
+// RevokeMine - Cierra una sesión propia (ej. un teléfono perdido)
+func (h *SessionHandler) RevokeMine(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+	h.revoke(w, middleware.GetUserID(r.Context()), id, models.SessionRevokedLogout)
+}
+
+// UserSessions - Sesiones activas de un miembro (admin)
+func (h *SessionHandler) UserSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	h.list(w, id, 0)
+}
+
+// RevokeUserSession - Admin cierra una sesión de un miembro
+func (h *SessionHandler) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	sessionID, err := strconv.ParseInt(r.PathValue("sessionId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+	if !guardTarget(w, r, h.userRepo, userID, "Permission denied to revoke this user's sessions") {
+		return
+	}
+	h.revoke(w, userID, sessionID, models.SessionRevokedAdmin)
+}
+
+// RevokeAllUserSessions - Admin cierra todas las sesiones de un miembro
+func (h *SessionHandler) RevokeAllUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !guardTarget(w, r, h.userRepo, userID, "Permission denied to revoke this user's sessions") {
+		return
+	}
+	if err := h.sessionRepo.RevokeAllSessions(userID, models.SessionRevokedAdmin); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "All sessions revoked"})
+}
+
+func (h *SessionHandler) revoke(w http.ResponseWriter, userID, sessionID int64, reason string) {
+	err := h.sessionRepo.RevokeSession(userID, sessionID, reason)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
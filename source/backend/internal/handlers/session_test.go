@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+)
+
+func TestSessionHandler_ManagerCannotRevokeOwnerSessions(t *testing.T) {
+	handler := NewSessionHandler(nil)
+	handler.SetUserRepo(&mockUserRepo{user: &models.User{ID: 1, Role: models.RoleOwner}})
+
+	cases := []struct {
+		name   string
+		target string
+		serve  http.HandlerFunc
+	}{
+		{"all sessions", "/api/v1/users/1/sessions", handler.RevokeAllUserSessions},
+		{"single session", "/api/v1/users/1/sessions/7", handler.RevokeUserSession},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("DELETE", c.target, nil)
+		req.SetPathValue("id", "1")
+		req.SetPathValue("sessionId", "7")
+		req = req.WithContext(middleware.WithAuth(req.Context(), 2, models.RoleManager))
+		rr := httptest.NewRecorder()
+
+		c.serve(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", c.name, rr.Code)
+		}
+	}
+}
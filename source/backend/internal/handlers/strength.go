@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// StrengthHandler - Registro de fuerza por movimiento, récords y calculadora de porcentajes
+type StrengthHandler struct {
+	strengthService *services.StrengthService
+	strengthRepo    *repository.StrengthRepository
+}
+
+func NewStrengthHandler(strengthService *services.StrengthService, strengthRepo *repository.StrengthRepository) *StrengthHandler {
+	return &StrengthHandler{strengthService: strengthService, strengthRepo: strengthRepo}
+}
+
+func respondStrengthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrMovementNotFound), errors.Is(err, services.ErrNoOneRM):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrLiftInvalid), errors.Is(err, services.ErrLiftInvalidRPE),
+		errors.Is(err, services.ErrLiftInvalidDate), errors.Is(err, services.ErrPercentsRequired):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process lift")
+	}
+}
+
+// targetUser - El miembro autenticado, o ?user_id= para el staff con results.view
+func targetUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID := middleware.GetUserID(r.Context())
+	uid := r.URL.Query().Get("user_id")
+	if uid == "" {
+		return userID, true
+	}
+	id, err := strconv.ParseInt(uid, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user_id")
+		return 0, false
+	}
+	if id != userID && !middleware.HasPermission(r.Context(), models.PermResultsView) {
+		respondError(w, http.StatusForbidden, "Permission denied")
+		return 0, false
+	}
+	return id, true
+}
+
+// plateIncrement - ?round= incremento de discos para redondear las cargas (0.5 kg por defecto)
+func plateIncrement(r *http.Request) float64 {
+	if v, err := strconv.ParseFloat(r.URL.Query().Get("round"), 64); err == nil && v > 0 {
+		return v
+	}
+	return 0.5
+}
+
+// Log - Registra una serie; un coach puede registrar a nombre de un miembro con results.manage
+func (h *StrengthHandler) Log(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	var req models.LogLiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.UserID != nil && *req.UserID != userID {
+		if !middleware.HasPermission(r.Context(), models.PermResultsManage) {
+			respondError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		userID = *req.UserID
+	}
+	l, err := h.strengthService.Log(userID, &req)
+	if err != nil {
+		respondStrengthError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, l)
+}
+
+// List - Historial de levantamientos (?movement_id=, ?user_id=)
+func (h *StrengthHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	movementID, _ := strconv.ParseInt(r.URL.Query().Get("movement_id"), 10, 64)
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	list, err := h.strengthService.History(userID, movementID, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch lifts")
+		return
+	}
+	if list == nil {
+		list = []*models.LiftLog{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+// Delete - El miembro borra un levantamiento propio
+func (h *StrengthHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lift ID")
+		return
+	}
+	if err := h.strengthRepo.Delete(id, middleware.GetUserID(r.Context())); err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Lift not found")
+		return
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete lift")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Summary - 1RM de referencia, 1RM estimado y tabla 1/3/5RM por movimiento (?movement_id=, ?user_id=)
+func (h *StrengthHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	movementID, _ := strconv.ParseInt(r.URL.Query().Get("movement_id"), 10, 64)
+	list, err := h.strengthService.Summary(userID, movementID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch lift summary")
+		return
+	}
+	if list == nil {
+		list = []*models.LiftSummary{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+// Percentages - Cargas objetivo sobre el 1RM del miembro (?movement_id=1&pct=70,75,80&round=2.5)
+func (h *StrengthHandler) Percentages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	movementID, err := strconv.ParseInt(r.URL.Query().Get("movement_id"), 10, 64)
+	if err != nil || movementID <= 0 {
+		respondError(w, http.StatusBadRequest, "movement_id is required")
+		return
+	}
+	var percents []float64
+	for _, p := range strings.Split(r.URL.Query().Get("pct"), ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid pct")
+			return
+		}
+		percents = append(percents, v)
+	}
+	resp, err := h.strengthService.Percentages(userID, movementID, percents, plateIncrement(r))
+	if err != nil {
+		respondStrengthError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// RoutineTargets - Carga del miembro para las líneas de la rutina prescritas en % (?level=scaled, ?round=, ?user_id=)
+func (h *StrengthHandler) RoutineTargets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+	routineID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid routine ID")
+		return
+	}
+	// Las cargas revelarían el WOD antes de su publicación
+	if !canSeeUnpublished(r) {
+		releaseAt, err := h.strengthService.RoutineReleaseAt(routineID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to fetch routine")
+			return
+		}
+		if releaseAt != nil {
+			respondError(w, http.StatusNotFound, "Routine not found")
+			return
+		}
+	}
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = models.LevelRx
+	}
+	targets, err := h.strengthService.RoutineTargets(userID, routineID, level, plateIncrement(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to calculate targets")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"routine_id": routineID, "level": level, "targets": targets})
+}
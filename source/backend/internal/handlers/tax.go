@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// TaxHandler - Documentos tributarios electrónicos: CAF, emisión, reenvío y descarga de boletas/facturas
+type TaxHandler struct {
+	taxService *services.TaxService
+	taxRepo    *repository.TaxRepository
+}
+
+func NewTaxHandler(taxService *services.TaxService, taxRepo *repository.TaxRepository) *TaxHandler {
+	return &TaxHandler{taxService: taxService, taxRepo: taxRepo}
+}
+
+// respondTaxError traduce los errores de emisión a códigos HTTP
+func respondTaxError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrDTEDisabled):
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrInvalidDocType), errors.Is(err, services.ErrReceptorIncomplete),
+		errors.Is(err, services.ErrSourceNotCompleted), errors.Is(err, services.ErrNothingToInvoice),
+		errors.Is(err, services.ErrInvalidCAF), errors.Is(err, services.ErrCAFEmisorMismatch),
+		errors.Is(err, services.ErrUnsupportedCAFType):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSourceNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repository.ErrTaxDocumentExists), errors.Is(err, repository.ErrNoFoliosAvailable):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to issue tax document")
+	}
+}
+
+// ImportCAF - Carga un CAF del SII (XML en el body o multipart "file")
+func (h *TaxHandler) ImportCAF(w http.ResponseWriter, r *http.Request) {
+	var data []byte
+	var err error
+	if file, _, ferr := r.FormFile("file"); ferr == nil {
+		defer file.Close()
+		data, err = io.ReadAll(io.LimitReader(file, 1<<20))
+	} else {
+		data, err = io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	}
+	if err != nil || len(data) == 0 {
+		respondError(w, http.StatusBadRequest, "CAF file is required")
+		return
+	}
+
+	caf, err := h.taxService.ImportCAF(data)
+	if err != nil {
+		respondTaxError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, caf)
+}
+
+// ListCAFs - Rangos de folios cargados y disponibles
+func (h *TaxHandler) ListCAFs(w http.ResponseWriter, r *http.Request) {
+	cafs, err := h.taxRepo.ListCAFs()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch CAFs")
+		return
+	}
+	if cafs == nil {
+		cafs = []*models.CAF{}
+	}
+	respondJSON(w, http.StatusOK, cafs)
+}
+
+// Issue - Emite manualmente el documento de un pago o venta
+func (h *TaxHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	var req models.IssueTaxDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.SourceID <= 0 {
+		respondError(w, http.StatusBadRequest, "source_id is required")
+		return
+	}
+
+	var doc *models.TaxDocument
+	var err error
+	switch req.SourceType {
+	case models.TaxSourcePayment:
+		doc, err = h.taxService.IssueForPayment(req.SourceID, req.DocType, req.Receptor)
+	case models.TaxSourceSale:
+		doc, err = h.taxService.IssueForSale(req.SourceID, req.DocType, req.Receptor)
+	default:
+		respondError(w, http.StatusBadRequest, "source_type must be payment or sale")
+		return
+	}
+	if err != nil {
+		respondTaxError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, doc)
+}
+
+func (h *TaxHandler) list(w http.ResponseWriter, r *http.Request, userID *int64) {
+	limit := 50
+	offset := 0
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	docs, err := h.taxRepo.ListDocuments(userID, limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tax documents")
+		return
+	}
+	if docs == nil {
+		docs = []*models.TaxDocument{}
+	}
+	respondJSON(w, http.StatusOK, docs)
+}
+
+// List - Todos los documentos emitidos (admin)
+func (h *TaxHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, nil)
+}
+
+// MyDocuments - Boletas/facturas del miembro autenticado
+func (h *TaxHandler) MyDocuments(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	h.list(w, r, &userID)
+}
+
+// getDocument carga el documento del path; los miembros solo acceden a los propios
+func (h *TaxHandler) getDocument(w http.ResponseWriter, r *http.Request) (*models.TaxDocument, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return nil, false
+	}
+	doc, err := h.taxRepo.GetDocument(id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Tax document not found")
+		return nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tax document")
+		return nil, false
+	}
+	if !middleware.HasPermission(r.Context(), models.PermTaxView) &&
+		(doc.UserID == nil || *doc.UserID != middleware.GetUserID(r.Context())) {
+		respondError(w, http.StatusNotFound, "Tax document not found")
+		return nil, false
+	}
+	return doc, true
+}
+
+func (h *TaxHandler) Get(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.getDocument(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// XML - Descarga el DTE timbrado (ISO-8859-1)
+func (h *TaxHandler) XML(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.getDocument(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=ISO-8859-1")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="T%dF%d.xml"`, doc.DocType, doc.Folio))
+	w.Write(services.EncodeLatin1(doc.XML))
+}
+
+// PDF - Representación impresa con timbre
+func (h *TaxHandler) PDF(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.getDocument(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="T%dF%d.pdf"`, doc.DocType, doc.Folio))
+	w.Write(h.taxService.PDF(doc))
+}
+
+// Send - Reintenta el envío de un documento
+func (h *TaxHandler) Send(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.getDocument(w, r)
+	if !ok {
+		return
+	}
+	if err := h.taxService.Send(doc); err != nil {
+		respondError(w, http.StatusBadGateway, "Failed to send tax document: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, doc)
+}
+
+// GetBillingProfile - Datos de facturación del miembro
+func (h *TaxHandler) GetBillingProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.taxService.GetBillingProfile(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch billing profile")
+		return
+	}
+	respondJSON(w, http.StatusOK, profile)
+}
+
+// UpdateBillingProfile - Guarda RUT, razón social, giro y dirección para facturas
+func (h *TaxHandler) UpdateBillingProfile(w http.ResponseWriter, r *http.Request) {
+	var req models.TaxReceptor
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.RUT != "" && models.NormalizeRUT(req.RUT) == "" {
+		respondError(w, http.StatusBadRequest, "Invalid RUT")
+		return
+	}
+	if err := h.taxService.SaveBillingProfile(middleware.GetUserID(r.Context()), &req); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save billing profile")
+		return
+	}
+	respondJSON(w, http.StatusOK, req)
+}
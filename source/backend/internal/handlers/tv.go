@@ -35,7 +35,8 @@ func (h *TVHandler) GetToday(w http.ResponseWriter, r *http.Request) {
 		tv := &models.TVSchedule{ScheduleWithDetails: *s}
 
 		// Get routine info
-		if sr, err := h.routineRepo.GetScheduleRoutine(s.ID); err == nil && sr != nil {
+		// La pantalla es pública: no muestra rutinas aún sin publicar
+		if sr, err := h.routineRepo.GetScheduleRoutine(s.ID); err == nil && sr != nil && sr.Published(time.Now()) {
 			tv.RoutineName = sr.RoutineName
 			tv.RoutineType = sr.RoutineType
 			tv.RoutineContent = sr.RoutineContent
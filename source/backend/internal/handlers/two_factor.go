@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// TwoFactorHandler - 2FA (TOTP): activación, segundo paso del login, códigos de recuperación y política del staff
+type TwoFactorHandler struct {
+	authService *services.AuthService
+	userRepo    repository.UserRepo
+}
+
+func NewTwoFactorHandler(authService *services.AuthService) *TwoFactorHandler {
+	return &TwoFactorHandler{authService: authService}
+}
+
+// SetUserRepo permite comprobar la jerarquía antes de resetear el 2FA de otro usuario
+func (h *TwoFactorHandler) SetUserRepo(repo repository.UserRepo) {
+	h.userRepo = repo
+}
+
+// respondTwoFactorError traduce los errores de 2FA a respuestas HTTP
+func respondTwoFactorError(w http.ResponseWriter, err error) {
+	switch err {
+	case services.ErrInvalid2FACode:
+		respondError(w, http.StatusUnauthorized, "Invalid two-factor code")
+	case services.ErrInvalidToken:
+		respondError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+	case services.ErrTwoFactorEnabled, services.ErrTwoFactorNotEnabled, services.ErrTwoFactorNotStarted:
+		respondError(w, http.StatusConflict, err.Error())
+	case services.ErrTwoFactorRequired:
+		respondError(w, http.StatusForbidden, err.Error())
+	case services.ErrTwoFactorUnavailable:
+		respondError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Two-factor operation failed")
+	}
+}
+
+func decodeTwoFactorCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "Code is required")
+		return "", false
+	}
+	return req.Code, true
+}
+
+// Status - Estado del 2FA del usuario autenticado
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.authService.TwoFactorStatus(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+// Setup - Genera el secreto y la URI de aprovisionamiento (QR)
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	setup, err := h.authService.BeginTwoFactorSetup(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, setup)
+}
+
+// Enable - Confirma la activación con el primer código y entrega los códigos de recuperación
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	codes, err := h.authService.EnableTwoFactor(middleware.GetUserID(r.Context()), code)
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	if err := h.authService.DisableTwoFactor(middleware.GetUserID(r.Context()), code); err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RecoveryCodes - Regenera los códigos de recuperación (invalida los anteriores)
+func (h *TwoFactorHandler) RecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+	codes, err := h.authService.RegenerateRecoveryCodes(middleware.GetUserID(r.Context()), code)
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// LoginSetup - Secreto para el staff que debe activar 2FA antes de su primer login con la política vigente
+func (h *TwoFactorHandler) LoginSetup(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+		respondError(w, http.StatusBadRequest, "MFA token is required")
+		return
+	}
+	setup, err := h.authService.BeginTwoFactorLoginSetup(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, setup)
+}
+
+// LoginVerify - Segundo paso del login: canjea el desafío y el código por los tokens
+func (h *TwoFactorHandler) LoginVerify(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		respondError(w, http.StatusBadRequest, "MFA token and code or recovery code are required")
+		return
+	}
+
+	req.Client = clientInfo(r)
+	req.Client.Remember = req.RememberDevice
+	resp, err := h.authService.VerifyTwoFactorLogin(&req)
+	if respondAccountLocked(w, err) {
+		return
+	}
+	if err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// Reset - Quita el 2FA de otro usuario; queda en el log de auditoría
+func (h *TwoFactorHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !guardTarget(w, r, h.userRepo, id, "Permission denied to reset this user's two-factor authentication") {
+		return
+	}
+	if err := h.authService.ResetTwoFactor(id); err != nil {
+		respondTwoFactorError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Two-factor authentication reset"})
+}
+
+// GetSettings - Políticas de seguridad (2FA obligatorio para el staff)
+func (h *TwoFactorHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.authService.SecuritySettings()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch security settings")
+		return
+	}
+	respondJSON(w, http.StatusOK, settings)
+}
+
+func (h *TwoFactorHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.SecuritySettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	settings, err := h.authService.UpdateSecuritySettings(&req, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to update security settings")
+		return
+	}
+	respondJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+)
+
+func twoFactorResetRequest(role models.Role) *http.Request {
+	req := httptest.NewRequest("DELETE", "/api/v1/users/1/2fa", nil)
+	req.SetPathValue("id", "1")
+	return req.WithContext(middleware.WithAuth(req.Context(), 2, role))
+}
+
+func TestTwoFactorHandler_Reset_ManagerCannotResetOwner(t *testing.T) {
+	handler := NewTwoFactorHandler(nil)
+	handler.SetUserRepo(&mockUserRepo{user: &models.User{ID: 1, Role: models.RoleOwner}})
+	rr := httptest.NewRecorder()
+
+	handler.Reset(rr, twoFactorResetRequest(models.RoleManager))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestTwoFactorHandler_Reset_UnknownUser(t *testing.T) {
+	handler := NewTwoFactorHandler(nil)
+	handler.SetUserRepo(&mockUserRepo{err: sql.ErrNoRows})
+	rr := httptest.NewRecorder()
+
+	handler.Reset(rr, twoFactorResetRequest(models.RoleOwner))
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
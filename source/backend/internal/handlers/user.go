@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -11,7 +12,15 @@ import (
 )
 
 type UserHandler struct {
-	userRepo repository.UserRepo
+	userRepo    repository.UserRepo
+	ledgerRepo  *repository.LedgerRepository
+	sessionRepo repository.SessionRepo
+	eraser      userEraser
+}
+
+// userEraser anonimiza la cuenta conservando los registros financieros
+type userEraser interface {
+	EraseNow(userID, adminID int64) error
 }
 
 func NewUserHandler(userRepo repository.UserRepo) *UserHandler {
@@ -20,6 +29,64 @@ func NewUserHandler(userRepo repository.UserRepo) *UserHandler {
 	}
 }
 
+func (h *UserHandler) SetLedgerRepo(repo *repository.LedgerRepository) {
+	h.ledgerRepo = repo
+}
+
+func (h *UserHandler) SetSessionRepo(repo repository.SessionRepo) {
+	h.sessionRepo = repo
+}
+
+func (h *UserHandler) SetEraser(eraser userEraser) {
+	h.eraser = eraser
+}
+
+// recordInvitationGrant deja constancia en la cuenta del miembro de las clases de invitación otorgadas
+func (h *UserHandler) recordInvitationGrant(r *http.Request, userID int64, classes int) {
+	if h.ledgerRepo == nil || classes == 0 {
+		return
+	}
+	adminID := middleware.GetUserID(r.Context())
+	_ = h.ledgerRepo.AddEntry(&models.LedgerEntry{
+		UserID:      userID,
+		Type:        models.LedgerInvitationGrant,
+		Classes:     classes,
+		Description: "Clases de invitación",
+		CreatedBy:   &adminID,
+	})
+}
+
+// outranks indica si el rol del usuario tiene permisos que quien lo edita no tiene (p. ej. recepción sobre el dueño)
+func outranks(r *http.Request, user *models.User) bool {
+	target, _ := middleware.RolePermissions(r.Context(), user.Role)
+	return exceedsCaller(r, target)
+}
+
+// guardTarget carga al usuario afectado y corta con 404/403 si no existe o tiene más permisos que quien actúa
+func guardTarget(w http.ResponseWriter, r *http.Request, users repository.UserRepo, id int64, denied string) bool {
+	user, err := users.GetByID(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return false
+	}
+	if outranks(r, user) {
+		respondError(w, http.StatusForbidden, denied)
+		return false
+	}
+	return true
+}
+
+// exceedsCaller indica si alguno de los permisos no lo tiene quien hace la petición
+func exceedsCaller(r *http.Request, perms map[string]bool) bool {
+	granted, _ := middleware.RolePermissions(r.Context(), middleware.GetRole(r.Context()))
+	for p, ok := range perms {
+		if ok && !granted[p] {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	user, err := h.userRepo.GetByID(userID)
@@ -54,6 +121,9 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	if req.AvatarURL != nil {
 		user.AvatarURL = *req.AvatarURL
 	}
+	if req.RUT != nil {
+		user.RUT = models.NormalizeRUT(*req.RUT)
+	}
 
 	if err := h.userRepo.Update(user); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update user")
@@ -125,6 +195,10 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusNotFound, "User not found")
 		return
 	}
+	if outranks(r, user) {
+		respondError(w, http.StatusForbidden, "Permission denied to edit this user")
+		return
+	}
 
 	if req.Name != "" {
 		user.Name = req.Name
@@ -135,13 +209,42 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.AvatarURL != nil {
 		user.AvatarURL = *req.AvatarURL
 	}
+	if req.RUT != nil {
+		user.RUT = models.NormalizeRUT(*req.RUT)
+	}
+	// Desactivar o cambiar el rol invalida al instante las sesiones y access tokens vigentes
+	revokeReason := ""
 	if req.Active != nil {
+		if user.Active && !*req.Active {
+			revokeReason = models.SessionRevokedDeactivated
+		}
 		user.Active = *req.Active
 	}
+	if req.Role != "" && req.Role != user.Role {
+		if !middleware.HasPermission(r.Context(), models.PermRolesManage) {
+			respondError(w, http.StatusForbidden, "Permission denied to change roles")
+			return
+		}
+		perms, ok := middleware.RolePermissions(r.Context(), req.Role)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Unknown role")
+			return
+		}
+		// Nadie asigna un rol con más permisos que el propio (p. ej. un editor de roles haciéndose dueño)
+		if exceedsCaller(r, perms) {
+			respondError(w, http.StatusForbidden, "Permission denied to assign this role")
+			return
+		}
+	}
 	if req.Role != "" {
+		if req.Role != user.Role && revokeReason == "" {
+			revokeReason = models.SessionRevokedRoleChange
+		}
 		user.Role = req.Role
 	}
+	invitationDelta := 0
 	if req.InvitationClasses != nil {
+		invitationDelta = *req.InvitationClasses - user.InvitationClasses
 		user.InvitationClasses = *req.InvitationClasses
 	}
 
@@ -149,6 +252,13 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to update user")
 		return
 	}
+	h.recordInvitationGrant(r, user.ID, invitationDelta)
+	if revokeReason != "" && h.sessionRepo != nil {
+		if err := h.sessionRepo.RevokeAllSessions(user.ID, revokeReason); err != nil {
+			respondError(w, http.StatusInternalServerError, "User updated but failed to revoke sessions")
+			return
+		}
+	}
 
 	respondJSON(w, http.StatusOK, user)
 }
@@ -178,6 +288,7 @@ func (h *UserHandler) AddInvitation(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to add invitation")
 		return
 	}
+	h.recordInvitationGrant(r, id, req.Count)
 
 	user, err := h.userRepo.GetByID(id)
 	if err != nil {
@@ -194,6 +305,29 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if outranks(r, user) {
+		respondError(w, http.StatusForbidden, "Permission denied to delete this user")
+		return
+	}
+
+	// Con eraser la cuenta se anonimiza; el borrado físico arrastraría pagos y documentos que se deben conservar
+	if h.eraser != nil {
+		if err := h.eraser.EraseNow(id, middleware.GetUserID(r.Context())); err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to delete user")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	if err := h.userRepo.Delete(id); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to delete user")
 		return
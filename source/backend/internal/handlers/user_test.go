@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+)
+
+func frontDeskRequest(r *http.Request) *http.Request {
+	ctx := middleware.WithAuth(r.Context(), 2, models.RoleFrontDesk)
+	return r.WithContext(ctx)
+}
+
+func TestUserHandler_Update_FrontDeskCannotDeactivateOwner(t *testing.T) {
+	owner := &models.User{ID: 1, Role: models.RoleOwner, Active: true}
+	handler := NewUserHandler(&mockUserRepo{user: owner})
+
+	req := httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewReader([]byte(`{"active":false}`)))
+	req.SetPathValue("id", "1")
+	req = frontDeskRequest(req)
+	rr := httptest.NewRecorder()
+
+	handler.Update(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+	if !owner.Active {
+		t.Fatal("owner should remain active")
+	}
+}
+
+func TestUserHandler_Update_FrontDeskCanDeactivateMember(t *testing.T) {
+	member := &models.User{ID: 3, Role: models.RoleMember, Active: true}
+	handler := NewUserHandler(&mockUserRepo{user: member})
+
+	req := httptest.NewRequest("PUT", "/api/v1/users/3", bytes.NewReader([]byte(`{"active":false}`)))
+	req.SetPathValue("id", "3")
+	req = frontDeskRequest(req)
+	rr := httptest.NewRecorder()
+
+	handler.Update(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if member.Active {
+		t.Fatal("member should be deactivated")
+	}
+}
+
+func TestUserHandler_Delete_FrontDeskCannotDeleteAdmin(t *testing.T) {
+	handler := NewUserHandler(&mockUserRepo{user: &models.User{ID: 1, Role: models.RoleAdmin}})
+
+	req := httptest.NewRequest("DELETE", "/api/v1/users/1", nil)
+	req.SetPathValue("id", "1")
+	req = frontDeskRequest(req)
+	rr := httptest.NewRecorder()
+
+	handler.Delete(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+// stubRoles resuelve los roles integrados más roles personalizados de prueba, sin base de datos
+type stubRoles map[models.Role][]string
+
+func (s stubRoles) Permissions(role models.Role) (map[string]bool, bool) {
+	list, ok := s[role]
+	if def, builtin := models.BuiltinRoles[role]; builtin {
+		list, ok = def.Permissions, true
+	}
+	perms := map[string]bool{}
+	for _, p := range list {
+		perms[p] = true
+	}
+	return perms, ok
+}
+
+// roleEditorScope - Tenant con un rol personalizado que administra roles pero no tiene acceso total
+var roleEditorScope = &middleware.Scope{PermissionResolver: stubRoles{
+	"editor":  {models.PermRolesManage, models.PermUsersManage, models.PermUsersView},
+	"auditor": {models.PermUsersView, models.PermSecurityManage},
+}}
+
+func serveScoped(h http.HandlerFunc, rr http.ResponseWriter, r *http.Request) {
+	middleware.WithScope(roleEditorScope)(h).ServeHTTP(rr, r)
+}
+
+func editorRequest(method, target, body string, id string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	req.SetPathValue("id", id)
+	req.SetPathValue("name", id)
+	return req.WithContext(middleware.WithAuth(req.Context(), 2, "editor"))
+}
+
+func TestUserHandler_Update_RoleEditorCannotGrantHigherRole(t *testing.T) {
+	for name, target := range map[string]*models.User{
+		"member": {ID: 3, Role: models.RoleMember, Active: true},
+		"self":   {ID: 2, Role: "editor", Active: true},
+	} {
+		role := target.Role
+		handler := NewUserHandler(&mockUserRepo{user: target})
+		rr := httptest.NewRecorder()
+
+		serveScoped(handler.Update, rr, editorRequest("PUT", "/api/v1/users/x", `{"role":"owner"}`, "3"))
+
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403 promoting to owner, got %d", name, rr.Code)
+		}
+		if target.Role != role {
+			t.Fatalf("%s: role should stay %q, got %q", name, role, target.Role)
+		}
+	}
+}
+
+func TestUserHandler_Update_RoleEditorCanGrantOwnPermissions(t *testing.T) {
+	member := &models.User{ID: 3, Role: models.RoleMember, Active: true}
+	handler := NewUserHandler(&mockUserRepo{user: member})
+	rr := httptest.NewRecorder()
+
+	serveScoped(handler.Update, rr, editorRequest("PUT", "/api/v1/users/3", `{"role":"editor"}`, "3"))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if member.Role != "editor" {
+		t.Fatalf("expected role editor, got %q", member.Role)
+	}
+}
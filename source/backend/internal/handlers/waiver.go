@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"boxmagic/internal/middleware"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+	"boxmagic/internal/services"
+)
+
+// WaiverHandler - Documentos de exención versionados, firma del miembro/apoderado y copias firmadas en PDF
+type WaiverHandler struct {
+	waiverService *services.WaiverService
+	waiverRepo    *repository.WaiverRepository
+	branding      services.ReceiptBranding
+}
+
+func NewWaiverHandler(waiverService *services.WaiverService, waiverRepo *repository.WaiverRepository, branding services.ReceiptBranding) *WaiverHandler {
+	return &WaiverHandler{waiverService: waiverService, waiverRepo: waiverRepo, branding: branding}
+}
+
+func respondWaiverError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrWaiverNotFound), errors.Is(err, sql.ErrNoRows):
+		respondError(w, http.StatusNotFound, services.ErrWaiverNotFound.Error())
+	case errors.Is(err, services.ErrWaiverNotApplicable):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrWaiverInvalidType), errors.Is(err, services.ErrWaiverInvalidAppliesTo),
+		errors.Is(err, services.ErrWaiverContentMissing), errors.Is(err, services.ErrWaiverNotAccepted),
+		errors.Is(err, services.ErrWaiverSignerMissing), errors.Is(err, services.ErrWaiverGuardianMissing),
+		errors.Is(err, services.ErrWaiverGuardianSigner):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, "Failed to process waiver")
+	}
+}
+
+// ListDocuments - Documentos vigentes (?history=true incluye versiones retiradas)
+func (h *WaiverHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	docs, err := h.waiverRepo.ListDocuments(r.URL.Query().Get("history") == "true")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch waiver documents")
+		return
+	}
+	if docs == nil {
+		docs = []*models.WaiverDocument{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"documents": docs})
+}
+
+// Publish - Publica una nueva versión del documento; reemplaza a la vigente del mismo tipo
+func (h *WaiverHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	var req models.WaiverDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	d, err := h.waiverService.Publish(&req, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondWaiverError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, d)
+}
+
+// Retire - Deja de exigir un documento; las firmas existentes se conservan
+func (h *WaiverHandler) Retire(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	if err := h.waiverRepo.Retire(id); err != nil {
+		respondWaiverError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Document retired"})
+}
+
+// MyWaivers - Documentos que el miembro debe firmar y sus firmas
+func (h *WaiverHandler) MyWaivers(w http.ResponseWriter, r *http.Request) {
+	m, err := h.waiverService.MemberStatus(middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch waivers")
+		return
+	}
+	respondJSON(w, http.StatusOK, m)
+}
+
+// Sign - El miembro (o su apoderado si es menor) firma la versión vigente del documento
+func (h *WaiverHandler) Sign(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	var req models.SignWaiverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	a, err := h.waiverService.Sign(middleware.GetUserID(r.Context()), id, &req, middleware.ClientInfo(r))
+	if err != nil {
+		respondWaiverError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, a)
+}
+
+// UserWaivers - Estado de firmas de un miembro (admin)
+func (h *WaiverHandler) UserWaivers(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	m, err := h.waiverService.MemberStatus(id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch waivers")
+		return
+	}
+	respondJSON(w, http.StatusOK, m)
+}
+
+// Pending - Miembros activos con documentos obligatorios sin firmar
+func (h *WaiverHandler) Pending(w http.ResponseWriter, r *http.Request) {
+	list, err := h.waiverService.Pending()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch pending waivers")
+		return
+	}
+	if list == nil {
+		list = []*models.MemberWaivers{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+// SignedPDF - Copia firmada en PDF; el miembro solo accede a las propias
+func (h *WaiverHandler) SignedPDF(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid signature ID")
+		return
+	}
+	sw, err := h.waiverRepo.GetSigned(id)
+	if err == sql.ErrNoRows || (err == nil && !canAccess(r, models.PermWaiversView, &sw.UserID)) {
+		respondError(w, http.StatusNotFound, "Signature not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch signature")
+		return
+	}
+	writePDF(w, fmt.Sprintf("%s-%d.pdf", sw.DocumentType, sw.ID), services.RenderSignedWaiver(sw, h.branding))
+}
@@ -0,0 +1,229 @@
+// Package jwtkeys firma y verifica los JWT con claves asimétricas (RS256 / EdDSA) identificadas por kid.
+//
+// Las claves viven en un directorio: un archivo <kid>.pem (PKCS#8) por clave y un archivo "active" con el
+// kid que firma. Todas las claves del directorio verifican, lo que permite rotar sin invalidar tokens:
+// se agrega la clave nueva, se activa cuando todas las réplicas la cargaron y se borra la anterior cuando
+// vencieron sus tokens. La CLI cmd/jwtkeys automatiza esos pasos.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ActiveFile guarda el kid de la clave de firma
+const ActiveFile = "active"
+
+var (
+	ErrNoKeys         = errors.New("no signing keys found")
+	ErrNoActiveKey    = errors.New("active key not found")
+	ErrUnknownKey     = errors.New("unknown key id")
+	ErrUnsupportedKey = errors.New("unsupported key type (use RSA or Ed25519)")
+)
+
+type key struct {
+	kid     string
+	private crypto.Signer
+	method  jwt.SigningMethod
+}
+
+// KeySet - Claves cargadas desde el directorio; se puede recargar en caliente
+type KeySet struct {
+	dir string
+
+	mu     sync.RWMutex
+	keys   map[string]*key
+	active string
+}
+
+// Load lee todas las claves del directorio y la clave activa
+func Load(dir string) (*KeySet, error) {
+	ks := &KeySet{dir: dir}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) Reload() error {
+	files, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*key, len(files))
+	for _, f := range files {
+		k, err := readKey(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(f), err)
+		}
+		keys[k.kid] = k
+	}
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+	active, err := ReadActive(ks.dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[active]; !ok {
+		return fmt.Errorf("%w: %s", ErrNoActiveKey, active)
+	}
+
+	ks.mu.Lock()
+	ks.keys, ks.active = keys, active
+	ks.mu.Unlock()
+	return nil
+}
+
+// Watch recarga el directorio periódicamente para tomar claves nuevas o rotadas sin reiniciar
+func (ks *KeySet) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := ks.Reload(); err != nil {
+			log.Printf("[JWTKEYS] reload: %v (se mantienen las claves anteriores)", err)
+		}
+	}
+}
+
+func readKey(path string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	k := &key{kid: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch p := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.private, k.method = p, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		k.private, k.method = p, jwt.SigningMethodEdDSA
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return k, nil
+}
+
+// ReadActive devuelve el kid activo del directorio
+func ReadActive(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ActiveFile))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoActiveKey, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Sign firma los claims con la clave activa e incluye su kid en el header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	k := ks.keys[ks.active]
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	return token.SignedString(k.private)
+}
+
+// Keyfunc elige la clave pública por kid y exige que el algoritmo del token corresponda a esa clave
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return k.private.Public(), nil
+}
+
+// JWK - Clave pública en formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS devuelve las claves públicas de verificación, la activa primero
+func (ks *KeySet) JWKS() []JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if kids[i] == ks.active || kids[j] == ks.active {
+			return kids[i] == ks.active
+		}
+		return kids[i] < kids[j]
+	})
+
+	b64 := base64.RawURLEncoding
+	jwks := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		k := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64.EncodeToString(pub.N.Bytes())
+			jwk.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = b64.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
+
+// Sign firma con el KeySet si está configurado y si no con HS256 y el secreto compartido
+func Sign(ks *KeySet, secret string, claims jwt.Claims) (string, error) {
+	if ks != nil {
+		return ks.Sign(claims)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// Keyfunc verifica con el KeySet si está configurado (solo RS256/EdDSA) y si no con HS256
+func Keyfunc(ks *KeySet, secret string) jwt.Keyfunc {
+	if ks != nil {
+		return ks.Keyfunc
+	}
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(secret), nil
+	}
+}
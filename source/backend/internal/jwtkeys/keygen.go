@@ -0,0 +1,74 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Algoritmos soportados para claves nuevas
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrActiveKeyRemoval = errors.New("cannot remove the active key; activate another one first")
+
+// Generate crea una clave nueva en el directorio (sin activarla) y devuelve su kid
+func Generate(dir, alg string) (string, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q (use %s or %s)", alg, AlgRS256, AlgEdDSA)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	kid := time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(suffix)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// Activate deja el kid como clave de firma
+func Activate(dir, kid string) error {
+	if _, err := readKey(filepath.Join(dir, kid+".pem")); err != nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return os.WriteFile(filepath.Join(dir, ActiveFile), []byte(kid+"\n"), 0600)
+}
+
+// Remove retira una clave de verificación (sus tokens dejan de ser válidos)
+func Remove(dir, kid string) error {
+	if active, err := ReadActive(dir); err == nil && active == kid {
+		return ErrActiveKeyRemoval
+	}
+	return os.Remove(filepath.Join(dir, kid+".pem"))
+}
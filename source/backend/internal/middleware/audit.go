@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"boxmagic/internal/models"
+)
+
+// Auditor toma fotos de las entidades y persiste las entradas del log de auditoría
+type Auditor interface {
+	Snapshot(entityType string, id int64) json.RawMessage
+	Record(e *models.AuditEntry)
+}
+
+// SetAuditor activa el log de auditoría; sin auditor el middleware Audit no hace nada
+func SetAuditor(a Auditor) {
+	defaultScope.Auditor = a
+}
+
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies indica los proxies (IP o CIDR) cuyo X-Forwarded-For se respeta; sin ellos se usa RemoteAddr
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+func trustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP devuelve la IP del cliente. X-Forwarded-For solo se considera si la conexión viene de un proxy
+// de confianza, y se recorre desde la derecha: el primer salto que no es proxy propio es el cliente. Los
+// valores a la izquierda los escribe el propio cliente y no sirven para limitar ni auditar.
+func ClientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if !trustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !trustedProxy(hop) {
+			return hop
+		}
+		ip = hop
+	}
+	return ip
+}
+
+// ClientInfo obtiene IP (ver ClientIP) y user agent del request
+func ClientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{IP: ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// maxAuditBody acota cuánto de la respuesta se guarda para obtener el ID de lo creado
+const maxAuditBody = 1 << 20
+
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(b) <= maxAuditBody {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditTarget deduce la acción y el ID de la entidad a partir del patrón de la ruta:
+// el primer comodín es la entidad y los segmentos literales posteriores la sub-acción.
+func auditTarget(r *http.Request) (action, entityID string) {
+	pattern := r.Pattern
+	if i := strings.Index(pattern, " "); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(pattern, "/api/v1/"), "/"), "/")
+
+	var literals []string
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") {
+			if entityID == "" {
+				entityID = r.PathValue(strings.Trim(seg, "{}."))
+			}
+			continue
+		}
+		if i > 0 {
+			literals = append(literals, seg)
+		}
+	}
+
+	action = strings.Join(literals, ".")
+	switch {
+	case action == "" && r.Method == http.MethodPost:
+		action = "create"
+	case action == "" && r.Method == http.MethodDelete:
+		action = "delete"
+	case action == "":
+		action = "update"
+	case r.Method == http.MethodDelete:
+		action += ".remove"
+	case r.Method == http.MethodPut || r.Method == http.MethodPatch:
+		action += ".update"
+	}
+	return action, entityID
+}
+
+// createdID busca el "id" de la entidad creada en la respuesta ({"id":..} o {"<entidad>":{"id":..}})
+func createdID(body []byte, entityType string) string {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(body, &obj) != nil {
+		return ""
+	}
+	raw, ok := obj["id"]
+	if !ok {
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(obj[entityType], &nested) != nil {
+			return ""
+		}
+		raw = nested["id"]
+	}
+	var id int64
+	if json.Unmarshal(raw, &id) != nil || id <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// Audit registra las mutaciones exitosas sobre la entidad con foto antes/después, actor e IP
+func Audit(entityType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auditor := scopeFrom(r.Context()).Auditor
+			if auditor == nil || r.Method == http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			action, entityID := auditTarget(r)
+			id, _ := strconv.ParseInt(entityID, 10, 64)
+			var before json.RawMessage
+			if id > 0 {
+				before = auditor.Snapshot(entityType, id)
+			}
+
+			rec := &auditRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status >= 400 {
+				return
+			}
+
+			if entityID == "" {
+				entityID = createdID(rec.body.Bytes(), entityType)
+				id, _ = strconv.ParseInt(entityID, 10, 64)
+			}
+			var after json.RawMessage
+			if _, ok := models.AuditEntityTables[entityType]; ok && id > 0 {
+				after = auditor.Snapshot(entityType, id)
+			} else if json.Valid(rec.body.Bytes()) {
+				after = json.RawMessage(bytes.TrimSpace(rec.body.Bytes()))
+			}
+
+			client := ClientInfo(r)
+			entry := &models.AuditEntry{
+				ActorID:    GetUserID(r.Context()),
+				ActorRole:  GetRole(r.Context()),
+				Action:     action,
+				EntityType: entityType,
+				EntityID:   entityID,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Before:     before,
+				After:      after,
+				IP:         client.IP,
+				UserAgent:  client.UserAgent,
+			}
+			if imp := GetImpersonator(r.Context()); imp != nil {
+				entry.OnBehalfOf = &entry.ActorID
+				entry.ActorID, entry.ActorRole = imp.ActorID, imp.ActorRole
+				imp.audited = true
+			}
+			auditor.Record(entry)
+		})
+	}
+}
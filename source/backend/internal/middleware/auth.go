@@ -3,21 +3,78 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 
 	"boxmagic/internal/config"
+	"boxmagic/internal/jwtkeys"
 	"boxmagic/internal/models"
 )
 
 type contextKey string
 
 const (
-	userIDKey contextKey = "userID"
-	roleKey   contextKey = "role"
+	userIDKey        contextKey = "userID"
+	roleKey          contextKey = "role"
+	sessionIDKey     contextKey = "sessionID"
+	impersonationKey contextKey = "impersonation"
 )
 
+// AccessChecker valida que el token siga vigente (usuario activo, token_version, sesión no revocada)
+type AccessChecker interface {
+	CheckAccess(userID, sessionID int64, tokenVersion int) error
+}
+
+// SetAccessChecker activa la validación de sesiones en Auth; sin checker solo se valida la firma del JWT
+func SetAccessChecker(c AccessChecker) {
+	defaultScope.AccessChecker = c
+}
+
+// Impersonator - Admin que actúa como el miembro del token (claim "act"); GetUserID sigue siendo el miembro
+type Impersonator struct {
+	SessionID int64 // impersonation_sessions.id (claim "imp")
+	ActorID   int64
+	ActorRole models.Role
+	Write     bool // Sin permiso de escritura solo se aceptan GET/HEAD/OPTIONS
+	audited   bool // La ruta ya registró la acción con Audit
+}
+
+// ImpersonationChecker valida que la impersonación no haya terminado ni vencido
+type ImpersonationChecker interface {
+	CheckImpersonation(id, actorID, userID int64) error
+}
+
+// SetImpersonationChecker activa la validación de impersonaciones; sin checker solo se valida la firma y "exp"
+func SetImpersonationChecker(c ImpersonationChecker) {
+	defaultScope.Impersonations = c
+}
+
+// impersonatorFromClaims lee {"act": {"sub", "role"}, "imp", "imp_w"}; nil si el token no es de impersonación
+func impersonatorFromClaims(claims jwt.MapClaims) (*Impersonator, bool) {
+	raw, present := claims["act"]
+	if !present {
+		return nil, true
+	}
+	act, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	sub, ok1 := act["sub"].(float64)
+	role, ok2 := act["role"].(string)
+	imp, ok3 := claims["imp"].(float64)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, false
+	}
+	write, _ := claims["imp_w"].(bool)
+	return &Impersonator{SessionID: int64(imp), ActorID: int64(sub), ActorRole: models.Role(role), Write: write}, true
+}
+
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -35,12 +92,7 @@ func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 
 			tokenString := parts[1]
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return []byte(cfg.JWTSecret), nil
-			})
+			token, err := jwt.Parse(tokenString, jwtkeys.Keyfunc(cfg.JWTKeys, cfg.JWTSecret))
 
 			if err != nil || !token.Valid {
 				http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
@@ -65,15 +117,93 @@ func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 			}
 			userID := int64(subFloat)
 			role := models.Role(roleStr)
+			// Tokens emitidos antes de las sesiones no traen sid/ver (se tratan como 0)
+			sid, _ := claims["sid"].(float64)
+			ver, _ := claims["ver"].(float64)
+
+			// Con varios tenants el secreto es compartido: el token solo vale en el tenant que lo emitió
+			if tid, _ := claims["tid"].(string); tid != cfg.TenantSlug {
+				http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
+				return
+			}
+
+			imp, ok := impersonatorFromClaims(claims)
+			if !ok {
+				http.Error(w, `{"error":"Invalid token claims"}`, http.StatusUnauthorized)
+				return
+			}
+
+			scope := scopeFrom(r.Context())
+			if checker := scope.AccessChecker; checker != nil {
+				if err := checker.CheckAccess(userID, int64(sid), int(ver)); err != nil {
+					http.Error(w, `{"error":"Session expired or revoked"}`, http.StatusUnauthorized)
+					return
+				}
+			}
+			if imp != nil {
+				if checker := scope.Impersonations; checker != nil {
+					if err := checker.CheckImpersonation(imp.SessionID, imp.ActorID, userID); err != nil {
+						http.Error(w, `{"error":"Impersonation ended or expired"}`, http.StatusUnauthorized)
+						return
+					}
+				}
+				if !imp.Write && !readOnlyMethod(r.Method) {
+					http.Error(w, `{"error":"Impersonation session is read-only"}`, http.StatusForbidden)
+					return
+				}
+			}
 
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
 			ctx = context.WithValue(ctx, roleKey, role)
+			ctx = context.WithValue(ctx, sessionIDKey, int64(sid))
+			if imp == nil {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
 
-			next.ServeHTTP(w, r.WithContext(ctx))
+			ctx = context.WithValue(ctx, impersonationKey, imp)
+			r = r.WithContext(ctx)
+			auditor := scope.Auditor
+			if auditor == nil || readOnlyMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Toda escritura hecha durante una impersonación queda en el log a nombre del admin, aunque la ruta no use Audit
+			rec := &auditRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status >= 400 || imp.audited {
+				return
+			}
+			client := ClientInfo(r)
+			auditor.Record(&models.AuditEntry{
+				ActorID:    imp.ActorID,
+				ActorRole:  imp.ActorRole,
+				OnBehalfOf: &userID,
+				Action:     "request",
+				EntityType: models.AuditEntityImpersonation,
+				EntityID:   strconv.FormatInt(imp.SessionID, 10),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				IP:         client.IP,
+				UserAgent:  client.UserAgent,
+			})
 		})
 	}
 }
 
+// NoImpersonation bloquea la ruta durante una impersonación (aunque tenga escritura): descargas de datos
+// personales como el export GDPR, certificados y boletas no deben salir a nombre del miembro
+func NoImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetImpersonator(r.Context()) != nil {
+			http.Error(w, `{"error":"Not available while impersonating"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminOnly exige el rol admin literal; las rutas usan RequirePermission
 func AdminOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		role := GetRole(r.Context())
@@ -85,6 +215,54 @@ func AdminOnly(next http.Handler) http.Handler {
 	})
 }
 
+// PermissionResolver traduce un rol a su conjunto de permisos; ok=false si el rol no existe
+type PermissionResolver interface {
+	Permissions(role models.Role) (map[string]bool, bool)
+}
+
+// SetPermissionResolver habilita los roles personalizados; sin resolver solo se usan los roles integrados
+func SetPermissionResolver(r PermissionResolver) {
+	defaultScope.PermissionResolver = r
+}
+
+// RolePermissions devuelve los permisos del rol, resueltos en cada request para reflejar cambios al instante
+func RolePermissions(ctx context.Context, role models.Role) (map[string]bool, bool) {
+	if resolver := scopeFrom(ctx).PermissionResolver; resolver != nil {
+		return resolver.Permissions(role)
+	}
+	def, ok := models.BuiltinRoles[role]
+	if !ok {
+		return nil, false
+	}
+	perms := make(map[string]bool, len(def.Permissions))
+	for _, p := range def.Permissions {
+		perms[p] = true
+	}
+	return perms, true
+}
+
+// HasPermission indica si el usuario autenticado tiene el permiso
+func HasPermission(ctx context.Context, perm string) bool {
+	perms, _ := RolePermissions(ctx, GetRole(ctx))
+	return perms[perm]
+}
+
+// RequirePermission deja pasar solo a roles con alguno de los permisos indicados
+func RequirePermission(perms ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, _ := RolePermissions(r.Context(), GetRole(r.Context()))
+			for _, p := range perms {
+				if granted[p] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, `{"error":"Permission denied"}`, http.StatusForbidden)
+		})
+	}
+}
+
 func GetUserID(ctx context.Context) int64 {
 	if id, ok := ctx.Value(userIDKey).(int64); ok {
 		return id
@@ -99,6 +277,28 @@ func GetRole(ctx context.Context) models.Role {
 	return ""
 }
 
+// GetImpersonator devuelve el admin que actúa como el miembro; nil si el token no es de impersonación
+func GetImpersonator(ctx context.Context) *Impersonator {
+	imp, _ := ctx.Value(impersonationKey).(*Impersonator)
+	return imp
+}
+
+// GetActorID devuelve quién ejecuta realmente la acción: el admin durante una impersonación, si no el usuario
+func GetActorID(ctx context.Context) int64 {
+	if imp := GetImpersonator(ctx); imp != nil {
+		return imp.ActorID
+	}
+	return GetUserID(ctx)
+}
+
+// GetSessionID devuelve la sesión del access token (0 si el token no trae sesión)
+func GetSessionID(ctx context.Context) int64 {
+	if id, ok := ctx.Value(sessionIDKey).(int64); ok {
+		return id
+	}
+	return 0
+}
+
 func WithAuth(ctx context.Context, userID int64, role models.Role) context.Context {
 	ctx = context.WithValue(ctx, userIDKey, userID)
 	ctx = context.WithValue(ctx, roleKey, role)
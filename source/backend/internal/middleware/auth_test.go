@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 
 	"boxmagic/internal/config"
+	"boxmagic/internal/jwtkeys"
 	"boxmagic/internal/models"
 )
 
@@ -158,6 +160,38 @@ func TestAdminOnly_User(t *testing.T) {
 	}
 }
 
+func TestRequirePermission_Coach(t *testing.T) {
+	handler := RequirePermission(models.PermRoutinesManage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req = req.WithContext(WithAuth(req.Context(), 1, models.RoleCoach))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestRequirePermission_Denied(t *testing.T) {
+	handler := RequirePermission(models.PermPaymentsCreate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("should not reach handler")
+	}))
+
+	for _, role := range []models.Role{models.RoleCoach, models.RoleMember, "unknown"} {
+		req := httptest.NewRequest("POST", "/", nil)
+		req = req.WithContext(WithAuth(req.Context(), 1, role))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", role, rr.Code)
+		}
+	}
+}
+
 func TestGetUserID_NoContext(t *testing.T) {
 	id := GetUserID(context.Background())
 	if id != 0 {
@@ -181,3 +215,211 @@ func TestWithAuth(t *testing.T) {
 		t.Fatal("expected admin")
 	}
 }
+
+type stubAccessChecker struct{ err error }
+
+func (s stubAccessChecker) CheckAccess(userID, sessionID int64, tokenVersion int) error { return s.err }
+
+func TestAuth_RevokedSession(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	SetAccessChecker(stubAccessChecker{err: errors.New("revoked")})
+	defer SetAccessChecker(nil)
+
+	token := makeJWT("test-secret", jwt.MapClaims{
+		"sub":  float64(42),
+		"role": "user",
+		"sid":  float64(7),
+		"ver":  float64(1),
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+	handler := Auth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("should not reach handler")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+}
+
+func TestAuth_RejectsTokenFromOtherTenant(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "shared-secret", TenantSlug: "norte"}
+	handler := Auth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := map[string]struct {
+		tid  interface{}
+		want int
+	}{
+		"same tenant":  {"norte", http.StatusOK},
+		"other tenant": {"sur", http.StatusUnauthorized},
+		"no tenant":    {nil, http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		claims := jwt.MapClaims{"sub": float64(1), "role": "owner", "exp": time.Now().Add(time.Hour).Unix()}
+		if tc.tid != nil {
+			claims["tid"] = tc.tid
+		}
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+makeJWT("shared-secret", claims))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rr.Code)
+		}
+	}
+}
+
+func TestAuth_KeySetVerifiesByKid(t *testing.T) {
+	dir := t.TempDir()
+	oldKid, _ := jwtkeys.Generate(dir, jwtkeys.AlgEdDSA)
+	jwtkeys.Activate(dir, oldKid)
+	oldKeys, err := jwtkeys.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": float64(1), "role": "admin", "exp": time.Now().Add(time.Hour).Unix()}
+	oldToken, _ := oldKeys.Sign(claims)
+
+	// Rotación: la clave nueva firma y la anterior sigue verificando
+	newKid, _ := jwtkeys.Generate(dir, jwtkeys.AlgRS256)
+	jwtkeys.Activate(dir, newKid)
+	keys, _ := jwtkeys.Load(dir)
+	newToken, _ := keys.Sign(claims)
+
+	cfg := &config.Config{JWTSecret: "test-secret", JWTKeys: keys}
+	handler := Auth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := map[string]struct {
+		token string
+		want  int
+	}{
+		"active key":  {newToken, http.StatusOK},
+		"rotated key": {oldToken, http.StatusOK},
+		"hs256":       {makeJWT("test-secret", claims), http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rr.Code)
+		}
+	}
+
+	jwtkeys.Remove(dir, oldKid)
+	keys.Reload()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+oldToken)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("removed key: expected 401, got %d", rr.Code)
+	}
+}
+
+type stubResolver map[models.Role][]string
+
+func (s stubResolver) Permissions(role models.Role) (map[string]bool, bool) {
+	perms, ok := s[role]
+	granted := map[string]bool{}
+	for _, p := range perms {
+		granted[p] = true
+	}
+	return granted, ok
+}
+
+func TestRequirePermission_UsesTenantScope(t *testing.T) {
+	// El mismo rol personalizado existe en ambos tenants con permisos distintos
+	norte := &Scope{PermissionResolver: stubResolver{"recepcion": {models.PermPaymentsApprove}}}
+	sur := &Scope{PermissionResolver: stubResolver{"recepcion": {}}}
+	final := RequirePermission(models.PermPaymentsApprove)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for scope, want := range map[*Scope]int{norte: http.StatusOK, sur: http.StatusForbidden} {
+		req := httptest.NewRequest("POST", "/api/v1/payments/1/approve", nil)
+		req = req.WithContext(WithAuth(req.Context(), 5, "recepcion"))
+		rr := httptest.NewRecorder()
+		WithScope(scope)(final).ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("expected %d, got %d", want, rr.Code)
+		}
+	}
+}
+
+func TestAuth_ImpersonationReadOnly(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	token := makeJWT("test-secret", jwt.MapClaims{
+		"sub":  float64(42),
+		"role": "user",
+		"act":  map[string]interface{}{"sub": float64(1), "role": "admin"},
+		"imp":  float64(9),
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	})
+
+	var gotID, gotActor int64
+	handler := Auth(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = GetUserID(r.Context())
+		gotActor = GetActorID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if gotID != 42 || gotActor != 1 {
+		t.Fatalf("expected user 42 acted by 1, got %d by %d", gotID, gotActor)
+	}
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 on write, got %d", rr.Code)
+	}
+}
+
+func TestNoImpersonation_BlocksPersonalDataDownloads(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	claims := jwt.MapClaims{"sub": float64(42), "role": "user", "exp": time.Now().Add(time.Hour).Unix()}
+	own := makeJWT("test-secret", claims)
+	claims["act"] = map[string]interface{}{"sub": float64(1), "role": "admin"}
+	claims["imp"] = float64(9)
+	impersonated := makeJWT("test-secret", claims)
+
+	handler := Auth(cfg)(NoImpersonation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	cases := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"member", own, http.StatusOK},
+		{"impersonating admin", impersonated, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/v1/users/me/export", nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, rr.Code)
+		}
+	}
+}
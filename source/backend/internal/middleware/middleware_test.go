@@ -1,9 +1,15 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
 )
 
 func TestCORS_Headers(t *testing.T) {
@@ -60,3 +66,196 @@ func TestLogger(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+type fakeAuditor struct {
+	entries   []*models.AuditEntry
+	snapshots int
+}
+
+func (f *fakeAuditor) Snapshot(entityType string, id int64) json.RawMessage {
+	f.snapshots++
+	return json.RawMessage(fmt.Sprintf(`{"id":%d,"n":%d}`, id, f.snapshots))
+}
+
+func (f *fakeAuditor) Record(e *models.AuditEntry) { f.entries = append(f.entries, e) }
+
+func TestAudit_RecordsSuccessfulMutations(t *testing.T) {
+	fa := &fakeAuditor{}
+	SetAuditor(fa)
+	defer SetAuditor(nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/payments/{id}/approve", Audit(models.AuditEntityPayment)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	mux.Handle("PUT /api/v1/plans/{id}", Audit(models.AuditEntityPlan)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	})))
+
+	req := httptest.NewRequest("POST", "/api/v1/payments/7/approve", nil)
+	req = req.WithContext(WithAuth(req.Context(), 3, models.RoleAdmin))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/api/v1/plans/1", nil))
+
+	if len(fa.entries) != 1 {
+		t.Fatalf("expected 1 entry (failed requests are skipped), got %d", len(fa.entries))
+	}
+	e := fa.entries[0]
+	if e.Action != "approve" || e.EntityType != models.AuditEntityPayment || e.EntityID != "7" || e.ActorID != 3 {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if string(e.Before) != `{"id":7,"n":1}` || string(e.After) != `{"id":7,"n":2}` {
+		t.Fatalf("expected before/after snapshots, got %s / %s", e.Before, e.After)
+	}
+}
+
+func TestAudit_UntabledEntityKeepsResponse(t *testing.T) {
+	fa := &fakeAuditor{}
+	SetAuditor(fa)
+	defer SetAuditor(nil)
+
+	// El {id} de la ruta es el miembro, no un movimiento: la foto posterior es la respuesta
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/ledger/users/{id}/entries", Audit(models.AuditEntityLedger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":40,"user_id":7,"amount":-5000}`))
+	})))
+
+	req := httptest.NewRequest("POST", "/api/v1/ledger/users/7/entries", nil)
+	req = req.WithContext(WithAuth(req.Context(), 3, models.RoleAdmin))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(fa.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(fa.entries))
+	}
+	e := fa.entries[0]
+	if e.Action != "users.entries" || e.EntityID != "7" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if string(e.After) != `{"id":40,"user_id":7,"amount":-5000}` {
+		t.Fatalf("expected the response as after snapshot, got %s", e.After)
+	}
+}
+
+func TestLimiter_PerIP(t *testing.T) {
+	SetRateLimitStore(NewMemoryRateLimitStore())
+	handler := NewLimiter("test", config.RateLimit{Limit: 2, Window: time.Minute}).PerIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if i < 2 && rr.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rr.Code)
+		}
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" || rr.Header().Get("RateLimit-Remaining") != "0" || rr.Header().Get("RateLimit-Limit") != "2" {
+		t.Fatalf("missing rate limit headers: %v", rr.Header())
+	}
+
+	// Otra IP tiene su propio contador
+	req := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a different IP, got %d", rr.Code)
+	}
+}
+
+func TestLimiter_PerIP_IgnoresSpoofedForwardedFor(t *testing.T) {
+	SetRateLimitStore(NewMemoryRateLimitStore())
+	handler := NewLimiter("test", config.RateLimit{Limit: 2, Window: time.Minute}).PerIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Sin proxy de confianza, rotar X-Forwarded-For no crea contadores nuevos
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected spoofed X-Forwarded-For to share one bucket, got %d", rr.Code)
+	}
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	cases := []struct {
+		remote, xff, want string
+	}{
+		{"203.0.113.9:1234", "198.51.100.1", "203.0.113.9"},                  // Cliente directo: se ignora el header
+		{"10.0.0.5:80", "198.51.100.1", "198.51.100.1"},                      // Detrás del proxy
+		{"10.0.0.5:80", "1.2.3.4, 198.51.100.1", "198.51.100.1"},             // El salto de la izquierda lo escribe el cliente
+		{"10.0.0.5:80", "198.51.100.1, 192.0.2.1, 10.0.0.7", "198.51.100.1"}, // Varios proxies propios
+		{"10.0.0.5:80", "", "10.0.0.5"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remote
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if got := ClientIP(req); got != c.want {
+			t.Errorf("remote %s, xff %q: expected %s, got %s", c.remote, c.xff, c.want, got)
+		}
+	}
+}
+
+func TestTenantSlug_Resolution(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/plans", nil)
+	req.Host = "boxnorte.example.com:443"
+	if got, err := TenantSlug(req, "example.com"); err != nil || got != "boxnorte" {
+		t.Fatalf("subdomain: expected boxnorte, got %q (%v)", got, err)
+	}
+
+	req.Header.Set(TenantHeader, "BoxNorte")
+	if got, err := TenantSlug(req, "example.com"); err != nil || got != "boxnorte" {
+		t.Fatalf("matching header: expected boxnorte, got %q (%v)", got, err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/plans", nil)
+	req.Host = "api.other.com"
+	req.Header.Set(TenantHeader, "BoxSur")
+	if got, err := TenantSlug(req, "example.com"); err != nil || got != "boxsur" {
+		t.Fatalf("header: expected boxsur, got %q (%v)", got, err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/plans", nil)
+	req.Host = "api.other.com"
+	token := makeJWT("any", map[string]interface{}{"sub": 1, "tid": "boxcentro"})
+	req.Header.Set("Authorization", "Bearer "+token)
+	if got, err := TenantSlug(req, "example.com"); err != nil || got != "boxcentro" {
+		t.Fatalf("claim: expected boxcentro, got %q (%v)", got, err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/plans", nil)
+	req.Host = "a.b.example.com"
+	if got, err := TenantSlug(req, "example.com"); err != nil || got != "" {
+		t.Fatalf("nested subdomain should not resolve, got %q (%v)", got, err)
+	}
+}
+
+func TestTenantSlug_HeaderConflictsWithSubdomain(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/users", nil)
+	req.Host = "boxnorte.example.com"
+	req.Header.Set(TenantHeader, "boxsur")
+	if got, err := TenantSlug(req, "example.com"); err != ErrTenantMismatch || got != "" {
+		t.Fatalf("expected ErrTenantMismatch, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"boxmagic/internal/config"
+)
+
+// RateLimitStore cuenta hits por clave en ventanas fijas
+type RateLimitStore interface {
+	Hit(key string, window time.Duration) (count int, resetAt time.Time, err error)
+}
+
+// MemoryRateLimitStore - Store por defecto; solo sirve con una réplica
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*rateBucket)}
+}
+
+func (s *MemoryRateLimitStore) Hit(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, b := range s.buckets {
+			if now.After(b.resetAt) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || now.After(b.resetAt) {
+		b = &rateBucket{resetAt: now.Add(window)}
+		s.buckets[key] = b
+	}
+	b.count++
+	return b.count, b.resetAt, nil
+}
+
+var rateLimitStore RateLimitStore = NewMemoryRateLimitStore()
+
+// SetRateLimitStore reemplaza el store (p. ej. Postgres para varias réplicas)
+func SetRateLimitStore(s RateLimitStore) {
+	rateLimitStore = s
+}
+
+// Limiter aplica un límite con nombre (grupo de rutas) por IP, por usuario o por una clave arbitraria
+type Limiter struct {
+	name string
+	rule config.RateLimit
+}
+
+func NewLimiter(name string, rule config.RateLimit) *Limiter {
+	return &Limiter{name: name, rule: rule}
+}
+
+// Allow cuenta el hit, escribe los headers RateLimit-* y responde 429 con Retry-After si se excedió
+func (l *Limiter) Allow(w http.ResponseWriter, key string) bool {
+	if l == nil || l.rule.Limit <= 0 || key == "" {
+		return true
+	}
+	count, resetAt, err := rateLimitStore.Hit(l.name+":"+key, l.rule.Window)
+	if err != nil {
+		// Si el store falla se deja pasar: preferimos disponibilidad a bloquear a todos
+		log.Printf("[RATELIMIT] %s: %v", l.name, err)
+		return true
+	}
+
+	reset := int(time.Until(resetAt).Seconds() + 0.999)
+	if reset < 0 {
+		reset = 0
+	}
+	remaining := l.rule.Limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(l.rule.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(reset))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", l.rule.Limit, int(l.rule.Window.Seconds())))
+
+	if count > l.rule.Limit {
+		h.Set("Retry-After", strconv.Itoa(reset))
+		http.Error(w, `{"error":"Too many requests"}`, http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// PerIP limita por IP del cliente (ver ClientIP: X-Forwarded-For solo cuenta detrás de un proxy de confianza)
+func (l *Limiter) PerIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(w, "ip:"+ClientIP(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
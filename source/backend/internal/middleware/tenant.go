@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scope - Dependencias del middleware que pertenecen a un tenant (sesiones, roles y auditoría viven en su schema).
+// En modo single-tenant se usa defaultScope, configurado con los Set*.
+type Scope struct {
+	AccessChecker      AccessChecker
+	PermissionResolver PermissionResolver
+	Auditor            Auditor
+	Impersonations     ImpersonationChecker
+}
+
+var defaultScope = &Scope{}
+
+const scopeKey contextKey = "scope"
+
+// WithScope deja el scope del tenant en el contexto para Auth, RequirePermission y Audit
+func WithScope(s *Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeKey, s)))
+		})
+	}
+}
+
+func scopeFrom(ctx context.Context) *Scope {
+	if s, ok := ctx.Value(scopeKey).(*Scope); ok && s != nil {
+		return s
+	}
+	return defaultScope
+}
+
+// TenantHeader permite indicar el tenant explícitamente (apps móviles, integraciones)
+const TenantHeader = "X-Tenant"
+
+// ErrTenantMismatch - El header X-Tenant apunta a un tenant distinto del subdominio
+var ErrTenantMismatch = errors.New("tenant header does not match the host")
+
+// TenantSlug resuelve el tenant del request, en orden: header X-Tenant, subdominio de baseDomain
+// (boxnorte.example.com) y claim "tid" del JWT. Si el header y el subdominio no coinciden se rechaza
+// el request en vez de elegir uno. El claim se lee sin verificar solo para enrutar: Auth del tenant
+// valida la firma y que el tid coincida.
+func TenantSlug(r *http.Request, baseDomain string) (string, error) {
+	header := strings.ToLower(strings.TrimSpace(r.Header.Get(TenantHeader)))
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if baseDomain != "" && strings.HasSuffix(host, "."+baseDomain) {
+		if sub := strings.TrimSuffix(host, "."+baseDomain); sub != "" && !strings.Contains(sub, ".") {
+			if header != "" && header != sub {
+				return "", ErrTenantMismatch
+			}
+			return sub, nil
+		}
+	}
+	if header != "" {
+		return header, nil
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(auth, "Bearer "), claims); err == nil {
+			if tid, ok := claims["tid"].(string); ok {
+				return tid, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// PlatformAdmin protege la API de super-admin con el token de plataforma (fuera de cualquier tenant)
+func PlatformAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				http.Error(w, `{"error":"Platform admin access required"}`, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
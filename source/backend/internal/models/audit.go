@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entidades auditadas
+const (
+	AuditEntityPlan          = "plan"
+	AuditEntityPayment       = "payment"
+	AuditEntityUser          = "user"
+	AuditEntityClass         = "class"
+	AuditEntityDiscipline    = "discipline"
+	AuditEntitySchedule      = "schedule"
+	AuditEntityDiscountCode  = "discount_code"
+	AuditEntityProduct       = "product"
+	AuditEntitySale          = "sale"
+	AuditEntityTag           = "tag"
+	AuditEntityWaiver        = "waiver"
+	AuditEntityErasure       = "erasure_request"
+	AuditEntityImpersonation = "impersonation"
+	AuditEntitySettings      = "settings" // Sin tabla: se guarda la respuesta como foto posterior
+	AuditEntityBankImport    = "bank_import"
+	AuditEntityBankTx        = "bank_transaction"
+	AuditEntityCashSession   = "cash_session"
+	AuditEntityLedger        = "ledger" // Sin tabla: el {id} de la ruta es el miembro; se guarda la respuesta
+	AuditEntityGiftCard      = "gift_card"
+	AuditEntityCustomProgram = "custom_program"
+	AuditEntityTaxCAF        = "tax_caf"
+	AuditEntityTaxDocument   = "tax_document"
+	AuditEntityRole          = "role" // Sin tabla: se identifica por nombre; se guarda la respuesta
+	AuditEntityInstructor    = "instructor"
+	AuditEntityBooking       = "booking"
+	AuditEntityRoutine       = "routine"
+	AuditEntityBenchmark     = "benchmark"
+	AuditEntityTrack         = "programming_track"
+	AuditEntityMemberNote    = "member_note" // Sin tabla: el {id} de la ruta es el miembro; se guarda la respuesta
+	AuditEntityChallenge     = "challenge"
+	AuditEntityLead          = "lead"
+	AuditEntityOnrampProgram = "onramp_program"
+	AuditEntityOnramp        = "onramp_enrollment" // Sin tabla: la ruta apunta al miembro; se guarda la respuesta
+	AuditEntityMovement      = "movement"
+	AuditEntityEvent         = "event"
+)
+
+// AuditEntityTables - Tabla de la que se toma la foto antes/después de cada entidad
+var AuditEntityTables = map[string]string{
+	AuditEntityPlan:          "plans",
+	AuditEntityPayment:       "payments",
+	AuditEntityUser:          "users",
+	AuditEntityClass:         "classes",
+	AuditEntityDiscipline:    "disciplines",
+	AuditEntitySchedule:      "class_schedules",
+	AuditEntityDiscountCode:  "discount_codes",
+	AuditEntityProduct:       "products",
+	AuditEntitySale:          "sales",
+	AuditEntityTag:           "tags",
+	AuditEntityWaiver:        "waiver_documents",
+	AuditEntityErasure:       "erasure_requests",
+	AuditEntityImpersonation: "impersonation_sessions",
+	AuditEntityBankImport:    "bank_statement_imports",
+	AuditEntityBankTx:        "bank_transactions",
+	AuditEntityCashSession:   "cash_sessions",
+	AuditEntityGiftCard:      "gift_cards",
+	AuditEntityCustomProgram: "custom_programs",
+	AuditEntityTaxCAF:        "tax_cafs",
+	AuditEntityTaxDocument:   "tax_documents",
+	AuditEntityInstructor:    "instructors",
+	AuditEntityBooking:       "bookings",
+	AuditEntityRoutine:       "routines",
+	AuditEntityBenchmark:     "benchmarks",
+	AuditEntityTrack:         "programming_tracks",
+	AuditEntityChallenge:     "challenges",
+	AuditEntityLead:          "leads",
+	AuditEntityOnrampProgram: "onramp_programs",
+	AuditEntityMovement:      "movements",
+	AuditEntityEvent:         "events",
+}
+
+// AuditRedactedFields nunca se guardan en el log
+var AuditRedactedFields = []string{"password_hash", "token_version", "caf_xml", "private_key"}
+
+// AuditUserPersonalFields - Datos personales del miembro en las fotos de users; se borran del log al anonimizarlo
+var AuditUserPersonalFields = []string{"email", "name", "phone", "rut", "birth_date", "sex", "weight_kg", "height_cm", "avatar_url"}
+
+// AuditChange - Valor de un campo antes y después del cambio
+type AuditChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditEntry - Registro inmutable de una acción administrativa
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	ActorID    int64                  `json:"actor_id"`
+	ActorName  string                 `json:"actor_name,omitempty"`
+	ActorRole  Role                   `json:"actor_role"`
+	OnBehalfOf *int64                 `json:"on_behalf_of,omitempty"` // Miembro suplantado si la acción ocurrió durante una impersonación
+	Action     string                 `json:"action"`                 // create, update, delete o sub-acción (approve, cancel, ...)
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	Before     json.RawMessage        `json:"before,omitempty"`
+	After      json.RawMessage        `json:"after,omitempty"`
+	Changes    map[string]AuditChange `json:"changes,omitempty"`
+	IP         string                 `json:"ip,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+type AuditFilter struct {
+	ActorID    *int64
+	EntityType string
+	EntityID   string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+}
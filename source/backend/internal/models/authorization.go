@@ -2,13 +2,98 @@ package models
 
 import "time"
 
+// Tipos de documento que el miembro firma
+const (
+	WaiverLiability    = "liability"     // Exención de responsabilidad
+	WaiverMinorConsent = "minor_consent" // Autorización del apoderado para menores
+	WaiverPhotoRelease = "photo_release" // Uso de imagen
+)
+
+// ValidWaiverTypes - Tipos aceptados al publicar un documento
+var ValidWaiverTypes = map[string]bool{WaiverLiability: true, WaiverMinorConsent: true, WaiverPhotoRelease: true}
+
+// A quién aplica un documento según la edad del miembro
+const (
+	WaiverAppliesAll    = "all"
+	WaiverAppliesMinors = "minors"
+	WaiverAppliesAdults = "adults"
+)
+
+// AdultAge - Desde esta edad el miembro firma por sí mismo; antes firma su apoderado
+const AdultAge = 18
+
+// WaiverDocument - Versión de un documento a firmar. Cada tipo tiene versiones; solo la última activa está vigente
+// y al publicar una nueva los miembros deben volver a firmar.
+type WaiverDocument struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Required  bool      `json:"required"` // Sin firmarlo no se puede reservar
+	AppliesTo string    `json:"applies_to"`
+	Active    bool      `json:"active"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WaiverDocumentRequest struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Required  bool   `json:"required"`
+	AppliesTo string `json:"applies_to"`
+}
+
+// Authorization - Firma de un documento con la evidencia (nombre tipeado, fecha, IP, hash del texto firmado)
 type Authorization struct {
-	ID           int64     `json:"id"`
-	UserID       int64     `json:"user_id"`
-	DocumentType string    `json:"document_type"`
-	SignedAt     time.Time `json:"signed_at"`
-	GuardianName string    `json:"guardian_name,omitempty"`
-	GuardianRut  string    `json:"guardian_rut,omitempty"`
-	Notes        string    `json:"notes,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"user_id"`
+	DocumentType    string    `json:"document_type"`
+	DocumentID      *int64    `json:"document_id,omitempty"`
+	DocumentVersion int       `json:"document_version,omitempty"`
+	DocumentTitle   string    `json:"document_title,omitempty"`
+	SignedAt        time.Time `json:"signed_at"`
+	SignerName      string    `json:"signer_name,omitempty"` // Nombre tipeado como firma
+	GuardianName    string    `json:"guardian_name,omitempty"`
+	GuardianRut     string    `json:"guardian_rut,omitempty"`
+	IP              string    `json:"ip,omitempty"`
+	UserAgent       string    `json:"user_agent,omitempty"`
+	DocumentHash    string    `json:"document_hash,omitempty"` // SHA-256 del texto firmado
+	Notes           string    `json:"notes,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type SignWaiverRequest struct {
+	SignerName   string `json:"signer_name"`
+	Accept       bool   `json:"accept"`
+	GuardianName string `json:"guardian_name,omitempty"` // Obligatorio si el miembro es menor de edad
+	GuardianRut  string `json:"guardian_rut,omitempty"`
+}
+
+// WaiverStatus - Documento vigente y su firma (si la hay) para un miembro
+type WaiverStatus struct {
+	Document  *WaiverDocument `json:"document"`
+	Signature *Authorization  `json:"signature,omitempty"`
+	Signed    bool            `json:"signed"`
+}
+
+// MemberWaivers - Estado de firmas de un miembro
+type MemberWaivers struct {
+	UserID          int64           `json:"user_id"`
+	UserName        string          `json:"user_name,omitempty"`
+	UserEmail       string          `json:"user_email,omitempty"`
+	Minor           bool            `json:"minor"`
+	Waivers         []*WaiverStatus `json:"waivers"`
+	PendingRequired int             `json:"pending_required"`
+	CanBook         bool            `json:"can_book"`
+}
+
+// SignedWaiver - Firma con el texto exacto firmado, para exportar la copia
+type SignedWaiver struct {
+	Authorization
+	UserName  string `json:"user_name"`
+	UserEmail string `json:"user_email"`
+	UserRUT   string `json:"user_rut,omitempty"`
+	Body      string `json:"body"`
 }
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Formatos de cartola soportados
+const (
+	BankFormatAuto       = "auto"
+	BankFormatCSV        = "csv"        // CSV genérico con encabezados (fecha, monto, rut, nombre, comentario)
+	BankFormatBancoChile = "bancochile" // Cartola Banco de Chile: Fecha;Descripción;Canal o Sucursal;Cargos;Abonos;Saldo
+)
+
+// Estados de una línea de cartola en la bandeja de conciliación
+const (
+	BankTxUnmatched = "unmatched" // Sin coincidencia
+	BankTxSuggested = "suggested" // Coincidencia automática o reasignada, pendiente de confirmar
+	BankTxConfirmed = "confirmed" // Pago completado
+	BankTxIgnored   = "ignored"   // Descartada por admin (ej. abono que no es una mensualidad)
+)
+
+type BankStatementImport struct {
+	ID         int64     `json:"id"`
+	Filename   string    `json:"filename"`
+	Format     string    `json:"format"`
+	TotalLines int       `json:"total_lines"`
+	Duplicates int       `json:"duplicates"`
+	Matched    int       `json:"matched"`
+	CreatedBy  int64     `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BankTransaction es un abono leído desde la cartola
+type BankTransaction struct {
+	ID               int64      `json:"id"`
+	ImportID         int64      `json:"import_id"`
+	Date             time.Time  `json:"date"`
+	Amount           int64      `json:"amount"`
+	SenderRUT        string     `json:"sender_rut,omitempty"`
+	SenderName       string     `json:"sender_name,omitempty"`
+	Comment          string     `json:"comment,omitempty"`
+	OperationID      string     `json:"operation_id,omitempty"` // N° de operación del banco, si la cartola lo trae
+	Fingerprint      string     `json:"-"`                      // Evita importar dos veces la misma línea
+	Status           string     `json:"status"`
+	MatchedPaymentID *int64     `json:"matched_payment_id,omitempty"`
+	MatchedUserID    *int64     `json:"matched_user_id,omitempty"`
+	MatchScore       int        `json:"match_score"`
+	MatchReason      string     `json:"match_reason,omitempty"`
+	ResolvedBy       *int64     `json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type BankTransactionWithDetails struct {
+	BankTransaction
+	MatchedUserName   string `json:"matched_user_name,omitempty"`
+	MatchedPlanName   string `json:"matched_plan_name,omitempty"`
+	MatchedPaymentAmt *int64 `json:"matched_payment_amount,omitempty"`
+}
+
+// ConfirmBankTransactionRequest - Si no hay pago pendiente asociado, se crea uno con user_id + plan_id
+type ConfirmBankTransactionRequest struct {
+	PaymentID *int64 `json:"payment_id,omitempty"`
+	UserID    *int64 `json:"user_id,omitempty"`
+	PlanID    *int64 `json:"plan_id,omitempty"`
+}
+
+type ReassignBankTransactionRequest struct {
+	PaymentID *int64 `json:"payment_id,omitempty"`
+	UserID    *int64 `json:"user_id,omitempty"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Categorías de benchmark
+const (
+	BenchmarkGirl  = "girl"
+	BenchmarkHero  = "hero"
+	BenchmarkOpen  = "open"
+	BenchmarkOther = "other"
+)
+
+// Tipos de score; definen cómo se comparan los resultados
+const (
+	ScoreTime       = "time"        // "12:34", menor es mejor; "CAP+5" si no terminó en el time cap
+	ScoreRoundsReps = "rounds_reps" // "5+12", mayor es mejor
+	ScoreReps       = "reps"        // "150", mayor es mejor
+	ScoreLoad       = "load"        // "100" kg, mayor es mejor
+)
+
+// Benchmark - WOD con nombre (Girls, Heroes, Open) al que se vinculan las rutinas que lo programan,
+// para que el historial del miembro no se reparta entre distintas rutinas
+type Benchmark struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	ScoreType   string    `json:"score_type"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content"`
+	TimeCap     int       `json:"time_cap,omitempty"` // minutos
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type BenchmarkRequest struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	ScoreType   string `json:"score_type"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content"`
+	TimeCap     int    `json:"time_cap,omitempty"`
+	Active      *bool  `json:"active,omitempty"`
+}
+
+// BenchmarkResult - Resultado del miembro en alguna de las rutinas vinculadas al benchmark
+type BenchmarkResult struct {
+	ResultID     int64      `json:"result_id"`
+	UserID       int64      `json:"user_id"`
+	RoutineID    int64      `json:"routine_id"`
+	Score        string     `json:"score"`
+	Rx           bool       `json:"rx"`
+	Notes        string     `json:"notes,omitempty"`
+	ScheduleDate *time.Time `json:"schedule_date,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	IsPR         bool       `json:"is_pr"` // Mejoró su mejor marca previa (Rx y scaled por separado)
+}
+
+// BenchmarkHistory - Progresión del miembro en un benchmark y su posición en el box
+type BenchmarkHistory struct {
+	Benchmark    *Benchmark         `json:"benchmark"`
+	Results      []*BenchmarkResult `json:"results"` // Del más antiguo al más reciente
+	BestRx       *BenchmarkResult   `json:"best_rx,omitempty"`
+	BestScaled   *BenchmarkResult   `json:"best_scaled,omitempty"`
+	PercentileRx *float64           `json:"percentile_rx,omitempty"` // % de miembros del box con mejor Rx igual o peor
+	RxAthletes   int                `json:"rx_athletes"`
+}
+
+// BenchmarkPR - Mejor marca del miembro en un benchmark
+type BenchmarkPR struct {
+	BenchmarkID   int64     `json:"benchmark_id"`
+	BenchmarkName string    `json:"benchmark_name"`
+	Category      string    `json:"category"`
+	Score         string    `json:"score"`
+	Rx            bool      `json:"rx"`
+	Attempts      int       `json:"attempts"`
+	AchievedAt    time.Time `json:"achieved_at"`
+}
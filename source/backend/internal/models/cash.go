@@ -0,0 +1,135 @@
+package models
+
+import "time"
+
+// Estados de una sesión de caja
+const (
+	CashSessionOpen   = "open"
+	CashSessionClosed = "closed" // Inmutable: ya no admite movimientos ni cambios
+)
+
+// Tipos de movimiento manual de caja
+const (
+	CashMovementIn  = "in"  // Ingreso (ej. reposición de sencillo)
+	CashMovementOut = "out" // Egreso / caja chica (ej. compra de insumos)
+)
+
+// CashSessionMethods son los medios de pago que se cuadran al cierre
+var CashSessionMethods = []string{PaymentMethodEfectivo, PaymentMethodDebito, PaymentMethodTransferencia}
+
+// PaymentMethodAliases - Nombres que usan pagos y ventas POS para cada medio de pago (el vacío es efectivo).
+// Lo comparten NormalizePaymentMethod y la agrupación SQL del arqueo.
+var PaymentMethodAliases = []struct {
+	Method  string
+	Aliases []string
+}{
+	{PaymentMethodEfectivo, []string{"", "cash", PaymentMethodEfectivo}},
+	{PaymentMethodDebito, []string{"debit", "card", "tarjeta", PaymentMethodDebito}},
+	{PaymentMethodTransferencia, []string{"transfer", PaymentMethodTransferencia}},
+}
+
+// NormalizePaymentMethod unifica los nombres usados por pagos y ventas POS (ej. "cash" = "efectivo")
+func NormalizePaymentMethod(method string) string {
+	for _, m := range PaymentMethodAliases {
+		for _, alias := range m.Aliases {
+			if method == alias {
+				return m.Method
+			}
+		}
+	}
+	return method
+}
+
+type CashSession struct {
+	ID           int64              `json:"id"`
+	Status       string             `json:"status"`
+	OpeningFloat int64              `json:"opening_float"` // Sencillo inicial en el cajón
+	OpenedBy     int64              `json:"opened_by"`
+	OpenedByName string             `json:"opened_by_name,omitempty"`
+	OpenedAt     time.Time          `json:"opened_at"`
+	ClosedBy     *int64             `json:"closed_by,omitempty"`
+	ClosedByName string             `json:"closed_by_name,omitempty"`
+	ClosedAt     *time.Time         `json:"closed_at,omitempty"`
+	Notes        string             `json:"notes,omitempty"`
+	Totals       []CashSessionTotal `json:"totals,omitempty"`
+	Movements    []CashMovement     `json:"movements,omitempty"`
+}
+
+// CashSessionTotal - Cuadratura por medio de pago. Difference = Counted - Expected (negativo = falta dinero).
+type CashSessionTotal struct {
+	Method     string `json:"method"`
+	Payments   int64  `json:"payments"`
+	Sales      int64  `json:"sales"`
+	Expected   int64  `json:"expected"`
+	Counted    int64  `json:"counted"`
+	Difference int64  `json:"difference"`
+}
+
+type CashMovement struct {
+	ID            int64     `json:"id"`
+	SessionID     int64     `json:"session_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	Reason        string    `json:"reason"`
+	CreatedBy     int64     `json:"created_by"`
+	CreatedByName string    `json:"created_by_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CashMethodSums son los montos registrados en la sesión por medio de pago
+type CashMethodSums struct {
+	Payments map[string]int64
+	Sales    map[string]int64
+	In       int64
+	Out      int64
+}
+
+// BuildCashTotals calcula lo esperado por medio de pago; el efectivo incluye sencillo inicial y movimientos manuales.
+func BuildCashTotals(openingFloat int64, sums CashMethodSums, counted map[string]int64) []CashSessionTotal {
+	methods := append([]string{}, CashSessionMethods...)
+	seen := map[string]bool{}
+	for _, m := range methods {
+		seen[m] = true
+	}
+	// Medios no estándar (si existieran) también se reportan
+	for _, src := range []map[string]int64{sums.Payments, sums.Sales} {
+		for m := range src {
+			if !seen[m] {
+				seen[m] = true
+				methods = append(methods, m)
+			}
+		}
+	}
+
+	totals := make([]CashSessionTotal, 0, len(methods))
+	for _, m := range methods {
+		t := CashSessionTotal{Method: m, Payments: sums.Payments[m], Sales: sums.Sales[m]}
+		t.Expected = t.Payments + t.Sales
+		if m == PaymentMethodEfectivo {
+			t.Expected += openingFloat + sums.In - sums.Out
+		}
+		if counted != nil {
+			t.Counted = counted[m]
+			t.Difference = t.Counted - t.Expected
+		}
+		totals = append(totals, t)
+	}
+	return totals
+}
+
+type OpenCashSessionRequest struct {
+	OpeningFloat int64  `json:"opening_float"`
+	Notes        string `json:"notes"`
+}
+
+type CreateCashMovementRequest struct {
+	Type   string `json:"type"` // in, out
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// CloseCashSessionRequest - Montos contados por medio de pago (efectivo, debito, transferencia)
+type CloseCashSessionRequest struct {
+	Counted map[string]int64 `json:"counted"`
+	Notes   string           `json:"notes"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePaymentMethod(t *testing.T) {
+	cases := map[string]string{
+		"":              PaymentMethodEfectivo,
+		"cash":          PaymentMethodEfectivo,
+		"efectivo":      PaymentMethodEfectivo,
+		"debit":         PaymentMethodDebito,
+		"card":          PaymentMethodDebito,
+		"tarjeta":       PaymentMethodDebito,
+		"debito":        PaymentMethodDebito,
+		"transfer":      PaymentMethodTransferencia,
+		"transferencia": PaymentMethodTransferencia,
+		"account":       "account",
+		"webpay":        "webpay",
+	}
+	for in, want := range cases {
+		if got := NormalizePaymentMethod(in); got != want {
+			t.Errorf("NormalizePaymentMethod(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildCashTotals(t *testing.T) {
+	sums := CashMethodSums{
+		Payments: map[string]int64{PaymentMethodEfectivo: 30000, PaymentMethodDebito: 45000, PaymentMethodTransferencia: 25000},
+		Sales:    map[string]int64{PaymentMethodEfectivo: 4500, PaymentMethodDebito: 2000},
+		In:       5000,
+		Out:      3000,
+	}
+
+	cases := []struct {
+		name    string
+		counted map[string]int64
+		want    []CashSessionTotal
+	}{
+		{"live totals without count", nil, []CashSessionTotal{
+			{Method: PaymentMethodEfectivo, Payments: 30000, Sales: 4500, Expected: 46500},
+			{Method: PaymentMethodDebito, Payments: 45000, Sales: 2000, Expected: 47000},
+			{Method: PaymentMethodTransferencia, Payments: 25000, Expected: 25000},
+		}},
+		{"exact count", map[string]int64{PaymentMethodEfectivo: 46500, PaymentMethodDebito: 47000, PaymentMethodTransferencia: 25000}, []CashSessionTotal{
+			{Method: PaymentMethodEfectivo, Payments: 30000, Sales: 4500, Expected: 46500, Counted: 46500},
+			{Method: PaymentMethodDebito, Payments: 45000, Sales: 2000, Expected: 47000, Counted: 47000},
+			{Method: PaymentMethodTransferencia, Payments: 25000, Expected: 25000, Counted: 25000},
+		}},
+		{"cash short, debit over, transfer not counted", map[string]int64{PaymentMethodEfectivo: 45000, PaymentMethodDebito: 48000}, []CashSessionTotal{
+			{Method: PaymentMethodEfectivo, Payments: 30000, Sales: 4500, Expected: 46500, Counted: 45000, Difference: -1500},
+			{Method: PaymentMethodDebito, Payments: 45000, Sales: 2000, Expected: 47000, Counted: 48000, Difference: 1000},
+			{Method: PaymentMethodTransferencia, Payments: 25000, Expected: 25000, Difference: -25000},
+		}},
+	}
+	for _, c := range cases {
+		// El sencillo inicial (10000) y los movimientos manuales solo afectan al efectivo
+		if got := BuildCashTotals(10000, sums, c.counted); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBuildCashTotals_ReportsNonStandardMethods(t *testing.T) {
+	sums := CashMethodSums{Payments: map[string]int64{"webpay": 12000}}
+	totals := BuildCashTotals(0, sums, map[string]int64{PaymentMethodEfectivo: 0})
+
+	if len(totals) != len(CashSessionMethods)+1 {
+		t.Fatalf("expected standard methods plus webpay, got %+v", totals)
+	}
+	last := totals[len(totals)-1]
+	if last.Method != "webpay" || last.Expected != 12000 || last.Difference != -12000 {
+		t.Fatalf("unexpected webpay total: %+v", last)
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// SettingCustomRoutinePrice - Precio por defecto (CLP) de cada rutina personalizada facturable
+const SettingCustomRoutinePrice = "custom_routine_price"
+
+// Estados de una programación personalizada entregada
+const (
+	CustomProgramIncluded = "included" // No facturable (incluida en el plan del miembro)
+	CustomProgramPending  = "pending"  // Facturable, se cargará en el cierre del mes
+	CustomProgramBilled   = "billed"   // Cargada a la cuenta del miembro
+	CustomProgramVoid     = "void"     // Anulada antes de facturarse
+)
+
+// CustomProgram - Rutina personalizada entregada a un miembro y su cobro
+type CustomProgram struct {
+	ID             int64      `json:"id"`
+	RoutineID      int64      `json:"routine_id"`
+	RoutineName    string     `json:"routine_name"`
+	UserID         int64      `json:"user_id"`
+	UserName       string     `json:"user_name,omitempty"`
+	CoachID        *int64     `json:"coach_id,omitempty"`      // Usuario que la programó
+	InstructorID   *int64     `json:"instructor_id,omitempty"` // Coach asignado a la rutina; se lleva los ingresos
+	CoachName      string     `json:"coach_name,omitempty"`
+	Amount         int64      `json:"amount"`
+	Status         string     `json:"status"`
+	Period         string     `json:"period,omitempty"` // Mes facturado (YYYY-MM)
+	LedgerEntryID  *int64     `json:"ledger_entry_id,omitempty"`
+	DeliveredAt    time.Time  `json:"delivered_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// MyCustomProgram - Programa entregado tal como lo ve el miembro
+type MyCustomProgram struct {
+	CustomProgram
+	Content         string `json:"content"`
+	ContentScaled   string `json:"content_scaled,omitempty"`
+	ContentBeginner string `json:"content_beginner,omitempty"`
+}
+
+type CustomProgrammingSettings struct {
+	Price int64 `json:"price"`
+}
+
+// BillingRun - Resultado del cierre mensual de programación personalizada
+type BillingRun struct {
+	Period   string         `json:"period"`
+	Members  int            `json:"members"`
+	Programs int            `json:"programs"`
+	Total    int64          `json:"total"`
+	Entries  []*LedgerEntry `json:"entries"`
+}
+
+// CoachProgrammingRevenue - Ingresos por programación personalizada de un coach en el período
+type CoachProgrammingRevenue struct {
+	InstructorID *int64 `json:"instructor_id,omitempty"`
+	CoachID      *int64 `json:"coach_id,omitempty"` // Solo si la rutina no tiene coach asignado
+	Coach        string `json:"coach"`
+	Programs     int    `json:"programs"`
+	Members      int    `json:"members"`
+	Billed       int64  `json:"billed"`
+	Pending      int64  `json:"pending"`
+	Voided       int    `json:"voided"`
+}
+
+type BillCustomProgramsRequest struct {
+	Month string `json:"month"` // YYYY-MM; vacío = mes anterior
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Impersonation - Sesión en que un admin actúa como un miembro para dar soporte. El token lleva el claim "act"
+// con el admin; por defecto solo permite lecturas.
+type Impersonation struct {
+	ID        int64      `json:"id"`
+	AdminID   int64      `json:"admin_id"`
+	AdminName string     `json:"admin_name,omitempty"`
+	UserID    int64      `json:"user_id"`
+	UserName  string     `json:"user_name,omitempty"`
+	Reason    string     `json:"reason"`
+	Write     bool       `json:"write"` // Permite acciones de escritura (reservar, cancelar, ...)
+	IP        string     `json:"ip,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+type StartImpersonationRequest struct {
+	UserID  int64  `json:"user_id"`
+	Reason  string `json:"reason"`
+	Write   bool   `json:"write,omitempty"`
+	Minutes int    `json:"minutes,omitempty"` // Duración; por defecto y como máximo IMPERSONATION_TTL
+}
+
+// ImpersonationResponse - Access token que actúa como el miembro (sin refresh token: no se puede extender)
+type ImpersonationResponse struct {
+	AccessToken   string         `json:"access_token"`
+	Impersonation *Impersonation `json:"impersonation"`
+	User          *User          `json:"user"`
+}
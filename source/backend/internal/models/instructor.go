@@ -12,6 +12,7 @@ type Instructor struct {
 	Specialty string    `json:"specialty,omitempty"` // CrossFit, Halterofilia, etc.
 	Bio       string    `json:"bio,omitempty"`
 	Active    bool      `json:"active"`
+	UserID    *int64    `json:"user_id,omitempty"` // Cuenta con la que el coach entra al portal
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -24,6 +25,7 @@ type CreateInstructorRequest struct {
 	Phone     string `json:"phone,omitempty"`
 	Specialty string `json:"specialty,omitempty"`
 	Bio       string `json:"bio,omitempty"`
+	UserID    *int64 `json:"user_id,omitempty"`
 }
 
 type UpdateInstructorRequest struct {
@@ -33,4 +35,20 @@ type UpdateInstructorRequest struct {
 	Specialty string `json:"specialty,omitempty"`
 	Bio       string `json:"bio,omitempty"`
 	Active    *bool  `json:"active,omitempty"`
+	UserID    *int64 `json:"user_id,omitempty"` // 0 desvincula la cuenta
+}
+
+// Portal del coach
+
+type CoachSchedule struct {
+	ScheduleWithDetails
+	CheckedIn   int    `json:"checked_in"`
+	RoutineID   *int64 `json:"routine_id,omitempty"`
+	RoutineName string `json:"routine_name,omitempty"`
+}
+
+// CoachAttendee - Reserva de la clase con las notas y lesiones vigentes del miembro
+type CoachAttendee struct {
+	BookingWithUser
+	Notes []*MemberNote `json:"notes"`
 }
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Tipos de movimiento en la cuenta del miembro.
+// Monto positivo = saldo a favor del miembro; negativo = deuda (cuenta por cobrar).
+const (
+	LedgerPayment         = "payment"            // Abono del miembro para saldar su cuenta
+	LedgerRefund          = "refund"             // Devolución como saldo a favor
+	LedgerStoreCredit     = "store_credit"       // Crédito otorgado por el box
+	LedgerGiftCardLoad    = "gift_card_load"     // Carga de gift card canjeada
+	LedgerPOSCharge       = "pos_charge"         // Compra en POS cargada a la cuenta o pagada con saldo
+	LedgerInvitationGrant = "invitation_grant"   // Clases de invitación otorgadas (sin monto)
+	LedgerAdjustment      = "adjustment"         // Ajuste manual (+/-)
+	LedgerCustomProgram   = "custom_programming" // Cierre mensual de rutinas personalizadas facturables
+)
+
+// Tipos que un admin puede registrar manualmente
+var ManualLedgerTypes = map[string]bool{
+	LedgerRefund: true, LedgerStoreCredit: true, LedgerAdjustment: true,
+}
+
+// Medios de pago POS que no entran al cajón: se registran en la cuenta del miembro
+const (
+	SalePaymentAccount     = "account"      // Fiado: queda como deuda en la cuenta
+	SalePaymentStoreCredit = "store_credit" // Se descuenta del saldo a favor
+)
+
+type LedgerEntry struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"user_id"`
+	Type          string    `json:"type"`
+	Amount        int64     `json:"amount"`
+	Classes       int       `json:"classes,omitempty"` // Solo invitation_grant
+	Description   string    `json:"description"`
+	PaymentMethod string    `json:"payment_method,omitempty"` // Solo payment (efectivo, debito, transferencia)
+	SaleID        *int64    `json:"sale_id,omitempty"`
+	GiftCardID    *int64    `json:"gift_card_id,omitempty"`
+	CreatedBy     *int64    `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// MemberStatement - Estado de cuenta del miembro
+type MemberStatement struct {
+	UserID  int64          `json:"user_id"`
+	Balance int64          `json:"balance"`
+	Entries []*LedgerEntry `json:"entries"`
+}
+
+// MemberBalance - Miembro con saldo (para listar cuentas por cobrar)
+type MemberBalance struct {
+	UserID    int64  `json:"user_id"`
+	UserName  string `json:"user_name"`
+	UserEmail string `json:"user_email"`
+	Balance   int64  `json:"balance"`
+}
+
+type GiftCard struct {
+	ID         int64      `json:"id"`
+	Code       string     `json:"code"`
+	Amount     int64      `json:"amount"`
+	SaleID     *int64     `json:"sale_id,omitempty"`
+	RedeemedBy *int64     `json:"redeemed_by,omitempty"`
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateLedgerEntryRequest struct {
+	Type        string `json:"type"` // refund, store_credit, adjustment
+	Amount      int64  `json:"amount"`
+	Description string `json:"description"`
+}
+
+// SettleAccountRequest - Si amount es 0 se salda la deuda completa
+type SettleAccountRequest struct {
+	Amount        int64  `json:"amount"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+// RedeemGiftCardRequest - UserID solo lo usa un admin para canjear a nombre de un miembro
+type RedeemGiftCardRequest struct {
+	Code   string `json:"code"`
+	UserID *int64 `json:"user_id,omitempty"`
+}
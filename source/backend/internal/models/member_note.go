@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	MemberNoteGeneral = "note"
+	MemberNoteInjury  = "injury"
+)
+
+// MemberNote - Nota del staff o lesión informada; visible para los coaches que tienen al miembro en clase
+type MemberNote struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	AuthorID   *int64     `json:"author_id,omitempty"`
+	AuthorName string     `json:"author_name,omitempty"`
+	Kind       string     `json:"kind"`
+	Body       string     `json:"body"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateMemberNoteRequest struct {
+	Kind string `json:"kind"`
+	Body string `json:"body"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -11,6 +12,8 @@ const (
 	PaymentCompleted PaymentStatus = "completed"
 	PaymentFailed    PaymentStatus = "failed"
 	PaymentRefunded  PaymentStatus = "refunded"
+	PaymentRejected  PaymentStatus = "rejected" // Transferencia rechazada por admin
+	PaymentExpired   PaymentStatus = "expired"  // Transferencia pendiente sin revisar a tiempo
 )
 
 const (
@@ -24,17 +27,25 @@ var ValidPaymentMethods = map[string]bool{
 }
 
 type Payment struct {
-	ID            int64         `json:"id"`
-	UserID        int64         `json:"user_id"`
-	PlanID        int64         `json:"plan_id"`
-	Amount        int64         `json:"amount"`
-	Currency      string        `json:"currency"`
-	Status        PaymentStatus `json:"status"`
-	PaymentMethod string        `json:"payment_method,omitempty"`
-	ExternalID    string        `json:"external_id,omitempty"`
-	ProofImageURL string        `json:"proof_image_url,omitempty"` // Para transferencia: URL de comprobante
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
+	ID             int64         `json:"id"`
+	UserID         int64         `json:"user_id"`
+	PlanID         int64         `json:"plan_id"`
+	Amount         int64         `json:"amount"`
+	Currency       string        `json:"currency"`
+	Status         PaymentStatus `json:"status"`
+	PaymentMethod  string        `json:"payment_method,omitempty"`
+	ExternalID     string        `json:"external_id,omitempty"`
+	ProofImageURL  string        `json:"proof_image_url,omitempty"` // Para transferencia: URL de comprobante
+	DiscountCodeID *int64        `json:"discount_code_id,omitempty"`
+	// Revisión de transferencias enviadas por el miembro
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ReviewedBy      *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`        // Solo pagos pendientes
+	ReferenceCode   string     `json:"reference_code,omitempty"`    // Código que el miembro pone en el comentario de la transferencia
+	TaxDocumentType int        `json:"tax_document_type,omitempty"` // DTE solicitado (39 boleta, 33 factura); 0 = por defecto
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type Subscription struct {
@@ -58,6 +69,28 @@ type CreatePaymentRequest struct {
 	PaymentMethod string `json:"payment_method"`  // efectivo, debito, transferencia
 	ProofImageURL string `json:"proof_image_url"` // Opcional; obligatorio para transferencia
 	DiscountCode  string `json:"discount_code,omitempty"`
+	// Documento tributario: tipo (39, 41, 33) y datos del receptor para factura
+	DocumentType int          `json:"document_type,omitempty"`
+	Receptor     *TaxReceptor `json:"receptor,omitempty"`
+}
+
+// PaymentReference genera el código de referencia de un pago (ej. BM-123) usado para conciliar transferencias
+func PaymentReference(paymentID int64) string {
+	return fmt.Sprintf("BM-%d", paymentID)
+}
+
+// SubmitTransferRequest - Miembro informa una transferencia con comprobante (queda pendiente de aprobación)
+type SubmitTransferRequest struct {
+	PlanID        int64  `json:"plan_id"`
+	ProofImageURL string `json:"proof_image_url"` // URL obtenida desde /api/v1/upload
+	DiscountCode  string `json:"discount_code,omitempty"`
+	// Documento tributario solicitado; se emite al aprobar la transferencia
+	DocumentType int          `json:"document_type,omitempty"`
+	Receptor     *TaxReceptor `json:"receptor,omitempty"`
+}
+
+type RejectPaymentRequest struct {
+	Reason string `json:"reason"`
 }
 
 type PaymentWithDetails struct {
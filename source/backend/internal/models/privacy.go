@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Estados de una solicitud de eliminación de cuenta
+const (
+	ErasurePending   = "pending"   // Esperando revisión del staff
+	ErasureApproved  = "approved"  // Aprobada; se anonimiza al cumplirse el periodo de gracia
+	ErasureRejected  = "rejected"  // Rechazada por el staff (p. ej. deuda pendiente)
+	ErasureCancelled = "cancelled" // El miembro se arrepintió antes de la anonimización
+	ErasureCompleted = "completed"
+)
+
+// ErasedUserName reemplaza el nombre de las cuentas anonimizadas
+const ErasedUserName = "Cuenta eliminada"
+
+// ErasureRequest - Solicitud de eliminación de cuenta. La cuenta no se borra: se anonimizan los datos
+// personales y se conservan pagos, ventas, cuenta corriente y documentos tributarios.
+type ErasureRequest struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	UserName     string     `json:"user_name,omitempty"`
+	UserEmail    string     `json:"user_email,omitempty"`
+	Status       string     `json:"status"`
+	Reason       string     `json:"reason,omitempty"`
+	RequestedBy  *int64     `json:"requested_by,omitempty"` // Staff que la registró; nil si la pidió el miembro
+	ReviewedBy   *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNotes  string     `json:"review_notes,omitempty"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"` // Fin del periodo de gracia
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ErasureRequestInput struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type ReviewErasureRequest struct {
+	Notes string `json:"notes,omitempty"`
+}
@@ -21,18 +21,21 @@ type SaleItem struct {
 	ProductName string `json:"product_name"`
 	Quantity    int    `json:"quantity"`
 	UnitPrice   int64  `json:"unit_price"`
+	GiftCard    bool   `json:"gift_card,omitempty"` // Emite una gift card por unidad con valor UnitPrice
 }
 
 type Sale struct {
-	ID            int64      `json:"id"`
-	UserID        *int64     `json:"user_id"`
-	Total         int64      `json:"total"`
-	PaymentMethod string     `json:"payment_method"`
-	Notes         string     `json:"notes"`
-	CreatedBy     int64      `json:"created_by"`
-	CreatedAt     time.Time  `json:"created_at"`
-	Items         []SaleItem `json:"items"`
-	UserName      string     `json:"user_name"`
+	ID            int64        `json:"id"`
+	UserID        *int64       `json:"user_id"`
+	Total         int64        `json:"total"`
+	PaymentMethod string       `json:"payment_method"`
+	Notes         string       `json:"notes"`
+	CreatedBy     int64        `json:"created_by"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Items         []SaleItem   `json:"items"`
+	UserName      string       `json:"user_name"`
+	GiftCards     []GiftCard   `json:"gift_cards,omitempty"`
+	TaxDocument   *TaxDocument `json:"tax_document,omitempty"`
 }
 
 type CreateSaleRequest struct {
@@ -40,4 +43,7 @@ type CreateSaleRequest struct {
 	PaymentMethod string     `json:"payment_method"`
 	Notes         string     `json:"notes"`
 	Items         []SaleItem `json:"items"`
+	// Documento tributario (39, 41, 33) y receptor para factura
+	DocumentType int          `json:"document_type,omitempty"`
+	Receptor     *TaxReceptor `json:"receptor,omitempty"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// ProgrammingTrack - Línea de programación (p. ej. "CrossFit", "Barbell club") con un calendario de rutinas por día.
+// Los horarios de las clases o disciplinas vinculadas reciben solos la rutina de su fecha.
+type ProgrammingTrack struct {
+	ID                int64          `json:"id"`
+	Name              string         `json:"name"`
+	Description       string         `json:"description,omitempty"`
+	PublishDaysBefore int            `json:"publish_days_before"` // El WOD se publica N días antes de la clase...
+	PublishTime       string         `json:"publish_time"`        // ...a esta hora (HH:MM)
+	Active            bool           `json:"active"`
+	CreatedBy         *int64         `json:"created_by,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Targets           []*TrackTarget `json:"targets"`
+}
+
+// TrackTarget - Clase o disciplina que sigue el track; la clase tiene prioridad sobre su disciplina
+type TrackTarget struct {
+	ClassID      *int64 `json:"class_id,omitempty"`
+	DisciplineID *int64 `json:"discipline_id,omitempty"`
+	Name         string `json:"name"`
+}
+
+// TrackDay - Rutina programada en el track para una fecha
+type TrackDay struct {
+	ID          int64      `json:"id"`
+	TrackID     int64      `json:"track_id"`
+	Date        time.Time  `json:"date"`
+	RoutineID   int64      `json:"routine_id"`
+	RoutineName string     `json:"routine_name"`
+	Notes       string     `json:"notes,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"` // Excepción a la hora de publicación del track
+	ReleasesAt  time.Time  `json:"releases_at"`          // Momento en que los miembros ven la rutina
+}
+
+// Requests
+
+type TrackRequest struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	PublishDaysBefore *int   `json:"publish_days_before,omitempty"`
+	PublishTime       string `json:"publish_time,omitempty"`
+	Active            *bool  `json:"active,omitempty"`
+}
+
+type TrackDayRequest struct {
+	RoutineID int64      `json:"routine_id"`
+	Notes     string     `json:"notes,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// TrackTargetsRequest reemplaza las clases y disciplinas vinculadas al track
+type TrackTargetsRequest struct {
+	ClassIDs      []int64 `json:"class_ids"`
+	DisciplineIDs []int64 `json:"discipline_ids"`
+}
+
+// CopyWeekRequest copia los 7 días que empiezan en From a la semana que empieza en To
+type CopyWeekRequest struct {
+	From      string `json:"from"` // YYYY-MM-DD
+	To        string `json:"to"`   // YYYY-MM-DD
+	Overwrite bool   `json:"overwrite"`
+}
+
+// ShiftDaysRequest mueve los días entre From y To (vacío = hasta el final del calendario) Days días
+type ShiftDaysRequest struct {
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+	Days int    `json:"days"`
+}
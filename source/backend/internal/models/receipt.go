@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PaymentReceipt - Datos del comprobante de un pago (para reembolsos de empresa)
+type PaymentReceipt struct {
+	PaymentWithDetails
+	UserRUT        string     `json:"user_rut,omitempty"`
+	PlanPrice      int64      `json:"plan_price"`
+	DiscountCode   string     `json:"discount_code,omitempty"`
+	DiscountAmount int64      `json:"discount_amount"`
+	StartDate      *time.Time `json:"start_date,omitempty"` // Periodo de la suscripción activada por el pago
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	TaxDocType     int        `json:"tax_doc_type,omitempty"` // Boleta/factura asociada, si se emitió
+	TaxFolio       int64      `json:"tax_folio,omitempty"`
+}
+
+// YearlyStatement - Certificado anual de pagos de un miembro
+type YearlyStatement struct {
+	UserID    int64             `json:"user_id"`
+	UserName  string            `json:"user_name"`
+	UserEmail string            `json:"user_email"`
+	UserRUT   string            `json:"user_rut,omitempty"`
+	Year      int               `json:"year"`
+	Payments  []*PaymentReceipt `json:"payments"`
+	Total     int64             `json:"total"` // Solo pagos completados
+}
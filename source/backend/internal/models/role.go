@@ -0,0 +1,178 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Roles del staff. RoleUser es el miembro y RoleAdmin se mantiene como alias con acceso total
+const (
+	RoleOwner     Role = "owner"
+	RoleManager   Role = "manager"
+	RoleFrontDesk Role = "front_desk"
+	RoleCoach     Role = "coach"
+	RoleMember         = RoleUser
+)
+
+// Permisos con nombre "recurso.acción"
+const (
+	PermPlansManage       = "plans.manage"
+	PermPaymentsView      = "payments.view"
+	PermPaymentsCreate    = "payments.create"
+	PermPaymentsApprove   = "payments.approve" // Aprobar/rechazar transferencias, reembolsos
+	PermBankReconcile     = "bank.reconcile"
+	PermReceiptsSend      = "receipts.send"
+	PermInstructorsManage = "instructors.manage"
+	PermClassesManage     = "classes.manage" // Disciplinas, clases y horarios
+	PermBookingsView      = "bookings.view"
+	PermBookingsCheckin   = "bookings.checkin"
+	PermRoutinesManage    = "routines.manage"
+	PermResultsView       = "results.view"
+	PermResultsManage     = "results.manage" // Registrar resultados a nombre de otros
+	PermUsersView         = "users.view"
+	PermUsersManage       = "users.manage"
+	PermUsersDelete       = "users.delete"
+	PermUsersImpersonate  = "users.impersonate" // Entrar como un miembro para dar soporte
+	PermSessionsManage    = "sessions.manage"
+	PermRolesManage       = "roles.manage" // Crear roles y asignarlos a usuarios
+	PermDiscountsManage   = "discounts.manage"
+	PermChallengesManage  = "challenges.manage"
+	PermStatsView         = "stats.view"
+	PermReportsExport     = "reports.export"
+	PermLeadsManage       = "leads.manage"
+	PermOnrampManage      = "onramp.manage"
+	PermMovementsManage   = "movements.manage"
+	PermBenchmarksManage  = "benchmarks.manage" // Catálogo de benchmarks (Girls, Heroes, Open)
+	PermEventsManage      = "events.manage"
+	PermTagsManage        = "tags.manage"
+	PermProductsManage    = "products.manage"
+	PermSalesView         = "sales.view"
+	PermSalesCreate       = "sales.create"
+	PermCashView          = "cash.view"
+	PermCashManage        = "cash.manage"
+	PermLedgerView        = "ledger.view"
+	PermLedgerManage      = "ledger.manage" // Cargos, abonos, saldo a favor y gift cards
+	PermTaxView           = "tax.view"
+	PermTaxManage         = "tax.manage"
+	PermAuditView         = "audit.view"
+	PermSecurityManage    = "security.manage" // Políticas de seguridad (2FA obligatorio)
+	PermWaiversView       = "waivers.view"
+	PermWaiversManage     = "waivers.manage" // Publicar y retirar documentos de exención
+)
+
+// AllPermissions - Catálogo completo con su descripción
+var AllPermissions = map[string]string{
+	PermPlansManage:       "Crear y editar planes",
+	PermPaymentsView:      "Ver pagos",
+	PermPaymentsCreate:    "Registrar pagos",
+	PermPaymentsApprove:   "Aprobar transferencias y reembolsar pagos",
+	PermBankReconcile:     "Conciliación bancaria",
+	PermReceiptsSend:      "Ver y enviar comprobantes y certificados",
+	PermInstructorsManage: "Administrar instructores",
+	PermClassesManage:     "Administrar disciplinas, clases y horarios",
+	PermBookingsView:      "Ver reservas, asistencia y lista de espera",
+	PermBookingsCheckin:   "Hacer check-in de reservas",
+	PermRoutinesManage:    "Crear y asignar rutinas",
+	PermResultsView:       "Ver resultados de miembros",
+	PermResultsManage:     "Registrar resultados de otros miembros",
+	PermUsersView:         "Ver miembros",
+	PermUsersManage:       "Editar miembros",
+	PermUsersDelete:       "Eliminar miembros",
+	PermUsersImpersonate:  "Entrar como un miembro (soporte)",
+	PermSessionsManage:    "Ver y revocar sesiones de usuarios",
+	PermRolesManage:       "Administrar roles y asignarlos",
+	PermDiscountsManage:   "Administrar códigos de descuento",
+	PermChallengesManage:  "Administrar desafíos",
+	PermStatsView:         "Ver estadísticas",
+	PermReportsExport:     "Exportar datos",
+	PermLeadsManage:       "Administrar leads",
+	PermOnrampManage:      "Administrar programas onramp",
+	PermMovementsManage:   "Administrar movimientos",
+	PermBenchmarksManage:  "Administrar el catálogo de benchmarks",
+	PermEventsManage:      "Administrar eventos",
+	PermTagsManage:        "Administrar etiquetas",
+	PermProductsManage:    "Administrar productos e inventario",
+	PermSalesView:         "Ver ventas",
+	PermSalesCreate:       "Registrar ventas",
+	PermCashView:          "Ver caja",
+	PermCashManage:        "Abrir, mover y cerrar caja",
+	PermLedgerView:        "Ver cuentas de miembros y gift cards",
+	PermLedgerManage:      "Cargos, abonos y gift cards",
+	PermTaxView:           "Ver boletas y facturas",
+	PermTaxManage:         "Cargar CAF, emitir y reenviar documentos",
+	PermAuditView:         "Ver y exportar el log de auditoría",
+	PermSecurityManage:    "Configurar las políticas de seguridad",
+	PermWaiversView:       "Ver firmas de documentos y exportar copias",
+	PermWaiversManage:     "Publicar y retirar documentos de exención",
+}
+
+// PermissionNames devuelve todos los permisos ordenados
+func PermissionNames() []string {
+	names := make([]string, 0, len(AllPermissions))
+	for p := range AllPermissions {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func allExcept(excluded ...string) []string {
+	skip := map[string]bool{}
+	for _, p := range excluded {
+		skip[p] = true
+	}
+	var perms []string
+	for _, p := range PermissionNames() {
+		if !skip[p] {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// RoleDef - Rol con sus permisos (integrado o personalizado)
+type RoleDef struct {
+	Name        Role      `json:"name"`
+	Label       string    `json:"label"`
+	Description string    `json:"description,omitempty"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	Staff       bool      `json:"staff"` // false solo para el miembro
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// BuiltinRoles - Roles integrados; no se pueden editar ni eliminar
+var BuiltinRoles = map[Role]*RoleDef{
+	RoleOwner: {Name: RoleOwner, Label: "Dueño", Description: "Acceso total", Permissions: PermissionNames(), BuiltIn: true, Staff: true},
+	RoleAdmin: {Name: RoleAdmin, Label: "Administrador", Description: "Acceso total (rol histórico)", Permissions: PermissionNames(), BuiltIn: true, Staff: true},
+	RoleManager: {Name: RoleManager, Label: "Encargado", Description: "Operación completa salvo roles y eliminación de miembros",
+		Permissions: allExcept(PermRolesManage, PermUsersDelete, PermSecurityManage, PermUsersImpersonate), BuiltIn: true, Staff: true},
+	RoleFrontDesk: {Name: RoleFrontDesk, Label: "Recepción", Description: "Pagos, ventas, caja y check-in", Permissions: []string{
+		PermPaymentsView, PermPaymentsCreate, PermPaymentsApprove, PermReceiptsSend,
+		PermBookingsView, PermBookingsCheckin, PermUsersView, PermUsersManage,
+		PermSalesView, PermSalesCreate, PermCashView, PermCashManage,
+		PermLedgerView, PermLedgerManage, PermLeadsManage, PermTagsManage, PermTaxView, PermWaiversView,
+	}, BuiltIn: true, Staff: true},
+	// El coach toma asistencia desde su portal, limitado a las clases que dicta
+	RoleCoach: {Name: RoleCoach, Label: "Coach", Description: "Rutinas y resultados; asistencia desde el portal del coach", Permissions: []string{
+		PermRoutinesManage, PermResultsView, PermResultsManage,
+		PermMovementsManage, PermOnrampManage, PermChallengesManage, PermUsersView,
+	}, BuiltIn: true, Staff: true},
+	RoleMember: {Name: RoleMember, Label: "Miembro", Permissions: []string{}, BuiltIn: true},
+}
+
+var roleNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{1,39}$`)
+
+// ValidRoleName - Nombres de rol personalizados: minúsculas, dígitos y "_"
+func ValidRoleName(name string) bool {
+	return roleNameRe.MatchString(name)
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Label       string   `json:"label"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
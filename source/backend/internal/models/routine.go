@@ -20,28 +20,42 @@ type Routine struct {
 	Billable        bool      `json:"billable"`
 	TargetUserID    *int64    `json:"target_user_id,omitempty"`
 	IsCustom        bool      `json:"is_custom"`
+	BenchmarkID     *int64    `json:"benchmark_id,omitempty"` // Benchmark que programa esta rutina
+	Version         int       `json:"version"`                // Versión vigente; cada edición crea una nueva
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Rutina estructurada: Content, ContentScaled y ContentBeginner se generan a partir de los bloques
+	Blocks []*WorkoutBlock `json:"blocks,omitempty"`
 }
 
 type ScheduleRoutine struct {
-	ID              int64     `json:"id"`
-	ClassScheduleID int64     `json:"class_schedule_id"`
-	RoutineID       int64     `json:"routine_id"`
-	Notes           string    `json:"notes,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID               int64      `json:"id"`
+	ClassScheduleID  int64      `json:"class_schedule_id"`
+	RoutineID        int64      `json:"routine_id"`
+	Notes            string     `json:"notes,omitempty"`
+	RoutineVersionID *int64     `json:"routine_version_id,omitempty"` // Versión de la rutina que se realiza en la clase
+	TrackDayID       *int64     `json:"track_day_id,omitempty"`       // Asignada por un track de programación
+	PublishAt        *time.Time `json:"publish_at,omitempty"`         // Los miembros no la ven antes de esta hora
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Published indica si los miembros ya pueden ver la rutina asignada
+func (sr *ScheduleRoutine) Published(now time.Time) bool {
+	return sr.PublishAt == nil || !now.Before(*sr.PublishAt)
 }
 
 type UserRoutineResult struct {
-	ID              int64     `json:"id"`
-	UserID          int64     `json:"user_id"`
-	RoutineID       int64     `json:"routine_id"`
-	ClassScheduleID *int64    `json:"class_schedule_id,omitempty"`
-	Score           string    `json:"score,omitempty"`
-	Notes           string    `json:"notes,omitempty"`
-	Rx              bool      `json:"rx"`
-	IsPR            bool      `json:"is_pr"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID               int64     `json:"id"`
+	UserID           int64     `json:"user_id"`
+	RoutineID        int64     `json:"routine_id"`
+	ClassScheduleID  *int64    `json:"class_schedule_id,omitempty"`
+	Score            string    `json:"score,omitempty"`
+	Notes            string    `json:"notes,omitempty"`
+	Rx               bool      `json:"rx"`
+	IsPR             bool      `json:"is_pr"`
+	RoutineVersionID *int64    `json:"routine_version_id,omitempty"` // Versión de la rutina que se realizó
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // Requests
@@ -59,6 +73,10 @@ type CreateRoutineRequest struct {
 	Billable        bool   `json:"billable"`
 	TargetUserID    *int64 `json:"target_user_id,omitempty"`
 	IsCustom        bool   `json:"is_custom"`
+	Price           *int64 `json:"price,omitempty"` // Rutina personalizada facturable; por defecto el precio configurado
+	BenchmarkID     *int64 `json:"benchmark_id,omitempty"`
+
+	Blocks []*WorkoutBlock `json:"blocks,omitempty"` // Si viene, reemplaza al texto libre
 }
 
 type UpdateRoutineRequest struct {
@@ -75,11 +93,16 @@ type UpdateRoutineRequest struct {
 	Billable        *bool   `json:"billable,omitempty"`
 	TargetUserID    *int64  `json:"target_user_id,omitempty"`
 	IsCustom        *bool   `json:"is_custom,omitempty"`
+	Price           *int64  `json:"price,omitempty"`        // Nuevo precio si el cobro aún no se factura
+	BenchmarkID     *int64  `json:"benchmark_id,omitempty"` // 0 desvincula
+
+	Blocks *[]*WorkoutBlock `json:"blocks,omitempty"` // [] vuelve al texto libre
 }
 
 type AssignRoutineRequest struct {
-	RoutineID int64  `json:"routine_id"`
-	Notes     string `json:"notes,omitempty"`
+	RoutineID int64      `json:"routine_id"`
+	Notes     string     `json:"notes,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
 }
 
 type LogResultRequest struct {
@@ -88,6 +111,7 @@ type LogResultRequest struct {
 	Score           string `json:"score"`
 	Notes           string `json:"notes,omitempty"`
 	Rx              bool   `json:"rx"`
+	UserID          *int64 `json:"user_id,omitempty"` // Coach registra el resultado de un miembro (requiere results.manage)
 }
 
 type UpdateResultRequest struct {
@@ -108,6 +132,7 @@ type RoutineWithCreator struct {
 type ScheduleRoutineWithDetails struct {
 	ScheduleRoutine
 	RoutineName            string `json:"routine_name"`
+	RoutineVersion         int    `json:"routine_version"`
 	RoutineType            string `json:"routine_type"`
 	RoutineContent         string `json:"routine_content"`
 	RoutineContentScaled   string `json:"routine_content_scaled,omitempty"`
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// RoutineVersion - Foto inmutable de una rutina. Cada edición crea una nueva versión y los horarios
+// y resultados apuntan a la que realmente se hizo.
+type RoutineVersion struct {
+	ID              int64           `json:"id"`
+	RoutineID       int64           `json:"routine_id"`
+	Version         int             `json:"version"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description,omitempty"`
+	Type            string          `json:"type"`
+	Content         string          `json:"content"`
+	ContentScaled   string          `json:"content_scaled,omitempty"`
+	ContentBeginner string          `json:"content_beginner,omitempty"`
+	Duration        int             `json:"duration,omitempty"`
+	Difficulty      string          `json:"difficulty,omitempty"`
+	BenchmarkID     *int64          `json:"benchmark_id,omitempty"`
+	Blocks          []*WorkoutBlock `json:"blocks,omitempty"`
+	RolledBackFrom  *int            `json:"rolled_back_from,omitempty"` // Versión restaurada con esta
+	CreatedBy       *int64          `json:"created_by,omitempty"`
+	CreatedByName   string          `json:"created_by_name,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Schedules       int             `json:"schedules"` // Clases que la tienen asignada
+	Results         int             `json:"results"`   // Resultados registrados contra esta versión
+}
+
+// FieldChange - Campo que cambió entre dos versiones; los textos incluyen el diff por líneas
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Lines []DiffLine  `json:"lines,omitempty"`
+}
+
+// DiffLine - Línea del diff: Op " " sin cambios, "-" quitada, "+" agregada
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type RoutineDiff struct {
+	RoutineID int64          `json:"routine_id"`
+	From      int            `json:"from"`
+	To        int            `json:"to"`
+	Changes   []*FieldChange `json:"changes"`
+}
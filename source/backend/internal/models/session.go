@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Session - Sesión de login (familia de refresh tokens). Cada refresh rota el token dentro de la misma sesión.
+type Session struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Device        string     `json:"device"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	IP            string     `json:"ip,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	Remember      bool       `json:"remember_device"`
+	Current       bool       `json:"current"`
+}
+
+// Motivos de revocación de sesiones
+const (
+	SessionRevokedLogout      = "logout"
+	SessionRevokedLogoutAll   = "logout_all"
+	SessionRevokedReuse       = "refresh_token_reuse" // Se reutilizó un refresh token ya rotado (posible robo)
+	SessionRevokedPassword    = "password_change"
+	SessionRevokedDeactivated = "user_deactivated"
+	SessionRevokedRoleChange  = "role_change"
+	SessionRevokedAdmin       = "admin"
+)
+
+// RefreshTokenRecord - Refresh token guardado (solo su hash) con el estado de su sesión
+type RefreshTokenRecord struct {
+	ID             int64
+	UserID         int64
+	SessionID      int64
+	ExpiresAt      time.Time
+	UsedAt         *time.Time
+	SessionRevoked bool
+	Remember       bool
+}
+
+// ClientInfo - Datos del cliente que inicia o renueva una sesión
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Remember  bool // "Recordar este dispositivo": la sesión usa un refresh token de larga duración
+}
+
+// DeviceLabel resume el User-Agent en un nombre legible (ej. "Chrome en Android")
+func DeviceLabel(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Desconocido"
+	}
+
+	os := "otro"
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	app := ""
+	switch {
+	case strings.Contains(ua, "expo"), strings.Contains(ua, "okhttp"), strings.Contains(ua, "cfnetwork"):
+		app = "App"
+	case strings.Contains(ua, "edg/"):
+		app = "Edge"
+	case strings.Contains(ua, "chrome"):
+		app = "Chrome"
+	case strings.Contains(ua, "firefox"):
+		app = "Firefox"
+	case strings.Contains(ua, "safari"):
+		app = "Safari"
+	}
+	if app == "" {
+		return os
+	}
+	return app + " en " + os
+}
+
+// LoginLockout - Intentos fallidos de login y bloqueo progresivo de la cuenta
+type LoginLockout struct {
+	UserID      int64
+	Failures    int
+	LockCount   int // Bloqueos previos; cada uno duplica la duración del siguiente
+	LockedUntil *time.Time
+}
@@ -77,4 +77,18 @@ type MonthlyReport struct {
 	TotalAttendance int64              `json:"total_attendance"`
 	TopPlans        []*PlanStats       `json:"top_plans"`
 	TopClasses      []*ClassPopularity `json:"top_classes"`
+
+	// Ventas POS y cierres de caja del mes
+	SalesRevenue       int64             `json:"sales_revenue"`
+	CashSessionsClosed int64             `json:"cash_sessions_closed"`
+	CashDiscrepancy    int64             `json:"cash_discrepancy"` // Suma de diferencias contado - esperado
+	CashByMethod       []CashMethodTotal `json:"cash_by_method"`
+}
+
+// CashMethodTotal - Totales de los cierres de caja del mes por medio de pago
+type CashMethodTotal struct {
+	Method     string `json:"method"`
+	Expected   int64  `json:"expected"`
+	Counted    int64  `json:"counted"`
+	Difference int64  `json:"difference"`
 }
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// RepMaxReps - Repeticiones de la tabla de rep-max (1RM, 3RM, 5RM)
+var RepMaxReps = []int{1, 3, 5}
+
+// LiftLog - Serie de fuerza registrada por el miembro sobre un movimiento de la biblioteca
+type LiftLog struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	MovementID   int64     `json:"movement_id"`
+	MovementName string    `json:"movement_name,omitempty"`
+	RoutineID    *int64    `json:"routine_id,omitempty"` // Rutina de fuerza en que se hizo, si aplica
+	Sets         int       `json:"sets"`
+	Reps         int       `json:"reps"`
+	LoadKg       float64   `json:"load_kg"`
+	RPE          *float64  `json:"rpe,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	LoggedOn     time.Time `json:"logged_on"`
+	Estimated1RM float64   `json:"estimated_1rm"`         // Epley
+	Brzycki1RM   float64   `json:"estimated_1rm_brzycki"` // 0 sobre 36 repeticiones
+	IsPR         bool      `json:"is_pr"`                 // Mejor carga del miembro para esas repeticiones
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type LogLiftRequest struct {
+	MovementID int64    `json:"movement_id"`
+	RoutineID  *int64   `json:"routine_id,omitempty"`
+	Sets       int      `json:"sets,omitempty"` // 1 por defecto
+	Reps       int      `json:"reps"`
+	LoadKg     float64  `json:"load_kg"`
+	RPE        *float64 `json:"rpe,omitempty"`
+	Notes      string   `json:"notes,omitempty"`
+	LoggedOn   string   `json:"logged_on,omitempty"` // YYYY-MM-DD; hoy por defecto
+	UserID     *int64   `json:"user_id,omitempty"`   // Coach registra a nombre de un miembro (requiere results.manage)
+}
+
+// RepMax - Mejor carga levantada para un número de repeticiones
+type RepMax struct {
+	Reps     int       `json:"reps"`
+	LoadKg   float64   `json:"load_kg"`
+	LoggedOn time.Time `json:"logged_on"`
+	LiftID   int64     `json:"lift_id"`
+}
+
+// LiftSummary - Récords del miembro en un movimiento
+type LiftSummary struct {
+	MovementID   int64     `json:"movement_id"`
+	MovementName string    `json:"movement_name"`
+	OneRM        float64   `json:"one_rm"`        // 1RM de referencia para los porcentajes
+	OneRMSource  string    `json:"one_rm_source"` // actual (levantado) o estimated
+	Estimated1RM float64   `json:"estimated_1rm"`
+	RepMaxes     []*RepMax `json:"rep_maxes"`
+	LastLoggedOn time.Time `json:"last_logged_on"`
+	Lifts        int       `json:"lifts"`
+}
+
+// Origen del 1RM de referencia
+const (
+	OneRMActual    = "actual"
+	OneRMEstimated = "estimated"
+)
+
+// PercentageTarget - Carga objetivo de un porcentaje del 1RM del miembro
+type PercentageTarget struct {
+	Percent float64 `json:"percent"`
+	LoadKg  float64 `json:"load_kg"`
+}
+
+type PercentageResponse struct {
+	MovementID   int64               `json:"movement_id"`
+	MovementName string              `json:"movement_name"`
+	OneRM        float64             `json:"one_rm"`
+	OneRMSource  string              `json:"one_rm_source"`
+	Targets      []*PercentageTarget `json:"targets"`
+}
+
+// RoutineTarget - Carga objetivo del miembro para una línea de la rutina prescrita en % del 1RM
+type RoutineTarget struct {
+	BlockID      int64   `json:"block_id"`
+	LineID       int64   `json:"line_id"`
+	MovementID   int64   `json:"movement_id"`
+	MovementName string  `json:"movement_name"`
+	Reps         string  `json:"reps,omitempty"`
+	Percent      float64 `json:"percent"`
+	OneRM        float64 `json:"one_rm,omitempty"`
+	LoadKg       float64 `json:"load_kg,omitempty"` // 0 si el miembro no tiene 1RM registrado
+}
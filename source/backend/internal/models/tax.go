@@ -0,0 +1,110 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Tipos de documento tributario electrónico (DTE) soportados
+const (
+	DTEFactura      = 33 // Factura electrónica (requiere RUT y datos comerciales del receptor)
+	DTEBoleta       = 39 // Boleta electrónica afecta
+	DTEBoletaExenta = 41 // Boleta electrónica exenta
+)
+
+var DTENames = map[int]string{
+	DTEFactura:      "FACTURA ELECTRÓNICA",
+	DTEBoleta:       "BOLETA ELECTRÓNICA",
+	DTEBoletaExenta: "BOLETA NO AFECTA O EXENTA ELECTRÓNICA",
+}
+
+// Estados del documento frente al SII
+const (
+	TaxDocIssued = "issued" // Timbrado localmente, pendiente de envío
+	TaxDocSent   = "sent"   // Enviado; el sender devolvió un track id
+	TaxDocError  = "error"  // Falló el envío; se puede reintentar
+)
+
+const (
+	TaxSourcePayment = "payment"
+	TaxSourceSale    = "sale"
+)
+
+// IVARate - Tasa de IVA vigente en Chile
+const IVARate = 0.19
+
+// ReceptorGenericRUT - RUT genérico para boletas a consumidor final sin identificar
+const ReceptorGenericRUT = "66666666-6"
+
+// CalculateIVA desglosa un total con IVA incluido. En documentos exentos todo el monto va a exento.
+func CalculateIVA(total int64, docType int) (net, iva, exempt int64) {
+	if docType == DTEBoletaExenta {
+		return 0, 0, total
+	}
+	net = int64(math.Round(float64(total) / (1 + IVARate)))
+	return net, total - net, 0
+}
+
+// TaxReceptor - Datos del receptor. Para factura todos son obligatorios; para boleta basta el RUT (opcional).
+type TaxReceptor struct {
+	RUT          string `json:"rut"`
+	BusinessName string `json:"business_name"` // Razón social
+	Activity     string `json:"activity"`      // Giro
+	Address      string `json:"address"`
+	Commune      string `json:"commune"`
+	Email        string `json:"email,omitempty"`
+}
+
+// Complete indica si el receptor tiene los datos exigidos en una factura
+func (r *TaxReceptor) Complete() bool {
+	return r != nil && r.RUT != "" && r.BusinessName != "" && r.Activity != "" && r.Address != "" && r.Commune != ""
+}
+
+type TaxDocumentItem struct {
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unit_price"` // Bruto en boletas, neto en facturas
+	Amount    int64  `json:"amount"`
+}
+
+type TaxDocument struct {
+	ID           int64             `json:"id"`
+	DocType      int               `json:"doc_type"`
+	Folio        int64             `json:"folio"`
+	Status       string            `json:"status"`
+	SourceType   string            `json:"source_type"`
+	SourceID     int64             `json:"source_id"`
+	UserID       *int64            `json:"user_id,omitempty"`
+	Receptor     TaxReceptor       `json:"receptor"`
+	NetAmount    int64             `json:"net_amount"`
+	IVA          int64             `json:"iva"`
+	ExemptAmount int64             `json:"exempt_amount"`
+	Total        int64             `json:"total"`
+	Items        []TaxDocumentItem `json:"items"`
+	IssuedAt     time.Time         `json:"issued_at"`
+	XML          string            `json:"-"`
+	TED          string            `json:"-"` // Timbre electrónico (se imprime como PDF417)
+	TrackID      string            `json:"track_id,omitempty"`
+	SendError    string            `json:"send_error,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// CAF - Código de Autorización de Folios entregado por el SII
+type CAF struct {
+	ID           int64     `json:"id"`
+	DocType      int       `json:"doc_type"`
+	FolioFrom    int64     `json:"folio_from"`
+	FolioTo      int64     `json:"folio_to"`
+	NextFolio    int64     `json:"next_folio"`
+	AuthorizedAt string    `json:"authorized_at"`
+	Remaining    int64     `json:"remaining"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// IssueTaxDocumentRequest - Admin emite un DTE para un pago o venta completada
+type IssueTaxDocumentRequest struct {
+	SourceType string       `json:"source_type"` // payment, sale
+	SourceID   int64        `json:"source_id"`
+	DocType    int          `json:"doc_type"` // 39 (por defecto), 41, 33
+	Receptor   *TaxReceptor `json:"receptor,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateIVA(t *testing.T) {
+	cases := []struct {
+		total                int64
+		docType              int
+		net, iva, exemptWant int64
+	}{
+		{11900, DTEBoleta, 10000, 1900, 0},
+		{45000, DTEBoleta, 37815, 7185, 0},
+		{1000, DTEFactura, 840, 160, 0},
+		{1, DTEBoleta, 1, 0, 0},
+		{0, DTEBoleta, 0, 0, 0},
+		{45000, DTEBoletaExenta, 0, 0, 45000},
+	}
+	for _, c := range cases {
+		net, iva, exempt := CalculateIVA(c.total, c.docType)
+		if net != c.net || iva != c.iva || exempt != c.exemptWant {
+			t.Errorf("CalculateIVA(%d, %d) = %d, %d, %d; want %d, %d, %d",
+				c.total, c.docType, net, iva, exempt, c.net, c.iva, c.exemptWant)
+		}
+	}
+}
+
+func TestCalculateIVA_SplitAddsUpToTotal(t *testing.T) {
+	for total := int64(1); total <= 200000; total += 7 {
+		net, iva, exempt := CalculateIVA(total, DTEBoleta)
+		if net+iva != total || exempt != 0 {
+			t.Fatalf("total %d: net %d + iva %d (exempt %d) does not add up", total, net, iva, exempt)
+		}
+		// El neto es el entero más cercano a total / 1.19
+		if diff := math.Abs(float64(net) - float64(total)/(1+IVARate)); diff > 0.5 {
+			t.Fatalf("total %d: net %d is %.2f away from the exact net", total, net, diff)
+		}
+	}
+}
+
+func TestCalculateIVA_ExemptCarriesNoIVA(t *testing.T) {
+	for _, total := range []int64{0, 1, 990, 45000, 1234567} {
+		net, iva, exempt := CalculateIVA(total, DTEBoletaExenta)
+		if net != 0 || iva != 0 || exempt != total {
+			t.Errorf("exempt total %d: got net %d, iva %d, exempt %d", total, net, iva, exempt)
+		}
+	}
+}
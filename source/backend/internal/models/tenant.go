@@ -0,0 +1,63 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tenant - Gimnasio alojado en el deployment; sus datos viven en su propio schema de Postgres
+type Tenant struct {
+	ID        int64        `json:"id"`
+	Slug      string       `json:"slug"` // Subdominio y valor de X-Tenant / claim "tid"
+	Name      string       `json:"name"`
+	Schema    string       `json:"schema"`
+	Active    bool         `json:"active"`
+	Config    TenantConfig `json:"config"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// TenantConfig - Valores propios del tenant; los nil usan el valor global de config.Config
+type TenantConfig struct {
+	GymName                  *string `json:"gym_name,omitempty"`
+	GymAddress               *string `json:"gym_address,omitempty"`
+	GymContact               *string `json:"gym_contact,omitempty"`
+	AppURL                   *string `json:"app_url,omitempty"`
+	SMTPFrom                 *string `json:"smtp_from,omitempty"` // Remitente de los emails del tenant
+	InvitationClassPrice     *int64  `json:"invitation_class_price,omitempty"`
+	BeforeClassPhotoPrice    *int64  `json:"before_class_photo_price,omitempty"`
+	BookingWindowDays        *int    `json:"booking_window_days,omitempty"`
+	BookingCutoffHours       *int    `json:"booking_cutoff_hours,omitempty"`
+	PendingPaymentExpiryDays *int    `json:"pending_payment_expiry_days,omitempty"`
+}
+
+// CreateTenantRequest - Aprovisiona el tenant con su schema y la cuenta del dueño
+type CreateTenantRequest struct {
+	Slug          string       `json:"slug"`
+	Name          string       `json:"name"`
+	Config        TenantConfig `json:"config"`
+	OwnerEmail    string       `json:"owner_email"`
+	OwnerName     string       `json:"owner_name"`
+	OwnerPassword string       `json:"owner_password"`
+}
+
+type UpdateTenantRequest struct {
+	Name   *string       `json:"name"`
+	Active *bool         `json:"active"` // false suspende el tenant
+	Config *TenantConfig `json:"config"` // Reemplaza la configuración completa
+}
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,38}[a-z0-9]$`)
+
+// reservedTenantSlugs no se pueden usar como subdominio
+var reservedTenantSlugs = map[string]bool{"www": true, "api": true, "app": true, "admin": true, "platform": true, "public": true}
+
+func ValidTenantSlug(slug string) bool {
+	return tenantSlugPattern.MatchString(slug) && !reservedTenantSlugs[slug]
+}
+
+// TenantSchema - Nombre del schema del tenant (identificador seguro derivado del slug validado)
+func TenantSchema(slug string) string {
+	return "tenant_" + strings.ReplaceAll(slug, "-", "_")
+}
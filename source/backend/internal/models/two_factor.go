@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Claves de app_settings
+const (
+	SettingRequire2FAStaff  = "require_2fa_staff"
+	SettingMagicLinkEnabled = "magic_link_enabled"
+)
+
+// RecoveryCodeCount - Códigos de recuperación que se entregan al activar 2FA
+const RecoveryCodeCount = 10
+
+// TwoFactor - Secreto TOTP (RFC 6238) del usuario; EnabledAt nil mientras la activación está pendiente
+type TwoFactor struct {
+	UserID    int64
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64 // Último paso de 30s usado, para no aceptar el mismo código dos veces
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup - Datos para enrolar la app autenticadora (el front dibuja el QR con la URI)
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorLoginRequest - Segundo paso del login: código TOTP o uno de recuperación
+type TwoFactorLoginRequest struct {
+	MFAToken       string     `json:"mfa_token"`
+	Code           string     `json:"code"`
+	RecoveryCode   string     `json:"recovery_code"`
+	RememberDevice bool       `json:"remember_device"`
+	Client         ClientInfo `json:"-"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// SecuritySettings - Políticas de seguridad del gimnasio
+type SecuritySettings struct {
+	Require2FAStaff  bool `json:"require_2fa_staff"`
+	MagicLinkEnabled bool `json:"magic_link_enabled"` // Login sin contraseña para miembros
+}
+
+// SecuritySettingsRequest - Solo se actualizan los campos enviados
+type SecuritySettingsRequest struct {
+	Require2FAStaff  *bool `json:"require_2fa_staff"`
+	MagicLinkEnabled *bool `json:"magic_link_enabled"`
+}
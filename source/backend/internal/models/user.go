@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -17,49 +18,119 @@ type User struct {
 	PasswordHash      string     `json:"-"`
 	Name              string     `json:"name"`
 	Phone             string     `json:"phone,omitempty"`
-	AvatarURL         string     `json:"avatar_url,omitempty"`  // Foto de perfil
+	AvatarURL         string     `json:"avatar_url,omitempty"` // Foto de perfil
 	Role              Role       `json:"role"`
 	Active            bool       `json:"active"`
-	InvitationClasses int        `json:"invitation_classes"`    // Clases invitación disponibles
+	InvitationClasses int        `json:"invitation_classes"` // Clases invitación disponibles
 	BirthDate         *time.Time `json:"birth_date,omitempty"`
-	Sex               string     `json:"sex,omitempty"`         // "M" o "F"
+	Sex               string     `json:"sex,omitempty"` // "M" o "F"
 	WeightKg          float64    `json:"weight_kg,omitempty"`
 	HeightCm          float64    `json:"height_cm,omitempty"`
+	RUT               string     `json:"rut,omitempty"` // Normalizado sin puntos: 12345678-9
+	EmailVerified     bool       `json:"email_verified"`
+	TokenVersion      int        `json:"-"` // Se incrementa al revocar todas las sesiones; invalida access tokens emitidos antes
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone,omitempty"`
+	Email    string     `json:"email"`
+	Password string     `json:"password"`
+	Name     string     `json:"name"`
+	Phone    string     `json:"phone,omitempty"`
+	Client   ClientInfo `json:"-"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
+	Email          string     `json:"email"`
+	Password       string     `json:"password"`
+	RememberDevice bool       `json:"remember_device"`
+	Client         ClientInfo `json:"-"`
+}
+
+// MinPasswordLength - Largo mínimo para contraseñas nuevas (registro, reset y cambio)
+const MinPasswordLength = 8
+
+// Propósitos de los tokens de un solo uso enviados por email
+const (
+	TokenPasswordReset = "password_reset"
+	TokenEmailVerify   = "email_verify"
+	TokenMagicLink     = "magic_link"
+)
+
+// MagicLinkRequest - Pide el link de login sin contraseña
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkVerifyRequest - Canjea el link por una sesión
+type MagicLinkVerifyRequest struct {
+	Token          string     `json:"token"`
+	RememberDevice bool       `json:"remember_device"`
+	Client         ClientInfo `json:"-"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
 	Password string `json:"password"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string     `json:"current_password"`
+	NewPassword     string     `json:"new_password"`
+	Client          ClientInfo `json:"-"`
+}
+
 type UpdateUserRequest struct {
 	Name              string  `json:"name,omitempty"`
-	Phone             *string `json:"phone,omitempty"`    // nil=no change, ptr to ""=clear
+	Phone             *string `json:"phone,omitempty"`      // nil=no change, ptr to ""=clear
 	AvatarURL         *string `json:"avatar_url,omitempty"` // nil=no change, ptr to ""=clear
 	Active            *bool   `json:"active,omitempty"`
 	Role              Role    `json:"role,omitempty"`
 	InvitationClasses *int    `json:"invitation_classes,omitempty"`
+	RUT               *string `json:"rut,omitempty"`
 }
 
 type AddInvitationRequest struct {
 	Count int `json:"count"` // Clases invitación a agregar (típicamente 1)
 }
 
+// AuthResponse - Tokens de la sesión, o bien el desafío de 2FA cuando la cuenta lo requiere
 type AuthResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	User         *User  `json:"user"`
+	AccessToken      string   `json:"access_token,omitempty"`
+	RefreshToken     string   `json:"refresh_token,omitempty"`
+	User             *User    `json:"user,omitempty"`
+	MFARequired      bool     `json:"mfa_required,omitempty"`
+	MFASetupRequired bool     `json:"mfa_setup_required,omitempty"` // El staff debe activar 2FA antes de entrar
+	MFAToken         string   `json:"mfa_token,omitempty"`
+	RecoveryCodes    []string `json:"recovery_codes,omitempty"` // Solo al activar 2FA durante el login
 }
 
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string     `json:"refresh_token"`
+	Client       ClientInfo `json:"-"`
+}
+
+// NormalizeRUT deja un RUT chileno en formato 12345678-9 (sin puntos, DV en mayúscula).
+// Devuelve "" si el valor no parece un RUT.
+func NormalizeRUT(rut string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(rut) {
+		if (c >= '0' && c <= '9') || c == 'K' {
+			b.WriteRune(c)
+		}
+	}
+	clean := b.String()
+	if len(clean) < 2 {
+		return ""
+	}
+	return clean[:len(clean)-1] + "-" + clean[len(clean)-1:]
 }
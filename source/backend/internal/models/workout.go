@@ -0,0 +1,73 @@
+package models
+
+// Tipos de bloque de una rutina estructurada
+const (
+	BlockWarmup   = "warmup"
+	BlockStrength = "strength"
+	BlockSkill    = "skill"
+	BlockMetcon   = "metcon"
+	BlockCooldown = "cooldown"
+)
+
+// Formatos de bloque
+const (
+	FormatAMRAP    = "amrap"     // Máximo de rondas en Minutes
+	FormatEMOM     = "emom"      // Cada IntervalSeconds durante Minutes
+	FormatForTime  = "for_time"  // Rounds rondas con time cap Minutes
+	FormatTabata   = "tabata"    // Rounds x 20s/10s (8 por defecto)
+	FormatSetsReps = "sets_reps" // Sets series de las repeticiones de cada línea
+)
+
+// Niveles de prescripción
+const (
+	LevelRx       = "rx"
+	LevelScaled   = "scaled"
+	LevelBeginner = "beginner"
+)
+
+// Prescription - Qué hace el atleta en una línea para un nivel
+type Prescription struct {
+	Reps     string  `json:"reps,omitempty"`     // "10", "21-15-9", "max"
+	LoadKg   float64 `json:"load_kg,omitempty"`  // Carga fija
+	LoadPct  float64 `json:"load_pct,omitempty"` // % del 1RM del atleta
+	Distance int     `json:"distance_m,omitempty"`
+	Calories int     `json:"calories,omitempty"`
+	Seconds  int     `json:"seconds,omitempty"` // Holds, planchas, etc.
+}
+
+// WorkoutLine - Movimiento de un bloque con sus prescripciones Rx, scaled y beginner.
+// Scaled y Beginner nil = igual que el nivel superior.
+type WorkoutLine struct {
+	ID         int64         `json:"id,omitempty"`
+	MovementID *int64        `json:"movement_id,omitempty"`
+	Name       string        `json:"name"` // Nombre del movimiento (o texto libre, p. ej. "Descanso")
+	Rx         Prescription  `json:"rx"`
+	Scaled     *Prescription `json:"scaled,omitempty"`
+	Beginner   *Prescription `json:"beginner,omitempty"`
+	Notes      string        `json:"notes,omitempty"`
+}
+
+// At devuelve la prescripción de la línea para el nivel pedido
+func (l *WorkoutLine) At(level string) Prescription {
+	switch {
+	case level == LevelBeginner && l.Beginner != nil:
+		return *l.Beginner
+	case (level == LevelBeginner || level == LevelScaled) && l.Scaled != nil:
+		return *l.Scaled
+	}
+	return l.Rx
+}
+
+// WorkoutBlock - Bloque de la rutina (calentamiento, fuerza, metcon, ...)
+type WorkoutBlock struct {
+	ID              int64          `json:"id,omitempty"`
+	Kind            string         `json:"kind"`
+	Title           string         `json:"title,omitempty"`
+	Format          string         `json:"format,omitempty"`
+	Minutes         int            `json:"minutes,omitempty"`
+	Rounds          int            `json:"rounds,omitempty"`
+	Sets            int            `json:"sets,omitempty"`
+	IntervalSeconds int            `json:"interval_seconds,omitempty"`
+	Notes           string         `json:"notes,omitempty"`
+	Lines           []*WorkoutLine `json:"lines"`
+}
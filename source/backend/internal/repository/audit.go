@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// AuditRepository - Log de auditoría de acciones administrativas (solo inserción)
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Snapshot devuelve la fila de la entidad como JSON (sin campos sensibles); nil si no existe
+func (r *AuditRepository) Snapshot(entityType string, id int64) (json.RawMessage, error) {
+	table, ok := models.AuditEntityTables[entityType]
+	if !ok {
+		return nil, nil
+	}
+	var data []byte
+	query := fmt.Sprintf(`SELECT (to_jsonb(t) - $2::text[])::text FROM %s t WHERE t.id = $1`, table)
+	err := r.db.QueryRow(query, id, "{"+strings.Join(models.AuditRedactedFields, ",")+"}").Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func (r *AuditRepository) Insert(e *models.AuditEntry) error {
+	var changes []byte
+	if len(e.Changes) > 0 {
+		changes, _ = json.Marshal(e.Changes)
+	}
+	return r.db.QueryRow(`INSERT INTO audit_log (actor_id, actor_role, action, entity_type, entity_id, method, path,
+		before_data, after_data, changes, ip, user_agent, on_behalf_of)
+		VALUES ($1, $2, $3, $4, NULLIF($5,''), $6, $7, $8, $9, $10, NULLIF($11,''), NULLIF($12,''), $13)
+		RETURNING id, created_at`,
+		e.ActorID, e.ActorRole, e.Action, e.EntityType, e.EntityID, e.Method, e.Path,
+		nullJSON(e.Before), nullJSON(e.After), nullJSON(changes), e.IP, e.UserAgent, e.OnBehalfOf).Scan(&e.ID, &e.CreatedAt)
+}
+
+func (r *AuditRepository) List(f models.AuditFilter, limit, offset int) ([]*models.AuditEntry, error) {
+	where := []string{"1=1"}
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorID != nil {
+		add("a.actor_id = $%d", *f.ActorID)
+	}
+	if f.EntityType != "" {
+		add("a.entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID != "" {
+		add("a.entity_id = $%d", f.EntityID)
+	}
+	if f.Action != "" {
+		add("a.action = $%d", f.Action)
+	}
+	if f.From != nil {
+		add("a.created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("a.created_at < $%d", *f.To)
+	}
+
+	query := `SELECT a.id, COALESCE(a.actor_id,0), COALESCE(u.name,''), COALESCE(a.actor_role,''), a.action, a.entity_type,
+			COALESCE(a.entity_id,''), COALESCE(a.method,''), COALESCE(a.path,''),
+			COALESCE(a.before_data::text,''), COALESCE(a.after_data::text,''), COALESCE(a.changes::text,''),
+			COALESCE(a.ip,''), COALESCE(a.user_agent,''), a.on_behalf_of, a.created_at
+		FROM audit_log a LEFT JOIN users u ON u.id = a.actor_id
+		WHERE ` + strings.Join(where, " AND ") + ` ORDER BY a.created_at DESC, a.id DESC`
+	if limit > 0 {
+		args = append(args, limit, offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var before, after, changes string
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.ActorRole, &e.Action, &e.EntityType,
+			&e.EntityID, &e.Method, &e.Path, &before, &after, &changes, &e.IP, &e.UserAgent, &e.OnBehalfOf, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before != "" {
+			e.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			e.After = json.RawMessage(after)
+		}
+		if changes != "" {
+			json.Unmarshal([]byte(changes), &e.Changes)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Purge elimina las entradas anteriores a la fecha (retención)
+func (r *AuditRepository) Purge(before time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM audit_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+type BankRepository struct {
+	db *sql.DB
+}
+
+func NewBankRepository(db *sql.DB) *BankRepository {
+	return &BankRepository{db: db}
+}
+
+func (r *BankRepository) CreateImport(imp *models.BankStatementImport) error {
+	query := `INSERT INTO bank_statement_imports (filename, format, total_lines, duplicates, matched, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at`
+	return r.db.QueryRow(query, imp.Filename, imp.Format, imp.TotalLines, imp.Duplicates, imp.Matched, imp.CreatedBy).
+		Scan(&imp.ID, &imp.CreatedAt)
+}
+
+func (r *BankRepository) UpdateImportCounts(imp *models.BankStatementImport) error {
+	_, err := r.db.Exec(`UPDATE bank_statement_imports SET total_lines = $1, duplicates = $2, matched = $3 WHERE id = $4`,
+		imp.TotalLines, imp.Duplicates, imp.Matched, imp.ID)
+	return err
+}
+
+func (r *BankRepository) ListImports(limit, offset int) ([]*models.BankStatementImport, error) {
+	rows, err := r.db.Query(`SELECT id, COALESCE(filename,''), format, total_lines, duplicates, matched, COALESCE(created_by,0), created_at
+		FROM bank_statement_imports ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []*models.BankStatementImport
+	for rows.Next() {
+		imp := &models.BankStatementImport{}
+		if err := rows.Scan(&imp.ID, &imp.Filename, &imp.Format, &imp.TotalLines, &imp.Duplicates, &imp.Matched, &imp.CreatedBy, &imp.CreatedAt); err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	return imports, nil
+}
+
+// InsertTransaction guarda un abono. Devuelve false si la línea ya fue importada antes (misma huella).
+func (r *BankRepository) InsertTransaction(tx *models.BankTransaction) (bool, error) {
+	query := `INSERT INTO bank_transactions (import_id, date, amount, sender_rut, sender_name, comment, fingerprint, status,
+			  matched_payment_id, matched_user_id, match_score, match_reason, operation_id)
+			  VALUES ($1, $2, $3, NULLIF($4,''), NULLIF($5,''), NULLIF($6,''), $7, $8, $9, $10, $11, NULLIF($12,''), NULLIF($13,''))
+			  ON CONFLICT (fingerprint) DO NOTHING
+			  RETURNING id, created_at`
+	err := r.db.QueryRow(query, tx.ImportID, tx.Date, tx.Amount, tx.SenderRUT, tx.SenderName, tx.Comment, tx.Fingerprint, tx.Status,
+		tx.MatchedPaymentID, tx.MatchedUserID, tx.MatchScore, tx.MatchReason, tx.OperationID).Scan(&tx.ID, &tx.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+const bankTransactionSelect = `
+	SELECT t.id, t.import_id, t.date, t.amount, COALESCE(t.sender_rut,''), COALESCE(t.sender_name,''), COALESCE(t.comment,''),
+		   t.fingerprint, t.status, t.matched_payment_id, t.matched_user_id, t.match_score, COALESCE(t.match_reason,''),
+		   t.resolved_by, t.resolved_at, t.created_at, COALESCE(t.operation_id,''),
+		   COALESCE(u.name,''), COALESCE(pl.name,''), p.amount
+	FROM bank_transactions t
+	LEFT JOIN payments p ON t.matched_payment_id = p.id
+	LEFT JOIN plans pl ON p.plan_id = pl.id
+	LEFT JOIN users u ON t.matched_user_id = u.id`
+
+func scanBankTransaction(scanner interface{ Scan(...interface{}) error }) (*models.BankTransactionWithDetails, error) {
+	t := &models.BankTransactionWithDetails{}
+	err := scanner.Scan(
+		&t.ID, &t.ImportID, &t.Date, &t.Amount, &t.SenderRUT, &t.SenderName, &t.Comment,
+		&t.Fingerprint, &t.Status, &t.MatchedPaymentID, &t.MatchedUserID, &t.MatchScore, &t.MatchReason,
+		&t.ResolvedBy, &t.ResolvedAt, &t.CreatedAt, &t.OperationID,
+		&t.MatchedUserName, &t.MatchedPlanName, &t.MatchedPaymentAmt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTransactions - Bandeja de conciliación. Sin status devuelve las líneas abiertas (sin coincidencia o sugeridas).
+func (r *BankRepository) ListTransactions(status string, importID int64, limit, offset int) ([]*models.BankTransactionWithDetails, error) {
+	query := bankTransactionSelect + ` WHERE ($1 = '' AND t.status IN ('unmatched','suggested') OR t.status = $1)
+		AND ($2 = 0 OR t.import_id = $2)
+		ORDER BY t.date DESC, t.id DESC
+		LIMIT $3 OFFSET $4`
+	rows, err := r.db.Query(query, status, importID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txs []*models.BankTransactionWithDetails
+	for rows.Next() {
+		t, err := scanBankTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, nil
+}
+
+func (r *BankRepository) GetTransaction(id int64) (*models.BankTransactionWithDetails, error) {
+	return scanBankTransaction(r.db.QueryRow(bankTransactionSelect+` WHERE t.id = $1`, id))
+}
+
+// SetMatch reasigna manualmente la coincidencia de una línea abierta
+func (r *BankRepository) SetMatch(id int64, paymentID, userID *int64, reason string) error {
+	query := `UPDATE bank_transactions
+			  SET matched_payment_id = $1, matched_user_id = $2, match_score = 100, match_reason = $3, status = 'suggested'
+			  WHERE id = $4 AND status IN ('unmatched','suggested')`
+	return execExpectingRow(r.db, query, paymentID, userID, reason, id)
+}
+
+// Resolve cierra una línea como confirmada o ignorada. Devuelve sql.ErrNoRows si ya estaba cerrada.
+func (r *BankRepository) Resolve(id int64, status string, paymentID, userID *int64, resolvedBy int64) error {
+	query := `UPDATE bank_transactions
+			  SET status = $1, matched_payment_id = COALESCE($2, matched_payment_id), matched_user_id = COALESCE($3, matched_user_id),
+			  resolved_by = $4, resolved_at = NOW()
+			  WHERE id = $5 AND status IN ('unmatched','suggested')`
+	return execExpectingRow(r.db, query, status, paymentID, userID, resolvedBy, id)
+}
+
+// IsPaymentConfirmed indica si un pago ya fue conciliado con otra línea de cartola
+func (r *BankRepository) IsPaymentConfirmed(paymentID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM bank_transactions WHERE matched_payment_id = $1 AND status = 'confirmed')`, paymentID).Scan(&exists)
+	return exists, err
+}
+
+// ListMembersForMatching devuelve los datos mínimos de miembros para el cruce automático
+func (r *BankRepository) ListMembersForMatching() ([]*models.User, error) {
+	rows, err := r.db.Query(`SELECT id, name, email, COALESCE(rut,'') FROM users WHERE role = 'user'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		u := &models.User{}
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.RUT); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func execExpectingRow(db *sql.DB, query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// BenchmarkRepository - Catálogo de benchmarks y resultados de las rutinas vinculadas
+type BenchmarkRepository struct {
+	db *sql.DB
+}
+
+func NewBenchmarkRepository(db *sql.DB) *BenchmarkRepository {
+	return &BenchmarkRepository{db: db}
+}
+
+const benchmarkColumns = `b.id, b.name, b.category, b.score_type, COALESCE(b.description, ''), b.content, b.time_cap, b.active,
+	b.created_at, b.updated_at`
+
+func scanBenchmark(row interface{ Scan(...interface{}) error }) (*models.Benchmark, error) {
+	b := &models.Benchmark{}
+	err := row.Scan(&b.ID, &b.Name, &b.Category, &b.ScoreType, &b.Description, &b.Content, &b.TimeCap, &b.Active,
+		&b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// List devuelve el catálogo por nombre (category "" = todas)
+func (r *BenchmarkRepository) List(category string, includeInactive bool) ([]*models.Benchmark, error) {
+	rows, err := r.db.Query(`SELECT `+benchmarkColumns+` FROM benchmarks b
+		WHERE ($1 = '' OR b.category = $1) AND ($2 OR b.active) ORDER BY b.category, b.name`, category, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.Benchmark
+	for rows.Next() {
+		b, err := scanBenchmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+func (r *BenchmarkRepository) Get(id int64) (*models.Benchmark, error) {
+	return scanBenchmark(r.db.QueryRow(`SELECT `+benchmarkColumns+` FROM benchmarks b WHERE b.id = $1`, id))
+}
+
+// ForRoutine devuelve el benchmark vinculado a la rutina (sql.ErrNoRows si no tiene)
+func (r *BenchmarkRepository) ForRoutine(routineID int64) (*models.Benchmark, error) {
+	return scanBenchmark(r.db.QueryRow(`SELECT `+benchmarkColumns+` FROM benchmarks b
+		JOIN routines rt ON rt.benchmark_id = b.id WHERE rt.id = $1`, routineID))
+}
+
+// NameTaken indica si otro benchmark ya usa el nombre (sin distinguir mayúsculas)
+func (r *BenchmarkRepository) NameTaken(name string, excludeID int64) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM benchmarks WHERE LOWER(name) = LOWER($1) AND id <> $2)`, name, excludeID).Scan(&taken)
+	return taken, err
+}
+
+func (r *BenchmarkRepository) Create(b *models.Benchmark) error {
+	return r.db.QueryRow(`INSERT INTO benchmarks (name, category, score_type, description, content, time_cap, active)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, true) RETURNING id, created_at, updated_at`,
+		b.Name, b.Category, b.ScoreType, b.Description, b.Content, b.TimeCap).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+}
+
+func (r *BenchmarkRepository) Update(b *models.Benchmark) error {
+	return execExpectingRow(r.db, `UPDATE benchmarks SET name = $1, category = $2, score_type = $3, description = NULLIF($4, ''),
+		content = $5, time_cap = $6, active = $7, updated_at = NOW() WHERE id = $8`,
+		b.Name, b.Category, b.ScoreType, b.Description, b.Content, b.TimeCap, b.Active, b.ID)
+}
+
+// Results devuelve los resultados registrados en cualquier rutina vinculada al benchmark,
+// del más antiguo al más reciente (userID 0 = todos los miembros)
+func (r *BenchmarkRepository) Results(benchmarkID, userID int64) ([]*models.BenchmarkResult, error) {
+	rows, err := r.db.Query(`SELECT urr.id, urr.user_id, urr.routine_id, COALESCE(urr.score, ''), urr.rx, COALESCE(urr.notes, ''),
+			cs.date, urr.created_at
+		FROM user_routine_results urr
+		JOIN routines rt ON rt.id = urr.routine_id
+		LEFT JOIN class_schedules cs ON cs.id = urr.class_schedule_id
+		WHERE rt.benchmark_id = $1 AND ($2 = 0 OR urr.user_id = $2)
+		ORDER BY COALESCE(cs.date, urr.created_at), urr.id`, benchmarkID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.BenchmarkResult
+	for rows.Next() {
+		res := &models.BenchmarkResult{}
+		if err := rows.Scan(&res.ResultID, &res.UserID, &res.RoutineID, &res.Score, &res.Rx, &res.Notes,
+			&res.ScheduleDate, &res.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+	return list, rows.Err()
+}
+
+// UserResults devuelve los resultados del miembro en todos los benchmarks, agrupables por BenchmarkID
+func (r *BenchmarkRepository) UserResults(userID int64) (map[int64][]*models.BenchmarkResult, error) {
+	rows, err := r.db.Query(`SELECT rt.benchmark_id, urr.id, urr.user_id, urr.routine_id, COALESCE(urr.score, ''), urr.rx,
+			COALESCE(urr.notes, ''), cs.date, urr.created_at
+		FROM user_routine_results urr
+		JOIN routines rt ON rt.id = urr.routine_id
+		LEFT JOIN class_schedules cs ON cs.id = urr.class_schedule_id
+		WHERE rt.benchmark_id IS NOT NULL AND urr.user_id = $1
+		ORDER BY COALESCE(cs.date, urr.created_at), urr.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	byBenchmark := map[int64][]*models.BenchmarkResult{}
+	for rows.Next() {
+		var benchmarkID int64
+		res := &models.BenchmarkResult{}
+		if err := rows.Scan(&benchmarkID, &res.ResultID, &res.UserID, &res.RoutineID, &res.Score, &res.Rx, &res.Notes,
+			&res.ScheduleDate, &res.CreatedAt); err != nil {
+			return nil, err
+		}
+		byBenchmark[benchmarkID] = append(byBenchmark[benchmarkID], res)
+	}
+	return byBenchmark, rows.Err()
+}
+
+// SetResultPR corrige el flag de PR de un resultado según la comparación contra el benchmark
+func (r *BenchmarkRepository) SetResultPR(resultID int64, isPR bool) error {
+	_, err := r.db.Exec(`UPDATE user_routine_results SET is_pr = $1 WHERE id = $2`, isPR, resultID)
+	return err
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"boxmagic/internal/models"
+)
+
+var (
+	ErrCashSessionAlreadyOpen = errors.New("a cash session is already open")
+	ErrCashSessionNotOpen     = errors.New("cash session is not open")
+)
+
+type CashRepository struct {
+	db *sql.DB
+}
+
+func NewCashRepository(db *sql.DB) *CashRepository {
+	return &CashRepository{db: db}
+}
+
+// Open abre una sesión de caja. Solo puede haber una abierta a la vez.
+func (r *CashRepository) Open(session *models.CashSession) error {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM cash_sessions WHERE status = 'open')`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrCashSessionAlreadyOpen
+	}
+	session.Status = models.CashSessionOpen
+	return r.db.QueryRow(`INSERT INTO cash_sessions (status, opening_float, opened_by, notes)
+		VALUES ('open', $1, $2, NULLIF($3,'')) RETURNING id, opened_at`,
+		session.OpeningFloat, session.OpenedBy, session.Notes).Scan(&session.ID, &session.OpenedAt)
+}
+
+const cashSessionSelect = `
+	SELECT s.id, s.status, s.opening_float, COALESCE(s.opened_by,0), COALESCE(uo.name,''), s.opened_at,
+		   s.closed_by, COALESCE(uc.name,''), s.closed_at, COALESCE(s.notes,'')
+	FROM cash_sessions s
+	LEFT JOIN users uo ON s.opened_by = uo.id
+	LEFT JOIN users uc ON s.closed_by = uc.id`
+
+func scanCashSession(scanner interface{ Scan(...interface{}) error }) (*models.CashSession, error) {
+	s := &models.CashSession{}
+	err := scanner.Scan(&s.ID, &s.Status, &s.OpeningFloat, &s.OpenedBy, &s.OpenedByName, &s.OpenedAt,
+		&s.ClosedBy, &s.ClosedByName, &s.ClosedAt, &s.Notes)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *CashRepository) GetByID(id int64) (*models.CashSession, error) {
+	return scanCashSession(r.db.QueryRow(cashSessionSelect+` WHERE s.id = $1`, id))
+}
+
+// GetOpen devuelve la sesión abierta o sql.ErrNoRows si la caja está cerrada
+func (r *CashRepository) GetOpen() (*models.CashSession, error) {
+	return scanCashSession(r.db.QueryRow(cashSessionSelect + ` WHERE s.status = 'open'`))
+}
+
+func (r *CashRepository) List(from, to *time.Time, limit, offset int) ([]*models.CashSession, error) {
+	query := cashSessionSelect + ` WHERE ($1::timestamp IS NULL OR s.opened_at >= $1) AND ($2::timestamp IS NULL OR s.opened_at < $2)
+		ORDER BY s.opened_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.Query(query, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.CashSession
+	for rows.Next() {
+		s, err := scanCashSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// AddMovement registra un ingreso o egreso manual; falla si la sesión ya fue cerrada
+func (r *CashRepository) AddMovement(m *models.CashMovement) error {
+	err := r.db.QueryRow(`INSERT INTO cash_movements (session_id, type, amount, reason, created_by)
+		SELECT $1, $2, $3, $4, $5 WHERE EXISTS (SELECT 1 FROM cash_sessions WHERE id = $1 AND status = 'open')
+		RETURNING id, created_at`,
+		m.SessionID, m.Type, m.Amount, m.Reason, m.CreatedBy).Scan(&m.ID, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCashSessionNotOpen
+	}
+	return err
+}
+
+func (r *CashRepository) ListMovements(sessionID int64) ([]models.CashMovement, error) {
+	rows, err := r.db.Query(`SELECT m.id, m.session_id, m.type, m.amount, m.reason, COALESCE(m.created_by,0), COALESCE(u.name,''), m.created_at
+		FROM cash_movements m LEFT JOIN users u ON m.created_by = u.id
+		WHERE m.session_id = $1 ORDER BY m.created_at`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []models.CashMovement
+	for rows.Next() {
+		m := models.CashMovement{}
+		if err := rows.Scan(&m.ID, &m.SessionID, &m.Type, &m.Amount, &m.Reason, &m.CreatedBy, &m.CreatedByName, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, nil
+}
+
+// methodCase agrupa los nombres de medio de pago de pagos y ventas POS igual que models.NormalizePaymentMethod
+var methodCase = buildMethodCase()
+
+func buildMethodCase() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, m := range models.PaymentMethodAliases {
+		quoted := make([]string, len(m.Aliases))
+		for i, alias := range m.Aliases {
+			quoted[i] = pq.QuoteLiteral(alias)
+		}
+		fmt.Fprintf(&b, "\n\tWHEN COALESCE(payment_method,'') IN (%s) THEN %s", strings.Join(quoted, ", "), pq.QuoteLiteral(m.Method))
+	}
+	b.WriteString("\n\tELSE payment_method END")
+	return b.String()
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func sumsBySession(q queryer, sessionID int64) (models.CashMethodSums, error) {
+	sums := models.CashMethodSums{Payments: map[string]int64{}, Sales: map[string]int64{}}
+
+	for _, src := range []struct {
+		query string
+		dest  map[string]int64
+	}{
+		{`SELECT ` + methodCase + `, COALESCE(SUM(amount),0) FROM payments WHERE cash_session_id = $1 AND status = 'completed' GROUP BY 1`, sums.Payments},
+		// Abonos a la cuenta del miembro (saldar deudas) también entran a caja
+		{`SELECT ` + methodCase + `, COALESCE(SUM(amount),0) FROM ledger_entries WHERE cash_session_id = $1 AND type = 'payment' GROUP BY 1`, sums.Payments},
+		// Ventas fiadas o pagadas con saldo a favor no entran al cajón
+		{`SELECT ` + methodCase + `, COALESCE(SUM(total),0) FROM sales WHERE cash_session_id = $1 AND COALESCE(payment_method,'') NOT IN ('account', 'store_credit') GROUP BY 1`, sums.Sales},
+	} {
+		rows, err := q.Query(src.query, sessionID)
+		if err != nil {
+			return sums, err
+		}
+		for rows.Next() {
+			var method string
+			var amount int64
+			if err := rows.Scan(&method, &amount); err != nil {
+				rows.Close()
+				return sums, err
+			}
+			src.dest[method] += amount
+		}
+		rows.Close()
+	}
+
+	err := q.QueryRow(`SELECT COALESCE(SUM(amount) FILTER (WHERE type = 'in'),0), COALESCE(SUM(amount) FILTER (WHERE type = 'out'),0)
+		FROM cash_movements WHERE session_id = $1`, sessionID).Scan(&sums.In, &sums.Out)
+	return sums, err
+}
+
+// Sums devuelve los montos registrados en la sesión (para mostrar el arqueo en vivo)
+func (r *CashRepository) Sums(sessionID int64) (models.CashMethodSums, error) {
+	return sumsBySession(r.db, sessionID)
+}
+
+// Close cierra la sesión con los montos contados y guarda la cuadratura. Tras el cierre la sesión es inmutable.
+func (r *CashRepository) Close(sessionID, closedBy int64, counted map[string]int64, notes string) ([]models.CashSessionTotal, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Bloquear la sesión para evitar dos cierres simultáneos
+	var openingFloat int64
+	err = tx.QueryRow(`SELECT opening_float FROM cash_sessions WHERE id = $1 AND status = 'open' FOR UPDATE`, sessionID).Scan(&openingFloat)
+	if err == sql.ErrNoRows {
+		return nil, ErrCashSessionNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sums, err := sumsBySession(tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	totals := models.BuildCashTotals(openingFloat, sums, counted)
+
+	for _, t := range totals {
+		_, err = tx.Exec(`INSERT INTO cash_session_totals (session_id, method, payments, sales, expected, counted, difference)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			sessionID, t.Method, t.Payments, t.Sales, t.Expected, t.Counted, t.Difference)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE cash_sessions SET status = 'closed', closed_by = $1, closed_at = NOW(),
+		notes = CASE WHEN $2 = '' THEN notes ELSE CONCAT_WS(E'\n', notes, $2::text) END
+		WHERE id = $3`, closedBy, notes, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return totals, tx.Commit()
+}
+
+// GetTotals devuelve la cuadratura guardada al cierre
+func (r *CashRepository) GetTotals(sessionID int64) ([]models.CashSessionTotal, error) {
+	rows, err := r.db.Query(`SELECT method, payments, sales, expected, counted, difference
+		FROM cash_session_totals WHERE session_id = $1
+		ORDER BY CASE method WHEN 'efectivo' THEN 0 WHEN 'debito' THEN 1 WHEN 'transferencia' THEN 2 ELSE 3 END, method`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []models.CashSessionTotal
+	for rows.Next() {
+		t := models.CashSessionTotal{}
+		if err := rows.Scan(&t.Method, &t.Payments, &t.Sales, &t.Expected, &t.Counted, &t.Difference); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, nil
+}
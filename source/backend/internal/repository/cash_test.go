@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+var whenClause = regexp.MustCompile(`WHEN COALESCE\(payment_method,''\) IN \(([^)]*)\) THEN '([a-z]+)'`)
+
+// methodCaseMapping lee el CASE generado como alias -> medio de pago
+func methodCaseMapping(t *testing.T) map[string]string {
+	t.Helper()
+	mapping := map[string]string{}
+	for _, m := range whenClause.FindAllStringSubmatch(methodCase, -1) {
+		for _, alias := range strings.Split(m[1], ", ") {
+			mapping[strings.Trim(alias, "'")] = m[2]
+		}
+	}
+	if !strings.HasSuffix(methodCase, "ELSE payment_method END") {
+		t.Fatalf("unknown methods must pass through unchanged: %s", methodCase)
+	}
+	return mapping
+}
+
+func TestMethodCase_MatchesNormalizePaymentMethod(t *testing.T) {
+	mapping := methodCaseMapping(t)
+
+	cases := []struct {
+		alias, want string
+	}{
+		{"", "efectivo"},
+		{"cash", "efectivo"},
+		{"efectivo", "efectivo"},
+		{"debit", "debito"},
+		{"card", "debito"},
+		{"tarjeta", "debito"},
+		{"debito", "debito"},
+		{"transfer", "transferencia"},
+		{"transferencia", "transferencia"},
+	}
+	for _, c := range cases {
+		if got := mapping[c.alias]; got != c.want {
+			t.Errorf("methodCase groups %q as %q, want %q", c.alias, got, c.want)
+		}
+		if got := models.NormalizePaymentMethod(c.alias); got != c.want {
+			t.Errorf("NormalizePaymentMethod(%q) = %q, want %q", c.alias, got, c.want)
+		}
+	}
+	if len(mapping) != len(cases) {
+		t.Errorf("methodCase has %d aliases, test covers %d: %v", len(mapping), len(cases), mapping)
+	}
+}
+
+func TestCashRepository_ClosedSessionRejectsMovements(t *testing.T) {
+	db := testTenantDB(t, "cash-test")
+	cashier := &models.User{Email: "caja@example.com", PasswordHash: "x", Name: "Caja", Role: models.RoleFrontDesk, Active: true}
+	if err := NewUserRepository(db).Create(cashier); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewCashRepository(db)
+	session := &models.CashSession{OpeningFloat: 10000, OpenedBy: cashier.ID}
+	if err := repo.Open(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Open(&models.CashSession{OpenedBy: cashier.ID}); err != ErrCashSessionAlreadyOpen {
+		t.Fatalf("second open: expected ErrCashSessionAlreadyOpen, got %v", err)
+	}
+	in := &models.CashMovement{SessionID: session.ID, Type: models.CashMovementIn, Amount: 5000, Reason: "Sencillo", CreatedBy: cashier.ID}
+	if err := repo.AddMovement(in); err != nil {
+		t.Fatal(err)
+	}
+
+	totals, err := repo.Close(session.ID, cashier.ID, map[string]int64{models.PaymentMethodEfectivo: 14000}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals[0].Method != models.PaymentMethodEfectivo || totals[0].Expected != 15000 || totals[0].Difference != -1000 {
+		t.Fatalf("unexpected cash total: %+v", totals[0])
+	}
+
+	out := &models.CashMovement{SessionID: session.ID, Type: models.CashMovementOut, Amount: 1000, Reason: "Tarde", CreatedBy: cashier.ID}
+	if err := repo.AddMovement(out); err != ErrCashSessionNotOpen {
+		t.Fatalf("movement on closed session: expected ErrCashSessionNotOpen, got %v", err)
+	}
+	if _, err := repo.Close(session.ID, cashier.ID, map[string]int64{models.PaymentMethodEfectivo: 0}, ""); err != ErrCashSessionNotOpen {
+		t.Fatalf("second close: expected ErrCashSessionNotOpen, got %v", err)
+	}
+}
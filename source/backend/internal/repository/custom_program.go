@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// CustomProgramRepository - Rutinas personalizadas entregadas y su facturación
+type CustomProgramRepository struct {
+	db *sql.DB
+}
+
+func NewCustomProgramRepository(db *sql.DB) *CustomProgramRepository {
+	return &CustomProgramRepository{db: db}
+}
+
+const customProgramColumns = `p.id, p.routine_id, rt.name, p.user_id, u.name, p.coach_id, p.instructor_id, COALESCE(i.name, c.name, ''),
+	p.amount, p.status,
+	COALESCE(p.period, ''), p.ledger_entry_id, p.delivered_at, p.acknowledged_at`
+
+const customProgramJoins = `FROM custom_programs p
+	JOIN routines rt ON rt.id = p.routine_id
+	JOIN users u ON u.id = p.user_id
+	LEFT JOIN users c ON c.id = p.coach_id
+	LEFT JOIN instructors i ON i.id = p.instructor_id`
+
+func scanCustomProgram(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.CustomProgram, error) {
+	p := &models.CustomProgram{}
+	dest := append([]interface{}{&p.ID, &p.RoutineID, &p.RoutineName, &p.UserID, &p.UserName, &p.CoachID, &p.InstructorID, &p.CoachName,
+		&p.Amount, &p.Status, &p.Period, &p.LedgerEntryID, &p.DeliveredAt, &p.AcknowledgedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Deliver registra la entrega de la rutina al miembro; una rutina se entrega (y cobra) una sola vez
+func (r *CustomProgramRepository) Deliver(p *models.CustomProgram) (created bool, err error) {
+	err = r.db.QueryRow(`INSERT INTO custom_programs (routine_id, user_id, coach_id, instructor_id, amount, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (routine_id) DO NOTHING
+		RETURNING id, delivered_at`, p.RoutineID, p.UserID, p.CoachID, p.InstructorID, p.Amount, p.Status).Scan(&p.ID, &p.DeliveredAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *CustomProgramRepository) Get(id int64) (*models.CustomProgram, error) {
+	return scanCustomProgram(r.db.QueryRow(`SELECT `+customProgramColumns+` `+customProgramJoins+` WHERE p.id = $1`, id))
+}
+
+func (r *CustomProgramRepository) GetByRoutine(routineID int64) (*models.CustomProgram, error) {
+	return scanCustomProgram(r.db.QueryRow(`SELECT `+customProgramColumns+` `+customProgramJoins+` WHERE p.routine_id = $1`, routineID))
+}
+
+// List filtra por miembro y estado (0 / "" = todos), de la entrega más reciente a la más antigua
+func (r *CustomProgramRepository) List(userID int64, status string, limit, offset int) ([]*models.CustomProgram, error) {
+	rows, err := r.db.Query(`SELECT `+customProgramColumns+` `+customProgramJoins+`
+		WHERE ($1 = 0 OR p.user_id = $1) AND ($2 = '' OR p.status = $2)
+		ORDER BY p.delivered_at DESC, p.id DESC LIMIT $3 OFFSET $4`, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.CustomProgram{}
+	for rows.Next() {
+		p, err := scanCustomProgram(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// ListForMember devuelve los programas entregados al miembro con el contenido de la rutina
+func (r *CustomProgramRepository) ListForMember(userID int64) ([]*models.MyCustomProgram, error) {
+	rows, err := r.db.Query(`SELECT `+customProgramColumns+`, rt.content, COALESCE(rt.content_scaled, ''), COALESCE(rt.content_beginner, '')
+		`+customProgramJoins+`
+		WHERE p.user_id = $1 AND p.status <> 'void'
+		ORDER BY p.delivered_at DESC, p.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.MyCustomProgram{}
+	for rows.Next() {
+		mp := &models.MyCustomProgram{}
+		p, err := scanCustomProgram(rows, &mp.Content, &mp.ContentScaled, &mp.ContentBeginner)
+		if err != nil {
+			return nil, err
+		}
+		mp.CustomProgram = *p
+		list = append(list, mp)
+	}
+	return list, rows.Err()
+}
+
+// Acknowledge marca el programa como recibido por el miembro (sql.ErrNoRows si no es suyo)
+func (r *CustomProgramRepository) Acknowledge(id, userID int64) error {
+	return execExpectingRow(r.db, `UPDATE custom_programs SET acknowledged_at = COALESCE(acknowledged_at, NOW())
+		WHERE id = $1 AND user_id = $2 AND status <> 'void'`, id, userID)
+}
+
+// SetStatus cambia el estado de un programa aún no facturado (sql.ErrNoRows si ya se facturó)
+func (r *CustomProgramRepository) SetStatus(id int64, status string, amount int64) error {
+	return execExpectingRow(r.db, `UPDATE custom_programs SET status = $1, amount = $2 WHERE id = $3 AND status <> 'billed'`,
+		status, amount, id)
+}
+
+// SetInstructor reasigna el coach de un programa aún no facturado; los facturados conservan su atribución
+func (r *CustomProgramRepository) SetInstructor(id int64, instructorID *int64) error {
+	return execExpectingRow(r.db, `UPDATE custom_programs SET instructor_id = $1 WHERE id = $2 AND status <> 'billed'`,
+		instructorID, id)
+}
+
+// BillPeriod carga a la cuenta de cada miembro, en un solo movimiento, todos los programas pendientes entregados
+// antes de to: también los de meses ya cerrados que se reactivaron después. Quedan facturados con el movimiento y el período.
+func (r *CustomProgramRepository) BillPeriod(period string, to time.Time, createdBy int64) (*models.BillingRun, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE no admite GROUP BY: se bloquean los pendientes y luego se agrupan
+	if _, err := tx.Exec(`SELECT id FROM custom_programs WHERE status = 'pending' AND delivered_at < $1
+		FOR UPDATE`, to); err != nil {
+		return nil, err
+	}
+
+	type memberTotal struct {
+		userID   int64
+		programs int
+		total    int64
+	}
+	rows, err := tx.Query(`SELECT user_id, COUNT(*), SUM(amount) FROM custom_programs
+		WHERE status = 'pending' AND delivered_at < $1
+		GROUP BY user_id ORDER BY user_id`, to)
+	if err != nil {
+		return nil, err
+	}
+	var totals []memberTotal
+	for rows.Next() {
+		var m memberTotal
+		if err := rows.Scan(&m.userID, &m.programs, &m.total); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		totals = append(totals, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	run := &models.BillingRun{Period: period, Entries: []*models.LedgerEntry{}}
+	for _, m := range totals {
+		e := &models.LedgerEntry{UserID: m.userID, Type: models.LedgerCustomProgram, Amount: -m.total, CreatedBy: &createdBy,
+			Description: fmt.Sprintf("Programación personalizada %s (%d)", period, m.programs)}
+		if m.total > 0 {
+			if err := tx.QueryRow(`INSERT INTO ledger_entries (user_id, type, amount, description, created_by)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+				e.UserID, e.Type, e.Amount, e.Description, e.CreatedBy).Scan(&e.ID, &e.CreatedAt); err != nil {
+				return nil, err
+			}
+		}
+		var entryID *int64
+		if e.ID != 0 {
+			entryID = &e.ID
+			run.Entries = append(run.Entries, e)
+		}
+		if _, err := tx.Exec(`UPDATE custom_programs SET status = 'billed', period = $1, ledger_entry_id = $2
+			WHERE user_id = $3 AND status = 'pending' AND delivered_at < $4`,
+			period, entryID, m.userID, to); err != nil {
+			return nil, err
+		}
+		run.Members++
+		run.Programs += m.programs
+		run.Total += m.total
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RevenueByCoach resume por coach los programas entregados entre dos fechas (to exclusivo). Cada programa cuenta
+// para el coach asignado a la rutina o, si no tiene, para el usuario que la programó.
+func (r *CustomProgramRepository) RevenueByCoach(from, to time.Time) ([]*models.CoachProgrammingRevenue, error) {
+	rows, err := r.db.Query(`SELECT p.instructor_id, CASE WHEN p.instructor_id IS NULL THEN p.coach_id END, COALESCE(i.name, c.name, ''),
+			COUNT(*) FILTER (WHERE p.status <> 'void'),
+			COUNT(DISTINCT p.user_id) FILTER (WHERE p.status <> 'void'),
+			COALESCE(SUM(p.amount) FILTER (WHERE p.status = 'billed'), 0),
+			COALESCE(SUM(p.amount) FILTER (WHERE p.status = 'pending'), 0),
+			COUNT(*) FILTER (WHERE p.status = 'void')
+		FROM custom_programs p
+		LEFT JOIN instructors i ON i.id = p.instructor_id
+		LEFT JOIN users c ON c.id = p.coach_id AND p.instructor_id IS NULL
+		WHERE p.delivered_at >= $1 AND p.delivered_at < $2
+		GROUP BY 1, 2, 3
+		ORDER BY 6 DESC, 7 DESC`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.CoachProgrammingRevenue{}
+	for rows.Next() {
+		c := &models.CoachProgrammingRevenue{}
+		if err := rows.Scan(&c.InstructorID, &c.CoachID, &c.Coach, &c.Programs, &c.Members, &c.Billed, &c.Pending, &c.Voided); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
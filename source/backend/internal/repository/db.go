@@ -555,6 +555,514 @@ func Migrate(db *sql.DB) error {
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 	CREATE INDEX IF NOT EXISTS idx_water_logs_user_date ON water_logs(user_id, logged_at);
+
+	-- Transferencias informadas por el miembro (cola de aprobación)
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS discount_code_id INTEGER REFERENCES discount_codes(id);
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS reviewed_by INTEGER REFERENCES users(id);
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+	CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(status);
+
+	-- Conciliación bancaria: cartolas importadas y sus abonos
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS rut VARCHAR(20);
+	CREATE INDEX IF NOT EXISTS idx_users_rut ON users(rut);
+	CREATE TABLE IF NOT EXISTS bank_statement_imports (
+		id SERIAL PRIMARY KEY,
+		filename VARCHAR(255),
+		format VARCHAR(30) NOT NULL,
+		total_lines INTEGER DEFAULT 0,
+		duplicates INTEGER DEFAULT 0,
+		matched INTEGER DEFAULT 0,
+		created_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS bank_transactions (
+		id SERIAL PRIMARY KEY,
+		import_id INTEGER REFERENCES bank_statement_imports(id) ON DELETE CASCADE,
+		date DATE NOT NULL,
+		amount BIGINT NOT NULL,
+		sender_rut VARCHAR(20),
+		sender_name VARCHAR(255),
+		comment TEXT,
+		fingerprint VARCHAR(64) UNIQUE NOT NULL,
+		status VARCHAR(20) DEFAULT 'unmatched',
+		matched_payment_id INTEGER REFERENCES payments(id) ON DELETE SET NULL,
+		matched_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		match_score INTEGER DEFAULT 0,
+		match_reason VARCHAR(50),
+		resolved_by INTEGER REFERENCES users(id),
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_bank_transactions_status ON bank_transactions(status);
+	ALTER TABLE bank_transactions ADD COLUMN IF NOT EXISTS operation_id VARCHAR(64);
+
+	-- Cuadratura de caja diaria
+	CREATE TABLE IF NOT EXISTS cash_sessions (
+		id SERIAL PRIMARY KEY,
+		status VARCHAR(20) NOT NULL DEFAULT 'open',
+		opening_float BIGINT NOT NULL DEFAULT 0,
+		opened_by INTEGER REFERENCES users(id),
+		opened_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		closed_by INTEGER REFERENCES users(id),
+		closed_at TIMESTAMP,
+		notes TEXT
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_sessions_single_open ON cash_sessions(status) WHERE status = 'open';
+	CREATE TABLE IF NOT EXISTS cash_movements (
+		id SERIAL PRIMARY KEY,
+		session_id INTEGER REFERENCES cash_sessions(id),
+		type VARCHAR(10) NOT NULL,
+		amount BIGINT NOT NULL,
+		reason TEXT NOT NULL,
+		created_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS cash_session_totals (
+		session_id INTEGER REFERENCES cash_sessions(id),
+		method VARCHAR(50) NOT NULL,
+		payments BIGINT NOT NULL DEFAULT 0,
+		sales BIGINT NOT NULL DEFAULT 0,
+		expected BIGINT NOT NULL DEFAULT 0,
+		counted BIGINT NOT NULL DEFAULT 0,
+		difference BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (session_id, method)
+	);
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS cash_session_id INTEGER REFERENCES cash_sessions(id);
+	ALTER TABLE sales ADD COLUMN IF NOT EXISTS cash_session_id INTEGER REFERENCES cash_sessions(id);
+	CREATE INDEX IF NOT EXISTS idx_payments_cash_session ON payments(cash_session_id);
+	CREATE INDEX IF NOT EXISTS idx_sales_cash_session ON sales(cash_session_id);
+
+	-- Cuenta del miembro: saldo derivado de movimientos, gift cards
+	CREATE TABLE IF NOT EXISTS gift_cards (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(20) UNIQUE NOT NULL,
+		amount BIGINT NOT NULL,
+		sale_id INTEGER REFERENCES sales(id),
+		redeemed_by INTEGER REFERENCES users(id),
+		redeemed_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS ledger_entries (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		type VARCHAR(30) NOT NULL,
+		amount BIGINT NOT NULL DEFAULT 0,
+		classes INTEGER NOT NULL DEFAULT 0,
+		description TEXT,
+		payment_method VARCHAR(50),
+		sale_id INTEGER REFERENCES sales(id),
+		gift_card_id INTEGER REFERENCES gift_cards(id),
+		cash_session_id INTEGER REFERENCES cash_sessions(id),
+		created_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_ledger_entries_user ON ledger_entries(user_id, created_at);
+	ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS gift_card BOOLEAN DEFAULT false;
+
+	-- Documentos tributarios electrónicos (boleta/factura)
+	CREATE TABLE IF NOT EXISTS tax_cafs (
+		id SERIAL PRIMARY KEY,
+		doc_type INTEGER NOT NULL,
+		folio_from BIGINT NOT NULL,
+		folio_to BIGINT NOT NULL,
+		next_folio BIGINT NOT NULL,
+		authorized_at VARCHAR(20),
+		caf_xml TEXT NOT NULL,
+		private_key TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (doc_type, folio_from)
+	);
+	CREATE TABLE IF NOT EXISTS tax_documents (
+		id SERIAL PRIMARY KEY,
+		doc_type INTEGER NOT NULL,
+		folio BIGINT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'issued',
+		source_type VARCHAR(20) NOT NULL,
+		source_id INTEGER NOT NULL,
+		user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		receptor JSONB,
+		items JSONB,
+		net_amount BIGINT NOT NULL DEFAULT 0,
+		iva BIGINT NOT NULL DEFAULT 0,
+		exempt_amount BIGINT NOT NULL DEFAULT 0,
+		total BIGINT NOT NULL,
+		issued_at TIMESTAMP NOT NULL,
+		xml TEXT NOT NULL,
+		ted TEXT NOT NULL,
+		track_id VARCHAR(100),
+		send_error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (doc_type, folio),
+		UNIQUE (source_type, source_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_tax_documents_user ON tax_documents(user_id);
+	CREATE TABLE IF NOT EXISTS billing_profiles (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		rut VARCHAR(20),
+		business_name VARCHAR(255),
+		activity VARCHAR(255),
+		address VARCHAR(255),
+		commune VARCHAR(100),
+		email VARCHAR(255),
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS tax_document_type INTEGER DEFAULT 0;
+
+	-- Verificación de email y tokens de un solo uso (reset de contraseña, verificación)
+	-- Los usuarios existentes quedan verificados al agregar la columna; los nuevos parten sin verificar
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN DEFAULT true;
+	ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT false;
+	CREATE TABLE IF NOT EXISTS auth_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		purpose VARCHAR(30) NOT NULL,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_auth_tokens_user ON auth_tokens(user_id, purpose);
+
+	-- Sesiones: cada login abre una familia de refresh tokens que rotan; token_version invalida access tokens
+	CREATE TABLE IF NOT EXISTS auth_sessions (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		device VARCHAR(100),
+		user_agent TEXT,
+		ip VARCHAR(64),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		revoked_at TIMESTAMP,
+		revoked_reason VARCHAR(50)
+	);
+	CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id);
+	ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id INTEGER REFERENCES auth_sessions(id) ON DELETE CASCADE;
+	ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+	-- Tokens antiguos sin sesión (guardados en texto plano) quedan inválidos
+	DELETE FROM refresh_tokens WHERE session_id IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER DEFAULT 0;
+
+	-- Roles personalizados del staff (los integrados viven en el código)
+	CREATE TABLE IF NOT EXISTS roles (
+		name VARCHAR(40) PRIMARY KEY,
+		label VARCHAR(100) NOT NULL,
+		description TEXT,
+		permissions JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(40);
+
+	-- Portal del coach: instructor vinculado a una cuenta y notas/lesiones de miembros
+	ALTER TABLE instructors ADD COLUMN IF NOT EXISTS user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE SET NULL;
+	CREATE TABLE IF NOT EXISTS member_notes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		kind VARCHAR(20) NOT NULL DEFAULT 'note',
+		body TEXT NOT NULL,
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_member_notes_user ON member_notes(user_id);
+
+	-- Log de auditoría: solo inserción (un trigger rechaza UPDATE); el borrado es solo por retención
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id INTEGER,
+		actor_role VARCHAR(40),
+		action VARCHAR(50) NOT NULL,
+		entity_type VARCHAR(40) NOT NULL,
+		entity_id VARCHAR(64),
+		method VARCHAR(10),
+		path TEXT,
+		before_data JSONB,
+		after_data JSONB,
+		changes JSONB,
+		ip VARCHAR(64),
+		user_agent TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+	-- Única excepción: el borrado de datos personales (derecho al olvido) quita los datos del miembro de las fotos,
+	-- habilitado con SET LOCAL boxmagic.audit_redaction dentro de la transacción de anonimización
+	CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+	BEGIN
+		IF current_setting('boxmagic.audit_redaction', true) = 'on'
+			AND NEW.id = OLD.id AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id AND NEW.action = OLD.action
+			AND NEW.entity_type = OLD.entity_type AND NEW.entity_id IS NOT DISTINCT FROM OLD.entity_id
+			AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at THEN
+			RETURN NEW;
+		END IF;
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+	CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+	-- Rate limiting compartido entre réplicas y bloqueo progresivo de cuentas por intentos fallidos
+	CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+		key VARCHAR(255) PRIMARY KEY,
+		count INTEGER NOT NULL DEFAULT 0,
+		reset_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS login_lockouts (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		failures INTEGER NOT NULL DEFAULT 0,
+		lock_count INTEGER NOT NULL DEFAULT 0,
+		locked_until TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Autenticación en dos pasos (TOTP), códigos de recuperación y configuración general
+	CREATE TABLE IF NOT EXISTS user_totp (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		enabled_at TIMESTAMP,
+		last_step BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS user_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON user_recovery_codes(user_id);
+	CREATE TABLE IF NOT EXISTS app_settings (
+		key VARCHAR(100) PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Sesiones con "recordar este dispositivo" (refresh token de larga duración)
+	ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS remember BOOLEAN DEFAULT false;
+
+	-- Documentos de exención versionados y evidencia de la firma
+	CREATE TABLE IF NOT EXISTS waiver_documents (
+		id SERIAL PRIMARY KEY,
+		type VARCHAR(50) NOT NULL,
+		version INTEGER NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		body TEXT NOT NULL,
+		required BOOLEAN NOT NULL DEFAULT true,
+		applies_to VARCHAR(10) NOT NULL DEFAULT 'all',
+		active BOOLEAN NOT NULL DEFAULT true,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(type, version)
+	);
+	ALTER TABLE authorizations ADD COLUMN IF NOT EXISTS waiver_document_id INTEGER REFERENCES waiver_documents(id) ON DELETE RESTRICT;
+	ALTER TABLE authorizations ADD COLUMN IF NOT EXISTS signer_name VARCHAR(255);
+	ALTER TABLE authorizations ADD COLUMN IF NOT EXISTS ip VARCHAR(64);
+	ALTER TABLE authorizations ADD COLUMN IF NOT EXISTS user_agent TEXT;
+	ALTER TABLE authorizations ADD COLUMN IF NOT EXISTS document_hash VARCHAR(64);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_authorizations_user_document ON authorizations(user_id, waiver_document_id) WHERE waiver_document_id IS NOT NULL;
+
+	-- Eliminación de cuentas: se anonimiza al usuario y se conservan los registros financieros
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP;
+	CREATE TABLE IF NOT EXISTS erasure_requests (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		reason TEXT,
+		requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		reviewed_at TIMESTAMP,
+		review_notes TEXT,
+		scheduled_for TIMESTAMP,
+		completed_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_erasure_requests_open ON erasure_requests(user_id) WHERE status IN ('pending','approved');
+	CREATE INDEX IF NOT EXISTS idx_erasure_requests_status ON erasure_requests(status, scheduled_for);
+
+	-- Pagos, suscripciones y cuenta corriente ya no se borran en cascada con el usuario
+	DO $$
+	DECLARE
+		t TEXT;
+	BEGIN
+		FOREACH t IN ARRAY ARRAY['payments', 'subscriptions', 'ledger_entries'] LOOP
+			IF EXISTS (
+				SELECT 1 FROM pg_constraint
+				WHERE conrelid = t::regclass AND conname = t || '_user_id_fkey' AND confdeltype = 'c'
+			) THEN
+				EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I, ADD CONSTRAINT %I FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT',
+					t, t || '_user_id_fkey', t || '_user_id_fkey');
+			END IF;
+		END LOOP;
+	END $$;
+
+	-- Impersonación de miembros por el staff (soporte)
+	CREATE TABLE IF NOT EXISTS impersonation_sessions (
+		id SERIAL PRIMARY KEY,
+		admin_id INTEGER NOT NULL REFERENCES users(id),
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		reason TEXT NOT NULL,
+		write_access BOOLEAN NOT NULL DEFAULT false,
+		ip VARCHAR(64),
+		user_agent TEXT,
+		started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		ended_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_user ON impersonation_sessions(user_id, started_at DESC);
+	ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS on_behalf_of INTEGER;
+
+	-- Rutinas estructuradas: bloques con líneas que referencian la biblioteca de movimientos
+	CREATE TABLE IF NOT EXISTS routine_blocks (
+		id SERIAL PRIMARY KEY,
+		routine_id INTEGER NOT NULL REFERENCES routines(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		title VARCHAR(255),
+		format VARCHAR(20),
+		minutes INTEGER NOT NULL DEFAULT 0,
+		rounds INTEGER NOT NULL DEFAULT 0,
+		sets INTEGER NOT NULL DEFAULT 0,
+		interval_seconds INTEGER NOT NULL DEFAULT 0,
+		notes TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_routine_blocks_routine ON routine_blocks(routine_id, position);
+	CREATE TABLE IF NOT EXISTS routine_block_lines (
+		id SERIAL PRIMARY KEY,
+		block_id INTEGER NOT NULL REFERENCES routine_blocks(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		movement_id INTEGER REFERENCES movements(id) ON DELETE SET NULL,
+		name VARCHAR(255) NOT NULL,
+		rx JSONB NOT NULL DEFAULT '{}',
+		scaled JSONB,
+		beginner JSONB,
+		notes TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_routine_block_lines_block ON routine_block_lines(block_id, position);
+	CREATE INDEX IF NOT EXISTS idx_routine_block_lines_movement ON routine_block_lines(movement_id);
+
+	-- Registro de fuerza por movimiento (1RM estimado y rep-max)
+	CREATE TABLE IF NOT EXISTS lift_logs (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		movement_id INTEGER NOT NULL REFERENCES movements(id),
+		routine_id INTEGER REFERENCES routines(id) ON DELETE SET NULL,
+		sets INTEGER NOT NULL DEFAULT 1,
+		reps INTEGER NOT NULL,
+		load_kg NUMERIC(6,2) NOT NULL,
+		rpe NUMERIC(3,1),
+		notes TEXT,
+		logged_on DATE NOT NULL DEFAULT CURRENT_DATE,
+		estimated_1rm NUMERIC(6,2) NOT NULL,
+		is_pr BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_lift_logs_user_movement ON lift_logs(user_id, movement_id, logged_on DESC);
+
+	-- Catálogo de benchmarks; las rutinas que los programan se vinculan para unificar el historial
+	CREATE TABLE IF NOT EXISTS benchmarks (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL UNIQUE,
+		category VARCHAR(20) NOT NULL DEFAULT 'other',
+		score_type VARCHAR(20) NOT NULL,
+		description TEXT,
+		content TEXT NOT NULL,
+		time_cap INTEGER NOT NULL DEFAULT 0,
+		active BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE routines ADD COLUMN IF NOT EXISTS benchmark_id INTEGER REFERENCES benchmarks(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_routines_benchmark ON routines(benchmark_id);
+	-- Tracks de programación: calendario de rutinas que se asigna solo a los horarios de sus clases
+	CREATE TABLE IF NOT EXISTS programming_tracks (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL UNIQUE,
+		description TEXT,
+		publish_days_before INTEGER NOT NULL DEFAULT 1,
+		publish_time VARCHAR(5) NOT NULL DEFAULT '20:00',
+		active BOOLEAN NOT NULL DEFAULT true,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS programming_track_days (
+		id SERIAL PRIMARY KEY,
+		track_id INTEGER NOT NULL REFERENCES programming_tracks(id) ON DELETE CASCADE,
+		date DATE NOT NULL,
+		routine_id INTEGER NOT NULL REFERENCES routines(id),
+		notes TEXT,
+		publish_at TIMESTAMP,
+		UNIQUE (track_id, date)
+	);
+	CREATE TABLE IF NOT EXISTS programming_track_targets (
+		id SERIAL PRIMARY KEY,
+		track_id INTEGER NOT NULL REFERENCES programming_tracks(id) ON DELETE CASCADE,
+		class_id INTEGER UNIQUE REFERENCES classes(id) ON DELETE CASCADE,
+		discipline_id INTEGER UNIQUE REFERENCES disciplines(id) ON DELETE CASCADE,
+		CHECK ((class_id IS NULL) <> (discipline_id IS NULL))
+	);
+	-- track_day_id NULL = asignación manual, que el track nunca pisa
+	ALTER TABLE schedule_routines ADD COLUMN IF NOT EXISTS track_day_id INTEGER REFERENCES programming_track_days(id) ON DELETE CASCADE;
+	ALTER TABLE schedule_routines ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+	-- Versiones inmutables de las rutinas; horarios y resultados apuntan a la versión realizada
+	CREATE TABLE IF NOT EXISTS routine_versions (
+		id SERIAL PRIMARY KEY,
+		routine_id INTEGER NOT NULL REFERENCES routines(id) ON DELETE CASCADE,
+		version INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		type VARCHAR(50) NOT NULL DEFAULT 'wod',
+		content TEXT NOT NULL,
+		content_scaled TEXT NOT NULL DEFAULT '',
+		content_beginner TEXT NOT NULL DEFAULT '',
+		duration INTEGER NOT NULL DEFAULT 0,
+		difficulty VARCHAR(50) NOT NULL DEFAULT '',
+		benchmark_id INTEGER REFERENCES benchmarks(id) ON DELETE SET NULL,
+		blocks JSONB,
+		rolled_back_from INTEGER,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (routine_id, version)
+	);
+	ALTER TABLE routines ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE schedule_routines ADD COLUMN IF NOT EXISTS routine_version_id INTEGER REFERENCES routine_versions(id);
+	ALTER TABLE user_routine_results ADD COLUMN IF NOT EXISTS routine_version_id INTEGER REFERENCES routine_versions(id);
+	CREATE INDEX IF NOT EXISTS idx_user_routine_results_version ON user_routine_results(routine_version_id);
+	-- Versión inicial de las rutinas que aún no tienen historial, y vínculo de lo ya asignado o registrado
+	INSERT INTO routine_versions (routine_id, version, name, description, type, content, content_scaled, content_beginner,
+		duration, difficulty, benchmark_id, created_by, created_at)
+	SELECT r.id, r.version, r.name, COALESCE(r.description, ''), COALESCE(r.type, 'wod'), r.content, COALESCE(r.content_scaled, ''),
+		COALESCE(r.content_beginner, ''), COALESCE(r.duration, 0), COALESCE(r.difficulty, ''), r.benchmark_id, r.created_by,
+		COALESCE(r.updated_at, r.created_at, CURRENT_TIMESTAMP)
+	FROM routines r
+	WHERE NOT EXISTS (SELECT 1 FROM routine_versions v WHERE v.routine_id = r.id);
+	UPDATE schedule_routines sr SET routine_version_id = v.id FROM routine_versions v
+	WHERE sr.routine_version_id IS NULL AND v.routine_id = sr.routine_id
+	AND v.version = (SELECT MAX(version) FROM routine_versions WHERE routine_id = sr.routine_id);
+	UPDATE user_routine_results urr SET routine_version_id = v.id FROM routine_versions v
+	WHERE urr.routine_version_id IS NULL AND v.routine_id = urr.routine_id
+	AND v.version = (SELECT MAX(version) FROM routine_versions WHERE routine_id = urr.routine_id);
+	-- Programación personalizada entregada a cada miembro y su cobro mensual vía cuenta corriente
+	CREATE TABLE IF NOT EXISTS custom_programs (
+		id SERIAL PRIMARY KEY,
+		routine_id INTEGER NOT NULL UNIQUE REFERENCES routines(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		coach_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		amount BIGINT NOT NULL DEFAULT 0,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		period VARCHAR(7),
+		ledger_entry_id INTEGER REFERENCES ledger_entries(id) ON DELETE SET NULL,
+		delivered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		acknowledged_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_custom_programs_user ON custom_programs(user_id, delivered_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_custom_programs_pending ON custom_programs(status, delivered_at);
+	-- Los ingresos se atribuyen al coach asignado a la rutina; sin coach, a quien la programó
+	ALTER TABLE custom_programs ADD COLUMN IF NOT EXISTS instructor_id INTEGER REFERENCES instructors(id) ON DELETE SET NULL;
 	`
 
 	_, err := db.Exec(query)
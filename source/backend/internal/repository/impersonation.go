@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// ImpersonationRepository - Sesiones de soporte en que un admin actúa como un miembro
+type ImpersonationRepository struct {
+	db *sql.DB
+}
+
+func NewImpersonationRepository(db *sql.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+const impersonationColumns = `i.id, i.admin_id, a.name, i.user_id, u.name, i.reason, i.write_access, COALESCE(i.ip, ''),
+	COALESCE(i.user_agent, ''), i.started_at, i.expires_at, i.ended_at`
+
+const impersonationFrom = ` FROM impersonation_sessions i JOIN users a ON a.id = i.admin_id JOIN users u ON u.id = i.user_id`
+
+func scanImpersonation(row interface{ Scan(...interface{}) error }) (*models.Impersonation, error) {
+	i := &models.Impersonation{}
+	err := row.Scan(&i.ID, &i.AdminID, &i.AdminName, &i.UserID, &i.UserName, &i.Reason, &i.Write, &i.IP,
+		&i.UserAgent, &i.StartedAt, &i.ExpiresAt, &i.EndedAt)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *ImpersonationRepository) Create(i *models.Impersonation) error {
+	return r.db.QueryRow(`INSERT INTO impersonation_sessions (admin_id, user_id, reason, write_access, ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7) RETURNING id, started_at`,
+		i.AdminID, i.UserID, i.Reason, i.Write, i.IP, i.UserAgent, i.ExpiresAt).Scan(&i.ID, &i.StartedAt)
+}
+
+func (r *ImpersonationRepository) Get(id int64) (*models.Impersonation, error) {
+	return scanImpersonation(r.db.QueryRow(`SELECT `+impersonationColumns+impersonationFrom+` WHERE i.id = $1`, id))
+}
+
+// List devuelve las impersonaciones más recientes, opcionalmente de un miembro (userID 0 = todas)
+func (r *ImpersonationRepository) List(userID int64, limit int) ([]*models.Impersonation, error) {
+	rows, err := r.db.Query(`SELECT `+impersonationColumns+impersonationFrom+`
+		WHERE ($1 = 0 OR i.user_id = $1) ORDER BY i.started_at DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.Impersonation
+	for rows.Next() {
+		i, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
+
+// End cierra una sesión abierta del admin. Devuelve sql.ErrNoRows si ya terminó o es de otro admin.
+func (r *ImpersonationRepository) End(id, adminID int64) error {
+	return execExpectingRow(r.db, `UPDATE impersonation_sessions SET ended_at = NOW()
+		WHERE id = $1 AND admin_id = $2 AND ended_at IS NULL`, id, adminID)
+}
+
+// IsActive indica si la sesión sigue abierta y sin vencer para ese admin y miembro, y si el admin sigue activo
+func (r *ImpersonationRepository) IsActive(id, adminID, userID int64, now time.Time) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM impersonation_sessions
+		WHERE id = $1 AND admin_id = $2 AND user_id = $3 AND ended_at IS NULL AND expires_at > $4
+			AND admin_id IN (SELECT id FROM users WHERE active))`,
+		id, adminID, userID, now).Scan(&active)
+	return active, err
+}
@@ -16,21 +16,21 @@ func NewInstructorRepository(db *sql.DB) *InstructorRepository {
 }
 
 func (r *InstructorRepository) Create(instructor *models.Instructor) error {
-	query := `INSERT INTO instructors (name, email, phone, specialty, bio, active)
-			  VALUES ($1, $2, $3, $4, $5, true)
+	query := `INSERT INTO instructors (name, email, phone, specialty, bio, active, user_id)
+			  VALUES ($1, $2, $3, $4, $5, true, $6)
 			  RETURNING id, created_at, updated_at`
 	return r.db.QueryRow(query, instructor.Name, instructor.Email, instructor.Phone,
-		instructor.Specialty, instructor.Bio).Scan(&instructor.ID, &instructor.CreatedAt, &instructor.UpdatedAt)
+		instructor.Specialty, instructor.Bio, instructor.UserID).Scan(&instructor.ID, &instructor.CreatedAt, &instructor.UpdatedAt)
 }
 
 func (r *InstructorRepository) GetByID(id int64) (*models.Instructor, error) {
 	instructor := &models.Instructor{}
-	query := `SELECT id, name, email, phone, specialty, bio, active, created_at, updated_at
+	query := `SELECT id, name, email, phone, specialty, bio, active, user_id, created_at, updated_at
 			  FROM instructors WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
 		&instructor.ID, &instructor.Name, &instructor.Email, &instructor.Phone,
-		&instructor.Specialty, &instructor.Bio, &instructor.Active,
+		&instructor.Specialty, &instructor.Bio, &instructor.Active, &instructor.UserID,
 		&instructor.CreatedAt, &instructor.UpdatedAt,
 	)
 	if err != nil {
@@ -40,7 +40,7 @@ func (r *InstructorRepository) GetByID(id int64) (*models.Instructor, error) {
 }
 
 func (r *InstructorRepository) List(activeOnly bool) ([]*models.Instructor, error) {
-	query := `SELECT id, name, email, phone, specialty, bio, active, created_at, updated_at
+	query := `SELECT id, name, email, phone, specialty, bio, active, user_id, created_at, updated_at
 			  FROM instructors`
 	if activeOnly {
 		query += " WHERE active = true"
@@ -58,7 +58,7 @@ func (r *InstructorRepository) List(activeOnly bool) ([]*models.Instructor, erro
 		instructor := &models.Instructor{}
 		if err := rows.Scan(
 			&instructor.ID, &instructor.Name, &instructor.Email, &instructor.Phone,
-			&instructor.Specialty, &instructor.Bio, &instructor.Active,
+			&instructor.Specialty, &instructor.Bio, &instructor.Active, &instructor.UserID,
 			&instructor.CreatedAt, &instructor.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -69,10 +69,10 @@ func (r *InstructorRepository) List(activeOnly bool) ([]*models.Instructor, erro
 }
 
 func (r *InstructorRepository) Update(instructor *models.Instructor) error {
-	query := `UPDATE instructors SET name=$1, email=$2, phone=$3, specialty=$4, bio=$5, active=$6, updated_at=$7 WHERE id=$8`
+	query := `UPDATE instructors SET name=$1, email=$2, phone=$3, specialty=$4, bio=$5, active=$6, updated_at=$7, user_id=$8 WHERE id=$9`
 	instructor.UpdatedAt = time.Now()
 	_, err := r.db.Exec(query, instructor.Name, instructor.Email, instructor.Phone,
-		instructor.Specialty, instructor.Bio, instructor.Active, instructor.UpdatedAt, instructor.ID)
+		instructor.Specialty, instructor.Bio, instructor.Active, instructor.UpdatedAt, instructor.UserID, instructor.ID)
 	return err
 }
 
@@ -135,3 +135,85 @@ func (r *InstructorRepository) GetClassInstructors(classID int64) ([]*models.Ins
 	}
 	return instructors, nil
 }
+
+// Portal del coach
+
+// GetByUserID devuelve el instructor vinculado a la cuenta
+func (r *InstructorRepository) GetByUserID(userID int64) (*models.Instructor, error) {
+	instructor := &models.Instructor{}
+	query := `SELECT id, name, email, phone, specialty, bio, active, user_id, created_at, updated_at
+			  FROM instructors WHERE user_id = $1 AND active = true`
+
+	err := r.db.QueryRow(query, userID).Scan(
+		&instructor.ID, &instructor.Name, &instructor.Email, &instructor.Phone,
+		&instructor.Specialty, &instructor.Bio, &instructor.Active, &instructor.UserID,
+		&instructor.CreatedAt, &instructor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return instructor, nil
+}
+
+// ListSchedules lista las clases que dicta el instructor entre dos fechas, con check-ins y rutina asignada
+func (r *InstructorRepository) ListSchedules(instructorID int64, from, to time.Time) ([]*models.CoachSchedule, error) {
+	query := `SELECT cs.id, cs.class_id, cs.date, cs.capacity, cs.booked, cs.cancelled, cs.created_at,
+			         c.name, d.name, c.start_time, c.end_time,
+			         (SELECT COUNT(*) FROM bookings b WHERE b.class_schedule_id = cs.id AND b.checked_in_at IS NOT NULL),
+			         sr.routine_id, COALESCE(rt.name,'')
+			  FROM class_schedules cs
+			  JOIN classes c ON cs.class_id = c.id
+			  JOIN disciplines d ON c.discipline_id = d.id
+			  JOIN class_instructors ci ON ci.class_id = c.id
+			  LEFT JOIN schedule_routines sr ON sr.class_schedule_id = cs.id
+			  LEFT JOIN routines rt ON rt.id = sr.routine_id
+			  WHERE ci.instructor_id = $1 AND cs.date >= $2 AND cs.date <= $3
+			  ORDER BY cs.date, c.start_time`
+
+	rows, err := r.db.Query(query, instructorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*models.CoachSchedule
+	for rows.Next() {
+		s := &models.CoachSchedule{}
+		if err := rows.Scan(
+			&s.ID, &s.ClassID, &s.Date, &s.Capacity, &s.Booked, &s.Cancelled, &s.CreatedAt,
+			&s.ClassName, &s.DisciplineName, &s.StartTime, &s.EndTime,
+			&s.CheckedIn, &s.RoutineID, &s.RoutineName,
+		); err != nil {
+			return nil, err
+		}
+		s.Available = s.Capacity - s.Booked
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// TeachesSchedule indica si el instructor está asignado a la clase del horario
+func (r *InstructorRepository) TeachesSchedule(instructorID, scheduleID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM class_schedules cs
+		JOIN class_instructors ci ON ci.class_id = cs.class_id
+		WHERE cs.id = $1 AND ci.instructor_id = $2)`, scheduleID, instructorID).Scan(&ok)
+	return ok, err
+}
+
+// TeachesMember indica si el miembro tiene reservas en clases del instructor (últimos 30 días o futuras)
+func (r *InstructorRepository) TeachesMember(instructorID, userID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM bookings b
+		JOIN class_schedules cs ON cs.id = b.class_schedule_id
+		JOIN class_instructors ci ON ci.class_id = cs.class_id
+		WHERE b.user_id = $1 AND ci.instructor_id = $2 AND cs.date >= CURRENT_DATE - 30)`, userID, instructorID).Scan(&ok)
+	return ok, err
+}
+
+// BookingSchedule devuelve el horario al que pertenece una reserva
+func (r *InstructorRepository) BookingSchedule(bookingID int64) (int64, error) {
+	var scheduleID int64
+	err := r.db.QueryRow(`SELECT class_schedule_id FROM bookings WHERE id = $1`, bookingID).Scan(&scheduleID)
+	return scheduleID, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"boxmagic/internal/models"
@@ -14,11 +15,24 @@ type UserRepo interface {
 	Update(user *models.User) error
 	Delete(id int64) error
 	List(limit, offset int) ([]*models.User, error)
-	SaveRefreshToken(userID int64, token string, expiresAt time.Time) error
-	GetRefreshToken(token string) (int64, error)
-	DeleteRefreshToken(token string) error
 	AddInvitationClasses(userID int64, count int) error
 	UseInvitationClass(userID int64) (bool, error)
+	UpdatePassword(userID int64, passwordHash string) error
+	SetEmailVerified(userID int64) error
+	CreateAuthToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error
+	ConsumeAuthToken(purpose, tokenHash string) (int64, error)
+}
+
+type SessionRepo interface {
+	CreateSession(s *models.Session) error
+	SaveRefreshToken(sessionID, userID int64, tokenHash string, expiresAt time.Time) error
+	GetRefreshToken(tokenHash string) (*models.RefreshTokenRecord, error)
+	MarkRefreshTokenUsed(id int64) (bool, error)
+	TouchSession(id int64, client models.ClientInfo) error
+	RevokeSession(userID, sessionID int64, reason string) error
+	RevokeAllSessions(userID int64, reason string) error
+	ListSessions(userID int64) ([]*models.Session, error)
+	AccessState(userID, sessionID int64) (tokenVersion int, active, sessionValid bool, err error)
 }
 
 type PlanRepo interface {
@@ -35,6 +49,10 @@ type PaymentRepo interface {
 	UpdateStatus(id int64, status models.PaymentStatus) error
 	ListByUser(userID int64, limit, offset int) ([]*models.PaymentWithDetails, error)
 	ListAll(limit, offset int) ([]*models.PaymentWithDetails, error)
+	ListPending(limit, offset int) ([]*models.PaymentWithDetails, error)
+	Review(id int64, status models.PaymentStatus, reviewedBy int64, reason string) error
+	CompleteWithSubscription(id, reviewedBy int64, sub *models.Subscription) error
+	ExpirePendingPayments() (int64, error)
 	CreateSubscription(sub *models.Subscription) error
 	GetActiveSubscription(userID int64) (*models.SubscriptionWithPlan, error)
 	IncrementClassesUsed(subscriptionID int64) error
@@ -52,6 +70,11 @@ type InstructorRepo interface {
 	Delete(id int64) error
 	AssignToClass(classID int64, instructorIDs []int64) error
 	GetClassInstructors(classID int64) ([]*models.Instructor, error)
+	GetByUserID(userID int64) (*models.Instructor, error)
+	ListSchedules(instructorID int64, from, to time.Time) ([]*models.CoachSchedule, error)
+	TeachesSchedule(instructorID, scheduleID int64) (bool, error)
+	TeachesMember(instructorID, userID int64) (bool, error)
+	BookingSchedule(bookingID int64) (int64, error)
 }
 
 type ClassRepo interface {
@@ -86,7 +109,8 @@ type ClassRepo interface {
 type RoutineRepo interface {
 	Create(routine *models.Routine) error
 	GetByID(id int64) (*models.RoutineWithCreator, error)
-	List(routineType string, custom *bool, limit, offset int) ([]*models.RoutineWithCreator, error)
+	List(routineType string, custom *bool, publishedOnly bool, limit, offset int) ([]*models.RoutineWithCreator, error)
+	ReleaseAt(routineID int64) (*time.Time, error)
 	ListCustom(targetUserID *int64) ([]*models.RoutineWithCreator, error)
 	Update(routine *models.Routine) error
 	Delete(id int64) error
@@ -101,6 +125,11 @@ type RoutineRepo interface {
 	DeleteResult(resultID int64, userID int64) error
 	GetUserPRs(userID int64) ([]*models.UserResultWithDetails, error)
 	GetLeaderboard(scheduleID int64) ([]*models.LeaderboardEntry, error)
+	GetBlocks(routineID int64) ([]*models.WorkoutBlock, error)
+	ListByMovement(movementID int64, publishedOnly bool, limit, offset int) ([]*models.RoutineWithCreator, error)
+	SaveRevision(routine *models.Routine, replaceBlocks bool, createdBy int64, rolledBackFrom *int) (*models.RoutineVersion, bool, error)
+	ListVersions(routineID int64) ([]*models.RoutineVersion, error)
+	GetVersion(routineID int64, version int) (*models.RoutineVersion, error)
 }
 
 type DiscountCodeRepo interface {
@@ -137,3 +166,48 @@ type StatsRepo interface {
 	GetMonthlyReport(month string) (*models.MonthlyReport, error)
 	GetRetentionAlerts(inactiveDays, limit int) ([]*models.RetentionAlert, error)
 }
+
+type CashRepo interface {
+	Open(session *models.CashSession) error
+	GetByID(id int64) (*models.CashSession, error)
+	GetOpen() (*models.CashSession, error)
+	List(from, to *time.Time, limit, offset int) ([]*models.CashSession, error)
+	AddMovement(m *models.CashMovement) error
+	ListMovements(sessionID int64) ([]models.CashMovement, error)
+	Sums(sessionID int64) (models.CashMethodSums, error)
+	Close(sessionID, closedBy int64, counted map[string]int64, notes string) ([]models.CashSessionTotal, error)
+	GetTotals(sessionID int64) ([]models.CashSessionTotal, error)
+}
+
+type LedgerRepo interface {
+	AddEntry(e *models.LedgerEntry) error
+	Balance(userID int64) (int64, error)
+	ListEntries(userID int64, limit, offset int) ([]*models.LedgerEntry, error)
+	ListBalances(debtOnly bool) ([]*models.MemberBalance, error)
+	CreateGiftCard(gc *models.GiftCard) error
+	RedeemGiftCard(code string, userID int64, createdBy int64) (*models.LedgerEntry, error)
+	ListGiftCards(limit, offset int) ([]*models.GiftCard, error)
+}
+
+type SettingsRepo interface {
+	Get(key string) (string, error)
+	Set(key, value string, updatedBy int64) error
+}
+
+type PrivacyRepo interface {
+	Export(userID int64) (map[string]json.RawMessage, error)
+	PhotoURLs(userID int64) ([]string, error)
+	CreateErasure(e *models.ErasureRequest) error
+	GetErasure(id int64) (*models.ErasureRequest, error)
+	OpenErasure(userID int64) (*models.ErasureRequest, error)
+	Review(id int64, status string, reviewedBy int64, notes string, scheduledFor *time.Time) error
+	Cancel(userID int64) error
+	DueErasures(now time.Time) ([]*models.ErasureRequest, error)
+	Anonymize(requestID, userID int64) ([]string, error)
+}
+
+type AuditRepo interface {
+	Snapshot(entityType string, id int64) (json.RawMessage, error)
+	Insert(e *models.AuditEntry) error
+	Purge(before time.Time) (int64, error)
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"boxmagic/internal/models"
+)
+
+var (
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrGiftCardRedeemed = errors.New("gift card already redeemed")
+)
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// AddEntry registra un movimiento. Los abonos (payment) quedan asociados a la caja abierta para la cuadratura.
+func (r *LedgerRepository) AddEntry(e *models.LedgerEntry) error {
+	query := `INSERT INTO ledger_entries (user_id, type, amount, classes, description, payment_method, sale_id, gift_card_id, created_by, cash_session_id)
+			  VALUES ($1, $2, $3, $4, $5, NULLIF($6,''), $7, $8, $9,
+				CASE WHEN $2 = 'payment' THEN (SELECT id FROM cash_sessions WHERE status = 'open') END)
+			  RETURNING id, created_at`
+	return r.db.QueryRow(query, e.UserID, e.Type, e.Amount, e.Classes, e.Description, e.PaymentMethod, e.SaleID, e.GiftCardID, e.CreatedBy).
+		Scan(&e.ID, &e.CreatedAt)
+}
+
+// Balance - Saldo del miembro: suma de todos sus movimientos
+func (r *LedgerRepository) Balance(userID int64) (int64, error) {
+	var balance int64
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE user_id = $1`, userID).Scan(&balance)
+	return balance, err
+}
+
+func (r *LedgerRepository) ListEntries(userID int64, limit, offset int) ([]*models.LedgerEntry, error) {
+	rows, err := r.db.Query(`SELECT id, user_id, type, amount, classes, COALESCE(description,''), COALESCE(payment_method,''),
+		sale_id, gift_card_id, created_by, created_at
+		FROM ledger_entries WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.LedgerEntry
+	for rows.Next() {
+		e := &models.LedgerEntry{}
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Amount, &e.Classes, &e.Description, &e.PaymentMethod,
+			&e.SaleID, &e.GiftCardID, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ListBalances devuelve los miembros con saldo distinto de cero; debtOnly filtra las cuentas por cobrar
+func (r *LedgerRepository) ListBalances(debtOnly bool) ([]*models.MemberBalance, error) {
+	query := `SELECT u.id, u.name, u.email, SUM(l.amount) AS balance
+			  FROM ledger_entries l JOIN users u ON l.user_id = u.id
+			  GROUP BY u.id, u.name, u.email`
+	if debtOnly {
+		query += ` HAVING SUM(l.amount) < 0 ORDER BY balance ASC`
+	} else {
+		query += ` HAVING SUM(l.amount) <> 0 ORDER BY balance ASC`
+	}
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []*models.MemberBalance
+	for rows.Next() {
+		b := &models.MemberBalance{}
+		if err := rows.Scan(&b.UserID, &b.UserName, &b.UserEmail, &b.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, nil
+}
+
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateGiftCardCode genera códigos tipo GC-XXXX-XXXX (sin caracteres ambiguos como 0/O, 1/I)
+func generateGiftCardCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("GC-")
+	for i, c := range buf {
+		if i == 4 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(giftCardAlphabet[int(c)%len(giftCardAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// CreateGiftCard emite una gift card con código único
+func (r *LedgerRepository) CreateGiftCard(gc *models.GiftCard) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateGiftCardCode()
+		if err != nil {
+			return err
+		}
+		err = r.db.QueryRow(`INSERT INTO gift_cards (code, amount, sale_id) VALUES ($1, $2, $3)
+			ON CONFLICT (code) DO NOTHING RETURNING id, created_at`, code, gc.Amount, gc.SaleID).Scan(&gc.ID, &gc.CreatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		gc.Code = code
+		return nil
+	}
+	return errors.New("could not generate unique gift card code")
+}
+
+// RedeemGiftCard canjea la gift card y carga su valor en la cuenta del miembro (una sola vez)
+func (r *LedgerRepository) RedeemGiftCard(code string, userID int64, createdBy int64) (*models.LedgerEntry, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var giftCardID, amount int64
+	var redeemed bool
+	err = tx.QueryRow(`SELECT id, amount, redeemed_at IS NOT NULL FROM gift_cards WHERE code = $1 FOR UPDATE`, code).
+		Scan(&giftCardID, &amount, &redeemed)
+	if err == sql.ErrNoRows {
+		return nil, ErrGiftCardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if redeemed {
+		return nil, ErrGiftCardRedeemed
+	}
+
+	if _, err := tx.Exec(`UPDATE gift_cards SET redeemed_by = $1, redeemed_at = NOW() WHERE id = $2`, userID, giftCardID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.LedgerEntry{
+		UserID:      userID,
+		Type:        models.LedgerGiftCardLoad,
+		Amount:      amount,
+		Description: "Gift card " + code,
+		GiftCardID:  &giftCardID,
+		CreatedBy:   &createdBy,
+	}
+	err = tx.QueryRow(`INSERT INTO ledger_entries (user_id, type, amount, description, gift_card_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		entry.UserID, entry.Type, entry.Amount, entry.Description, entry.GiftCardID, entry.CreatedBy).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return entry, tx.Commit()
+}
+
+func (r *LedgerRepository) ListGiftCards(limit, offset int) ([]*models.GiftCard, error) {
+	rows, err := r.db.Query(`SELECT id, code, amount, sale_id, redeemed_by, redeemed_at, created_at
+		FROM gift_cards ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*models.GiftCard
+	for rows.Next() {
+		gc := &models.GiftCard{}
+		if err := rows.Scan(&gc.ID, &gc.Code, &gc.Amount, &gc.SaleID, &gc.RedeemedBy, &gc.RedeemedAt, &gc.CreatedAt); err != nil {
+			return nil, err
+		}
+		cards = append(cards, gc)
+	}
+	return cards, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"regexp"
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestGenerateGiftCardCode_Format(t *testing.T) {
+	format := regexp.MustCompile(`^GC-[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		code, err := generateGiftCardCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected gift card code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 199 {
+		t.Fatalf("codes repeat too often: %d distinct of 200", len(seen))
+	}
+}
+
+func TestLedgerRepository_BalanceAndGiftCards(t *testing.T) {
+	db := testTenantDB(t, "ledger-test")
+	users := NewUserRepository(db)
+	member := &models.User{Email: "socio@example.com", PasswordHash: "x", Name: "Socio", Role: models.RoleUser, Active: true}
+	other := &models.User{Email: "otro@example.com", PasswordHash: "x", Name: "Otro", Role: models.RoleUser, Active: true}
+	for _, u := range []*models.User{member, other} {
+		if err := users.Create(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := NewLedgerRepository(db)
+	for _, e := range []*models.LedgerEntry{
+		{UserID: member.ID, Type: models.LedgerPOSCharge, Amount: -18000, Description: "Fiado"},
+		{UserID: member.ID, Type: models.LedgerPayment, Amount: 8000, Description: "Abono", PaymentMethod: models.PaymentMethodEfectivo},
+		{UserID: other.ID, Type: models.LedgerStoreCredit, Amount: 5000, Description: "Crédito"},
+	} {
+		if err := repo.AddEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if balance, err := repo.Balance(member.ID); err != nil || balance != -10000 {
+		t.Fatalf("expected balance -10000, got %d (%v)", balance, err)
+	}
+	debts, err := repo.ListBalances(true)
+	if err != nil || len(debts) != 1 || debts[0].UserID != member.ID {
+		t.Fatalf("expected only the member in debt, got %+v (%v)", debts, err)
+	}
+
+	gc := &models.GiftCard{Amount: 25000}
+	if err := repo.CreateGiftCard(gc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RedeemGiftCard("GC-0000-0000", member.ID, member.ID); err != ErrGiftCardNotFound {
+		t.Fatalf("wrong code: expected ErrGiftCardNotFound, got %v", err)
+	}
+	if _, err := repo.RedeemGiftCard(" "+gc.Code+" ", member.ID, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RedeemGiftCard(gc.Code, other.ID, other.ID); err != ErrGiftCardRedeemed {
+		t.Fatalf("double redemption: expected ErrGiftCardRedeemed, got %v", err)
+	}
+	if balance, _ := repo.Balance(member.ID); balance != 15000 {
+		t.Fatalf("expected balance 15000 after the gift card, got %d", balance)
+	}
+	if balance, _ := repo.Balance(other.ID); balance != 5000 {
+		t.Fatalf("second redeemer must not be credited, got %d", balance)
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// MemberNoteRepository - Notas del staff y lesiones de los miembros
+type MemberNoteRepository struct {
+	db *sql.DB
+}
+
+func NewMemberNoteRepository(db *sql.DB) *MemberNoteRepository {
+	return &MemberNoteRepository{db: db}
+}
+
+const memberNoteColumns = `n.id, n.user_id, n.author_id, COALESCE(a.name,''), n.kind, n.body, n.resolved_at, n.created_at`
+
+func scanMemberNotes(rows *sql.Rows) ([]*models.MemberNote, error) {
+	defer rows.Close()
+	var notes []*models.MemberNote
+	for rows.Next() {
+		n := &models.MemberNote{}
+		if err := rows.Scan(&n.ID, &n.UserID, &n.AuthorID, &n.AuthorName, &n.Kind, &n.Body, &n.ResolvedAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, nil
+}
+
+func (r *MemberNoteRepository) Create(n *models.MemberNote) error {
+	return r.db.QueryRow(`INSERT INTO member_notes (user_id, author_id, kind, body) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`, n.UserID, n.AuthorID, n.Kind, n.Body).Scan(&n.ID, &n.CreatedAt)
+}
+
+// ListByUser lista las notas del miembro; con kind vacío devuelve todos los tipos
+func (r *MemberNoteRepository) ListByUser(userID int64, kind string, includeResolved bool) ([]*models.MemberNote, error) {
+	query := `SELECT ` + memberNoteColumns + ` FROM member_notes n LEFT JOIN users a ON a.id = n.author_id
+		WHERE n.user_id = $1 AND ($2 = '' OR n.kind = $2)`
+	if !includeResolved {
+		query += ` AND n.resolved_at IS NULL`
+	}
+	rows, err := r.db.Query(query+` ORDER BY n.created_at DESC`, userID, kind)
+	if err != nil {
+		return nil, err
+	}
+	return scanMemberNotes(rows)
+}
+
+// ListForSchedule devuelve las notas vigentes de los miembros con reserva en el horario, agrupadas por usuario
+func (r *MemberNoteRepository) ListForSchedule(scheduleID int64) (map[int64][]*models.MemberNote, error) {
+	rows, err := r.db.Query(`SELECT `+memberNoteColumns+` FROM member_notes n LEFT JOIN users a ON a.id = n.author_id
+		WHERE n.resolved_at IS NULL AND n.user_id IN (SELECT user_id FROM bookings WHERE class_schedule_id = $1)
+		ORDER BY n.kind = 'injury' DESC, n.created_at DESC`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := scanMemberNotes(rows)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int64][]*models.MemberNote)
+	for _, n := range notes {
+		byUser[n.UserID] = append(byUser[n.UserID], n)
+	}
+	return byUser, nil
+}
+
+// Resolve marca la nota como resuelta (p. ej. lesión recuperada); kind vacío acepta cualquier tipo
+func (r *MemberNoteRepository) Resolve(userID, noteID int64, kind string) error {
+	return execExpectingRow(r.db, `UPDATE member_notes SET resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND ($3 = '' OR kind = $3) AND resolved_at IS NULL`, noteID, userID, kind)
+}
@@ -17,11 +17,12 @@ func NewPaymentRepository(db *sql.DB) *PaymentRepository {
 
 func (r *PaymentRepository) Create(payment *models.Payment) error {
 	query := `
-		INSERT INTO payments (user_id, plan_id, amount, currency, status, payment_method, external_id, proof_image_url)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO payments (user_id, plan_id, amount, currency, status, payment_method, external_id, proof_image_url, discount_code_id, expires_at, tax_document_type, cash_session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+			CASE WHEN $5 = 'completed' THEN (SELECT id FROM cash_sessions WHERE status = 'open') END)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(
+	err := r.db.QueryRow(
 		query,
 		payment.UserID,
 		payment.PlanID,
@@ -31,12 +32,21 @@ func (r *PaymentRepository) Create(payment *models.Payment) error {
 		payment.PaymentMethod,
 		payment.ExternalID,
 		payment.ProofImageURL,
+		payment.DiscountCodeID,
+		payment.ExpiresAt,
+		payment.TaxDocumentType,
 	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	payment.ReferenceCode = models.PaymentReference(payment.ID)
+	return nil
 }
 
 func (r *PaymentRepository) GetByID(id int64) (*models.Payment, error) {
 	payment := &models.Payment{}
-	query := `SELECT id, user_id, plan_id, amount, currency, status, payment_method, COALESCE(external_id,''), COALESCE(proof_image_url,''), created_at, updated_at
+	query := `SELECT id, user_id, plan_id, amount, currency, status, payment_method, COALESCE(external_id,''), COALESCE(proof_image_url,''),
+			  discount_code_id, COALESCE(rejection_reason,''), reviewed_by, reviewed_at, expires_at, COALESCE(tax_document_type,0), created_at, updated_at
 			  FROM payments WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
@@ -49,12 +59,19 @@ func (r *PaymentRepository) GetByID(id int64) (*models.Payment, error) {
 		&payment.PaymentMethod,
 		&payment.ExternalID,
 		&payment.ProofImageURL,
+		&payment.DiscountCodeID,
+		&payment.RejectionReason,
+		&payment.ReviewedBy,
+		&payment.ReviewedAt,
+		&payment.ExpiresAt,
+		&payment.TaxDocumentType,
 		&payment.CreatedAt,
 		&payment.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	payment.ReferenceCode = models.PaymentReference(payment.ID)
 	return payment, nil
 }
 
@@ -64,46 +81,51 @@ func (r *PaymentRepository) UpdateStatus(id int64, status models.PaymentStatus)
 	return err
 }
 
-func (r *PaymentRepository) ListByUser(userID int64, limit, offset int) ([]*models.PaymentWithDetails, error) {
-	query := `
-		SELECT p.id, p.user_id, p.plan_id, p.amount, p.currency, p.status, p.payment_method, COALESCE(p.external_id,''), COALESCE(p.proof_image_url,''), p.created_at, p.updated_at,
+const paymentDetailsSelect = `
+		SELECT p.id, p.user_id, p.plan_id, p.amount, p.currency, p.status, p.payment_method, COALESCE(p.external_id,''), COALESCE(p.proof_image_url,''),
+			   p.discount_code_id, COALESCE(p.rejection_reason,''), p.reviewed_by, p.reviewed_at, p.expires_at, p.created_at, p.updated_at,
 			   u.name, u.email, pl.name
 		FROM payments p
 		JOIN users u ON p.user_id = u.id
-		JOIN plans pl ON p.plan_id = pl.id
-		WHERE p.user_id = $1
-		ORDER BY p.created_at DESC
-		LIMIT $2 OFFSET $3`
-
-	rows, err := r.db.Query(query, userID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+		JOIN plans pl ON p.plan_id = pl.id`
 
+func scanPaymentsWithDetails(rows *sql.Rows) ([]*models.PaymentWithDetails, error) {
 	var payments []*models.PaymentWithDetails
 	for rows.Next() {
 		p := &models.PaymentWithDetails{}
 		err := rows.Scan(
 			&p.ID, &p.UserID, &p.PlanID, &p.Amount, &p.Currency, &p.Status,
-			&p.PaymentMethod, &p.ExternalID, &p.ProofImageURL, &p.CreatedAt, &p.UpdatedAt,
+			&p.PaymentMethod, &p.ExternalID, &p.ProofImageURL,
+			&p.DiscountCodeID, &p.RejectionReason, &p.ReviewedBy, &p.ReviewedAt, &p.ExpiresAt,
+			&p.CreatedAt, &p.UpdatedAt,
 			&p.UserName, &p.UserEmail, &p.PlanName,
 		)
 		if err != nil {
 			return nil, err
 		}
+		p.ReferenceCode = models.PaymentReference(p.ID)
 		payments = append(payments, p)
 	}
 	return payments, nil
 }
 
+func (r *PaymentRepository) ListByUser(userID int64, limit, offset int) ([]*models.PaymentWithDetails, error) {
+	query := paymentDetailsSelect + `
+		WHERE p.user_id = $1
+		ORDER BY p.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPaymentsWithDetails(rows)
+}
+
 func (r *PaymentRepository) ListAll(limit, offset int) ([]*models.PaymentWithDetails, error) {
-	query := `
-		SELECT p.id, p.user_id, p.plan_id, p.amount, p.currency, p.status, p.payment_method, COALESCE(p.external_id,''), COALESCE(p.proof_image_url,''), p.created_at, p.updated_at,
-			   u.name, u.email, pl.name
-		FROM payments p
-		JOIN users u ON p.user_id = u.id
-		JOIN plans pl ON p.plan_id = pl.id
+	query := paymentDetailsSelect + `
 		ORDER BY p.created_at DESC
 		LIMIT $1 OFFSET $2`
 
@@ -113,20 +135,82 @@ func (r *PaymentRepository) ListAll(limit, offset int) ([]*models.PaymentWithDet
 	}
 	defer rows.Close()
 
-	var payments []*models.PaymentWithDetails
-	for rows.Next() {
-		p := &models.PaymentWithDetails{}
-		err := rows.Scan(
-			&p.ID, &p.UserID, &p.PlanID, &p.Amount, &p.Currency, &p.Status,
-			&p.PaymentMethod, &p.ExternalID, &p.ProofImageURL, &p.CreatedAt, &p.UpdatedAt,
-			&p.UserName, &p.UserEmail, &p.PlanName,
-		)
-		if err != nil {
-			return nil, err
-		}
-		payments = append(payments, p)
+	return scanPaymentsWithDetails(rows)
+}
+
+// ListPending - Cola de aprobación: transferencias informadas por miembros, más antiguas primero
+func (r *PaymentRepository) ListPending(limit, offset int) ([]*models.PaymentWithDetails, error) {
+	query := paymentDetailsSelect + `
+		WHERE p.status = 'pending'
+		ORDER BY p.created_at ASC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
 	}
-	return payments, nil
+	defer rows.Close()
+
+	return scanPaymentsWithDetails(rows)
+}
+
+// Review marca un pago pendiente como completado o rechazado. Devuelve sql.ErrNoRows si ya no está pendiente.
+func (r *PaymentRepository) Review(id int64, status models.PaymentStatus, reviewedBy int64, reason string) error {
+	query := `UPDATE payments
+			  SET status = $1, reviewed_by = $2, reviewed_at = NOW(), rejection_reason = NULLIF($3, ''), updated_at = NOW(),
+			  cash_session_id = CASE WHEN $1 = 'completed' THEN (SELECT id FROM cash_sessions WHERE status = 'open') END
+			  WHERE id = $4 AND status = 'pending'`
+	result, err := r.db.Exec(query, status, reviewedBy, reason, id)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CompleteWithSubscription aprueba un pago pendiente y crea su suscripción en una sola transacción:
+// si la suscripción falla, el pago sigue pendiente y se puede reintentar
+func (r *PaymentRepository) CompleteWithSubscription(id, reviewedBy int64, sub *models.Subscription) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE payments
+		SET status = 'completed', reviewed_by = $1, reviewed_at = NOW(), rejection_reason = NULL, updated_at = NOW(),
+		cash_session_id = (SELECT id FROM cash_sessions WHERE status = 'open')
+		WHERE id = $2 AND status = 'pending'`, reviewedBy, id)
+	if err != nil {
+		return err
+	}
+	n, _ := result.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = tx.QueryRow(`INSERT INTO subscriptions (user_id, plan_id, payment_id, start_date, end_date, classes_used, classes_allowed, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		sub.UserID, sub.PlanID, sub.PaymentID, sub.StartDate, sub.EndDate, sub.ClassesUsed, sub.ClassesAllowed, sub.Active,
+	).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExpirePendingPayments marca como expirados los pagos pendientes cuyo plazo de revisión venció
+func (r *PaymentRepository) ExpirePendingPayments() (int64, error) {
+	result, err := r.db.Exec(`UPDATE payments SET status = 'expired', updated_at = NOW()
+		WHERE status = 'pending' AND expires_at IS NOT NULL AND expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 // Subscriptions
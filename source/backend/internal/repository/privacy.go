@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// PrivacyRepository - Exportación de datos personales y anonimización de cuentas
+type PrivacyRepository struct {
+	db *sql.DB
+}
+
+func NewPrivacyRepository(db *sql.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// exportSections - Consultas de la exportación; cada una recibe el user_id y el resultado se guarda como <nombre>.json
+var exportSections = []struct {
+	Name  string
+	Query string
+}{
+	{"profile", `SELECT id, email, name, phone, avatar_url, role, birth_date, sex, weight_kg, height_cm, rut,
+		invitation_classes, created_at, updated_at FROM users WHERE id = $1`},
+	{"bookings", `SELECT b.id, c.name AS class_name, cs.date, c.start_time, c.end_time, b.status, b.checked_in_at,
+		b.before_photo_url, b.created_at
+		FROM bookings b JOIN class_schedules cs ON cs.id = b.class_schedule_id JOIN classes c ON c.id = cs.class_id
+		WHERE b.user_id = $1 ORDER BY cs.date, c.start_time`},
+	{"results", `SELECT r.id, rt.name AS routine_name, r.class_schedule_id, r.score, r.notes, r.rx, r.is_pr, r.created_at
+		FROM user_routine_results r LEFT JOIN routines rt ON rt.id = r.routine_id
+		WHERE r.user_id = $1 ORDER BY r.created_at`},
+	{"body_measurements", `SELECT id, weight_kg, body_fat_pct, chest_cm, waist_cm, hip_cm, arm_cm, thigh_cm, notes, photo_url,
+		measured_at, created_at FROM body_measurements WHERE user_id = $1 ORDER BY measured_at`},
+	{"nutrition_logs", `SELECT id, food_name, grams, calories, protein_g, carbs_g, fat_g, meal_type, logged_at, created_at
+		FROM nutrition_logs WHERE user_id = $1 ORDER BY logged_at, id`},
+	{"water_logs", `SELECT id, ml, logged_at, created_at FROM water_logs WHERE user_id = $1 ORDER BY logged_at, id`},
+	{"lifts", `SELECT l.id, m.name AS movement, l.sets, l.reps, l.load_kg, l.rpe, l.notes, l.logged_on, l.estimated_1rm, l.is_pr
+		FROM lift_logs l JOIN movements m ON m.id = l.movement_id WHERE l.user_id = $1 ORDER BY l.logged_on, l.id`},
+	{"custom_programs", `SELECT p.id, rt.name AS routine_name, p.amount, p.status, p.period, p.delivered_at, p.acknowledged_at
+		FROM custom_programs p JOIN routines rt ON rt.id = p.routine_id WHERE p.user_id = $1 ORDER BY p.delivered_at`},
+	{"comments", `SELECT id, result_id, content, created_at FROM result_comments WHERE user_id = $1 ORDER BY created_at`},
+}
+
+// Export devuelve cada sección de datos del miembro como un arreglo JSON
+func (r *PrivacyRepository) Export(userID int64) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(exportSections))
+	for _, s := range exportSections {
+		var raw []byte
+		err := r.db.QueryRow(`SELECT COALESCE(json_agg(t), '[]'::json) FROM (`+s.Query+`) t`, userID).Scan(&raw)
+		if err != nil {
+			return nil, err
+		}
+		data[s.Name] = raw
+	}
+	return data, nil
+}
+
+// photoURLsQuery - Fotos subidas por el miembro (perfil, antes de clase y medidas corporales)
+const photoURLsQuery = `SELECT avatar_url FROM users WHERE id = $1 AND COALESCE(avatar_url, '') <> ''
+	UNION SELECT before_photo_url FROM bookings WHERE user_id = $1 AND COALESCE(before_photo_url, '') <> ''
+	UNION SELECT photo_url FROM body_measurements WHERE user_id = $1 AND COALESCE(photo_url, '') <> ''`
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	var list []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (r *PrivacyRepository) PhotoURLs(userID int64) ([]string, error) {
+	rows, err := r.db.Query(photoURLsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+const erasureColumns = `e.id, e.user_id, u.name, u.email, e.status, COALESCE(e.reason, ''), e.requested_by, e.reviewed_by,
+	e.reviewed_at, COALESCE(e.review_notes, ''), e.scheduled_for, e.completed_at, e.created_at`
+
+func scanErasure(row interface{ Scan(...interface{}) error }) (*models.ErasureRequest, error) {
+	e := &models.ErasureRequest{}
+	err := row.Scan(&e.ID, &e.UserID, &e.UserName, &e.UserEmail, &e.Status, &e.Reason, &e.RequestedBy, &e.ReviewedBy,
+		&e.ReviewedAt, &e.ReviewNotes, &e.ScheduledFor, &e.CompletedAt, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// CreateErasure registra la solicitud; el índice único impide dos solicitudes abiertas para el mismo miembro
+func (r *PrivacyRepository) CreateErasure(e *models.ErasureRequest) error {
+	return r.db.QueryRow(`INSERT INTO erasure_requests (user_id, status, reason, requested_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING id, created_at`,
+		e.UserID, e.Status, e.Reason, e.RequestedBy).Scan(&e.ID, &e.CreatedAt)
+}
+
+func (r *PrivacyRepository) GetErasure(id int64) (*models.ErasureRequest, error) {
+	return scanErasure(r.db.QueryRow(`SELECT `+erasureColumns+` FROM erasure_requests e JOIN users u ON u.id = e.user_id
+		WHERE e.id = $1`, id))
+}
+
+// OpenErasure devuelve la solicitud pendiente o aprobada del miembro (sql.ErrNoRows si no tiene)
+func (r *PrivacyRepository) OpenErasure(userID int64) (*models.ErasureRequest, error) {
+	return scanErasure(r.db.QueryRow(`SELECT `+erasureColumns+` FROM erasure_requests e JOIN users u ON u.id = e.user_id
+		WHERE e.user_id = $1 AND e.status IN ('pending','approved')`, userID))
+}
+
+func (r *PrivacyRepository) listErasures(where string, args ...interface{}) ([]*models.ErasureRequest, error) {
+	rows, err := r.db.Query(`SELECT `+erasureColumns+` FROM erasure_requests e JOIN users u ON u.id = e.user_id
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.ErasureRequest
+	for rows.Next() {
+		e, err := scanErasure(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+// ListErasures lista las solicitudes, opcionalmente filtradas por estado
+func (r *PrivacyRepository) ListErasures(status string) ([]*models.ErasureRequest, error) {
+	return r.listErasures(`($1 = '' OR e.status = $1) ORDER BY e.created_at DESC`, status)
+}
+
+// Review aprueba o rechaza una solicitud pendiente. Devuelve sql.ErrNoRows si ya fue revisada.
+func (r *PrivacyRepository) Review(id int64, status string, reviewedBy int64, notes string, scheduledFor *time.Time) error {
+	return execExpectingRow(r.db, `UPDATE erasure_requests
+		SET status = $1, reviewed_by = $2, reviewed_at = NOW(), review_notes = NULLIF($3, ''), scheduled_for = $4
+		WHERE id = $5 AND status = 'pending'`, status, reviewedBy, notes, scheduledFor, id)
+}
+
+// Cancel retira la solicitud abierta del miembro mientras no se haya anonimizado
+func (r *PrivacyRepository) Cancel(userID int64) error {
+	return execExpectingRow(r.db, `UPDATE erasure_requests SET status = 'cancelled'
+		WHERE user_id = $1 AND status IN ('pending','approved')`, userID)
+}
+
+// DueErasures devuelve las solicitudes aprobadas cuyo periodo de gracia ya terminó
+func (r *PrivacyRepository) DueErasures(now time.Time) ([]*models.ErasureRequest, error) {
+	return r.listErasures(`e.status = 'approved' AND e.scheduled_for <= $1 ORDER BY e.id`, now)
+}
+
+// personalDataDeletes borran lo que no es registro financiero ni evidencia legal (firmas de documentos)
+var personalDataDeletes = []string{
+	`DELETE FROM body_measurements WHERE user_id = $1`,
+	`DELETE FROM nutrition_logs WHERE user_id = $1`,
+	`DELETE FROM water_logs WHERE user_id = $1`,
+	`DELETE FROM result_comments WHERE user_id = $1`,
+	`DELETE FROM fistbumps WHERE user_id = $1`,
+	`DELETE FROM feed_events WHERE user_id = $1`,
+	`DELETE FROM user_routine_results WHERE user_id = $1`,
+	`DELETE FROM lift_logs WHERE user_id = $1`,
+	`DELETE FROM user_badges WHERE user_id = $1`,
+	`DELETE FROM challenge_participants WHERE user_id = $1`,
+	`DELETE FROM event_registrations WHERE user_id = $1`,
+	`DELETE FROM onramp_enrollments WHERE user_id = $1`,
+	`DELETE FROM member_notes WHERE user_id = $1`,
+	`DELETE FROM user_tags WHERE user_id = $1`,
+	`DELETE FROM waitlist WHERE user_id = $1`,
+	`DELETE FROM billing_profiles WHERE user_id = $1`,
+	`DELETE FROM auth_sessions WHERE user_id = $1`,
+	`DELETE FROM auth_tokens WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+	`DELETE FROM user_totp WHERE user_id = $1`,
+	`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+	`DELETE FROM login_lockouts WHERE user_id = $1`,
+}
+
+// redactAuditLog quita los datos personales del miembro de las fotos del log de auditoría. El log es de solo
+// inserción; el trigger permite esta actualización únicamente con boxmagic.audit_redaction activo en la transacción.
+func redactAuditLog(tx *sql.Tx, userID int64) error {
+	if _, err := tx.Exec(`SET LOCAL boxmagic.audit_redaction = 'on'`); err != nil {
+		return err
+	}
+	fields := "{" + strings.Join(models.AuditUserPersonalFields, ",") + "}"
+	_, err := tx.Exec(`UPDATE audit_log SET before_data = before_data - $2::text[], after_data = after_data - $2::text[],
+			changes = changes - $2::text[]
+		WHERE entity_type = $3 AND entity_id = $1::text`, userID, fields, models.AuditEntityUser)
+	return err
+}
+
+// Anonymize ejecuta una solicitud aprobada: borra los datos personales del miembro, libera sus reservas futuras y deja la cuenta inactiva con
+// nombre y email genéricos; pagos, ventas, cuenta corriente y documentos tributarios se conservan.
+// Devuelve las URLs de las fotos que tenía para que se borren los archivos.
+func (r *PrivacyRepository) Anonymize(requestID, userID int64) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Bloquea la solicitud: si el miembro la canceló entretanto no se anonimiza
+	var locked int64
+	if err := tx.QueryRow(`SELECT id FROM erasure_requests WHERE id = $1 AND user_id = $2 AND status = 'approved' FOR UPDATE`,
+		requestID, userID).Scan(&locked); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(photoURLsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	photos, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reservas futuras: se liberan los cupos; el historial de asistencia queda sin foto
+	if _, err := tx.Exec(`UPDATE class_schedules cs SET booked = GREATEST(cs.booked - 1, 0)
+		FROM bookings b WHERE b.class_schedule_id = cs.id AND b.user_id = $1 AND b.status = 'booked' AND cs.date >= CURRENT_DATE`, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE bookings b SET status = 'cancelled'
+		FROM class_schedules cs WHERE cs.id = b.class_schedule_id AND b.user_id = $1 AND b.status = 'booked' AND cs.date >= CURRENT_DATE`, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE bookings SET before_photo_url = NULL WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	for _, q := range personalDataDeletes {
+		if _, err := tx.Exec(q, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET email = 'erased-' || id || '@erased.invalid', name = $2, phone = NULL,
+			avatar_url = NULL, birth_date = NULL, sex = NULL, weight_kg = NULL, height_cm = NULL, rut = NULL,
+			password_hash = '!', active = false, email_verified = false, invitation_classes = 0,
+			token_version = COALESCE(token_version, 0) + 1, erased_at = NOW(), updated_at = NOW()
+		WHERE id = $1`, userID, models.ErasedUserName); err != nil {
+		return nil, err
+	}
+	if err := redactAuditLog(tx, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE erasure_requests SET status = 'completed', completed_at = NOW() WHERE id = $1`, requestID); err != nil {
+		return nil, err
+	}
+	return photos, tx.Commit()
+}
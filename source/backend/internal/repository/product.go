@@ -64,7 +64,8 @@ func (r *ProductRepository) CreateSale(sale *models.Sale) error {
 	defer tx.Rollback()
 
 	err = tx.QueryRow(
-		`INSERT INTO sales (user_id, total, payment_method, notes, created_by) VALUES ($1,$2,$3,$4,$5) RETURNING id, created_at`,
+		`INSERT INTO sales (user_id, total, payment_method, notes, created_by, cash_session_id)
+		 VALUES ($1,$2,$3,$4,$5,(SELECT id FROM cash_sessions WHERE status = 'open')) RETURNING id, created_at`,
 		sale.UserID, sale.Total, sale.PaymentMethod, sale.Notes, sale.CreatedBy,
 	).Scan(&sale.ID, &sale.CreatedAt)
 	if err != nil {
@@ -74,8 +75,8 @@ func (r *ProductRepository) CreateSale(sale *models.Sale) error {
 	for i := range sale.Items {
 		item := &sale.Items[i]
 		err = tx.QueryRow(
-			`INSERT INTO sale_items (sale_id, product_id, product_name, quantity, unit_price) VALUES ($1,$2,$3,$4,$5) RETURNING id`,
-			sale.ID, item.ProductID, item.ProductName, item.Quantity, item.UnitPrice,
+			`INSERT INTO sale_items (sale_id, product_id, product_name, quantity, unit_price, gift_card) VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
+			sale.ID, item.ProductID, item.ProductName, item.Quantity, item.UnitPrice, item.GiftCard,
 		).Scan(&item.ID)
 		if err != nil {
 			return err
@@ -112,7 +113,7 @@ func (r *ProductRepository) ListSales(limit, offset int) ([]*models.Sale, error)
 }
 
 func (r *ProductRepository) GetSaleItems(saleID int64) ([]models.SaleItem, error) {
-	rows, err := r.db.Query(`SELECT id, sale_id, product_id, product_name, quantity, unit_price FROM sale_items WHERE sale_id = $1`, saleID)
+	rows, err := r.db.Query(`SELECT id, sale_id, product_id, product_name, quantity, unit_price, COALESCE(gift_card, false) FROM sale_items WHERE sale_id = $1`, saleID)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +121,7 @@ func (r *ProductRepository) GetSaleItems(saleID int64) ([]models.SaleItem, error
 	var items []models.SaleItem
 	for rows.Next() {
 		it := models.SaleItem{}
-		if err := rows.Scan(&it.ID, &it.SaleID, &it.ProductID, &it.ProductName, &it.Quantity, &it.UnitPrice); err != nil {
+		if err := rows.Scan(&it.ID, &it.SaleID, &it.ProductID, &it.ProductName, &it.Quantity, &it.UnitPrice, &it.GiftCard); err != nil {
 			return nil, err
 		}
 		items = append(items, it)
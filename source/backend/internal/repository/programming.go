@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// ProgrammingRepository - Tracks de programación, su calendario y su asignación a los horarios
+type ProgrammingRepository struct {
+	db *sql.DB
+}
+
+func NewProgrammingRepository(db *sql.DB) *ProgrammingRepository {
+	return &ProgrammingRepository{db: db}
+}
+
+const trackColumns = `t.id, t.name, COALESCE(t.description, ''), t.publish_days_before, t.publish_time, t.active, t.created_by,
+	t.created_at, t.updated_at`
+
+func scanTrack(row interface{ Scan(...interface{}) error }) (*models.ProgrammingTrack, error) {
+	t := &models.ProgrammingTrack{Targets: []*models.TrackTarget{}}
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.PublishDaysBefore, &t.PublishTime, &t.Active, &t.CreatedBy,
+		&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *ProgrammingRepository) List(includeInactive bool) ([]*models.ProgrammingTrack, error) {
+	rows, err := r.db.Query(`SELECT `+trackColumns+` FROM programming_tracks t WHERE $1 OR t.active ORDER BY t.name`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.ProgrammingTrack
+	for rows.Next() {
+		t, err := scanTrack(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, t := range list {
+		if t.Targets, err = r.Targets(t.ID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (r *ProgrammingRepository) Get(id int64) (*models.ProgrammingTrack, error) {
+	t, err := scanTrack(r.db.QueryRow(`SELECT `+trackColumns+` FROM programming_tracks t WHERE t.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if t.Targets, err = r.Targets(id); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NameTaken indica si otro track ya usa el nombre (sin distinguir mayúsculas)
+func (r *ProgrammingRepository) NameTaken(name string, excludeID int64) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM programming_tracks WHERE LOWER(name) = LOWER($1) AND id <> $2)`, name, excludeID).Scan(&taken)
+	return taken, err
+}
+
+func (r *ProgrammingRepository) Create(t *models.ProgrammingTrack) error {
+	return r.db.QueryRow(`INSERT INTO programming_tracks (name, description, publish_days_before, publish_time, active, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, true, $5) RETURNING id, created_at, updated_at`,
+		t.Name, t.Description, t.PublishDaysBefore, t.PublishTime, t.CreatedBy).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *ProgrammingRepository) Update(t *models.ProgrammingTrack) error {
+	return execExpectingRow(r.db, `UPDATE programming_tracks SET name = $1, description = NULLIF($2, ''), publish_days_before = $3,
+		publish_time = $4, active = $5, updated_at = NOW() WHERE id = $6`,
+		t.Name, t.Description, t.PublishDaysBefore, t.PublishTime, t.Active, t.ID)
+}
+
+// Targets devuelve las clases y disciplinas vinculadas al track
+func (r *ProgrammingRepository) Targets(trackID int64) ([]*models.TrackTarget, error) {
+	rows, err := r.db.Query(`SELECT tt.class_id, tt.discipline_id, COALESCE(c.name, d.name, '')
+		FROM programming_track_targets tt
+		LEFT JOIN classes c ON c.id = tt.class_id
+		LEFT JOIN disciplines d ON d.id = tt.discipline_id
+		WHERE tt.track_id = $1 ORDER BY tt.id`, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.TrackTarget{}
+	for rows.Next() {
+		tt := &models.TrackTarget{}
+		if err := rows.Scan(&tt.ClassID, &tt.DisciplineID, &tt.Name); err != nil {
+			return nil, err
+		}
+		list = append(list, tt)
+	}
+	return list, rows.Err()
+}
+
+// TargetExists indica si la clase o disciplina existe
+func (r *ProgrammingRepository) TargetExists(classID, disciplineID *int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM classes WHERE id = $1) OR EXISTS (SELECT 1 FROM disciplines WHERE id = $2)`,
+		classID, disciplineID).Scan(&exists)
+	return exists, err
+}
+
+// TargetTaken indica si la clase o disciplina ya sigue otro track
+func (r *ProgrammingRepository) TargetTaken(trackID int64, classID, disciplineID *int64) (bool, error) {
+	var taken bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM programming_track_targets
+		WHERE track_id <> $1 AND (class_id = $2 OR discipline_id = $3))`, trackID, classID, disciplineID).Scan(&taken)
+	return taken, err
+}
+
+// SetTargets reemplaza las clases y disciplinas vinculadas al track
+func (r *ProgrammingRepository) SetTargets(trackID int64, classIDs, disciplineIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM programming_track_targets WHERE track_id = $1`, trackID); err != nil {
+		return err
+	}
+	for _, id := range classIDs {
+		if _, err := tx.Exec(`INSERT INTO programming_track_targets (track_id, class_id) VALUES ($1, $2)`, trackID, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range disciplineIDs {
+		if _, err := tx.Exec(`INSERT INTO programming_track_targets (track_id, discipline_id) VALUES ($1, $2)`, trackID, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const trackDayColumns = `td.id, td.track_id, td.date, td.routine_id, rt.name, COALESCE(td.notes, ''), td.publish_at,
+	COALESCE(td.publish_at, (td.date - t.publish_days_before) + t.publish_time::time)`
+
+func scanTrackDay(row interface{ Scan(...interface{}) error }) (*models.TrackDay, error) {
+	d := &models.TrackDay{}
+	err := row.Scan(&d.ID, &d.TrackID, &d.Date, &d.RoutineID, &d.RoutineName, &d.Notes, &d.PublishAt, &d.ReleasesAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Days devuelve el calendario del track entre dos fechas (inclusive)
+func (r *ProgrammingRepository) Days(trackID int64, from, to time.Time) ([]*models.TrackDay, error) {
+	rows, err := r.db.Query(`SELECT `+trackDayColumns+`
+		FROM programming_track_days td
+		JOIN programming_tracks t ON t.id = td.track_id
+		JOIN routines rt ON rt.id = td.routine_id
+		WHERE td.track_id = $1 AND td.date >= $2::date AND td.date <= $3::date
+		ORDER BY td.date`, trackID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.TrackDay{}
+	for rows.Next() {
+		d, err := scanTrackDay(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// SetDay programa (o reemplaza) la rutina del track en la fecha
+func (r *ProgrammingRepository) SetDay(d *models.TrackDay) error {
+	return r.db.QueryRow(`INSERT INTO programming_track_days (track_id, date, routine_id, notes, publish_at)
+		VALUES ($1, $2::date, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (track_id, date) DO UPDATE SET routine_id = $3, notes = NULLIF($4, ''), publish_at = $5
+		RETURNING id`, d.TrackID, d.Date, d.RoutineID, d.Notes, d.PublishAt).Scan(&d.ID)
+}
+
+func (r *ProgrammingRepository) GetDay(trackID int64, date time.Time) (*models.TrackDay, error) {
+	return scanTrackDay(r.db.QueryRow(`SELECT `+trackDayColumns+`
+		FROM programming_track_days td
+		JOIN programming_tracks t ON t.id = td.track_id
+		JOIN routines rt ON rt.id = td.routine_id
+		WHERE td.track_id = $1 AND td.date = $2::date`, trackID, date))
+}
+
+// DeleteDay quita la rutina de la fecha; las asignaciones hechas por el track se borran en cascada
+func (r *ProgrammingRepository) DeleteDay(trackID int64, date time.Time) error {
+	return execExpectingRow(r.db, `DELETE FROM programming_track_days WHERE track_id = $1 AND date = $2::date`, trackID, date)
+}
+
+// CopyDays copia los días [from, from+days) desplazados offset días; con overwrite reemplaza los ya programados.
+// Devuelve cuántos días se escribieron.
+func (r *ProgrammingRepository) CopyDays(trackID int64, from time.Time, days, offset int, overwrite bool) (int64, error) {
+	conflict := `DO NOTHING`
+	if overwrite {
+		conflict = `DO UPDATE SET routine_id = EXCLUDED.routine_id, notes = EXCLUDED.notes, publish_at = EXCLUDED.publish_at`
+	}
+	res, err := r.db.Exec(`INSERT INTO programming_track_days (track_id, date, routine_id, notes, publish_at)
+		SELECT track_id, date + $4::int, routine_id, notes, publish_at + make_interval(days => $4::int)
+		FROM programming_track_days
+		WHERE track_id = $1 AND date >= $2::date AND date < $2::date + $3::int
+		ON CONFLICT (track_id, date) `+conflict, trackID, from, days, offset)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ShiftConflicts cuenta los días fuera del rango [from, to] que ocuparían las fechas destino al mover el rango
+// offset días (to nil = hasta el final del calendario)
+func (r *ProgrammingRepository) ShiftConflicts(trackID int64, from time.Time, to *time.Time, offset int) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM programming_track_days d
+		WHERE d.track_id = $1 AND NOT (d.date >= $2::date AND ($3::date IS NULL OR d.date <= $3::date))
+		AND EXISTS (SELECT 1 FROM programming_track_days s
+		            WHERE s.track_id = $1 AND s.date >= $2::date AND ($3::date IS NULL OR s.date <= $3::date)
+		            AND s.date + $4::int = d.date)`, trackID, from, to, offset).Scan(&n)
+	return n, err
+}
+
+// ShiftDays mueve los días del rango offset días, junto con su hora de publicación excepcional.
+// Se recorren en el sentido del movimiento para no chocar con la restricción única (track_id, date).
+func (r *ProgrammingRepository) ShiftDays(trackID int64, from time.Time, to *time.Time, offset int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	order := "ASC"
+	if offset > 0 {
+		order = "DESC"
+	}
+	rows, err := tx.Query(`SELECT id FROM programming_track_days
+		WHERE track_id = $1 AND date >= $2::date AND ($3::date IS NULL OR date <= $3::date)
+		ORDER BY date `+order, trackID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec(`UPDATE programming_track_days SET date = date + $1::int,
+			publish_at = publish_at + make_interval(days => $1::int) WHERE id = $2`, offset, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit()
+}
+
+// SyncSchedules rehace desde la fecha dada las asignaciones hechas por los tracks: cada horario no cancelado
+// de una clase vinculada (directamente o por su disciplina) recibe la versión vigente de la rutina del día.
+// Las asignaciones manuales se respetan.
+func (r *ProgrammingRepository) SyncSchedules(from time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Las clases con resultados conservan la rutina y versión con la que se hicieron
+	if _, err := tx.Exec(`DELETE FROM schedule_routines sr USING class_schedules cs
+		WHERE sr.class_schedule_id = cs.id AND sr.track_day_id IS NOT NULL AND cs.date >= $1::date
+		AND NOT EXISTS (SELECT 1 FROM user_routine_results urr WHERE urr.class_schedule_id = cs.id)`, from); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO schedule_routines (class_schedule_id, routine_id, notes, track_day_id, publish_at, routine_version_id)
+		SELECT cs.id, td.routine_id, COALESCE(td.notes, ''), td.id,
+		       COALESCE(td.publish_at, (td.date - t.publish_days_before) + t.publish_time::time),
+		       `+latestVersionSQL("td.routine_id")+`
+		FROM class_schedules cs
+		JOIN classes c ON c.id = cs.class_id
+		JOIN programming_track_targets tt ON tt.id = COALESCE(
+			(SELECT id FROM programming_track_targets WHERE class_id = c.id),
+			(SELECT id FROM programming_track_targets WHERE discipline_id = c.discipline_id))
+		JOIN programming_tracks t ON t.id = tt.track_id AND t.active
+		JOIN programming_track_days td ON td.track_id = t.id AND td.date = cs.date
+		WHERE cs.date >= $1::date AND NOT cs.cancelled
+		ON CONFLICT (class_schedule_id) DO NOTHING`, from)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
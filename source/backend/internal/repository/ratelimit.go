@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// RateLimitRepository - Contadores de rate limiting compartidos entre réplicas
+type RateLimitRepository struct {
+	db        *sql.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Hit incrementa el contador de la ventana vigente de forma atómica (implementa middleware.RateLimitStore)
+func (r *RateLimitRepository) Hit(key string, window time.Duration) (int, time.Time, error) {
+	r.sweep()
+
+	var count int
+	var resetAt time.Time
+	err := r.db.QueryRow(`INSERT INTO rate_limits (key, count, reset_at) VALUES ($1, 1, NOW() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limits.reset_at <= NOW() THEN 1 ELSE rate_limits.count + 1 END,
+			reset_at = CASE WHEN rate_limits.reset_at <= NOW() THEN EXCLUDED.reset_at ELSE rate_limits.reset_at END
+		RETURNING count, reset_at`, key, window.Seconds()).Scan(&count, &resetAt)
+	return count, resetAt, err
+}
+
+// sweep borra contadores vencidos como máximo una vez por minuto
+func (r *RateLimitRepository) sweep() {
+	r.mu.Lock()
+	if time.Since(r.lastSweep) < time.Minute {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSweep = time.Now()
+	r.mu.Unlock()
+	go r.db.Exec(`DELETE FROM rate_limits WHERE reset_at < NOW()`)
+}
+
+// LoginLockoutRepository - Intentos fallidos de login por cuenta
+type LoginLockoutRepository struct {
+	db *sql.DB
+}
+
+func NewLoginLockoutRepository(db *sql.DB) *LoginLockoutRepository {
+	return &LoginLockoutRepository{db: db}
+}
+
+// Get devuelve el estado de la cuenta; sin registro equivale a cero fallos
+func (r *LoginLockoutRepository) Get(userID int64) (*models.LoginLockout, error) {
+	l := &models.LoginLockout{UserID: userID}
+	err := r.db.QueryRow(`SELECT failures, lock_count, locked_until FROM login_lockouts WHERE user_id = $1`, userID).
+		Scan(&l.Failures, &l.LockCount, &l.LockedUntil)
+	if err == sql.ErrNoRows {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// RecordFailure suma un intento fallido y devuelve el estado actualizado
+func (r *LoginLockoutRepository) RecordFailure(userID int64) (*models.LoginLockout, error) {
+	l := &models.LoginLockout{UserID: userID}
+	err := r.db.QueryRow(`INSERT INTO login_lockouts (user_id, failures) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET failures = login_lockouts.failures + 1, updated_at = NOW()
+		RETURNING failures, lock_count, locked_until`, userID).
+		Scan(&l.Failures, &l.LockCount, &l.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Lock bloquea la cuenta hasta la fecha y reinicia el contador de fallos
+func (r *LoginLockoutRepository) Lock(userID int64, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE login_lockouts SET failures = 0, lock_count = lock_count + 1, locked_until = $2, updated_at = NOW()
+		WHERE user_id = $1`, userID, until)
+	return err
+}
+
+// Reset limpia el historial tras un login exitoso o un cambio de contraseña
+func (r *LoginLockoutRepository) Reset(userID int64) error {
+	_, err := r.db.Exec(`DELETE FROM login_lockouts WHERE user_id = $1`, userID)
+	return err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// ReceiptRepository reúne los datos para comprobantes PDF de pagos y certificados anuales
+type ReceiptRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptRepository(db *sql.DB) *ReceiptRepository {
+	return &ReceiptRepository{db: db}
+}
+
+const paymentReceiptSelect = `
+		SELECT p.id, p.user_id, p.plan_id, p.amount, p.currency, p.status, COALESCE(p.payment_method,''), p.created_at, p.updated_at,
+			   u.name, u.email, COALESCE(u.rut,''), pl.name, pl.price, COALESCE(dc.code,''),
+			   s.start_date, s.end_date, COALESCE(td.doc_type,0), COALESCE(td.folio,0)
+		FROM payments p
+		JOIN users u ON p.user_id = u.id
+		JOIN plans pl ON p.plan_id = pl.id
+		LEFT JOIN discount_codes dc ON dc.id = p.discount_code_id
+		LEFT JOIN LATERAL (SELECT start_date, end_date FROM subscriptions WHERE payment_id = p.id ORDER BY id LIMIT 1) s ON true
+		LEFT JOIN tax_documents td ON td.source_type = 'payment' AND td.source_id = p.id`
+
+func scanPaymentReceipt(scanner interface{ Scan(...interface{}) error }) (*models.PaymentReceipt, error) {
+	rc := &models.PaymentReceipt{}
+	err := scanner.Scan(&rc.ID, &rc.UserID, &rc.PlanID, &rc.Amount, &rc.Currency, &rc.Status, &rc.PaymentMethod, &rc.CreatedAt, &rc.UpdatedAt,
+		&rc.UserName, &rc.UserEmail, &rc.UserRUT, &rc.PlanName, &rc.PlanPrice, &rc.DiscountCode,
+		&rc.StartDate, &rc.EndDate, &rc.TaxDocType, &rc.TaxFolio)
+	if err != nil {
+		return nil, err
+	}
+	rc.ReferenceCode = models.PaymentReference(rc.ID)
+	// El descuento es la diferencia con el precio del plan (solo si el pago usó un código)
+	if rc.DiscountCode != "" && rc.PlanPrice > rc.Amount {
+		rc.DiscountAmount = rc.PlanPrice - rc.Amount
+	}
+	return rc, nil
+}
+
+func (r *ReceiptRepository) PaymentReceipt(paymentID int64) (*models.PaymentReceipt, error) {
+	return scanPaymentReceipt(r.db.QueryRow(paymentReceiptSelect+` WHERE p.id = $1`, paymentID))
+}
+
+// YearlyStatement lista los pagos completados y reembolsados de un miembro en el año calendario
+func (r *ReceiptRepository) YearlyStatement(userID int64, year int) (*models.YearlyStatement, error) {
+	st := &models.YearlyStatement{UserID: userID, Year: year, Payments: []*models.PaymentReceipt{}}
+	err := r.db.QueryRow(`SELECT name, email, COALESCE(rut,'') FROM users WHERE id = $1`, userID).
+		Scan(&st.UserName, &st.UserEmail, &st.UserRUT)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(paymentReceiptSelect+`
+		WHERE p.user_id = $1 AND p.status IN ('completed', 'refunded') AND EXTRACT(YEAR FROM p.created_at) = $2
+		ORDER BY p.created_at`, userID, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rc, err := scanPaymentReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		if rc.Status == models.PaymentCompleted {
+			st.Total += rc.Amount
+		}
+		st.Payments = append(st.Payments, rc)
+	}
+	return st, rows.Err()
+}
+
+// SaleReceipt devuelve la venta POS con sus items y el miembro asociado
+func (r *ReceiptRepository) SaleReceipt(saleID int64) (*models.Sale, error) {
+	s := &models.Sale{}
+	err := r.db.QueryRow(`SELECT s.id, s.user_id, s.total, COALESCE(s.payment_method,'cash'), COALESCE(s.notes,''), s.created_by, s.created_at, COALESCE(u.name,'')
+		FROM sales s LEFT JOIN users u ON u.id = s.user_id WHERE s.id = $1`, saleID).
+		Scan(&s.ID, &s.UserID, &s.Total, &s.PaymentMethod, &s.Notes, &s.CreatedBy, &s.CreatedAt, &s.UserName)
+	if err != nil {
+		return nil, err
+	}
+	s.Items, err = NewProductRepository(r.db).GetSaleItems(saleID)
+	if err != nil {
+		return nil, err
+	}
+	var doc models.TaxDocument
+	err = r.db.QueryRow(`SELECT doc_type, folio FROM tax_documents WHERE source_type = 'sale' AND source_id = $1`, saleID).
+		Scan(&doc.DocType, &doc.Folio)
+	if err == nil {
+		s.TaxDocument = &doc
+	}
+	return s, nil
+}
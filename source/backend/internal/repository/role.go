@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"boxmagic/internal/models"
+)
+
+// RoleRepository - Roles personalizados del staff
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+func scanRole(row interface{ Scan(...any) error }) (*models.RoleDef, error) {
+	role := &models.RoleDef{}
+	var perms []byte
+	if err := row.Scan(&role.Name, &role.Label, &role.Description, &perms, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(perms, &role.Permissions)
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	role.Staff = true
+	return role, nil
+}
+
+func (r *RoleRepository) List() ([]*models.RoleDef, error) {
+	rows, err := r.db.Query(`SELECT name, label, COALESCE(description,''), permissions, created_at, updated_at FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.RoleDef
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) Get(name models.Role) (*models.RoleDef, error) {
+	return scanRole(r.db.QueryRow(`SELECT name, label, COALESCE(description,''), permissions, created_at, updated_at FROM roles WHERE name = $1`, name))
+}
+
+func (r *RoleRepository) Create(role *models.RoleDef) error {
+	perms, _ := json.Marshal(role.Permissions)
+	return r.db.QueryRow(`INSERT INTO roles (name, label, description, permissions) VALUES ($1, $2, NULLIF($3,''), $4)
+		RETURNING created_at, updated_at`,
+		role.Name, role.Label, role.Description, perms).Scan(&role.CreatedAt, &role.UpdatedAt)
+}
+
+func (r *RoleRepository) Update(role *models.RoleDef) error {
+	perms, _ := json.Marshal(role.Permissions)
+	return execExpectingRow(r.db, `UPDATE roles SET label = $2, description = NULLIF($3,''), permissions = $4, updated_at = NOW() WHERE name = $1`,
+		role.Name, role.Label, role.Description, perms)
+}
+
+// Delete elimina el rol; falla si todavía hay usuarios asignados
+func (r *RoleRepository) Delete(name models.Role) error {
+	return execExpectingRow(r.db, `DELETE FROM roles WHERE name = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE role = $1)`, name)
+}
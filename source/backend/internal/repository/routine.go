@@ -16,14 +16,50 @@ func NewRoutineRepository(db *sql.DB) *RoutineRepository {
 	return &RoutineRepository{db: db}
 }
 
+// trackDayRelease - Hora en que se libera un día de track (e = día, et = track)
+const trackDayRelease = `COALESCE(e.publish_at, (e.date - et.publish_days_before) + et.publish_time::time)`
+
+// routineUnpublished - La rutina (alias r) solo está programada en clases o días de track que aún no se publican.
+// Las rutinas sin programar siguen visibles como parte de la biblioteca.
+const routineUnpublished = `((EXISTS (SELECT 1 FROM schedule_routines e WHERE e.routine_id = r.id AND e.publish_at > NOW())
+		OR EXISTS (SELECT 1 FROM programming_track_days e JOIN programming_tracks et ON et.id = e.track_id
+			WHERE e.routine_id = r.id AND ` + trackDayRelease + ` > NOW()))
+	AND NOT EXISTS (SELECT 1 FROM schedule_routines e WHERE e.routine_id = r.id AND (e.publish_at IS NULL OR e.publish_at <= NOW()))
+	AND NOT EXISTS (SELECT 1 FROM programming_track_days e JOIN programming_tracks et ON et.id = e.track_id
+		WHERE e.routine_id = r.id AND ` + trackDayRelease + ` <= NOW()))`
+
+// ReleaseAt devuelve cuándo se publica la rutina si aún no es visible para los miembros (nil si ya lo es)
+func (r *RoutineRepository) ReleaseAt(routineID int64) (*time.Time, error) {
+	var at *time.Time
+	err := r.db.QueryRow(`SELECT LEAST(
+			(SELECT MIN(e.publish_at) FROM schedule_routines e WHERE e.routine_id = r.id),
+			(SELECT MIN(`+trackDayRelease+`) FROM programming_track_days e JOIN programming_tracks et ON et.id = e.track_id
+				WHERE e.routine_id = r.id))
+		FROM routines r WHERE r.id = $1 AND `+routineUnpublished, routineID).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return at, err
+}
+
+// routineExecer - *sql.DB o *sql.Tx, para que las escrituras de rutinas puedan ir dentro de SaveRevision
+type routineExecer interface {
+	queryer
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 func (r *RoutineRepository) Create(routine *models.Routine) error {
-	query := `INSERT INTO routines (name, description, type, content, content_scaled, content_beginner, duration, difficulty, instructor_id, created_by, active, billable, target_user_id, is_custom)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, true, $11, $12, $13)
+	return createRoutine(r.db, routine)
+}
+
+func createRoutine(q routineExecer, routine *models.Routine) error {
+	query := `INSERT INTO routines (name, description, type, content, content_scaled, content_beginner, duration, difficulty, instructor_id, created_by, active, billable, target_user_id, is_custom, benchmark_id)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, true, $11, $12, $13, $14)
 			  RETURNING id, created_at, updated_at`
-	return r.db.QueryRow(query, routine.Name, routine.Description, routine.Type,
+	return q.QueryRow(query, routine.Name, routine.Description, routine.Type,
 		routine.Content, routine.ContentScaled, routine.ContentBeginner,
 		routine.Duration, routine.Difficulty, routine.InstructorID, routine.CreatedBy,
-		routine.Billable, routine.TargetUserID, routine.IsCustom).
+		routine.Billable, routine.TargetUserID, routine.IsCustom, routine.BenchmarkID).
 		Scan(&routine.ID, &routine.CreatedAt, &routine.UpdatedAt)
 }
 
@@ -31,7 +67,7 @@ func (r *RoutineRepository) GetByID(id int64) (*models.RoutineWithCreator, error
 	routine := &models.RoutineWithCreator{}
 	query := `SELECT r.id, r.name, r.description, r.type, r.content, COALESCE(r.content_scaled,''), COALESCE(r.content_beginner,''),
 			         r.duration, r.difficulty,
-			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom,
+			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom, r.benchmark_id, r.version,
 			         r.created_at, r.updated_at, u.name, i.name, tu.name
 			  FROM routines r
 			  JOIN users u ON r.created_by = u.id
@@ -43,7 +79,7 @@ func (r *RoutineRepository) GetByID(id int64) (*models.RoutineWithCreator, error
 		&routine.ID, &routine.Name, &routine.Description, &routine.Type, &routine.Content,
 		&routine.ContentScaled, &routine.ContentBeginner,
 		&routine.Duration, &routine.Difficulty, &routine.InstructorID, &routine.CreatedBy, &routine.Active,
-		&routine.Billable, &routine.TargetUserID, &routine.IsCustom,
+		&routine.Billable, &routine.TargetUserID, &routine.IsCustom, &routine.BenchmarkID, &routine.Version,
 		&routine.CreatedAt, &routine.UpdatedAt, &routine.CreatorName, &routine.InstructorName, &routine.TargetUserName,
 	)
 	if err != nil {
@@ -52,10 +88,11 @@ func (r *RoutineRepository) GetByID(id int64) (*models.RoutineWithCreator, error
 	return routine, nil
 }
 
-func (r *RoutineRepository) List(routineType string, custom *bool, limit, offset int) ([]*models.RoutineWithCreator, error) {
+// List filtra por tipo y personalizadas; publishedOnly oculta las rutinas aún no publicadas (vista de miembro)
+func (r *RoutineRepository) List(routineType string, custom *bool, publishedOnly bool, limit, offset int) ([]*models.RoutineWithCreator, error) {
 	query := `SELECT r.id, r.name, r.description, r.type, r.content, COALESCE(r.content_scaled,''), COALESCE(r.content_beginner,''),
 			         r.duration, r.difficulty,
-			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom,
+			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom, r.benchmark_id, r.version,
 			         r.created_at, r.updated_at, u.name, i.name, tu.name
 			  FROM routines r
 			  JOIN users u ON r.created_by = u.id
@@ -75,6 +112,9 @@ func (r *RoutineRepository) List(routineType string, custom *bool, limit, offset
 		args = append(args, *custom)
 		argN++
 	}
+	if publishedOnly {
+		query += " AND NOT " + routineUnpublished
+	}
 	query += " ORDER BY r.created_at DESC"
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argN, argN+1)
 	args = append(args, limit, offset)
@@ -92,7 +132,7 @@ func (r *RoutineRepository) List(routineType string, custom *bool, limit, offset
 			&routine.ID, &routine.Name, &routine.Description, &routine.Type, &routine.Content,
 			&routine.ContentScaled, &routine.ContentBeginner,
 			&routine.Duration, &routine.Difficulty, &routine.InstructorID, &routine.CreatedBy, &routine.Active,
-			&routine.Billable, &routine.TargetUserID, &routine.IsCustom,
+			&routine.Billable, &routine.TargetUserID, &routine.IsCustom, &routine.BenchmarkID, &routine.Version,
 			&routine.CreatedAt, &routine.UpdatedAt, &routine.CreatorName, &routine.InstructorName, &routine.TargetUserName,
 		); err != nil {
 			return nil, err
@@ -103,12 +143,16 @@ func (r *RoutineRepository) List(routineType string, custom *bool, limit, offset
 }
 
 func (r *RoutineRepository) Update(routine *models.Routine) error {
-	query := `UPDATE routines SET name=$1, description=$2, type=$3, content=$4, content_scaled=$5, content_beginner=$6, duration=$7, difficulty=$8, instructor_id=$9, active=$10, billable=$11, target_user_id=$12, is_custom=$13, updated_at=$14 WHERE id=$15`
+	return updateRoutine(r.db, routine)
+}
+
+func updateRoutine(q routineExecer, routine *models.Routine) error {
+	query := `UPDATE routines SET name=$1, description=$2, type=$3, content=$4, content_scaled=$5, content_beginner=$6, duration=$7, difficulty=$8, instructor_id=$9, active=$10, billable=$11, target_user_id=$12, is_custom=$13, updated_at=$14, benchmark_id=$15 WHERE id=$16`
 	routine.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query, routine.Name, routine.Description, routine.Type, routine.Content,
+	_, err := q.Exec(query, routine.Name, routine.Description, routine.Type, routine.Content,
 		routine.ContentScaled, routine.ContentBeginner,
 		routine.Duration, routine.Difficulty, routine.InstructorID, routine.Active,
-		routine.Billable, routine.TargetUserID, routine.IsCustom, routine.UpdatedAt, routine.ID)
+		routine.Billable, routine.TargetUserID, routine.IsCustom, routine.UpdatedAt, routine.BenchmarkID, routine.ID)
 	return err
 }
 
@@ -120,7 +164,7 @@ func (r *RoutineRepository) Delete(id int64) error {
 func (r *RoutineRepository) ListCustom(targetUserID *int64) ([]*models.RoutineWithCreator, error) {
 	query := `SELECT r.id, r.name, r.description, r.type, r.content, COALESCE(r.content_scaled,''), COALESCE(r.content_beginner,''),
 			         r.duration, r.difficulty,
-			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom,
+			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom, r.benchmark_id, r.version,
 			         r.created_at, r.updated_at, u.name, i.name, tu.name
 			  FROM routines r
 			  JOIN users u ON r.created_by = u.id
@@ -148,7 +192,7 @@ func (r *RoutineRepository) ListCustom(targetUserID *int64) ([]*models.RoutineWi
 			&routine.ID, &routine.Name, &routine.Description, &routine.Type, &routine.Content,
 			&routine.ContentScaled, &routine.ContentBeginner,
 			&routine.Duration, &routine.Difficulty, &routine.InstructorID, &routine.CreatedBy, &routine.Active,
-			&routine.Billable, &routine.TargetUserID, &routine.IsCustom,
+			&routine.Billable, &routine.TargetUserID, &routine.IsCustom, &routine.BenchmarkID, &routine.Version,
 			&routine.CreatedAt, &routine.UpdatedAt, &routine.CreatorName, &routine.InstructorName, &routine.TargetUserName,
 		); err != nil {
 			return nil, err
@@ -166,24 +210,31 @@ func (r *RoutineRepository) RemoveScheduleRoutine(scheduleID int64) error {
 // Schedule Routines
 
 func (r *RoutineRepository) AssignToSchedule(sr *models.ScheduleRoutine) error {
-	query := `INSERT INTO schedule_routines (class_schedule_id, routine_id, notes)
-			  VALUES ($1, $2, $3)
-			  ON CONFLICT (class_schedule_id) DO UPDATE SET routine_id = $2, notes = $3
-			  RETURNING id, created_at`
-	return r.db.QueryRow(query, sr.ClassScheduleID, sr.RoutineID, sr.Notes).Scan(&sr.ID, &sr.CreatedAt)
+	// Una asignación manual reemplaza la del track y deja de seguirlo; se fija la versión vigente de la rutina
+	query := `INSERT INTO schedule_routines (class_schedule_id, routine_id, notes, publish_at, routine_version_id)
+			  VALUES ($1, $2, $3, $4, ` + latestVersionSQL("$2") + `)
+			  ON CONFLICT (class_schedule_id) DO UPDATE SET routine_id = $2, notes = $3, publish_at = $4, track_day_id = NULL,
+			      routine_version_id = EXCLUDED.routine_version_id
+			  RETURNING id, routine_version_id, created_at`
+	sr.TrackDayID = nil
+	return r.db.QueryRow(query, sr.ClassScheduleID, sr.RoutineID, sr.Notes, sr.PublishAt).Scan(&sr.ID, &sr.RoutineVersionID, &sr.CreatedAt)
 }
 
 func (r *RoutineRepository) GetScheduleRoutine(scheduleID int64) (*models.ScheduleRoutineWithDetails, error) {
 	sr := &models.ScheduleRoutineWithDetails{}
-	query := `SELECT sr.id, sr.class_schedule_id, sr.routine_id, sr.notes, sr.created_at,
-			         rt.name, rt.type, rt.content, COALESCE(rt.content_scaled,''), COALESCE(rt.content_beginner,'')
+	// El contenido es el de la versión fijada, no el de la última edición
+	query := `SELECT sr.id, sr.class_schedule_id, sr.routine_id, sr.notes, sr.routine_version_id, sr.track_day_id, sr.publish_at, sr.created_at,
+			         COALESCE(rv.name, rt.name), COALESCE(rv.version, rt.version), COALESCE(rv.type, rt.type),
+			         COALESCE(rv.content, rt.content), COALESCE(rv.content_scaled, rt.content_scaled, ''),
+			         COALESCE(rv.content_beginner, rt.content_beginner, '')
 			  FROM schedule_routines sr
 			  JOIN routines rt ON sr.routine_id = rt.id
+			  LEFT JOIN routine_versions rv ON rv.id = sr.routine_version_id
 			  WHERE sr.class_schedule_id = $1`
 
 	err := r.db.QueryRow(query, scheduleID).Scan(
-		&sr.ID, &sr.ClassScheduleID, &sr.RoutineID, &sr.Notes, &sr.CreatedAt,
-		&sr.RoutineName, &sr.RoutineType, &sr.RoutineContent,
+		&sr.ID, &sr.ClassScheduleID, &sr.RoutineID, &sr.Notes, &sr.RoutineVersionID, &sr.TrackDayID, &sr.PublishAt, &sr.CreatedAt,
+		&sr.RoutineName, &sr.RoutineVersion, &sr.RoutineType, &sr.RoutineContent,
 		&sr.RoutineContentScaled, &sr.RoutineContentBeginner,
 	)
 	if err != nil {
@@ -201,11 +252,14 @@ func (r *RoutineRepository) LogResult(result *models.UserRoutineResult) error {
 		result.UserID, result.RoutineID).Scan(&count)
 	isPR := count == 0
 
-	query := `INSERT INTO user_routine_results (user_id, routine_id, class_schedule_id, score, notes, rx, is_pr)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7)
-			  RETURNING id, created_at`
+	// Versión realizada: la fijada en la clase si es esa rutina, si no la vigente
+	query := `INSERT INTO user_routine_results (user_id, routine_id, class_schedule_id, score, notes, rx, is_pr, routine_version_id)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(
+			      (SELECT routine_version_id FROM schedule_routines WHERE class_schedule_id = $3 AND routine_id = $2),
+			      ` + latestVersionSQL("$2") + `))
+			  RETURNING id, routine_version_id, created_at`
 	err := r.db.QueryRow(query, result.UserID, result.RoutineID, result.ClassScheduleID,
-		result.Score, result.Notes, result.Rx, isPR).Scan(&result.ID, &result.CreatedAt)
+		result.Score, result.Notes, result.Rx, isPR).Scan(&result.ID, &result.RoutineVersionID, &result.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -218,7 +272,7 @@ func (r *RoutineRepository) GetUserResults(userID int64, limit int, offset ...in
 	if len(offset) > 0 {
 		off = offset[0]
 	}
-	query := `SELECT urr.id, urr.user_id, urr.routine_id, urr.class_schedule_id, urr.score, urr.notes, urr.rx, COALESCE(urr.is_pr, false), urr.created_at,
+	query := `SELECT urr.id, urr.user_id, urr.routine_id, urr.class_schedule_id, urr.score, urr.notes, urr.rx, COALESCE(urr.is_pr, false), urr.routine_version_id, urr.created_at,
 			         rt.name, rt.type, cs.date,
 			         (SELECT COUNT(*) FROM fistbumps fb WHERE fb.result_id = urr.id)
 			  FROM user_routine_results urr
@@ -238,7 +292,7 @@ func (r *RoutineRepository) GetUserResults(userID int64, limit int, offset ...in
 	for rows.Next() {
 		res := &models.UserResultWithDetails{}
 		if err := rows.Scan(
-			&res.ID, &res.UserID, &res.RoutineID, &res.ClassScheduleID, &res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.CreatedAt,
+			&res.ID, &res.UserID, &res.RoutineID, &res.ClassScheduleID, &res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.RoutineVersionID, &res.CreatedAt,
 			&res.RoutineName, &res.RoutineType, &res.ScheduleDate, &res.FistbumpCount,
 		); err != nil {
 			return nil, err
@@ -249,7 +303,7 @@ func (r *RoutineRepository) GetUserResults(userID int64, limit int, offset ...in
 }
 
 func (r *RoutineRepository) GetRoutineHistory(routineID int64, userID int64) ([]*models.UserRoutineResult, error) {
-	query := `SELECT id, user_id, routine_id, class_schedule_id, score, notes, rx, COALESCE(is_pr, false), created_at
+	query := `SELECT id, user_id, routine_id, class_schedule_id, score, notes, rx, COALESCE(is_pr, false), routine_version_id, created_at
 			  FROM user_routine_results
 			  WHERE routine_id = $1 AND user_id = $2
 			  ORDER BY created_at DESC`
@@ -264,7 +318,7 @@ func (r *RoutineRepository) GetRoutineHistory(routineID int64, userID int64) ([]
 	for rows.Next() {
 		res := &models.UserRoutineResult{}
 		if err := rows.Scan(&res.ID, &res.UserID, &res.RoutineID, &res.ClassScheduleID,
-			&res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.CreatedAt); err != nil {
+			&res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.RoutineVersionID, &res.CreatedAt); err != nil {
 			return nil, err
 		}
 		results = append(results, res)
@@ -274,13 +328,13 @@ func (r *RoutineRepository) GetRoutineHistory(routineID int64, userID int64) ([]
 
 func (r *RoutineRepository) GetResultByID(resultID int64) (*models.UserRoutineResult, error) {
 	result := &models.UserRoutineResult{}
-	query := `SELECT id, user_id, routine_id, class_schedule_id, score, notes, rx, COALESCE(is_pr, false), created_at
+	query := `SELECT id, user_id, routine_id, class_schedule_id, score, notes, rx, COALESCE(is_pr, false), routine_version_id, created_at
 			  FROM user_routine_results
 			  WHERE id = $1`
 
 	err := r.db.QueryRow(query, resultID).Scan(
 		&result.ID, &result.UserID, &result.RoutineID, &result.ClassScheduleID,
-		&result.Score, &result.Notes, &result.Rx, &result.IsPR, &result.CreatedAt,
+		&result.Score, &result.Notes, &result.Rx, &result.IsPR, &result.RoutineVersionID, &result.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -303,7 +357,7 @@ func (r *RoutineRepository) DeleteResult(resultID int64, userID int64) error {
 }
 
 func (r *RoutineRepository) GetUserPRs(userID int64) ([]*models.UserResultWithDetails, error) {
-	query := `SELECT urr.id, urr.user_id, urr.routine_id, urr.class_schedule_id, urr.score, urr.notes, urr.rx, COALESCE(urr.is_pr, false), urr.created_at,
+	query := `SELECT urr.id, urr.user_id, urr.routine_id, urr.class_schedule_id, urr.score, urr.notes, urr.rx, COALESCE(urr.is_pr, false), urr.routine_version_id, urr.created_at,
 			         rt.name, rt.type, cs.date,
 			         (SELECT COUNT(*) FROM fistbumps fb WHERE fb.result_id = urr.id)
 			  FROM user_routine_results urr
@@ -322,7 +376,7 @@ func (r *RoutineRepository) GetUserPRs(userID int64) ([]*models.UserResultWithDe
 	for rows.Next() {
 		res := &models.UserResultWithDetails{}
 		if err := rows.Scan(
-			&res.ID, &res.UserID, &res.RoutineID, &res.ClassScheduleID, &res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.CreatedAt,
+			&res.ID, &res.UserID, &res.RoutineID, &res.ClassScheduleID, &res.Score, &res.Notes, &res.Rx, &res.IsPR, &res.RoutineVersionID, &res.CreatedAt,
 			&res.RoutineName, &res.RoutineType, &res.ScheduleDate, &res.FistbumpCount,
 		); err != nil {
 			return nil, err
@@ -337,6 +391,8 @@ func (r *RoutineRepository) GetLeaderboard(scheduleID int64) ([]*models.Leaderbo
 			  FROM user_routine_results urr
 			  JOIN users u ON urr.user_id = u.id
 			  WHERE urr.class_schedule_id = $1
+			  AND NOT EXISTS (SELECT 1 FROM schedule_routines sr WHERE sr.class_schedule_id = $1
+			                  AND sr.routine_version_id IS DISTINCT FROM urr.routine_version_id)
 			  ORDER BY urr.rx DESC, urr.score ASC`
 
 	rows, err := r.db.Query(query, scheduleID)
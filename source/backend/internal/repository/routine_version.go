@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+
+	"boxmagic/internal/models"
+)
+
+// Versiones inmutables de RoutineRepository
+
+// latestVersionSQL - Subconsulta con la versión vigente de la rutina indicada por el parámetro
+func latestVersionSQL(routineParam string) string {
+	return `(SELECT id FROM routine_versions WHERE routine_id = ` + routineParam + ` ORDER BY version DESC LIMIT 1)`
+}
+
+// blocksSnapshot serializa los bloques sin IDs, que cambian cada vez que se guardan
+func blocksSnapshot(blocks []*models.WorkoutBlock) []byte {
+	if len(blocks) == 0 {
+		return nil
+	}
+	clean := make([]models.WorkoutBlock, len(blocks))
+	for i, b := range blocks {
+		clean[i] = *b
+		clean[i].ID = 0
+		clean[i].Lines = make([]*models.WorkoutLine, len(b.Lines))
+		for j, l := range b.Lines {
+			line := *l
+			line.ID = 0
+			clean[i].Lines[j] = &line
+		}
+	}
+	raw, _ := json.Marshal(clean)
+	return raw
+}
+
+const routineVersionColumns = `v.id, v.routine_id, v.version, v.name, v.description, v.type, v.content, v.content_scaled,
+	v.content_beginner, v.duration, v.difficulty, v.benchmark_id, v.blocks, v.rolled_back_from, v.created_by,
+	COALESCE(u.name, ''), v.created_at,
+	(SELECT COUNT(*) FROM schedule_routines sr WHERE sr.routine_version_id = v.id),
+	(SELECT COUNT(*) FROM user_routine_results urr WHERE urr.routine_version_id = v.id)`
+
+func scanRoutineVersion(row interface{ Scan(...interface{}) error }) (*models.RoutineVersion, error) {
+	v := &models.RoutineVersion{}
+	var blocks []byte
+	err := row.Scan(&v.ID, &v.RoutineID, &v.Version, &v.Name, &v.Description, &v.Type, &v.Content, &v.ContentScaled,
+		&v.ContentBeginner, &v.Duration, &v.Difficulty, &v.BenchmarkID, &blocks, &v.RolledBackFrom, &v.CreatedBy,
+		&v.CreatedByName, &v.CreatedAt, &v.Schedules, &v.Results)
+	if err != nil {
+		return nil, err
+	}
+	if blocks != nil {
+		_ = json.Unmarshal(blocks, &v.Blocks)
+	}
+	return v, nil
+}
+
+// SaveRevision guarda la rutina (la crea si aún no tiene ID), reemplaza sus bloques si replaceBlocks y registra
+// la versión, todo en una sola transacción: si algo falla la rutina queda como estaba.
+// created indica si se creó una versión nueva.
+func (r *RoutineRepository) SaveRevision(routine *models.Routine, replace bool, createdBy int64, rolledBackFrom *int) (v *models.RoutineVersion, created bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	if routine.ID == 0 {
+		err = createRoutine(tx, routine)
+	} else {
+		err = updateRoutine(tx, routine)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if replace {
+		if err := replaceBlocks(tx, routine); err != nil {
+			return nil, false, err
+		}
+	}
+	version, created, err := saveVersion(tx, routine.ID, createdBy, rolledBackFrom)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	v, err = r.GetVersion(routine.ID, version)
+	return v, created, err
+}
+
+// saveVersion guarda el estado actual de la rutina como nueva versión si cambió respecto de la vigente
+// (o si es una restauración) y la fija en las clases de hoy en adelante que aún no tienen resultados.
+// Devuelve el número de la versión vigente.
+func saveVersion(tx *sql.Tx, routineID, createdBy int64, rolledBackFrom *int) (int, bool, error) {
+	cur := &models.RoutineVersion{RoutineID: routineID}
+	err := tx.QueryRow(`SELECT name, COALESCE(description, ''), COALESCE(type, 'wod'), content, COALESCE(content_scaled, ''),
+			COALESCE(content_beginner, ''), COALESCE(duration, 0), COALESCE(difficulty, ''), benchmark_id
+		FROM routines WHERE id = $1 FOR UPDATE`, routineID).Scan(&cur.Name, &cur.Description, &cur.Type, &cur.Content,
+		&cur.ContentScaled, &cur.ContentBeginner, &cur.Duration, &cur.Difficulty, &cur.BenchmarkID)
+	if err != nil {
+		return 0, false, err
+	}
+	blocks, err := getBlocks(tx, routineID)
+	if err != nil {
+		return 0, false, err
+	}
+	snapshot := blocksSnapshot(blocks)
+
+	latest, err := scanRoutineVersion(tx.QueryRow(`SELECT `+routineVersionColumns+`
+		FROM routine_versions v LEFT JOIN users u ON u.id = v.created_by
+		WHERE v.routine_id = $1 ORDER BY v.version DESC LIMIT 1`, routineID))
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	if latest != nil && rolledBackFrom == nil && sameVersionContent(latest, cur) &&
+		bytes.Equal(blocksSnapshot(latest.Blocks), snapshot) {
+		return latest.Version, false, nil
+	}
+
+	next := 1
+	if latest != nil {
+		next = latest.Version + 1
+	}
+	err = tx.QueryRow(`INSERT INTO routine_versions (routine_id, version, name, description, type, content, content_scaled,
+			content_beginner, duration, difficulty, benchmark_id, blocks, rolled_back_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at`,
+		routineID, next, cur.Name, cur.Description, cur.Type, cur.Content, cur.ContentScaled, cur.ContentBeginner,
+		cur.Duration, cur.Difficulty, cur.BenchmarkID, snapshot, rolledBackFrom, createdBy).Scan(&cur.ID, &cur.CreatedAt)
+	if err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.Exec(`UPDATE routines SET version = $1 WHERE id = $2`, next, routineID); err != nil {
+		return 0, false, err
+	}
+	// Las clases ya realizadas (con resultados) conservan la versión con la que se hicieron
+	if _, err := tx.Exec(`UPDATE schedule_routines sr SET routine_version_id = $1
+		FROM class_schedules cs
+		WHERE sr.class_schedule_id = cs.id AND sr.routine_id = $2 AND cs.date >= CURRENT_DATE
+		AND NOT EXISTS (SELECT 1 FROM user_routine_results urr WHERE urr.class_schedule_id = cs.id)`, cur.ID, routineID); err != nil {
+		return 0, false, err
+	}
+	return next, true, nil
+}
+
+func sameVersionContent(a, b *models.RoutineVersion) bool {
+	sameBenchmark := (a.BenchmarkID == nil && b.BenchmarkID == nil) ||
+		(a.BenchmarkID != nil && b.BenchmarkID != nil && *a.BenchmarkID == *b.BenchmarkID)
+	return a.Name == b.Name && a.Description == b.Description && a.Type == b.Type && a.Content == b.Content &&
+		a.ContentScaled == b.ContentScaled && a.ContentBeginner == b.ContentBeginner && a.Duration == b.Duration &&
+		a.Difficulty == b.Difficulty && sameBenchmark
+}
+
+// ListVersions devuelve el historial de la rutina, de la más reciente a la más antigua
+func (r *RoutineRepository) ListVersions(routineID int64) ([]*models.RoutineVersion, error) {
+	rows, err := r.db.Query(`SELECT `+routineVersionColumns+`
+		FROM routine_versions v LEFT JOIN users u ON u.id = v.created_by
+		WHERE v.routine_id = $1 ORDER BY v.version DESC`, routineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*models.RoutineVersion{}
+	for rows.Next() {
+		v, err := scanRoutineVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+func (r *RoutineRepository) GetVersion(routineID int64, version int) (*models.RoutineVersion, error) {
+	return scanRoutineVersion(r.db.QueryRow(`SELECT `+routineVersionColumns+`
+		FROM routine_versions v LEFT JOIN users u ON u.id = v.created_by
+		WHERE v.routine_id = $1 AND v.version = $2`, routineID, version))
+}
@@ -135,6 +135,68 @@ func SeedRoutines(db *sql.DB) error {
 	return nil
 }
 
+// SeedBenchmarks carga los benchmarks estándar si el catálogo está vacío y vincula las rutinas existentes con el mismo nombre
+func SeedBenchmarks(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM benchmarks").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	log.Println("No benchmarks found, seeding standard benchmarks...")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	benchmarks := []struct {
+		name, category, scoreType, content string
+		timeCap                            int
+	}{
+		{"Fran", "girl", "time", "21-15-9\nThruster 43/30 kg\nPull-up", 10},
+		{"Grace", "girl", "time", "30 Clean & Jerk 61/43 kg", 10},
+		{"Isabel", "girl", "time", "30 Snatch 61/43 kg", 10},
+		{"Diane", "girl", "time", "21-15-9\nDeadlift 102/70 kg\nHandstand Push-up", 15},
+		{"Elizabeth", "girl", "time", "21-15-9\nSquat Clean 61/43 kg\nRing Dip", 15},
+		{"Helen", "girl", "time", "3 rondas\n400 m Run\n21 KB Swing 24/16 kg\n12 Pull-up", 20},
+		{"Karen", "girl", "time", "150 Wall Ball 9/6 kg", 15},
+		{"Annie", "girl", "time", "50-40-30-20-10\nDouble-under\nSit-up", 15},
+		{"Jackie", "girl", "time", "1000 m Row\n50 Thruster 20/15 kg\n30 Pull-up", 20},
+		{"Nancy", "girl", "time", "5 rondas\n400 m Run\n15 Overhead Squat 43/30 kg", 25},
+		{"Kelly", "girl", "time", "5 rondas\n400 m Run\n30 Box Jump 60/50 cm\n30 Wall Ball 9/6 kg", 40},
+		{"Cindy", "girl", "rounds_reps", "AMRAP 20 min\n5 Pull-up\n10 Push-up\n15 Air Squat", 20},
+		{"Mary", "girl", "rounds_reps", "AMRAP 20 min\n5 Handstand Push-up\n10 Pistol\n15 Pull-up", 20},
+		{"Murph", "hero", "time", "1 mile Run\n100 Pull-up\n200 Push-up\n300 Air Squat\n1 mile Run\n(con chaleco 9/6 kg)", 60},
+		{"DT", "hero", "time", "5 rondas\n12 Deadlift 70/47 kg\n9 Hang Power Clean 70/47 kg\n6 Push Jerk 70/47 kg", 20},
+		{"JT", "hero", "time", "21-15-9\nHandstand Push-up\nRing Dip\nPush-up", 20},
+		{"Michael", "hero", "time", "3 rondas\n800 m Run\n50 Back Extension\n50 Sit-up", 40},
+		{"Open 14.5", "open", "time", "21-18-15-12-9-6-3\nThruster 43/30 kg\nBar-facing Burpee", 0},
+		{"Open 20.1", "open", "time", "10 rondas\n8 Ground-to-Overhead 43/30 kg\n10 Bar-facing Burpee", 15},
+		{"Open 23.1", "open", "reps", "AMRAP 14 min\n60 cal Row\n50 Toes-to-bar\n40 Wall Ball 9/6 kg\n30 Clean 61/43 kg\n20 Muscle-up", 14},
+	}
+	for _, b := range benchmarks {
+		if _, err := tx.Exec(`INSERT INTO benchmarks (name, category, score_type, content, time_cap)
+			VALUES ($1, $2, $3, $4, $5) ON CONFLICT (name) DO NOTHING`,
+			b.name, b.category, b.scoreType, b.content, b.timeCap); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE routines r SET benchmark_id = b.id FROM benchmarks b
+		WHERE r.benchmark_id IS NULL AND LOWER(r.name) = LOWER(b.name)`); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Println("Standard benchmarks seeded successfully")
+	return nil
+}
+
 // SeedClasses ensures default classes and schedules exist in the database
 func SeedClasses(db *sql.DB) error {
 	var classCount int
@@ -292,6 +354,9 @@ func Seed(db *sql.DB) error {
 		if err := SeedClasses(db); err != nil {
 			log.Printf("Warning: Failed to seed classes: %v", err)
 		}
+		if err := SeedBenchmarks(db); err != nil {
+			log.Printf("Warning: Failed to seed benchmarks: %v", err)
+		}
 		return nil
 	}
 
@@ -393,6 +458,9 @@ func Seed(db *sql.DB) error {
 	if err := SeedClasses(db); err != nil {
 		log.Printf("Warning: Failed to seed classes: %v", err)
 	}
+	if err := SeedBenchmarks(db); err != nil {
+		log.Printf("Warning: Failed to seed benchmarks: %v", err)
+	}
 
 	log.Println("Seed data created successfully")
 	log.Println("  Admin: admin@boxmagic.cl / admin123")
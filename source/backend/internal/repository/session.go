@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// SessionRepository - Sesiones de login y sus refresh tokens (guardados como hash)
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(s *models.Session) error {
+	return r.db.QueryRow(`INSERT INTO auth_sessions (user_id, device, user_agent, ip, remember) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_used_at`,
+		s.UserID, s.Device, s.UserAgent, s.IP, s.Remember).Scan(&s.ID, &s.CreatedAt, &s.LastUsedAt)
+}
+
+func (r *SessionRepository) SaveRefreshToken(sessionID, userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO refresh_tokens (session_id, user_id, token, expires_at) VALUES ($1, $2, $3, $4)`,
+		sessionID, userID, tokenHash, expiresAt)
+	return err
+}
+
+// GetRefreshToken busca el token por su hash, incluyendo los ya rotados (para detectar reutilización)
+func (r *SessionRepository) GetRefreshToken(tokenHash string) (*models.RefreshTokenRecord, error) {
+	rec := &models.RefreshTokenRecord{}
+	err := r.db.QueryRow(`SELECT t.id, t.user_id, t.session_id, t.expires_at, t.used_at, s.revoked_at IS NOT NULL, COALESCE(s.remember, false)
+		FROM refresh_tokens t JOIN auth_sessions s ON s.id = t.session_id
+		WHERE t.token = $1`, tokenHash).
+		Scan(&rec.ID, &rec.UserID, &rec.SessionID, &rec.ExpiresAt, &rec.UsedAt, &rec.SessionRevoked, &rec.Remember)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// MarkRefreshTokenUsed rota el token; devuelve false si otro request ya lo había usado
+func (r *SessionRepository) MarkRefreshTokenUsed(id int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *SessionRepository) TouchSession(id int64, client models.ClientInfo) error {
+	_, err := r.db.Exec(`UPDATE auth_sessions SET last_used_at = NOW(), ip = COALESCE(NULLIF($2,''), ip) WHERE id = $1`, id, client.IP)
+	return err
+}
+
+// RevokeSession revoca una sesión del usuario y elimina sus refresh tokens vigentes
+func (r *SessionRepository) RevokeSession(userID, sessionID int64, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, userID, reason)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec(`DELETE FROM refresh_tokens WHERE session_id = $1 AND used_at IS NULL`, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeAllSessions revoca todas las sesiones e incrementa token_version, invalidando al instante los access tokens emitidos
+func (r *SessionRepository) RevokeAllSessions(userID int64, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE auth_sessions SET revoked_at = NOW(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, reason); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM refresh_tokens WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE users SET token_version = COALESCE(token_version,0) + 1 WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListSessions lista las sesiones activas del usuario
+func (r *SessionRepository) ListSessions(userID int64) ([]*models.Session, error) {
+	rows, err := r.db.Query(`SELECT id, user_id, COALESCE(device,''), COALESCE(user_agent,''), COALESCE(ip,''), created_at, last_used_at, COALESCE(remember, false)
+		FROM auth_sessions WHERE user_id = $1 AND revoked_at IS NULL
+		AND EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.session_id = auth_sessions.id AND t.used_at IS NULL AND t.expires_at > NOW())
+		ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		s := &models.Session{}
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.Remember); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// AccessState devuelve lo necesario para validar un access token: versión vigente, usuario activo y sesión no revocada
+func (r *SessionRepository) AccessState(userID, sessionID int64) (tokenVersion int, active, sessionValid bool, err error) {
+	err = r.db.QueryRow(`SELECT COALESCE(u.token_version,0), u.active,
+			($2 = 0 OR EXISTS (SELECT 1 FROM auth_sessions s WHERE s.id = $2 AND s.user_id = u.id AND s.revoked_at IS NULL))
+		FROM users u WHERE u.id = $1`, userID, sessionID).Scan(&tokenVersion, &active, &sessionValid)
+	return
+}
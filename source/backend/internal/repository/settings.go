@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// SettingsRepository - Configuración general del gimnasio (clave/valor)
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get devuelve "" si la clave no está configurada
+func (r *SettingsRepository) Get(key string) (string, error) {
+	var value string
+	err := r.db.QueryRow(`SELECT value FROM app_settings WHERE key = $1`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (r *SettingsRepository) Set(key, value string, updatedBy int64) error {
+	_, err := r.db.Exec(`INSERT INTO app_settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+		key, value, updatedBy)
+	return err
+}
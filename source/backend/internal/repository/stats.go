@@ -288,5 +288,24 @@ func (r *StatsRepository) GetMonthlyReport(month string) (*models.MonthlyReport,
 	report.TopPlans, _ = r.GetPlanStats()
 	report.TopClasses, _ = r.GetClassPopularity(5)
 
+	// Cuadratura de caja: solo sesiones cerradas dentro del mes
+	r.db.QueryRow("SELECT COALESCE(SUM(total), 0) FROM sales WHERE created_at >= $1 AND created_at < $2", startDate, endDate).Scan(&report.SalesRevenue)
+	r.db.QueryRow("SELECT COUNT(*) FROM cash_sessions WHERE status = 'closed' AND closed_at >= $1 AND closed_at < $2", startDate, endDate).Scan(&report.CashSessionsClosed)
+	report.CashByMethod = []models.CashMethodTotal{}
+	rows, err := r.db.Query(`SELECT t.method, SUM(t.expected), SUM(t.counted), SUM(t.difference)
+		FROM cash_session_totals t JOIN cash_sessions s ON t.session_id = s.id
+		WHERE s.status = 'closed' AND s.closed_at >= $1 AND s.closed_at < $2
+		GROUP BY t.method ORDER BY t.method`, startDate, endDate)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var t models.CashMethodTotal
+			if err := rows.Scan(&t.Method, &t.Expected, &t.Counted, &t.Difference); err == nil {
+				report.CashByMethod = append(report.CashByMethod, t)
+				report.CashDiscrepancy += t.Difference
+			}
+		}
+	}
+
 	return report, nil
 }
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// StrengthRepository - Registro de levantamientos por movimiento
+type StrengthRepository struct {
+	db *sql.DB
+}
+
+func NewStrengthRepository(db *sql.DB) *StrengthRepository {
+	return &StrengthRepository{db: db}
+}
+
+const liftColumns = `l.id, l.user_id, l.movement_id, m.name, l.routine_id, l.sets, l.reps, l.load_kg::float8, l.rpe::float8,
+	COALESCE(l.notes, ''), l.logged_on, l.estimated_1rm::float8, l.is_pr, l.created_at`
+
+func scanLift(row interface{ Scan(...interface{}) error }) (*models.LiftLog, error) {
+	l := &models.LiftLog{}
+	err := row.Scan(&l.ID, &l.UserID, &l.MovementID, &l.MovementName, &l.RoutineID, &l.Sets, &l.Reps, &l.LoadKg, &l.RPE,
+		&l.Notes, &l.LoggedOn, &l.Estimated1RM, &l.IsPR, &l.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Create registra la serie; es PR si supera la mejor carga previa del miembro para esas repeticiones
+func (r *StrengthRepository) Create(l *models.LiftLog) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serializa los registros del miembro en el movimiento para que dos PR simultáneos no se pisen
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, l.UserID, l.MovementID); err != nil {
+		return err
+	}
+	var best sql.NullFloat64
+	if err := tx.QueryRow(`SELECT MAX(load_kg)::float8 FROM lift_logs WHERE user_id = $1 AND movement_id = $2 AND reps = $3`,
+		l.UserID, l.MovementID, l.Reps).Scan(&best); err != nil {
+		return err
+	}
+	l.IsPR = !best.Valid || l.LoadKg > best.Float64
+
+	err = tx.QueryRow(`INSERT INTO lift_logs (user_id, movement_id, routine_id, sets, reps, load_kg, rpe, notes, logged_on, estimated_1rm, is_pr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11) RETURNING id, created_at`,
+		l.UserID, l.MovementID, l.RoutineID, l.Sets, l.Reps, l.LoadKg, l.RPE, l.Notes, l.LoggedOn, l.Estimated1RM, l.IsPR).
+		Scan(&l.ID, &l.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *StrengthRepository) Get(id int64) (*models.LiftLog, error) {
+	return scanLift(r.db.QueryRow(`SELECT `+liftColumns+` FROM lift_logs l JOIN movements m ON m.id = l.movement_id WHERE l.id = $1`, id))
+}
+
+// List devuelve los levantamientos del miembro, del más reciente al más antiguo (movementID 0 = todos)
+func (r *StrengthRepository) List(userID, movementID int64, limit int) ([]*models.LiftLog, error) {
+	rows, err := r.db.Query(`SELECT `+liftColumns+` FROM lift_logs l JOIN movements m ON m.id = l.movement_id
+		WHERE l.user_id = $1 AND ($2 = 0 OR l.movement_id = $2)
+		ORDER BY l.logged_on DESC, l.id DESC LIMIT $3`, userID, movementID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.LiftLog
+	for rows.Next() {
+		l, err := scanLift(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (r *StrengthRepository) Delete(id, userID int64) error {
+	return execExpectingRow(r.db, `DELETE FROM lift_logs WHERE id = $1 AND user_id = $2`, id, userID)
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"boxmagic/internal/models"
+)
+
+var (
+	ErrNoFoliosAvailable = errors.New("no CAF folios available for document type")
+	ErrTaxDocumentExists = errors.New("tax document already issued for this source")
+)
+
+type TaxRepository struct {
+	db *sql.DB
+}
+
+func NewTaxRepository(db *sql.DB) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+// CreateCAF guarda un rango de folios autorizado junto con su llave privada (para timbrar)
+func (r *TaxRepository) CreateCAF(caf *models.CAF, rawCAF, privateKey string) error {
+	caf.NextFolio = caf.FolioFrom
+	err := r.db.QueryRow(`INSERT INTO tax_cafs (doc_type, folio_from, folio_to, next_folio, authorized_at, caf_xml, private_key)
+		VALUES ($1, $2, $3, $2, $4, $5, $6) RETURNING id, created_at`,
+		caf.DocType, caf.FolioFrom, caf.FolioTo, caf.AuthorizedAt, rawCAF, privateKey).Scan(&caf.ID, &caf.CreatedAt)
+	if err != nil {
+		return err
+	}
+	caf.Remaining = caf.FolioTo - caf.FolioFrom + 1
+	return nil
+}
+
+func (r *TaxRepository) ListCAFs() ([]*models.CAF, error) {
+	rows, err := r.db.Query(`SELECT id, doc_type, folio_from, folio_to, next_folio, COALESCE(authorized_at,''), GREATEST(folio_to - next_folio + 1, 0), created_at
+		FROM tax_cafs ORDER BY doc_type, folio_from`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cafs []*models.CAF
+	for rows.Next() {
+		c := &models.CAF{}
+		if err := rows.Scan(&c.ID, &c.DocType, &c.FolioFrom, &c.FolioTo, &c.NextFolio, &c.AuthorizedAt, &c.Remaining, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		cafs = append(cafs, c)
+	}
+	return cafs, nil
+}
+
+// IssueDocument toma el siguiente folio disponible, timbra el documento con stamp y lo guarda, todo en una transacción
+// para no dejar folios saltados si algo falla.
+func (r *TaxRepository) IssueDocument(doc *models.TaxDocument, stamp func(rawCAF, privateKey string) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM tax_documents WHERE source_type = $1 AND source_id = $2)`,
+		doc.SourceType, doc.SourceID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrTaxDocumentExists
+	}
+
+	var cafID int64
+	var rawCAF, privateKey string
+	err = tx.QueryRow(`SELECT id, next_folio, caf_xml, private_key FROM tax_cafs
+		WHERE doc_type = $1 AND next_folio <= folio_to
+		ORDER BY folio_from LIMIT 1 FOR UPDATE`, doc.DocType).Scan(&cafID, &doc.Folio, &rawCAF, &privateKey)
+	if err == sql.ErrNoRows {
+		return ErrNoFoliosAvailable
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := stamp(rawCAF, privateKey); err != nil {
+		return err
+	}
+
+	receptor, _ := json.Marshal(doc.Receptor)
+	items, _ := json.Marshal(doc.Items)
+	err = tx.QueryRow(`INSERT INTO tax_documents (doc_type, folio, status, source_type, source_id, user_id, receptor, items,
+		net_amount, iva, exempt_amount, total, issued_at, xml, ted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at`,
+		doc.DocType, doc.Folio, doc.Status, doc.SourceType, doc.SourceID, doc.UserID, receptor, items,
+		doc.NetAmount, doc.IVA, doc.ExemptAmount, doc.Total, doc.IssuedAt, doc.XML, doc.TED).Scan(&doc.ID, &doc.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE tax_cafs SET next_folio = next_folio + 1 WHERE id = $1`, cafID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TaxRepository) UpdateSendResult(id int64, status, trackID, sendError string) error {
+	_, err := r.db.Exec(`UPDATE tax_documents SET status = $1, track_id = NULLIF($2,''), send_error = NULLIF($3,'') WHERE id = $4`,
+		status, trackID, sendError, id)
+	return err
+}
+
+const taxDocumentSelect = `SELECT id, doc_type, folio, status, source_type, source_id, user_id, receptor, items,
+	net_amount, iva, exempt_amount, total, issued_at, xml, ted, COALESCE(track_id,''), COALESCE(send_error,''), created_at
+	FROM tax_documents`
+
+func scanTaxDocument(scanner interface{ Scan(...interface{}) error }) (*models.TaxDocument, error) {
+	d := &models.TaxDocument{}
+	var receptor, items []byte
+	err := scanner.Scan(&d.ID, &d.DocType, &d.Folio, &d.Status, &d.SourceType, &d.SourceID, &d.UserID, &receptor, &items,
+		&d.NetAmount, &d.IVA, &d.ExemptAmount, &d.Total, &d.IssuedAt, &d.XML, &d.TED, &d.TrackID, &d.SendError, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(receptor, &d.Receptor)
+	_ = json.Unmarshal(items, &d.Items)
+	return d, nil
+}
+
+func (r *TaxRepository) GetDocument(id int64) (*models.TaxDocument, error) {
+	return scanTaxDocument(r.db.QueryRow(taxDocumentSelect+` WHERE id = $1`, id))
+}
+
+// ListDocuments lista documentos emitidos; userID filtra los de un miembro
+func (r *TaxRepository) ListDocuments(userID *int64, limit, offset int) ([]*models.TaxDocument, error) {
+	rows, err := r.db.Query(taxDocumentSelect+` WHERE ($1::int IS NULL OR user_id = $1)
+		ORDER BY issued_at DESC, id DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*models.TaxDocument
+	for rows.Next() {
+		d, err := scanTaxDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// PaymentSource devuelve los datos de un pago necesarios para emitir su documento
+func (r *TaxRepository) PaymentSource(paymentID int64) (payment *models.PaymentWithDetails, err error) {
+	p := &models.PaymentWithDetails{}
+	err = r.db.QueryRow(`SELECT p.id, p.user_id, p.amount, p.status, COALESCE(pl.name,''), COALESCE(u.name,''), COALESCE(u.email,'')
+		FROM payments p LEFT JOIN plans pl ON p.plan_id = pl.id LEFT JOIN users u ON p.user_id = u.id
+		WHERE p.id = $1`, paymentID).Scan(&p.ID, &p.UserID, &p.Amount, &p.Status, &p.PlanName, &p.UserName, &p.UserEmail)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SaleSource devuelve la venta con sus items
+func (r *TaxRepository) SaleSource(saleID int64) (*models.Sale, error) {
+	s := &models.Sale{}
+	err := r.db.QueryRow(`SELECT id, user_id, total, COALESCE(payment_method,'') FROM sales WHERE id = $1`, saleID).
+		Scan(&s.ID, &s.UserID, &s.Total, &s.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`SELECT id, product_name, quantity, unit_price, COALESCE(gift_card, false) FROM sale_items WHERE sale_id = $1 ORDER BY id`, saleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		it := models.SaleItem{SaleID: saleID}
+		if err := rows.Scan(&it.ID, &it.ProductName, &it.Quantity, &it.UnitPrice, &it.GiftCard); err != nil {
+			return nil, err
+		}
+		s.Items = append(s.Items, it)
+	}
+	return s, nil
+}
+
+// GetBillingProfile devuelve los datos de facturación del miembro; sin perfil usa su nombre y RUT personal
+func (r *TaxRepository) GetBillingProfile(userID int64) (*models.TaxReceptor, error) {
+	rec := &models.TaxReceptor{}
+	err := r.db.QueryRow(`SELECT COALESCE(NULLIF(b.rut,''), u.rut, ''), COALESCE(NULLIF(b.business_name,''), u.name),
+		COALESCE(b.activity,''), COALESCE(b.address,''), COALESCE(b.commune,''), COALESCE(NULLIF(b.email,''), u.email)
+		FROM users u LEFT JOIN billing_profiles b ON b.user_id = u.id WHERE u.id = $1`, userID).
+		Scan(&rec.RUT, &rec.BusinessName, &rec.Activity, &rec.Address, &rec.Commune, &rec.Email)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *TaxRepository) SaveBillingProfile(userID int64, rec *models.TaxReceptor) error {
+	_, err := r.db.Exec(`INSERT INTO billing_profiles (user_id, rut, business_name, activity, address, commune, email, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET rut = $2, business_name = $3, activity = $4, address = $5, commune = $6, email = $7, updated_at = NOW()`,
+		userID, rec.RUT, rec.BusinessName, rec.Activity, rec.Address, rec.Commune, rec.Email)
+	return err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+func TestTaxRepository_IssuesSequentialFolios(t *testing.T) {
+	repo := NewTaxRepository(testTenantDB(t, "folio-test"))
+	caf := &models.CAF{DocType: models.DTEBoleta, FolioFrom: 1500, FolioTo: 1502}
+	if err := repo.CreateCAF(caf, "<AUTORIZACION/>", "key"); err != nil {
+		t.Fatal(err)
+	}
+	issue := func(sourceID int64, stamp func(string, string) error) (*models.TaxDocument, error) {
+		doc := &models.TaxDocument{DocType: models.DTEBoleta, Status: models.TaxDocIssued, SourceType: models.TaxSourceSale,
+			SourceID: sourceID, Total: 1190, IssuedAt: time.Now()}
+		return doc, repo.IssueDocument(doc, stamp)
+	}
+	ok := func(string, string) error { return nil }
+
+	for i, want := range []int64{1500, 1501} {
+		doc, err := issue(int64(i+1), ok)
+		if err != nil || doc.Folio != want {
+			t.Fatalf("document %d: folio %d (%v), want %d", i+1, doc.Folio, err, want)
+		}
+	}
+	// Un timbraje fallido no consume folio
+	if _, err := issue(3, func(string, string) error { return errors.New("bad key") }); err == nil {
+		t.Fatal("expected stamping error")
+	}
+	if _, err := issue(1, ok); err != ErrTaxDocumentExists {
+		t.Fatalf("same source twice: expected ErrTaxDocumentExists, got %v", err)
+	}
+	if doc, err := issue(3, ok); err != nil || doc.Folio != 1502 {
+		t.Fatalf("after a failed stamp: folio %d (%v), want 1502", doc.Folio, err)
+	}
+	if _, err := issue(4, ok); err != ErrNoFoliosAvailable {
+		t.Fatalf("exhausted CAF: expected ErrNoFoliosAvailable, got %v", err)
+	}
+}
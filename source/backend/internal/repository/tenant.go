@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"boxmagic/internal/models"
+)
+
+// TenantRepository - Registro de tenants (schema public de la base principal)
+type TenantRepository struct {
+	db *sql.DB
+}
+
+func NewTenantRepository(db *sql.DB) *TenantRepository {
+	return &TenantRepository{db: db}
+}
+
+// MigratePlatform crea el registro de tenants y los contadores de rate limiting; el resto de las tablas vive en el schema de cada tenant
+func MigratePlatform(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS tenants (
+		id SERIAL PRIMARY KEY,
+		slug VARCHAR(40) UNIQUE NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		schema_name VARCHAR(63) UNIQUE NOT NULL,
+		active BOOLEAN DEFAULT true,
+		config JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	-- Contadores de rate limiting compartidos (RATE_LIMIT_STORE=postgres), comunes a todos los tenants
+	CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+		key VARCHAR(255) PRIMARY KEY,
+		count INTEGER NOT NULL DEFAULT 0,
+		reset_at TIMESTAMPTZ NOT NULL
+	);
+	`)
+	return err
+}
+
+// CreateSchema crea el schema del tenant si no existe
+func CreateSchema(db *sql.DB, schema string) error {
+	_, err := db.Exec(`CREATE SCHEMA IF NOT EXISTS ` + pq.QuoteIdentifier(schema))
+	return err
+}
+
+// DropSchema elimina el schema del tenant con todas sus tablas (deshace un alta fallida)
+func DropSchema(db *sql.DB, schema string) error {
+	_, err := db.Exec(`DROP SCHEMA IF EXISTS ` + pq.QuoteIdentifier(schema) + ` CASCADE`)
+	return err
+}
+
+// NewTenantDB abre un pool cuyas conexiones usan el schema del tenant (search_path), de modo que
+// todos los repositorios construidos sobre él quedan acotados al tenant
+func NewTenantDB(databaseURL, schema string) (*sql.DB, error) {
+	db, err := NewDB(tenantDSN(databaseURL, schema))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(2)
+	return db, nil
+}
+
+// tenantDSN fija el search_path del schema en la URL o en el DSN clave=valor, reemplazando uno previo
+func tenantDSN(databaseURL, schema string) string {
+	if u, err := url.Parse(databaseURL); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(databaseURL) + " search_path=" + schema
+}
+
+const tenantColumns = `id, slug, name, schema_name, active, config, created_at, updated_at`
+
+func scanTenant(row interface{ Scan(...interface{}) error }) (*models.Tenant, error) {
+	t := &models.Tenant{}
+	var cfg []byte
+	if err := row.Scan(&t.ID, &t.Slug, &t.Name, &t.Schema, &t.Active, &cfg, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(cfg, &t.Config)
+	return t, nil
+}
+
+func (r *TenantRepository) List() ([]*models.Tenant, error) {
+	rows, err := r.db.Query(`SELECT ` + tenantColumns + ` FROM tenants ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []*models.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
+
+func (r *TenantRepository) GetBySlug(slug string) (*models.Tenant, error) {
+	return scanTenant(r.db.QueryRow(`SELECT `+tenantColumns+` FROM tenants WHERE slug = $1`, slug))
+}
+
+func (r *TenantRepository) Create(t *models.Tenant) error {
+	cfg, _ := json.Marshal(t.Config)
+	return r.db.QueryRow(`INSERT INTO tenants (slug, name, schema_name, active, config) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		t.Slug, t.Name, t.Schema, t.Active, cfg).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *TenantRepository) Update(t *models.Tenant) error {
+	cfg, _ := json.Marshal(t.Config)
+	return execExpectingRow(r.db, `UPDATE tenants SET name = $2, active = $3, config = $4, updated_at = NOW() WHERE id = $1`,
+		t.ID, t.Name, t.Active, cfg)
+}
+
+func (r *TenantRepository) Delete(id int64) error {
+	return execExpectingRow(r.db, `DELETE FROM tenants WHERE id = $1`, id)
+}
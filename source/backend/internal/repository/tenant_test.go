@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestTenantDSN_SetsSearchPath(t *testing.T) {
+	cases := []struct {
+		name, url, want string
+	}{
+		{"url", "postgres://u:p@db:5432/box?sslmode=disable", "postgres://u:p@db:5432/box?search_path=tenant_norte&sslmode=disable"},
+		{"url overrides previous schema", "postgres://db/box?search_path=tenant_sur", "postgres://db/box?search_path=tenant_norte"},
+		{"key value", "host=db dbname=box ", "host=db dbname=box search_path=tenant_norte"},
+	}
+	for _, c := range cases {
+		if got := tenantDSN(c.url, "tenant_norte"); got != c.want {
+			t.Errorf("%s: tenantDSN = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// testTenantDB crea un schema de prueba migrado; requiere Postgres (TEST_DATABASE_URL=postgres://... go test ./internal/repository)
+func testTenantDB(t *testing.T, slug string) *sql.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	admin, err := NewDB(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := models.TenantSchema(slug)
+	t.Cleanup(func() { DropSchema(admin, schema) })
+	if err := CreateSchema(admin, schema); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewTenantDB(databaseURL, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestTenantDB_IsolatesSchemas(t *testing.T) {
+	norte := NewPlanRepository(testTenantDB(t, "isolation-norte"))
+	sur := NewPlanRepository(testTenantDB(t, "isolation-sur"))
+
+	plan := &models.Plan{Name: "Solo norte", Price: 30000, Currency: "CLP", Duration: 30, Active: true}
+	if err := norte.Create(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if plans, err := sur.List(false); err != nil || len(plans) != 0 {
+		t.Fatalf("other tenant sees %d plans (%v)", len(plans), err)
+	}
+	if _, err := sur.GetByID(plan.ID); err == nil {
+		t.Fatal("other tenant can read the plan by ID")
+	}
+	if plans, err := norte.List(false); err != nil || len(plans) != 1 {
+		t.Fatalf("owning tenant sees %d plans (%v)", len(plans), err)
+	}
+}
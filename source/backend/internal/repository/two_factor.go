@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// TwoFactorRepository - Secretos TOTP y códigos de recuperación (guardados como hash)
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// Get devuelve sql.ErrNoRows si el usuario nunca inició la activación
+func (r *TwoFactorRepository) Get(userID int64) (*models.TwoFactor, error) {
+	tf := &models.TwoFactor{UserID: userID}
+	err := r.db.QueryRow(`SELECT secret, enabled_at, last_step FROM user_totp WHERE user_id = $1`, userID).
+		Scan(&tf.Secret, &tf.EnabledAt, &tf.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+// SavePending guarda un secreto nuevo sin activar (reemplaza una activación pendiente anterior)
+func (r *TwoFactorRepository) SavePending(userID int64, secret string) error {
+	_, err := r.db.Exec(`INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled_at = NULL, last_step = 0, created_at = NOW()
+		WHERE user_totp.enabled_at IS NULL`, userID, secret)
+	return err
+}
+
+// Enable activa 2FA y reemplaza los códigos de recuperación
+func (r *TwoFactorRepository) Enable(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE user_totp SET enabled_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes invalida los códigos anteriores y guarda los nuevos
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseStep registra el paso TOTP usado; false si ya se usó ese paso o uno posterior (replay)
+func (r *TwoFactorRepository) UseStep(userID, step int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UseRecoveryCode consume un código de recuperación; false si no existe o ya se usó
+func (r *TwoFactorRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// Delete desactiva 2FA y borra secreto y códigos
+func (r *TwoFactorRepository) Delete(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
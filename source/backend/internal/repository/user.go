@@ -17,8 +17,8 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, phone, avatar_url, role, active, invitation_classes, birth_date, sex, weight_kg, height_cm)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO users (email, password_hash, name, phone, avatar_url, role, active, invitation_classes, birth_date, sex, weight_kg, height_cm, rut)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''))
 		RETURNING id, created_at, updated_at`
 
 	return r.db.QueryRow(
@@ -35,12 +35,13 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.Sex,
 		user.WeightKg,
 		user.HeightCm,
+		user.RUT,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 }
 
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), created_at, updated_at
+	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), COALESCE(rut,''), COALESCE(email_verified,false), COALESCE(token_version,0), created_at, updated_at
 			  FROM users WHERE email = $1`
 
 	err := r.db.QueryRow(query, email).Scan(
@@ -57,6 +58,9 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Sex,
 		&user.WeightKg,
 		&user.HeightCm,
+		&user.RUT,
+		&user.EmailVerified,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -68,7 +72,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 
 func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), created_at, updated_at
+	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), COALESCE(rut,''), COALESCE(email_verified,false), COALESCE(token_version,0), created_at, updated_at
 			  FROM users WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
@@ -85,6 +89,9 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 		&user.Sex,
 		&user.WeightKg,
 		&user.HeightCm,
+		&user.RUT,
+		&user.EmailVerified,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -97,11 +104,11 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 func (r *UserRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET name = $1, phone = $2, avatar_url = $3, role = $4, active = $5, invitation_classes = $6, updated_at = $7, birth_date = $8, sex = $9, weight_kg = $10, height_cm = $11
-		WHERE id = $12`
+		SET name = $1, phone = $2, avatar_url = $3, role = $4, active = $5, invitation_classes = $6, updated_at = $7, birth_date = $8, sex = $9, weight_kg = $10, height_cm = $11, rut = NULLIF($12, '')
+		WHERE id = $13`
 
 	user.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query, user.Name, user.Phone, user.AvatarURL, user.Role, user.Active, user.InvitationClasses, user.UpdatedAt, user.BirthDate, user.Sex, user.WeightKg, user.HeightCm, user.ID)
+	_, err := r.db.Exec(query, user.Name, user.Phone, user.AvatarURL, user.Role, user.Active, user.InvitationClasses, user.UpdatedAt, user.BirthDate, user.Sex, user.WeightKg, user.HeightCm, user.RUT, user.ID)
 	return err
 }
 
@@ -116,7 +123,7 @@ func (r *UserRepository) DeleteByEmail(email string) error {
 }
 
 func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
-	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), created_at, updated_at
+	query := `SELECT id, email, password_hash, name, phone, COALESCE(avatar_url,''), role, active, invitation_classes, birth_date, COALESCE(sex,''), COALESCE(weight_kg,0), COALESCE(height_cm,0), COALESCE(rut,''), COALESCE(email_verified,false), COALESCE(token_version,0), created_at, updated_at
 			  FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 
 	rows, err := r.db.Query(query, limit, offset)
@@ -142,6 +149,9 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 			&user.Sex,
 			&user.WeightKg,
 			&user.HeightCm,
+			&user.RUT,
+			&user.EmailVerified,
+			&user.TokenVersion,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -153,24 +163,6 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 	return users, nil
 }
 
-func (r *UserRepository) SaveRefreshToken(userID int64, token string, expiresAt time.Time) error {
-	query := `INSERT INTO refresh_tokens (user_id, token, expires_at) VALUES ($1, $2, $3)`
-	_, err := r.db.Exec(query, userID, token, expiresAt)
-	return err
-}
-
-func (r *UserRepository) GetRefreshToken(token string) (int64, error) {
-	var userID int64
-	query := `SELECT user_id FROM refresh_tokens WHERE token = $1 AND expires_at > NOW()`
-	err := r.db.QueryRow(query, token).Scan(&userID)
-	return userID, err
-}
-
-func (r *UserRepository) DeleteRefreshToken(token string) error {
-	_, err := r.db.Exec("DELETE FROM refresh_tokens WHERE token = $1", token)
-	return err
-}
-
 func (r *UserRepository) AddInvitationClasses(userID int64, count int) error {
 	query := `UPDATE users SET invitation_classes = invitation_classes + $1, updated_at = NOW() WHERE id = $2`
 	_, err := r.db.Exec(query, count, userID)
@@ -189,3 +181,41 @@ func (r *UserRepository) UseInvitationClass(userID int64) (bool, error) {
 	n, _ := result.RowsAffected()
 	return n > 0, nil
 }
+
+// UpdatePassword reemplaza el hash de la contraseña
+func (r *UserRepository) UpdatePassword(userID int64, passwordHash string) error {
+	_, err := r.db.Exec(`UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`, passwordHash, userID)
+	return err
+}
+
+func (r *UserRepository) SetEmailVerified(userID int64) error {
+	_, err := r.db.Exec(`UPDATE users SET email_verified = true, updated_at = NOW() WHERE id = $1`, userID)
+	return err
+}
+
+// CreateAuthToken guarda el hash de un token de un solo uso; invalida los anteriores del mismo propósito
+func (r *UserRepository) CreateAuthToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM auth_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO auth_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userID, purpose, tokenHash, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeAuthToken marca el token como usado y devuelve su usuario; falla si no existe, expiró o ya se usó
+func (r *UserRepository) ConsumeAuthToken(purpose, tokenHash string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow(`UPDATE auth_tokens SET used_at = NOW()
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, purpose, tokenHash).Scan(&userID)
+	return userID, err
+}
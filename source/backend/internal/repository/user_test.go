@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+func TestUserRepository_ConsumeAuthToken(t *testing.T) {
+	db := testTenantDB(t, "auth-token-test")
+	repo := NewUserRepository(db)
+	user := &models.User{Email: "socio@example.com", PasswordHash: "x", Name: "Socio", Role: models.RoleMember, Active: true}
+	if err := repo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateAuthToken(user.ID, models.TokenMagicLink, "hash-ok", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := repo.ConsumeAuthToken(models.TokenEmailVerify, "hash-ok"); err == nil {
+		t.Errorf("wrong purpose should be rejected, got user %d", id)
+	}
+	if id, err := repo.ConsumeAuthToken(models.TokenMagicLink, "hash-ok"); err != nil || id != user.ID {
+		t.Fatalf("first consume = %d, %v; want %d", id, err, user.ID)
+	}
+	if _, err := repo.ConsumeAuthToken(models.TokenMagicLink, "hash-ok"); err == nil {
+		t.Error("second consume should be rejected")
+	}
+
+	if err := repo.CreateAuthToken(user.ID, models.TokenMagicLink, "hash-expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ConsumeAuthToken(models.TokenMagicLink, "hash-expired"); err == nil {
+		t.Error("expired token should be rejected")
+	}
+
+	// Emitir uno nuevo invalida el anterior sin usar
+	repo.CreateAuthToken(user.ID, models.TokenMagicLink, "hash-old", time.Now().Add(time.Minute))
+	repo.CreateAuthToken(user.ID, models.TokenMagicLink, "hash-new", time.Now().Add(time.Minute))
+	if _, err := repo.ConsumeAuthToken(models.TokenMagicLink, "hash-old"); err == nil {
+		t.Error("superseded token should be rejected")
+	}
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+
+	"boxmagic/internal/models"
+)
+
+// WaiverRepository - Documentos de exención versionados y sus firmas (tabla authorizations)
+type WaiverRepository struct {
+	db *sql.DB
+}
+
+func NewWaiverRepository(db *sql.DB) *WaiverRepository {
+	return &WaiverRepository{db: db}
+}
+
+const waiverDocumentColumns = `id, type, version, title, body, required, applies_to, active, created_by, created_at`
+
+func scanWaiverDocuments(rows *sql.Rows) ([]*models.WaiverDocument, error) {
+	defer rows.Close()
+	var docs []*models.WaiverDocument
+	for rows.Next() {
+		d := &models.WaiverDocument{}
+		if err := rows.Scan(&d.ID, &d.Type, &d.Version, &d.Title, &d.Body, &d.Required, &d.AppliesTo, &d.Active, &d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// Publish crea la siguiente versión del tipo y retira las anteriores, que dejan de estar vigentes
+func (r *WaiverRepository) Publish(d *models.WaiverDocument) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serializa publicaciones concurrentes del mismo tipo
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('waiver:' || $1))`, d.Type); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM waiver_documents WHERE type = $1`, d.Type).Scan(&d.Version); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE waiver_documents SET active = false WHERE type = $1 AND active`, d.Type); err != nil {
+		return err
+	}
+	d.Active = true
+	if err := tx.QueryRow(`INSERT INTO waiver_documents (type, version, title, body, required, applies_to, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, true, $7) RETURNING id, created_at`,
+		d.Type, d.Version, d.Title, d.Body, d.Required, d.AppliesTo, d.CreatedBy).Scan(&d.ID, &d.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *WaiverRepository) GetDocument(id int64) (*models.WaiverDocument, error) {
+	rows, err := r.db.Query(`SELECT `+waiverDocumentColumns+` FROM waiver_documents WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := scanWaiverDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return docs[0], nil
+}
+
+// ListDocuments lista los documentos vigentes; con history incluye las versiones retiradas
+func (r *WaiverRepository) ListDocuments(history bool) ([]*models.WaiverDocument, error) {
+	query := `SELECT ` + waiverDocumentColumns + ` FROM waiver_documents`
+	if !history {
+		query += ` WHERE active`
+	}
+	rows, err := r.db.Query(query + ` ORDER BY type, version DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return scanWaiverDocuments(rows)
+}
+
+// Retire deja de exigir el documento sin publicar una versión nueva
+func (r *WaiverRepository) Retire(id int64) error {
+	return execExpectingRow(r.db, `UPDATE waiver_documents SET active = false WHERE id = $1 AND active`, id)
+}
+
+const authorizationColumns = `a.id, a.user_id, a.document_type, a.waiver_document_id, COALESCE(d.version, 0), COALESCE(d.title, ''),
+	a.signed_at, COALESCE(a.signer_name, ''), COALESCE(a.guardian_name, ''), COALESCE(a.guardian_rut, ''),
+	COALESCE(a.ip, ''), COALESCE(a.user_agent, ''), COALESCE(a.document_hash, ''), COALESCE(a.notes, ''), a.created_at`
+
+func scanAuthorization(row interface{ Scan(...interface{}) error }, a *models.Authorization) error {
+	return row.Scan(&a.ID, &a.UserID, &a.DocumentType, &a.DocumentID, &a.DocumentVersion, &a.DocumentTitle,
+		&a.SignedAt, &a.SignerName, &a.GuardianName, &a.GuardianRut, &a.IP, &a.UserAgent, &a.DocumentHash, &a.Notes, &a.CreatedAt)
+}
+
+// Sign guarda la firma; firmar dos veces la misma versión devuelve la firma existente
+func (r *WaiverRepository) Sign(a *models.Authorization) error {
+	err := r.db.QueryRow(`INSERT INTO authorizations (user_id, document_type, waiver_document_id, signed_at, signer_name,
+			guardian_name, guardian_rut, ip, user_agent, document_hash)
+		VALUES ($1, $2, $3, NOW(), $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9)
+		ON CONFLICT (user_id, waiver_document_id) WHERE waiver_document_id IS NOT NULL DO NOTHING
+		RETURNING id, signed_at, created_at`,
+		a.UserID, a.DocumentType, a.DocumentID, a.SignerName, a.GuardianName, a.GuardianRut, a.IP, a.UserAgent, a.DocumentHash,
+	).Scan(&a.ID, &a.SignedAt, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return scanAuthorization(r.db.QueryRow(`SELECT `+authorizationColumns+` FROM authorizations a
+			LEFT JOIN waiver_documents d ON d.id = a.waiver_document_id
+			WHERE a.user_id = $1 AND a.waiver_document_id = $2`, a.UserID, a.DocumentID), a)
+	}
+	return err
+}
+
+// ListByUser devuelve todas las firmas del miembro, las más recientes primero
+func (r *WaiverRepository) ListByUser(userID int64) ([]*models.Authorization, error) {
+	rows, err := r.db.Query(`SELECT `+authorizationColumns+` FROM authorizations a
+		LEFT JOIN waiver_documents d ON d.id = a.waiver_document_id
+		WHERE a.user_id = $1 ORDER BY a.signed_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.Authorization
+	for rows.Next() {
+		a := &models.Authorization{}
+		if err := scanAuthorization(rows, a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+// GetSigned devuelve la firma con el texto firmado y los datos del miembro
+func (r *WaiverRepository) GetSigned(id int64) (*models.SignedWaiver, error) {
+	s := &models.SignedWaiver{}
+	row := r.db.QueryRow(`SELECT `+authorizationColumns+`, u.name, u.email, COALESCE(u.rut, ''), COALESCE(d.body, '')
+		FROM authorizations a
+		JOIN users u ON u.id = a.user_id
+		LEFT JOIN waiver_documents d ON d.id = a.waiver_document_id
+		WHERE a.id = $1`, id)
+	a := &s.Authorization
+	err := row.Scan(&a.ID, &a.UserID, &a.DocumentType, &a.DocumentID, &a.DocumentVersion, &a.DocumentTitle,
+		&a.SignedAt, &a.SignerName, &a.GuardianName, &a.GuardianRut, &a.IP, &a.UserAgent, &a.DocumentHash, &a.Notes, &a.CreatedAt,
+		&s.UserName, &s.UserEmail, &s.UserRUT, &s.Body)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// PendingRequired lista los miembros activos con documentos obligatorios vigentes sin firmar.
+// adultAge decide qué documentos aplican según birth_date (sin fecha se lo trata como adulto).
+func (r *WaiverRepository) PendingRequired(adultAge int) ([]*models.MemberWaivers, error) {
+	rows, err := r.db.Query(`SELECT u.id, u.name, u.email, COALESCE(u.birth_date > CURRENT_DATE - make_interval(years => $1), false),
+			d.id, d.type, d.version, d.title, d.applies_to
+		FROM users u CROSS JOIN waiver_documents d
+		WHERE u.role = 'user' AND u.active = true AND d.active AND d.required
+		  AND (d.applies_to = 'all'
+		    OR (d.applies_to = 'minors' AND u.birth_date > CURRENT_DATE - make_interval(years => $1))
+		    OR (d.applies_to = 'adults' AND (u.birth_date IS NULL OR u.birth_date <= CURRENT_DATE - make_interval(years => $1))))
+		  AND NOT EXISTS (SELECT 1 FROM authorizations a WHERE a.user_id = u.id AND a.waiver_document_id = d.id)
+		ORDER BY u.name, u.id, d.type`, adultAge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*models.MemberWaivers
+	var cur *models.MemberWaivers
+	for rows.Next() {
+		var m models.MemberWaivers
+		d := &models.WaiverDocument{Active: true, Required: true}
+		if err := rows.Scan(&m.UserID, &m.UserName, &m.UserEmail, &m.Minor, &d.ID, &d.Type, &d.Version, &d.Title, &d.AppliesTo); err != nil {
+			return nil, err
+		}
+		if cur == nil || cur.UserID != m.UserID {
+			cur = &m
+			list = append(list, cur)
+		}
+		cur.Waivers = append(cur.Waivers, &models.WaiverStatus{Document: d})
+		cur.PendingRequired++
+	}
+	return list, nil
+}
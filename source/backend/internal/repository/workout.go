@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"boxmagic/internal/models"
+)
+
+// Rutinas estructuradas (bloques y líneas de movimientos) de RoutineRepository
+
+func marshalPrescription(p *models.Prescription) interface{} {
+	if p == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(p)
+	return raw
+}
+
+func unmarshalPrescription(raw []byte) *models.Prescription {
+	if raw == nil {
+		return nil
+	}
+	p := &models.Prescription{}
+	if json.Unmarshal(raw, p) != nil {
+		return nil
+	}
+	return p
+}
+
+// replaceBlocks reemplaza la estructura de la rutina junto con el texto generado a partir de ella
+func replaceBlocks(tx *sql.Tx, routine *models.Routine) error {
+	if _, err := tx.Exec(`UPDATE routines SET content = $1, content_scaled = $2, content_beginner = $3, updated_at = NOW() WHERE id = $4`,
+		routine.Content, routine.ContentScaled, routine.ContentBeginner, routine.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM routine_blocks WHERE routine_id = $1`, routine.ID); err != nil {
+		return err
+	}
+	for i, b := range routine.Blocks {
+		err := tx.QueryRow(`INSERT INTO routine_blocks (routine_id, position, kind, title, format, minutes, rounds, sets, interval_seconds, notes)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, NULLIF($10, '')) RETURNING id`,
+			routine.ID, i, b.Kind, b.Title, b.Format, b.Minutes, b.Rounds, b.Sets, b.IntervalSeconds, b.Notes).Scan(&b.ID)
+		if err != nil {
+			return err
+		}
+		for j, l := range b.Lines {
+			err := tx.QueryRow(`INSERT INTO routine_block_lines (block_id, position, movement_id, name, rx, scaled, beginner, notes)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')) RETURNING id`,
+				b.ID, j, l.MovementID, l.Name, marshalPrescription(&l.Rx), marshalPrescription(l.Scaled),
+				marshalPrescription(l.Beginner), l.Notes).Scan(&l.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetBlocks devuelve los bloques de la rutina en orden; vacío si es de texto libre
+func (r *RoutineRepository) GetBlocks(routineID int64) ([]*models.WorkoutBlock, error) {
+	return getBlocks(r.db, routineID)
+}
+
+func getBlocks(q queryer, routineID int64) ([]*models.WorkoutBlock, error) {
+	rows, err := q.Query(`SELECT b.id, b.kind, COALESCE(b.title, ''), COALESCE(b.format, ''), b.minutes, b.rounds, b.sets,
+			b.interval_seconds, COALESCE(b.notes, ''), l.id, l.movement_id, COALESCE(m.name, l.name), l.rx, l.scaled, l.beginner,
+			COALESCE(l.notes, '')
+		FROM routine_blocks b
+		LEFT JOIN routine_block_lines l ON l.block_id = b.id
+		LEFT JOIN movements m ON m.id = l.movement_id
+		WHERE b.routine_id = $1
+		ORDER BY b.position, l.position`, routineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*models.WorkoutBlock
+	for rows.Next() {
+		b := &models.WorkoutBlock{}
+		var lineID, movementID sql.NullInt64
+		var name, notes sql.NullString
+		var rx, scaled, beginner []byte
+		if err := rows.Scan(&b.ID, &b.Kind, &b.Title, &b.Format, &b.Minutes, &b.Rounds, &b.Sets, &b.IntervalSeconds, &b.Notes,
+			&lineID, &movementID, &name, &rx, &scaled, &beginner, &notes); err != nil {
+			return nil, err
+		}
+		if n := len(blocks); n > 0 && blocks[n-1].ID == b.ID {
+			b = blocks[n-1]
+		} else {
+			b.Lines = []*models.WorkoutLine{}
+			blocks = append(blocks, b)
+		}
+		if !lineID.Valid {
+			continue
+		}
+		l := &models.WorkoutLine{ID: lineID.Int64, Name: name.String, Notes: notes.String,
+			Scaled: unmarshalPrescription(scaled), Beginner: unmarshalPrescription(beginner)}
+		if movementID.Valid {
+			l.MovementID = &movementID.Int64
+		}
+		if p := unmarshalPrescription(rx); p != nil {
+			l.Rx = *p
+		}
+		b.Lines = append(b.Lines, l)
+	}
+	return blocks, rows.Err()
+}
+
+// ListByMovement devuelve las rutinas activas que incluyen el movimiento en alguno de sus bloques
+func (r *RoutineRepository) ListByMovement(movementID int64, publishedOnly bool, limit, offset int) ([]*models.RoutineWithCreator, error) {
+	rows, err := r.db.Query(`SELECT r.id, r.name, r.description, r.type, r.content, COALESCE(r.content_scaled,''), COALESCE(r.content_beginner,''),
+			         r.duration, r.difficulty,
+			         r.instructor_id, r.created_by, r.active, r.billable, r.target_user_id, r.is_custom, r.benchmark_id, r.version,
+			         r.created_at, r.updated_at, u.name, i.name, tu.name
+			  FROM routines r
+			  JOIN users u ON r.created_by = u.id
+			  LEFT JOIN instructors i ON r.instructor_id = i.id
+			  LEFT JOIN users tu ON r.target_user_id = tu.id
+			  WHERE r.active = true AND EXISTS (SELECT 1 FROM routine_blocks b JOIN routine_block_lines l ON l.block_id = b.id
+			                                    WHERE b.routine_id = r.id AND l.movement_id = $1)
+			    AND (NOT $4 OR NOT `+routineUnpublished+`)
+			  ORDER BY r.created_at DESC LIMIT $2 OFFSET $3`, movementID, limit, offset, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []*models.RoutineWithCreator
+	for rows.Next() {
+		routine := &models.RoutineWithCreator{}
+		if err := rows.Scan(
+			&routine.ID, &routine.Name, &routine.Description, &routine.Type, &routine.Content,
+			&routine.ContentScaled, &routine.ContentBeginner,
+			&routine.Duration, &routine.Difficulty, &routine.InstructorID, &routine.CreatedBy, &routine.Active,
+			&routine.Billable, &routine.TargetUserID, &routine.IsCustom, &routine.BenchmarkID, &routine.Version,
+			&routine.CreatedAt, &routine.UpdatedAt, &routine.CreatorName, &routine.InstructorName, &routine.TargetUserName,
+		); err != nil {
+			return nil, err
+		}
+		routines = append(routines, routine)
+	}
+	return routines, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// AuditService - Registra las acciones administrativas y aplica la retención configurada
+type AuditService struct {
+	auditRepo     repository.AuditRepo
+	retentionDays int
+}
+
+func NewAuditService(auditRepo repository.AuditRepo, retentionDays int) *AuditService {
+	return &AuditService{auditRepo: auditRepo, retentionDays: retentionDays}
+}
+
+// Snapshot implementa middleware.Auditor
+func (s *AuditService) Snapshot(entityType string, id int64) json.RawMessage {
+	data, err := s.auditRepo.Snapshot(entityType, id)
+	if err != nil {
+		log.Printf("[AUDIT] snapshot %s %d: %v", entityType, id, err)
+	}
+	return data
+}
+
+// Record calcula el diff y guarda la entrada; un fallo se loguea sin afectar la respuesta ya enviada
+func (s *AuditService) Record(e *models.AuditEntry) {
+	// Si la acción anonimizó al miembro, la foto previa no debe volver a dejar sus datos en el log
+	if e.EntityType == models.AuditEntityUser && erased(e.After) {
+		e.Before = withoutFields(e.Before, models.AuditUserPersonalFields)
+		e.After = withoutFields(e.After, models.AuditUserPersonalFields)
+	}
+	e.Changes = DiffJSON(e.Before, e.After)
+	if err := s.auditRepo.Insert(e); err != nil {
+		log.Printf("[AUDIT] insert %s %s %s: %v", e.Action, e.EntityType, e.EntityID, err)
+	}
+}
+
+// DiffJSON compara dos objetos JSON campo a campo (primer nivel); ignora updated_at
+func DiffJSON(before, after json.RawMessage) map[string]models.AuditChange {
+	var b, a map[string]json.RawMessage
+	json.Unmarshal(before, &b)
+	json.Unmarshal(after, &a)
+	if b == nil && a == nil {
+		return nil
+	}
+
+	changes := map[string]models.AuditChange{}
+	for k, av := range a {
+		if k == "updated_at" {
+			continue
+		}
+		if bv, ok := b[k]; !ok || !bytes.Equal(compactJSON(bv), compactJSON(av)) {
+			changes[k] = models.AuditChange{Before: b[k], After: av}
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; !ok && k != "updated_at" {
+			changes[k] = models.AuditChange{Before: bv}
+		}
+	}
+	return changes
+}
+
+// erased indica si la foto de users corresponde a una cuenta anonimizada
+func erased(snapshot json.RawMessage) bool {
+	var u struct {
+		ErasedAt *string `json:"erased_at"`
+	}
+	return json.Unmarshal(snapshot, &u) == nil && u.ErasedAt != nil
+}
+
+func withoutFields(snapshot json.RawMessage, fields []string) json.RawMessage {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(snapshot, &m); err != nil || m == nil {
+		return snapshot
+	}
+	for _, f := range fields {
+		delete(m, f)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return snapshot
+	}
+	return data
+}
+
+func compactJSON(v json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, v); err != nil {
+		return v
+	}
+	return buf.Bytes()
+}
+
+// PurgeExpired borra las entradas fuera del periodo de retención (0 = conservar todo)
+func (s *AuditService) PurgeExpired() {
+	if s.retentionDays <= 0 {
+		return
+	}
+	n, err := s.auditRepo.Purge(time.Now().AddDate(0, 0, -s.retentionDays))
+	if err != nil {
+		log.Printf("[AUDIT] purge: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[AUDIT] purged %d entries older than %d days", n, s.retentionDays)
+	}
+}
+
+func (s *AuditService) RetentionDays() int {
+	return s.retentionDays
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+type memAudit struct {
+	repository.AuditRepo
+	entries []*models.AuditEntry
+}
+
+func (m *memAudit) Insert(e *models.AuditEntry) error {
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func TestAuditService_RecordStripsErasedUserFields(t *testing.T) {
+	repo := &memAudit{}
+	s := NewAuditService(repo, 0)
+
+	before := json.RawMessage(`{"id":1,"email":"ana@box.cl","name":"Ana","phone":"+56911111111","rut":"11.111.111-1","active":true,"erased_at":null}`)
+	after := json.RawMessage(`{"id":1,"email":"erased-1@invalid","name":"Cuenta eliminada","phone":null,"rut":null,"active":false,"erased_at":"` + time.Now().Format(time.RFC3339) + `"}`)
+	s.Record(&models.AuditEntry{Action: "delete", EntityType: models.AuditEntityUser, EntityID: "1", Before: before, After: after})
+
+	e := repo.entries[0]
+	for _, field := range models.AuditUserPersonalFields {
+		for name, snap := range map[string]json.RawMessage{"before": e.Before, "after": e.After} {
+			if strings.Contains(string(snap), `"`+field+`"`) {
+				t.Errorf("%s snapshot still has %q: %s", name, field, snap)
+			}
+		}
+		if _, ok := e.Changes[field]; ok {
+			t.Errorf("changes still has %q", field)
+		}
+	}
+	if _, ok := e.Changes["active"]; !ok {
+		t.Errorf("non-personal changes should be kept, got %v", e.Changes)
+	}
+	if strings.Contains(string(e.Before), "Ana") {
+		t.Errorf("before snapshot leaks the name: %s", e.Before)
+	}
+}
+
+func TestAuditService_RecordKeepsFieldsForActiveUser(t *testing.T) {
+	repo := &memAudit{}
+	s := NewAuditService(repo, 0)
+
+	before := json.RawMessage(`{"id":1,"name":"Ana","erased_at":null}`)
+	after := json.RawMessage(`{"id":1,"name":"Ana María","erased_at":null}`)
+	s.Record(&models.AuditEntry{Action: "update", EntityType: models.AuditEntityUser, EntityID: "1", Before: before, After: after})
+
+	if c, ok := repo.entries[0].Changes["name"]; !ok || string(c.After) != `"Ana María"` {
+		t.Errorf("name change should be recorded, got %v", repo.entries[0].Changes)
+	}
+}
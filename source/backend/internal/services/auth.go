@@ -2,14 +2,18 @@ package services
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
 	"boxmagic/internal/config"
+	"boxmagic/internal/jwtkeys"
 	"boxmagic/internal/models"
 	"boxmagic/internal/repository"
 )
@@ -18,21 +22,52 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user already exists")
 	ErrInvalidToken       = errors.New("invalid token")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	ErrEmailVerified      = errors.New("email already verified")
+	ErrTokenReuse         = errors.New("refresh token reuse detected")
+	ErrSessionRevoked     = errors.New("session expired or revoked")
 )
 
+// AccountLockedError - La cuenta está bloqueada temporalmente por intentos fallidos
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account locked until " + e.Until.Format(time.RFC3339)
+}
+
 type AuthService struct {
-	userRepo repository.UserRepo
-	cfg      *config.Config
+	userRepo     repository.UserRepo
+	sessionRepo  repository.SessionRepo
+	cfg          *config.Config
+	emailService *EmailService
+	lockoutRepo  *repository.LoginLockoutRepository
+	twoFactor    *repository.TwoFactorRepository
+	settingsRepo repository.SettingsRepo
 }
 
-func NewAuthService(userRepo repository.UserRepo, cfg *config.Config) *AuthService {
+func NewAuthService(userRepo repository.UserRepo, sessionRepo repository.SessionRepo, cfg *config.Config) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		cfg:      cfg,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		cfg:         cfg,
 	}
 }
 
+func (s *AuthService) SetEmailService(svc *EmailService) {
+	s.emailService = svc
+}
+
+// SetLockoutRepo activa el bloqueo progresivo de cuentas tras logins fallidos
+func (s *AuthService) SetLockoutRepo(repo *repository.LoginLockoutRepository) {
+	s.lockoutRepo = repo
+}
+
 func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+	if len(req.Password) < models.MinPasswordLength {
+		return nil, ErrWeakPassword
+	}
 	existing, _ := s.userRepo.GetByEmail(req.Email)
 	if existing != nil {
 		return nil, ErrUserExists
@@ -56,7 +91,11 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		return nil, err
 	}
 
-	return s.generateTokens(user)
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("[WARN] verification email for user %d: %v", user.ID, err)
+	}
+
+	return s.startSession(user, req.Client)
 }
 
 func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
@@ -69,47 +108,272 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 		return nil, ErrInvalidCredentials
 	}
 
+	// Mientras dure el bloqueo ni siquiera se compara la contraseña
+	if s.lockoutRepo != nil {
+		if l, err := s.lockoutRepo.Get(user.ID); err == nil && l.LockedUntil != nil && l.LockedUntil.After(time.Now()) {
+			return nil, &AccountLockedError{Until: *l.LockedUntil}
+		}
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.recordLoginFailure(user)
 	}
 
-	return s.generateTokens(user)
+	if s.lockoutRepo != nil {
+		s.lockoutRepo.Reset(user.ID)
+	}
+	if challenge, err := s.twoFactorChallenge(user); challenge != nil || err != nil {
+		return challenge, err
+	}
+	return s.startSession(user, req.Client)
 }
 
-func (s *AuthService) Refresh(refreshToken string) (*models.AuthResponse, error) {
-	userID, err := s.userRepo.GetRefreshToken(refreshToken)
+// recordLoginFailure cuenta el fallo y, al llegar al máximo, bloquea la cuenta con una duración que
+// se duplica en cada bloqueo sucesivo (hasta LoginLockoutMax) y avisa al usuario por email
+func (s *AuthService) recordLoginFailure(user *models.User) error {
+	if s.lockoutRepo == nil || s.cfg.LoginMaxFailures <= 0 {
+		return ErrInvalidCredentials
+	}
+	l, err := s.lockoutRepo.RecordFailure(user.ID)
 	if err != nil {
+		log.Printf("[WARN] login failure for user %d: %v", user.ID, err)
+		return ErrInvalidCredentials
+	}
+	if l.Failures < s.cfg.LoginMaxFailures {
+		return ErrInvalidCredentials
+	}
+
+	duration := s.cfg.LoginLockoutBase
+	for i := 0; i < l.LockCount && duration < s.cfg.LoginLockoutMax; i++ {
+		duration *= 2
+	}
+	if duration > s.cfg.LoginLockoutMax {
+		duration = s.cfg.LoginLockoutMax
+	}
+	until := time.Now().Add(duration)
+	if err := s.lockoutRepo.Lock(user.ID, until); err != nil {
+		log.Printf("[WARN] lock user %d: %v", user.ID, err)
+		return ErrInvalidCredentials
+	}
+	if s.emailService != nil {
+		go s.emailService.SendAccountLocked(user.Email, user.Name, until)
+	}
+	return &AccountLockedError{Until: until}
+}
+
+// Refresh rota el refresh token dentro de su sesión. Si llega un token ya rotado se asume robo
+// y se revoca la sesión completa (el atacante y el usuario legítimo deben volver a iniciar sesión).
+func (s *AuthService) Refresh(req *models.RefreshRequest) (*models.AuthResponse, error) {
+	rec, err := s.sessionRepo.GetRefreshToken(hashToken(req.RefreshToken))
+	if err != nil || rec.SessionRevoked {
 		return nil, ErrInvalidToken
 	}
+	if rec.UsedAt != nil {
+		s.revokeReusedFamily(rec)
+		return nil, ErrTokenReuse
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	rotated, err := s.sessionRepo.MarkRefreshTokenUsed(rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		s.revokeReusedFamily(rec)
+		return nil, ErrTokenReuse
+	}
+
+	user, err := s.userRepo.GetByID(rec.UserID)
+	if err != nil || !user.Active {
+		return nil, ErrInvalidToken
+	}
+	_ = s.sessionRepo.TouchSession(rec.SessionID, req.Client)
+
+	return s.generateTokens(user, rec.SessionID, rec.Remember)
+}
+
+func (s *AuthService) revokeReusedFamily(rec *models.RefreshTokenRecord) {
+	log.Printf("[WARN] refresh token reuse for user %d session %d; revoking session", rec.UserID, rec.SessionID)
+	_ = s.sessionRepo.RevokeSession(rec.UserID, rec.SessionID, models.SessionRevokedReuse)
+}
+
+// Logout cierra la sesión del access token actual
+func (s *AuthService) Logout(userID, sessionID int64) error {
+	if sessionID == 0 {
+		return ErrSessionRevoked
+	}
+	return s.sessionRepo.RevokeSession(userID, sessionID, models.SessionRevokedLogout)
+}
+
+// LogoutAll cierra todas las sesiones del usuario, incluida la actual
+func (s *AuthService) LogoutAll(userID int64) error {
+	return s.sessionRepo.RevokeAllSessions(userID, models.SessionRevokedLogoutAll)
+}
+
+// CheckAccess valida un access token contra el estado actual: usuario activo, token_version y sesión vigente.
+// Lo usa middleware.Auth en cada request, por lo que revocar o desactivar tiene efecto inmediato.
+func (s *AuthService) CheckAccess(userID, sessionID int64, tokenVersion int) error {
+	version, active, sessionValid, err := s.sessionRepo.AccessState(userID, sessionID)
+	if err != nil || !active || !sessionValid || version != tokenVersion {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// issueAuthToken genera un token de un solo uso; en la base solo queda su hash
+func (s *AuthService) issueAuthToken(userID int64, purpose string, ttl time.Duration) (string, error) {
+	token := generateRandomToken()
+	if err := s.userRepo.CreateAuthToken(userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AuthService) appLink(path, token string) string {
+	return s.cfg.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *AuthService) sendVerification(user *models.User) error {
+	token, err := s.issueAuthToken(user.ID, models.TokenEmailVerify, s.cfg.EmailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	if s.emailService != nil {
+		go s.emailService.SendEmailVerification(user.Email, user.Name, s.appLink("/verify-email", token))
+	}
+	return nil
+}
 
+// ResendVerification vuelve a enviar el link de verificación (invalida el anterior)
+func (s *AuthService) ResendVerification(userID int64) error {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return ErrInvalidToken
+	}
+	if user.EmailVerified {
+		return ErrEmailVerified
+	}
+	return s.sendVerification(user)
+}
+
+func (s *AuthService) VerifyEmail(token string) error {
+	userID, err := s.userRepo.ConsumeAuthToken(models.TokenEmailVerify, hashToken(token))
+	if err != nil {
+		return ErrInvalidToken
 	}
+	return s.userRepo.SetEmailVerified(userID)
+}
 
-	s.userRepo.DeleteRefreshToken(refreshToken)
+// RequestPasswordReset envía el link de recuperación. No informa si el email existe.
+func (s *AuthService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.Active {
+		return nil
+	}
+	token, err := s.issueAuthToken(user.ID, models.TokenPasswordReset, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	if s.emailService != nil {
+		go s.emailService.SendPasswordReset(user.Email, user.Name, s.appLink("/reset-password", token))
+	}
+	return nil
+}
 
-	return s.generateTokens(user)
+// ResetPassword fija una nueva contraseña con un token de recuperación y cierra todas las sesiones
+func (s *AuthService) ResetPassword(token, password string) error {
+	if len(password) < models.MinPasswordLength {
+		return ErrWeakPassword
+	}
+	userID, err := s.userRepo.ConsumeAuthToken(models.TokenPasswordReset, hashToken(token))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := s.setPassword(userID, password); err != nil {
+		return err
+	}
+	// El link llegó a su email, así que también queda verificado
+	return s.userRepo.SetEmailVerified(userID)
 }
 
-func (s *AuthService) generateTokens(user *models.User) (*models.AuthResponse, error) {
+// ChangePassword cambia la contraseña del usuario autenticado, revoca todas sus sesiones y entrega una sesión nueva
+func (s *AuthService) ChangePassword(userID int64, req *models.ChangePasswordRequest) (*models.AuthResponse, error) {
+	if len(req.NewPassword) < models.MinPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := s.setPassword(userID, req.NewPassword); err != nil {
+		return nil, err
+	}
+	// Se recarga para firmar con la token_version nueva
+	if user, err = s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return s.startSession(user, req.Client)
+}
+
+func (s *AuthService) setPassword(userID int64, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(userID, string(hashed)); err != nil {
+		return err
+	}
+	// Recuperar la contraseña también desbloquea la cuenta
+	if s.lockoutRepo != nil {
+		s.lockoutRepo.Reset(userID)
+	}
+	return s.sessionRepo.RevokeAllSessions(userID, models.SessionRevokedPassword)
+}
+
+// startSession abre una sesión nueva (familia de refresh tokens) para el dispositivo del cliente
+func (s *AuthService) startSession(user *models.User, client models.ClientInfo) (*models.AuthResponse, error) {
+	session := &models.Session{
+		UserID:    user.ID,
+		Device:    models.DeviceLabel(client.UserAgent),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		Remember:  client.Remember,
+	}
+	if err := s.sessionRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.generateTokens(user, session.ID, session.Remember)
+}
+
+func (s *AuthService) generateTokens(user *models.User, sessionID int64, remember bool) (*models.AuthResponse, error) {
 	claims := jwt.MapClaims{
-		"sub":  user.ID,
+		"sub":   user.ID,
 		"email": user.Email,
-		"role": user.Role,
-		"exp":  time.Now().Add(s.cfg.JWTExpiry).Unix(),
+		"role":  user.Role,
+		"sid":   sessionID,
+		"ver":   user.TokenVersion,
+		"exp":   time.Now().Add(s.cfg.JWTExpiry).Unix(),
+	}
+	if s.cfg.TenantSlug != "" {
+		claims["tid"] = s.cfg.TenantSlug
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	accessToken, err := jwtkeys.Sign(s.cfg.JWTKeys, s.cfg.JWTSecret, claims)
 	if err != nil {
 		return nil, err
 	}
 
 	refreshToken := generateRandomToken()
 	expiresAt := time.Now().Add(s.cfg.RefreshTokenExpiry)
+	if remember {
+		expiresAt = time.Now().Add(s.cfg.RememberDeviceExpiry)
+	}
 
-	if err := s.userRepo.SaveRefreshToken(user.ID, refreshToken, expiresAt); err != nil {
+	if err := s.sessionRepo.SaveRefreshToken(sessionID, user.ID, hashToken(refreshToken), expiresAt); err != nil {
 		return nil, err
 	}
 
@@ -125,3 +389,8 @@ func generateRandomToken() string {
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
 }
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
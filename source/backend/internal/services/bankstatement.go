@@ -0,0 +1,476 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+var (
+	ErrUnknownBankFormat = errors.New("unknown bank statement format")
+	ErrEmptyStatement    = errors.New("bank statement has no credit lines")
+	ErrStatementAmount   = errors.New("invalid amount in bank statement")
+)
+
+var (
+	rutPattern       = regexp.MustCompile(`\b(\d{1,2}\.?\d{3}\.?\d{3}-?[\dkK])\b`)
+	referencePattern = regexp.MustCompile(`(?i)\bBM-?(\d+)\b`)
+)
+
+// ParseBankStatement lee una cartola y devuelve solo los abonos (montos positivos).
+func ParseBankStatement(r io.Reader, format string) ([]*models.BankTransaction, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	// Quitar BOM de Excel
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	lines := splitLines(data)
+	headerIdx, delim := findHeader(lines)
+	if headerIdx < 0 {
+		return nil, "", ErrUnknownBankFormat
+	}
+	header := splitRecord(lines[headerIdx], delim)
+
+	if format == "" || format == models.BankFormatAuto {
+		format = detectFormat(header)
+	}
+
+	var txs []*models.BankTransaction
+	switch format {
+	case models.BankFormatCSV:
+		txs, err = parseGenericCSV(header, lines[headerIdx+1:], delim, headerIdx+2)
+	case models.BankFormatBancoChile:
+		txs, err = parseBancoChile(header, lines[headerIdx+1:], delim, headerIdx+2)
+	default:
+		return nil, "", ErrUnknownBankFormat
+	}
+	if err != nil {
+		return nil, format, err
+	}
+	if len(txs) == 0 {
+		return nil, format, ErrEmptyStatement
+	}
+	// Dos abonos idénticos el mismo día (p. ej. dos planes pagados por un apoderado) son movimientos distintos:
+	// el contador de repeticiones dentro de la cartola los diferencia sin cambiar la huella del primero
+	seen := map[string]int{}
+	for _, tx := range txs {
+		key := fingerprint(tx, 0)
+		seen[key]++
+		tx.Fingerprint = fingerprint(tx, seen[key])
+		tx.Status = models.BankTxUnmatched
+	}
+	return txs, format, nil
+}
+
+func splitLines(data []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// findHeader busca la fila de encabezados (las cartolas bancarias traen líneas de preámbulo)
+func findHeader(lines []string) (int, rune) {
+	for i, line := range lines {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "fecha") && !strings.Contains(lower, "date") {
+			continue
+		}
+		delim := ','
+		if strings.Count(line, ";") > strings.Count(line, ",") {
+			delim = ';'
+		}
+		return i, delim
+	}
+	return -1, ','
+}
+
+func splitRecord(line string, delim rune) []string {
+	cr := csv.NewReader(strings.NewReader(line))
+	cr.Comma = delim
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	rec, err := cr.Read()
+	if err != nil {
+		return strings.Split(line, string(delim))
+	}
+	for i := range rec {
+		rec[i] = strings.TrimSpace(rec[i])
+	}
+	return rec
+}
+
+func detectFormat(header []string) string {
+	var hasCargos, hasAbonos bool
+	for _, h := range header {
+		n := normalizeText(h)
+		if strings.HasPrefix(n, "cargos") {
+			hasCargos = true
+		}
+		if strings.HasPrefix(n, "abonos") {
+			hasAbonos = true
+		}
+	}
+	if hasCargos && hasAbonos {
+		return models.BankFormatBancoChile
+	}
+	return models.BankFormatCSV
+}
+
+func columnIndex(header []string, aliases ...string) int {
+	for i, h := range header {
+		n := normalizeText(h)
+		for _, a := range aliases {
+			if n == a || strings.HasPrefix(n, a+" ") {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseGenericCSV - El CSV genérico puede venir con montos "45.000", "45,000" o "45000.00": los separadores
+// se detectan en cada monto. firstRow es el número de la primera fila de datos, para reportar errores.
+// operationAliases - Columnas con el identificador de la operación que asigna el banco
+var operationAliases = []string{"n° operacion", "nº operacion", "no operacion", "nro operacion", "numero operacion",
+	"id operacion", "operacion", "n° documento", "nro documento", "transaction id", "operation id"}
+
+func parseGenericCSV(header, lines []string, delim rune, firstRow int) ([]*models.BankTransaction, error) {
+	dateCol := columnIndex(header, "fecha", "date")
+	amountCol := columnIndex(header, "monto", "amount", "abono", "abonos", "credito")
+	rutCol := columnIndex(header, "rut", "rut origen", "sender rut")
+	nameCol := columnIndex(header, "nombre", "name", "origen", "sender name")
+	commentCol := columnIndex(header, "comentario", "comment", "glosa", "descripcion", "description", "mensaje")
+	operationCol := columnIndex(header, operationAliases...)
+	if dateCol < 0 || amountCol < 0 {
+		return nil, fmt.Errorf("%w: csv requires fecha and monto columns", ErrUnknownBankFormat)
+	}
+
+	var txs []*models.BankTransaction
+	for i, line := range lines {
+		rec := splitRecord(line, delim)
+		date, err := parseStatementDate(field(rec, dateCol))
+		if err != nil {
+			continue
+		}
+		amount, err := parseCLP(field(rec, amountCol), clpAuto)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrStatementAmount, firstRow+i, err)
+		}
+		if amount <= 0 {
+			continue
+		}
+		tx := &models.BankTransaction{
+			Date:        date,
+			Amount:      amount,
+			SenderRUT:   models.NormalizeRUT(field(rec, rutCol)),
+			SenderName:  field(rec, nameCol),
+			Comment:     field(rec, commentCol),
+			OperationID: field(rec, operationCol),
+		}
+		if tx.SenderRUT == "" {
+			tx.SenderRUT = extractRUT(tx.Comment)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// parseBancoChile: la descripción trae el origen, ej. "Traspaso De:Juan Perez 12.345.678-9"
+// Los montos vienen en formato chileno: "." de miles y "," decimal.
+func parseBancoChile(header, lines []string, delim rune, firstRow int) ([]*models.BankTransaction, error) {
+	dateCol := columnIndex(header, "fecha")
+	descCol := columnIndex(header, "descripcion")
+	creditCol := columnIndex(header, "abonos")
+	operationCol := columnIndex(header, operationAliases...)
+	if dateCol < 0 || descCol < 0 || creditCol < 0 {
+		return nil, fmt.Errorf("%w: missing Fecha/Descripción/Abonos columns", ErrUnknownBankFormat)
+	}
+
+	var txs []*models.BankTransaction
+	for i, line := range lines {
+		rec := splitRecord(line, delim)
+		date, err := parseStatementDate(field(rec, dateCol))
+		if err != nil {
+			continue
+		}
+		amount, err := parseCLP(field(rec, creditCol), clpChilean)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrStatementAmount, firstRow+i, err)
+		}
+		if amount <= 0 {
+			continue
+		}
+		desc := field(rec, descCol)
+		txs = append(txs, &models.BankTransaction{
+			Date:        date,
+			Amount:      amount,
+			SenderRUT:   extractRUT(desc),
+			SenderName:  extractSenderName(desc),
+			Comment:     desc,
+			OperationID: field(rec, operationCol),
+		})
+	}
+	return txs, nil
+}
+
+func field(rec []string, idx int) string {
+	if idx < 0 || idx >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[idx])
+}
+
+func parseStatementDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "02-01-2006", "02/01/06", "2/1/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// clpFormat - Separadores de miles y decimal de los montos de una cartola; el valor cero los detecta en cada monto
+type clpFormat struct {
+	thousands, decimal byte
+}
+
+var (
+	clpChilean = clpFormat{thousands: '.', decimal: ','}
+	clpAuto    = clpFormat{}
+)
+
+// parseCLP interpreta montos enteros en pesos como "$45.000", "-45000", "45.000,00" o "$45,000".
+// Vacío es 0. Se rechazan los montos con centavos distintos de cero y los separadores mal agrupados.
+func parseCLP(s string, f clpFormat) (int64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(s, "CLP"), "clp"))
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative, s = true, s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	s = strings.ReplaceAll(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "$")), " ", "")
+	if s == "" {
+		return 0, nil
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '.' && c != ',' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	if f == clpAuto {
+		f = detectCLPFormat(s)
+	}
+	integer := s
+	if f.decimal != 0 {
+		if i := strings.IndexByte(s, f.decimal); i >= 0 {
+			integer = s[:i]
+			if decimals := s[i+1:]; decimals == "" || strings.Trim(decimals, "0") != "" {
+				return 0, fmt.Errorf("amount %q is not a whole number of pesos", s)
+			}
+		}
+	}
+	if f.thousands != 0 && strings.IndexByte(integer, f.thousands) >= 0 {
+		groups := strings.Split(integer, string(f.thousands))
+		for i, g := range groups {
+			if (i == 0 && (len(g) == 0 || len(g) > 3)) || (i > 0 && len(g) != 3) {
+				return 0, fmt.Errorf("invalid thousands grouping in %q", s)
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+
+	n, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// detectCLPFormat deduce los separadores de un monto: si aparecen ambos, el último es el decimal; si aparece
+// uno solo, es de miles cuando se repite o lo siguen exactamente tres dígitos ("45.000"), si no es decimal ("45000.00")
+func detectCLPFormat(s string) clpFormat {
+	dot, comma := strings.LastIndexByte(s, '.'), strings.LastIndexByte(s, ',')
+	switch {
+	case dot >= 0 && comma >= 0:
+		if dot > comma {
+			return clpFormat{thousands: ',', decimal: '.'}
+		}
+		return clpChilean
+	case dot < 0 && comma < 0:
+		return clpFormat{}
+	}
+	sep, last := byte('.'), dot
+	if comma >= 0 {
+		sep, last = ',', comma
+	}
+	if strings.Count(s, string(sep)) > 1 || len(s)-last-1 == 3 {
+		return clpFormat{thousands: sep}
+	}
+	return clpFormat{decimal: sep}
+}
+
+func extractRUT(s string) string {
+	if m := rutPattern.FindString(s); m != "" && strings.Contains(m, "-") {
+		return models.NormalizeRUT(m)
+	}
+	return ""
+}
+
+func extractSenderName(desc string) string {
+	name := desc
+	lower := strings.ToLower(desc)
+	for _, marker := range []string{"traspaso de:", "transferencia de:", "tef de:", " de:"} {
+		if i := strings.Index(lower, marker); i >= 0 {
+			name = desc[i+len(marker):]
+			break
+		}
+	}
+	name = rutPattern.ReplaceAllString(name, "")
+	return strings.TrimSpace(name)
+}
+
+// fingerprint identifica el abono para no importarlo dos veces. Con el ID de operación del banco basta con él;
+// si no, se usan los datos del movimiento y occurrence (> 1) distingue los abonos idénticos de una misma cartola.
+func fingerprint(tx *models.BankTransaction, occurrence int) string {
+	key := fmt.Sprintf("%s|%d|%s|%s|%s",
+		tx.Date.Format("2006-01-02"), tx.Amount, tx.SenderRUT, tx.SenderName, tx.Comment)
+	if tx.OperationID != "" {
+		key = fmt.Sprintf("op|%s|%s|%d", tx.OperationID, tx.Date.Format("2006-01-02"), tx.Amount)
+	} else if occurrence > 1 {
+		key += fmt.Sprintf("|#%d", occurrence)
+	}
+	h := sha1.Sum([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+func normalizeText(s string) string {
+	r := strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n", "_", " ", "(", " ", ")", " ")
+	return strings.Join(strings.Fields(r.Replace(strings.ToLower(s))), " ")
+}
+
+// BankMatch es la mejor coincidencia encontrada para un abono
+type BankMatch struct {
+	PaymentID *int64
+	UserID    *int64
+	Score     int
+	Reason    string
+}
+
+// MatchBankTransaction busca el pago pendiente o miembro que corresponde a un abono.
+// Prioridad: código de referencia > RUT + monto > nombre + monto > RUT del miembro > monto único.
+// usedPayments evita asignar el mismo pago pendiente a dos abonos de una misma cartola.
+func MatchBankTransaction(tx *models.BankTransaction, pending []*models.PaymentWithDetails, members []*models.User, usedPayments map[int64]bool) *BankMatch {
+	membersByID := make(map[int64]*models.User, len(members))
+	for _, m := range members {
+		membersByID[m.ID] = m
+	}
+
+	available := make([]*models.PaymentWithDetails, 0, len(pending))
+	for _, p := range pending {
+		if !usedPayments[p.ID] {
+			available = append(available, p)
+		}
+	}
+
+	// 1. Código de referencia en el comentario
+	if m := referencePattern.FindStringSubmatch(tx.Comment); m != nil {
+		refID, _ := strconv.ParseInt(m[1], 10, 64)
+		for _, p := range available {
+			if p.ID == refID {
+				return paymentMatch(p, 100, "reference code")
+			}
+		}
+	}
+
+	var sameAmount []*models.PaymentWithDetails
+	for _, p := range available {
+		if p.Amount == tx.Amount {
+			sameAmount = append(sameAmount, p)
+		}
+	}
+
+	// 2. RUT del remitente coincide con el miembro del pago pendiente
+	if tx.SenderRUT != "" {
+		for _, p := range sameAmount {
+			if u := membersByID[p.UserID]; u != nil && u.RUT == tx.SenderRUT {
+				return paymentMatch(p, 90, "rut and amount")
+			}
+		}
+	}
+
+	// 3. Nombre del remitente similar al del miembro
+	if tx.SenderName != "" {
+		for _, p := range sameAmount {
+			if namesMatch(tx.SenderName, p.UserName) {
+				return paymentMatch(p, 75, "name and amount")
+			}
+		}
+	}
+
+	// 4. Sin pago pendiente, pero el RUT corresponde a un miembro
+	if tx.SenderRUT != "" {
+		for _, u := range members {
+			if u.RUT == tx.SenderRUT {
+				id := u.ID
+				return &BankMatch{UserID: &id, Score: 60, Reason: "member rut"}
+			}
+		}
+	}
+
+	// 5. Un único pago pendiente con ese monto
+	if len(sameAmount) == 1 {
+		return paymentMatch(sameAmount[0], 40, "amount only")
+	}
+	return nil
+}
+
+func paymentMatch(p *models.PaymentWithDetails, score int, reason string) *BankMatch {
+	paymentID, userID := p.ID, p.UserID
+	return &BankMatch{PaymentID: &paymentID, UserID: &userID, Score: score, Reason: reason}
+}
+
+// namesMatch: al menos dos palabras del nombre del miembro aparecen en el nombre del remitente
+// (o todas, si el miembro registró una sola palabra)
+func namesMatch(sender, member string) bool {
+	senderWords := map[string]bool{}
+	for _, w := range strings.Fields(normalizeText(sender)) {
+		senderWords[w] = true
+	}
+	memberWords := strings.Fields(normalizeText(member))
+	if len(memberWords) == 0 {
+		return false
+	}
+	hits := 0
+	for _, w := range memberWords {
+		if len(w) > 1 && senderWords[w] {
+			hits++
+		}
+	}
+	return hits >= 2 || (len(memberWords) == 1 && hits == 1)
+}
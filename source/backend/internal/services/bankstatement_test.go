@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestParseCLP(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		format  clpFormat
+		want    int64
+		wantErr bool
+	}{
+		// Banco de Chile: "." de miles y "," decimal
+		{"chile plain", "45000", clpChilean, 45000, false},
+		{"chile thousands", "45.000", clpChilean, 45000, false},
+		{"chile millions", "1.234.567", clpChilean, 1234567, false},
+		{"chile zero decimals", "45.000,00", clpChilean, 45000, false},
+		{"chile currency sign", "$ 45.000", clpChilean, 45000, false},
+		{"chile empty", "", clpChilean, 0, false},
+		{"chile cents", "45.000,50", clpChilean, 0, true},
+		{"chile dot decimals", "45000.00", clpChilean, 0, true},
+		{"chile bad grouping", "45.00", clpChilean, 0, true},
+
+		// CSV genérico: separadores detectados en cada monto
+		{"auto plain", "45000", clpAuto, 45000, false},
+		{"auto dot thousands", "45.000", clpAuto, 45000, false},
+		{"auto comma thousands", "$45,000", clpAuto, 45000, false},
+		{"auto dot decimals", "45000.00", clpAuto, 45000, false},
+		{"auto comma decimals", "45000,00", clpAuto, 45000, false},
+		{"auto chilean", "45.000,00", clpAuto, 45000, false},
+		{"auto us", "45,000.00", clpAuto, 45000, false},
+		{"auto repeated separator", "1,234,567", clpAuto, 1234567, false},
+		{"auto negative", "-45.000", clpAuto, -45000, false},
+		{"auto clp prefix", "CLP 45.000", clpAuto, 45000, false},
+		{"auto cents", "45000.50", clpAuto, 0, true},
+		{"auto us cents", "45,000.99", clpAuto, 0, true},
+		{"auto text", "cuarenta mil", clpAuto, 0, true},
+		{"auto bad grouping", "4,50,000", clpAuto, 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseCLP(c.in, c.format)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseCLP(%q) expected error, got %d", c.name, c.in, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: parseCLP(%q) = %d, %v; want %d", c.name, c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestParseBankStatement_Formats(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		format  string
+		amounts []int64
+	}{
+		{
+			name: "banco chile",
+			data: "Cartola de movimientos\nCuenta;00-123-45678-90\n" +
+				"Fecha;Descripción;Cargos (CLP);Abonos (CLP);Saldo (CLP)\n" +
+				"02/05/2025;Traspaso De:Juan Perez 12.345.678-5;;45.000;145.000\n" +
+				"03/05/2025;Pago servicios;10.000;;135.000\n" +
+				"04/05/2025;Traspaso De:Ana Soto;;1.234.000,00;1.369.000\n",
+			format:  models.BankFormatBancoChile,
+			amounts: []int64{45000, 1234000},
+		},
+		{
+			name: "generic csv with decimals",
+			data: "fecha,monto,rut,nombre,comentario\n" +
+				"2025-05-02,45000.00,12345678-5,Juan Perez,BM-12\n" +
+				"2025-05-03,-5000.00,,,comision\n",
+			format:  models.BankFormatCSV,
+			amounts: []int64{45000},
+		},
+		{
+			name:    "generic csv with chilean thousands",
+			data:    "Fecha;Monto;Nombre\n02/05/2025;$45.000;Juan Perez\n03/05/2025;\"$ 30.000\";Ana Soto\n",
+			format:  models.BankFormatCSV,
+			amounts: []int64{45000, 30000},
+		},
+	}
+	for _, c := range cases {
+		txs, format, err := ParseBankStatement(strings.NewReader(c.data), models.BankFormatAuto)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if format != c.format {
+			t.Errorf("%s: detected format %q, want %q", c.name, format, c.format)
+		}
+		if len(txs) != len(c.amounts) {
+			t.Fatalf("%s: got %d transactions, want %d", c.name, len(txs), len(c.amounts))
+		}
+		for i, tx := range txs {
+			if tx.Amount != c.amounts[i] {
+				t.Errorf("%s: line %d amount %d, want %d", c.name, i, tx.Amount, c.amounts[i])
+			}
+		}
+	}
+}
+
+func TestParseBankStatement_RejectsFractionalAmounts(t *testing.T) {
+	data := "fecha,monto,nombre\n2025-05-02,45000.50,Juan Perez\n"
+	if _, _, err := ParseBankStatement(strings.NewReader(data), models.BankFormatAuto); !errors.Is(err, ErrStatementAmount) {
+		t.Fatalf("expected ErrStatementAmount, got %v", err)
+	}
+}
+
+func TestParseBankStatement_IdenticalTransfersKeepDistinctFingerprints(t *testing.T) {
+	data := "fecha,monto,rut,nombre,comentario\n" +
+		"2025-05-02,45000,12345678-5,Juan Perez,Plan mensual\n" +
+		"2025-05-02,45000,12345678-5,Juan Perez,Plan mensual\n"
+	txs, _, err := ParseBankStatement(strings.NewReader(data), models.BankFormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[0].Fingerprint == txs[1].Fingerprint {
+		t.Fatalf("identical transfers must not collapse into one: %+v", txs)
+	}
+
+	// Reimportar la misma cartola produce las mismas huellas y se detecta como duplicada
+	again, _, _ := ParseBankStatement(strings.NewReader(data), models.BankFormatAuto)
+	if again[0].Fingerprint != txs[0].Fingerprint || again[1].Fingerprint != txs[1].Fingerprint {
+		t.Fatal("fingerprints must be stable across imports")
+	}
+}
+
+func TestParseBankStatement_OperationIDFingerprint(t *testing.T) {
+	data := "Fecha;N° Operación;Monto;Nombre\n" +
+		"02/05/2025;9001;45.000;Juan Perez\n" +
+		"02/05/2025;9002;45.000;Juan Perez\n"
+	txs, _, err := ParseBankStatement(strings.NewReader(data), models.BankFormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[0].OperationID != "9001" || txs[1].OperationID != "9002" {
+		t.Fatalf("expected operation IDs to be read: %+v", txs)
+	}
+	if txs[0].Fingerprint == txs[1].Fingerprint {
+		t.Fatal("different operations must have different fingerprints")
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrBenchmarkNotFound     = errors.New("benchmark not found")
+	ErrBenchmarkInvalid      = errors.New("name and content are required")
+	ErrBenchmarkCategory     = errors.New("invalid benchmark category")
+	ErrBenchmarkScoreType    = errors.New("invalid score type")
+	ErrBenchmarkNameConflict = errors.New("a benchmark with that name already exists")
+)
+
+var benchmarkCategories = map[string]bool{
+	models.BenchmarkGirl: true, models.BenchmarkHero: true, models.BenchmarkOpen: true, models.BenchmarkOther: true,
+}
+
+var scoreTypes = map[string]bool{
+	models.ScoreTime: true, models.ScoreRoundsReps: true, models.ScoreReps: true, models.ScoreLoad: true,
+}
+
+// BenchmarkService - Catálogo de benchmarks, historial del miembro entre todas las rutinas vinculadas y percentil en el box
+type BenchmarkService struct {
+	benchmarkRepo *repository.BenchmarkRepository
+}
+
+func NewBenchmarkService(benchmarkRepo *repository.BenchmarkRepository) *BenchmarkService {
+	return &BenchmarkService{benchmarkRepo: benchmarkRepo}
+}
+
+// cappedTimePenalty - Segundos que suma un "CAP+n" para quedar detrás de cualquier tiempo terminado
+const cappedTimePenalty = 1e9
+
+// ParseScore convierte el score a un valor comparable donde mayor siempre es mejor.
+// time: "12:34" o "1:02:03" (se niega), o "CAP+5" si no terminó en el time cap (le faltaron 5 reps);
+// rounds_reps: "5+12" (rondas×1000 + reps); reps y load: número.
+func ParseScore(scoreType, score string) (float64, bool) {
+	score = strings.TrimSpace(strings.ToLower(score))
+	switch scoreType {
+	case models.ScoreTime:
+		if rest, capped := strings.CutPrefix(score, "cap"); capped {
+			missing, ok := strings.CutPrefix(strings.TrimSpace(rest), "+")
+			if !ok {
+				return 0, false
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(missing))
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			return -(cappedTimePenalty + float64(n)), true
+		}
+		parts := strings.Split(score, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return 0, false
+		}
+		total := 0
+		for _, p := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			total = total*60 + n
+		}
+		return -float64(total), true
+	case models.ScoreRoundsReps:
+		rounds, reps, _ := strings.Cut(score, "+")
+		nr, err := strconv.Atoi(strings.TrimSpace(rounds))
+		if err != nil || nr < 0 {
+			return 0, false
+		}
+		np := 0
+		if strings.TrimSpace(reps) != "" {
+			if np, err = strconv.Atoi(strings.TrimSpace(reps)); err != nil || np < 0 {
+				return 0, false
+			}
+		}
+		return float64(nr*1000 + np), true
+	case models.ScoreReps, models.ScoreLoad:
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(score, "kg")), 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+func (s *BenchmarkService) validate(req *models.BenchmarkRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || strings.TrimSpace(req.Content) == "" {
+		return ErrBenchmarkInvalid
+	}
+	if req.Category == "" {
+		req.Category = models.BenchmarkOther
+	}
+	if !benchmarkCategories[req.Category] {
+		return ErrBenchmarkCategory
+	}
+	if !scoreTypes[req.ScoreType] {
+		return ErrBenchmarkScoreType
+	}
+	return nil
+}
+
+func (s *BenchmarkService) Create(req *models.BenchmarkRequest) (*models.Benchmark, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	b := &models.Benchmark{Name: req.Name, Category: req.Category, ScoreType: req.ScoreType, Description: req.Description,
+		Content: req.Content, TimeCap: req.TimeCap, Active: true}
+	if taken, err := s.benchmarkRepo.NameTaken(b.Name, 0); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, ErrBenchmarkNameConflict
+	}
+	if err := s.benchmarkRepo.Create(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *BenchmarkService) Update(id int64, req *models.BenchmarkRequest) (*models.Benchmark, error) {
+	b, err := s.benchmarkRepo.Get(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrBenchmarkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	if taken, err := s.benchmarkRepo.NameTaken(req.Name, id); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, ErrBenchmarkNameConflict
+	}
+	b.Name, b.Category, b.ScoreType, b.Description, b.Content, b.TimeCap = req.Name, req.Category, req.ScoreType, req.Description, req.Content, req.TimeCap
+	if req.Active != nil {
+		b.Active = *req.Active
+	}
+	if err := s.benchmarkRepo.Update(b); err != nil {
+		return nil, err
+	}
+	return s.benchmarkRepo.Get(id)
+}
+
+// markPRs marca como PR cada resultado que supera la mejor marca previa (Rx y scaled por separado)
+// y devuelve los mejores de cada uno
+func markPRs(scoreType string, results []*models.BenchmarkResult) (bestRx, bestScaled *models.BenchmarkResult) {
+	var bestRxValue, bestScaledValue float64
+	for _, res := range results {
+		v, ok := ParseScore(scoreType, res.Score)
+		if !ok {
+			continue
+		}
+		best, bestValue := &bestScaled, &bestScaledValue
+		if res.Rx {
+			best, bestValue = &bestRx, &bestRxValue
+		}
+		if *best == nil || v > *bestValue {
+			res.IsPR = true
+			*best, *bestValue = res, v
+		}
+	}
+	return bestRx, bestScaled
+}
+
+// History devuelve la progresión del miembro en el benchmark y su percentil Rx entre los miembros del box
+func (s *BenchmarkService) History(benchmarkID, userID int64) (*models.BenchmarkHistory, error) {
+	b, err := s.benchmarkRepo.Get(benchmarkID)
+	if err == sql.ErrNoRows {
+		return nil, ErrBenchmarkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.benchmarkRepo.Results(benchmarkID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mejor Rx de cada miembro
+	bestByUser := map[int64]float64{}
+	h := &models.BenchmarkHistory{Benchmark: b, Results: []*models.BenchmarkResult{}}
+	for _, res := range all {
+		if res.UserID == userID {
+			h.Results = append(h.Results, res)
+		}
+		if !res.Rx {
+			continue
+		}
+		if v, ok := ParseScore(b.ScoreType, res.Score); ok {
+			if cur, seen := bestByUser[res.UserID]; !seen || v > cur {
+				bestByUser[res.UserID] = v
+			}
+		}
+	}
+	h.BestRx, h.BestScaled = markPRs(b.ScoreType, h.Results)
+	h.RxAthletes = len(bestByUser)
+
+	if pct, ok := percentile(bestByUser, userID); ok {
+		h.PercentileRx = &pct
+	}
+	return h, nil
+}
+
+// percentile - % de miembros con marca igual o peor que la del miembro, incluyéndose a sí mismo (un decimal)
+func percentile(bestByUser map[int64]float64, userID int64) (float64, bool) {
+	mine, ok := bestByUser[userID]
+	if !ok {
+		return 0, false
+	}
+	below, ties := 0, 0
+	for uid, v := range bestByUser {
+		switch {
+		case uid == userID:
+		case v < mine:
+			below++
+		case v == mine:
+			ties++
+		}
+	}
+	pct := float64(below+ties+1) / float64(len(bestByUser)) * 100
+	return float64(int(pct*10+0.5)) / 10, true
+}
+
+// PRs devuelve la mejor marca del miembro en cada benchmark que ha hecho (Rx si tiene, si no scaled)
+func (s *BenchmarkService) PRs(userID int64) ([]*models.BenchmarkPR, error) {
+	byBenchmark, err := s.benchmarkRepo.UserResults(userID)
+	if err != nil {
+		return nil, err
+	}
+	list := []*models.BenchmarkPR{}
+	for benchmarkID, results := range byBenchmark {
+		b, err := s.benchmarkRepo.Get(benchmarkID)
+		if err != nil {
+			return nil, err
+		}
+		bestRx, bestScaled := markPRs(b.ScoreType, results)
+		best := bestRx
+		if best == nil {
+			best = bestScaled
+		}
+		if best == nil {
+			continue
+		}
+		pr := &models.BenchmarkPR{BenchmarkID: b.ID, BenchmarkName: b.Name, Category: b.Category, Score: best.Score,
+			Rx: best.Rx, Attempts: len(results), AchievedAt: best.CreatedAt}
+		if best.ScheduleDate != nil {
+			pr.AchievedAt = *best.ScheduleDate
+		}
+		list = append(list, pr)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BenchmarkName < list[j].BenchmarkName })
+	return list, nil
+}
+
+// ResultIsPR recalcula el PR de un resultado recién registrado contra todas las rutinas del benchmark.
+// ok es false si la rutina no está vinculada a un benchmark.
+func (s *BenchmarkService) ResultIsPR(result *models.UserRoutineResult) (isPR, ok bool, err error) {
+	b, err := s.benchmarkRepo.ForRoutine(result.RoutineID)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	v, parsed := ParseScore(b.ScoreType, result.Score)
+	if !parsed {
+		return false, true, s.benchmarkRepo.SetResultPR(result.ID, false)
+	}
+	previous, err := s.benchmarkRepo.Results(b.ID, result.UserID)
+	if err != nil {
+		return false, false, err
+	}
+	isPR = true
+	for _, res := range previous {
+		if res.ResultID == result.ID || res.Rx != result.Rx {
+			continue
+		}
+		if pv, ok := ParseScore(b.ScoreType, res.Score); ok && pv >= v {
+			isPR = false
+			break
+		}
+	}
+	return isPR, true, s.benchmarkRepo.SetResultPR(result.ID, isPR)
+}
+
+func (s *BenchmarkService) Get(id int64) (*models.Benchmark, error) {
+	b, err := s.benchmarkRepo.Get(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrBenchmarkNotFound
+	}
+	return b, err
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestParseScore(t *testing.T) {
+	cases := []struct {
+		scoreType string
+		score     string
+		want      float64
+		ok        bool
+	}{
+		{models.ScoreTime, "12:34", -754, true},
+		{models.ScoreTime, " 7:05 ", -425, true},
+		{models.ScoreTime, "1:02:03", -3723, true},
+		{models.ScoreTime, "CAP+5", -(cappedTimePenalty + 5), true},
+		{models.ScoreTime, "cap + 12", -(cappedTimePenalty + 12), true},
+		{models.ScoreTime, "CAP+0", -cappedTimePenalty, true},
+		{models.ScoreTime, "CAP", 0, false},
+		{models.ScoreTime, "CAP+x", 0, false},
+		{models.ScoreTime, "CAP+-3", 0, false},
+		{models.ScoreTime, "754", 0, false},
+		{models.ScoreTime, "1:02:03:04", 0, false},
+		{models.ScoreTime, "-1:00", 0, false},
+		{models.ScoreTime, "ab:cd", 0, false},
+		{models.ScoreRoundsReps, "5+12", 5012, true},
+		{models.ScoreRoundsReps, "5", 5000, true},
+		{models.ScoreRoundsReps, "5+", 5000, true},
+		{models.ScoreRoundsReps, "-1+2", 0, false},
+		{models.ScoreRoundsReps, "a+1", 0, false},
+		{models.ScoreRoundsReps, "5+x", 0, false},
+		{models.ScoreReps, "150", 150, true},
+		{models.ScoreReps, "-3", 0, false},
+		{models.ScoreLoad, "100kg", 100, true},
+		{models.ScoreLoad, "102.5", 102.5, true},
+		{models.ScoreLoad, "heavy", 0, false},
+		{"unknown", "10", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := ParseScore(c.scoreType, c.score)
+		if ok != c.ok || (ok && got != c.want) {
+			t.Errorf("ParseScore(%s, %q) = %v, %v; want %v, %v", c.scoreType, c.score, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestParseScore_CappedRanksBehindFinishedTimes(t *testing.T) {
+	order := []string{"4:59", "59:59", "9:59:59", "CAP+1", "CAP+5", "CAP+40"} // De mejor a peor
+	for i := 1; i < len(order); i++ {
+		better, _ := ParseScore(models.ScoreTime, order[i-1])
+		worse, _ := ParseScore(models.ScoreTime, order[i])
+		if better <= worse {
+			t.Errorf("%q should rank ahead of %q", order[i-1], order[i])
+		}
+	}
+}
+
+type benchmarkScore struct {
+	value string
+	rx    bool
+}
+
+func benchmarkResults(scores ...benchmarkScore) []*models.BenchmarkResult {
+	var list []*models.BenchmarkResult
+	for i, sc := range scores {
+		list = append(list, &models.BenchmarkResult{ResultID: int64(i + 1), Score: sc.value, Rx: sc.rx})
+	}
+	return list
+}
+
+func TestMarkPRs(t *testing.T) {
+	results := benchmarkResults(
+		benchmarkScore{"10:00", true}, // PR Rx
+		benchmarkScore{"11:00", true},
+		benchmarkScore{"9:00", false}, // PR scaled, independiente del Rx
+		benchmarkScore{"DNF", true},   // No se puede comparar: se ignora
+		benchmarkScore{"9:30", true},  // PR Rx
+		benchmarkScore{"9:30", true},  // Empatar no es PR
+		benchmarkScore{"CAP+3", true},
+	)
+	bestRx, bestScaled := markPRs(models.ScoreTime, results)
+
+	wantPR := []bool{true, false, true, false, true, false, false}
+	for i, res := range results {
+		if res.IsPR != wantPR[i] {
+			t.Errorf("result %d (%s): IsPR = %v, want %v", i+1, res.Score, res.IsPR, wantPR[i])
+		}
+	}
+	if bestRx != results[4] || bestScaled != results[2] {
+		t.Fatalf("unexpected bests: rx %+v, scaled %+v", bestRx, bestScaled)
+	}
+}
+
+func TestMarkPRs_CappedThenFinished(t *testing.T) {
+	results := benchmarkResults(benchmarkScore{"CAP+10", true}, benchmarkScore{"CAP+4", true}, benchmarkScore{"CAP+6", true}, benchmarkScore{"14:30", true})
+	bestRx, bestScaled := markPRs(models.ScoreTime, results)
+
+	wantPR := []bool{true, true, false, true}
+	for i, res := range results {
+		if res.IsPR != wantPR[i] {
+			t.Errorf("result %d (%s): IsPR = %v, want %v", i+1, res.Score, res.IsPR, wantPR[i])
+		}
+	}
+	if bestRx != results[3] || bestScaled != nil {
+		t.Fatalf("unexpected bests: rx %+v, scaled %+v", bestRx, bestScaled)
+	}
+}
+
+func TestMarkPRs_RoundsReps(t *testing.T) {
+	results := benchmarkResults(benchmarkScore{"5+12", true}, benchmarkScore{"6", true}, benchmarkScore{"5+20", true})
+	bestRx, _ := markPRs(models.ScoreRoundsReps, results)
+	if !results[0].IsPR || !results[1].IsPR || results[2].IsPR || bestRx != results[1] {
+		t.Fatalf("6 rounds beats 5+20: %+v %+v %+v", results[0], results[1], results[2])
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// Valores ya comparables (mayor es mejor): los usuarios 1 y 3 empatan
+	best := map[int64]float64{1: -600, 2: -700, 3: -600, 4: -500}
+	cases := []struct {
+		userID int64
+		want   float64
+	}{
+		{4, 100},
+		{1, 75}, // Uno peor + un empate + él mismo
+		{3, 75},
+		{2, 25},
+	}
+	for _, c := range cases {
+		got, ok := percentile(best, c.userID)
+		if !ok || got != c.want {
+			t.Errorf("user %d: percentile = %v, %v; want %v", c.userID, got, ok, c.want)
+		}
+	}
+
+	if got, _ := percentile(map[int64]float64{1: 10, 2: 20, 3: 30}, 2); got != 66.7 {
+		t.Errorf("expected one decimal rounding, got %v", got)
+	}
+	if got, _ := percentile(map[int64]float64{1: 10}, 1); got != 100 {
+		t.Errorf("only athlete should be at 100, got %v", got)
+	}
+	if _, ok := percentile(best, 99); ok {
+		t.Error("member without Rx results has no percentile")
+	}
+}
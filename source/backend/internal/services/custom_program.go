@@ -0,0 +1,193 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrCustomProgramNotFound = errors.New("custom program not found")
+	ErrCustomProgramBilled   = errors.New("custom program already billed")
+	ErrCustomProgramPrice    = errors.New("price cannot be negative")
+	ErrMonthInvalid          = errors.New("month must be YYYY-MM")
+	ErrBillingMonthOpen      = errors.New("month has not closed yet")
+)
+
+// CustomProgramService - Entrega de rutinas personalizadas, su cobro y el cierre mensual a la cuenta del miembro
+type CustomProgramService struct {
+	programRepo  *repository.CustomProgramRepository
+	settingsRepo *repository.SettingsRepository
+}
+
+func NewCustomProgramService(programRepo *repository.CustomProgramRepository, settingsRepo *repository.SettingsRepository) *CustomProgramService {
+	return &CustomProgramService{programRepo: programRepo, settingsRepo: settingsRepo}
+}
+
+// Settings devuelve el precio por defecto de la programación personalizada (0 si no está configurado)
+func (s *CustomProgramService) Settings() (*models.CustomProgrammingSettings, error) {
+	v, err := s.settingsRepo.Get(models.SettingCustomRoutinePrice)
+	if err != nil {
+		return nil, err
+	}
+	price, _ := strconv.ParseInt(v, 10, 64)
+	return &models.CustomProgrammingSettings{Price: price}, nil
+}
+
+func (s *CustomProgramService) UpdateSettings(req *models.CustomProgrammingSettings, updatedBy int64) (*models.CustomProgrammingSettings, error) {
+	if req.Price < 0 {
+		return nil, ErrCustomProgramPrice
+	}
+	if err := s.settingsRepo.Set(models.SettingCustomRoutinePrice, strconv.FormatInt(req.Price, 10), updatedBy); err != nil {
+		return nil, err
+	}
+	return s.Settings()
+}
+
+// Deliver registra la entrega de una rutina personalizada a su miembro. Las facturables quedan pendientes
+// al precio indicado o, si no viene, al configurado. Si la rutina deja de ser facturable antes del cierre
+// el cobro se anula; si vuelve a serlo se reactiva.
+func (s *CustomProgramService) Deliver(routine *models.Routine, price *int64) (*models.CustomProgram, error) {
+	if price != nil && *price < 0 {
+		return nil, ErrCustomProgramPrice
+	}
+	custom := routine.IsCustom && routine.TargetUserID != nil
+	billable := custom && routine.Billable
+
+	existing, err := s.programRepo.GetByRoutine(routine.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if existing == nil && !custom {
+		return nil, nil
+	}
+	if existing != nil {
+		switch {
+		case existing.Status == models.CustomProgramBilled:
+		case !billable && existing.Status == models.CustomProgramPending:
+			err = s.programRepo.SetStatus(existing.ID, models.CustomProgramVoid, existing.Amount)
+		case billable && existing.Status != models.CustomProgramPending:
+			amount := existing.Amount
+			if price != nil {
+				amount = *price
+			} else if amount == 0 {
+				if amount, err = s.defaultPrice(); err != nil {
+					return nil, err
+				}
+			}
+			err = s.programRepo.SetStatus(existing.ID, models.CustomProgramPending, amount)
+		case billable && price != nil && *price != existing.Amount:
+			err = s.programRepo.SetStatus(existing.ID, models.CustomProgramPending, *price)
+		}
+		if err == nil && existing.Status != models.CustomProgramBilled && !sameID(existing.InstructorID, routine.InstructorID) {
+			err = s.programRepo.SetInstructor(existing.ID, routine.InstructorID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.programRepo.GetByRoutine(routine.ID)
+	}
+
+	// Los ingresos van al coach asignado a la rutina; CoachID registra quién la programó
+	coachID := routine.CreatedBy
+	p := &models.CustomProgram{RoutineID: routine.ID, UserID: *routine.TargetUserID, CoachID: &coachID,
+		InstructorID: routine.InstructorID, Status: models.CustomProgramIncluded}
+	if billable {
+		p.Status = models.CustomProgramPending
+		if price != nil {
+			p.Amount = *price
+		} else if p.Amount, err = s.defaultPrice(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.programRepo.Deliver(p); err != nil {
+		return nil, err
+	}
+	return s.programRepo.GetByRoutine(routine.ID)
+}
+
+func sameID(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func (s *CustomProgramService) defaultPrice() (int64, error) {
+	settings, err := s.Settings()
+	if err != nil {
+		return 0, err
+	}
+	return settings.Price, nil
+}
+
+// Acknowledge - El miembro confirma que recibió el programa
+func (s *CustomProgramService) Acknowledge(id, userID int64) (*models.CustomProgram, error) {
+	if err := s.programRepo.Acknowledge(id, userID); err == sql.ErrNoRows {
+		return nil, ErrCustomProgramNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.programRepo.Get(id)
+}
+
+// Void anula el cobro de un programa aún no facturado
+func (s *CustomProgramService) Void(id int64) (*models.CustomProgram, error) {
+	p, err := s.programRepo.Get(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrCustomProgramNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Status == models.CustomProgramBilled {
+		return nil, ErrCustomProgramBilled
+	}
+	if err := s.programRepo.SetStatus(id, models.CustomProgramVoid, p.Amount); err == sql.ErrNoRows {
+		return nil, ErrCustomProgramBilled
+	} else if err != nil {
+		return nil, err
+	}
+	return s.programRepo.Get(id)
+}
+
+// monthRange devuelve [primer día del mes, primer día del mes siguiente)
+func monthRange(month string) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrMonthInvalid
+	}
+	return from, from.AddDate(0, 1, 0), nil
+}
+
+// BillMonth hace el cierre del mes (vacío = mes anterior): un cargo por miembro en su cuenta corriente con
+// todos sus programas pendientes entregados hasta el fin de ese mes, incluidos los de cierres anteriores que se
+// reactivaron. Volver a correrlo solo factura lo que quedó pendiente.
+func (s *CustomProgramService) BillMonth(month string, createdBy int64) (*models.BillingRun, error) {
+	now := time.Now()
+	if month == "" {
+		month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0).Format("2006-01")
+	}
+	_, to, err := monthRange(month)
+	if err != nil {
+		return nil, err
+	}
+	if to.After(now) {
+		return nil, ErrBillingMonthOpen
+	}
+	return s.programRepo.BillPeriod(month, to, createdBy)
+}
+
+// RevenueByCoach - Ingresos por programación personalizada de cada coach entre dos meses (inclusive)
+func (s *CustomProgramService) RevenueByCoach(fromMonth, toMonth string) ([]*models.CoachProgrammingRevenue, error) {
+	from, _, err := monthRange(fromMonth)
+	if err != nil {
+		return nil, err
+	}
+	_, to, err := monthRange(toMonth)
+	if err != nil {
+		return nil, err
+	}
+	return s.programRepo.RevenueByCoach(from, to)
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+)
+
+var (
+	ErrInvalidCAF = errors.New("invalid CAF file")
+)
+
+// ParsedCAF - Contenido relevante de un archivo CAF del SII
+type ParsedCAF struct {
+	EmisorRUT    string
+	DocType      int
+	FolioFrom    int64
+	FolioTo      int64
+	AuthorizedAt string
+	RawCAF       string // Elemento <CAF>...</CAF> que se incluye en cada timbre
+	PrivateKey   string // RSASK en PEM
+}
+
+type cafXML struct {
+	CAF struct {
+		DA struct {
+			RE  string `xml:"RE"`
+			TD  int    `xml:"TD"`
+			RNG struct {
+				D int64 `xml:"D"`
+				H int64 `xml:"H"`
+			} `xml:"RNG"`
+			FA string `xml:"FA"`
+		} `xml:"DA"`
+	} `xml:"CAF"`
+	RSASK string `xml:"RSASK"`
+}
+
+var (
+	cafElementPattern = regexp.MustCompile(`(?s)<CAF\b.*</CAF>`)
+	xmlWhitespace     = regexp.MustCompile(`>\s+<`)
+)
+
+// ParseCAF lee el XML de autorización de folios descargado desde el SII
+func ParseCAF(data []byte) (*ParsedCAF, error) {
+	var doc cafXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCAF, err)
+	}
+	da := doc.CAF.DA
+	if da.TD == 0 || da.RNG.D <= 0 || da.RNG.H < da.RNG.D {
+		return nil, fmt.Errorf("%w: missing document type or folio range", ErrInvalidCAF)
+	}
+	if _, err := parseRSAPrivateKey(doc.RSASK); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCAF, err)
+	}
+	raw := cafElementPattern.Find(data)
+	if raw == nil {
+		return nil, fmt.Errorf("%w: missing CAF element", ErrInvalidCAF)
+	}
+	return &ParsedCAF{
+		EmisorRUT:    models.NormalizeRUT(da.RE),
+		DocType:      da.TD,
+		FolioFrom:    da.RNG.D,
+		FolioTo:      da.RNG.H,
+		AuthorizedAt: da.FA,
+		RawCAF:       xmlWhitespace.ReplaceAllString(strings.TrimSpace(string(raw)), "><"),
+		PrivateKey:   strings.TrimSpace(doc.RSASK),
+	}, nil
+}
+
+func parseRSAPrivateKey(pemData string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemData)))
+	if block == nil {
+		return nil, errors.New("RSASK is not a PEM key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("RSASK is not an RSA key")
+	}
+	return key, nil
+}
+
+// DTEEmisor - Datos del box como emisor, desde la configuración
+type DTEEmisor struct {
+	RUT          string
+	BusinessName string
+	Activity     string
+	Acteco       string
+	Address      string
+	Commune      string
+	Resolution   string // Ej. "Res. Ex. SII N° 80 de 2014"
+}
+
+func EmisorFromConfig(cfg *config.Config) DTEEmisor {
+	return DTEEmisor{
+		RUT:          models.NormalizeRUT(cfg.DTEEmisorRUT),
+		BusinessName: cfg.DTEEmisorName,
+		Activity:     cfg.DTEEmisorActivity,
+		Acteco:       cfg.DTEEmisorActeco,
+		Address:      cfg.DTEEmisorAddress,
+		Commune:      cfg.DTEEmisorCommune,
+		Resolution:   cfg.DTEResolution,
+	}
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// BuildTED arma y firma el timbre electrónico (DD firmado con la llave privada del CAF, SHA1withRSA)
+func BuildTED(doc *models.TaxDocument, emisorRUT, rawCAF, privateKeyPEM string, now time.Time) (string, error) {
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return "", err
+	}
+	firstItem := ""
+	if len(doc.Items) > 0 {
+		firstItem = doc.Items[0].Name
+	}
+	dd := "<DD>" +
+		"<RE>" + emisorRUT + "</RE>" +
+		"<TD>" + strconv.Itoa(doc.DocType) + "</TD>" +
+		"<F>" + strconv.FormatInt(doc.Folio, 10) + "</F>" +
+		"<FE>" + doc.IssuedAt.Format("2006-01-02") + "</FE>" +
+		"<RR>" + doc.Receptor.RUT + "</RR>" +
+		"<RSR>" + xmlText(truncate(doc.Receptor.BusinessName, 40)) + "</RSR>" +
+		"<MNT>" + strconv.FormatInt(doc.Total, 10) + "</MNT>" +
+		"<IT1>" + xmlText(truncate(firstItem, 40)) + "</IT1>" +
+		rawCAF +
+		"<TSTED>" + now.Format("2006-01-02T15:04:05") + "</TSTED>" +
+		"</DD>"
+
+	digest := sha1.Sum(EncodeLatin1(dd))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return `<TED version="1.0">` + dd + `<FRMT algoritmo="SHA1withRSA">` + base64.StdEncoding.EncodeToString(sig) + `</FRMT></TED>`, nil
+}
+
+// BuildDTEXML genera el XML del documento con el timbre incluido.
+// La firma XMLDSig con el certificado digital de la empresa la agrega el sender al armar el sobre de envío.
+func BuildDTEXML(doc *models.TaxDocument, emisor DTEEmisor, ted string, now time.Time) string {
+	var b strings.Builder
+	id := fmt.Sprintf("T%dF%d", doc.DocType, doc.Folio)
+	b.WriteString(`<?xml version="1.0" encoding="ISO-8859-1"?>`)
+	b.WriteString(`<DTE version="1.0"><Documento ID="` + id + `"><Encabezado>`)
+
+	b.WriteString("<IdDoc><TipoDTE>" + strconv.Itoa(doc.DocType) + "</TipoDTE><Folio>" + strconv.FormatInt(doc.Folio, 10) + "</Folio>")
+	b.WriteString("<FchEmis>" + doc.IssuedAt.Format("2006-01-02") + "</FchEmis>")
+	if doc.DocType != models.DTEFactura {
+		b.WriteString("<IndServicio>3</IndServicio>") // Boletas de venta y servicios
+	}
+	b.WriteString("</IdDoc>")
+
+	b.WriteString("<Emisor><RUTEmisor>" + emisor.RUT + "</RUTEmisor>")
+	if doc.DocType == models.DTEFactura {
+		b.WriteString("<RznSoc>" + xmlText(emisor.BusinessName) + "</RznSoc><GiroEmis>" + xmlText(truncate(emisor.Activity, 80)) + "</GiroEmis>")
+		if emisor.Acteco != "" {
+			b.WriteString("<Acteco>" + xmlText(emisor.Acteco) + "</Acteco>")
+		}
+	} else {
+		b.WriteString("<RznSocEmisor>" + xmlText(emisor.BusinessName) + "</RznSocEmisor><GiroEmisor>" + xmlText(truncate(emisor.Activity, 80)) + "</GiroEmisor>")
+	}
+	b.WriteString("<DirOrigen>" + xmlText(emisor.Address) + "</DirOrigen><CmnaOrigen>" + xmlText(emisor.Commune) + "</CmnaOrigen></Emisor>")
+
+	r := doc.Receptor
+	b.WriteString("<Receptor><RUTRecep>" + r.RUT + "</RUTRecep>")
+	if r.BusinessName != "" {
+		b.WriteString("<RznSocRecep>" + xmlText(truncate(r.BusinessName, 100)) + "</RznSocRecep>")
+	}
+	if doc.DocType == models.DTEFactura {
+		b.WriteString("<GiroRecep>" + xmlText(truncate(r.Activity, 40)) + "</GiroRecep>")
+	}
+	if r.Address != "" {
+		b.WriteString("<DirRecep>" + xmlText(r.Address) + "</DirRecep><CmnaRecep>" + xmlText(r.Commune) + "</CmnaRecep>")
+	}
+	b.WriteString("</Receptor>")
+
+	b.WriteString("<Totales>")
+	if doc.NetAmount > 0 {
+		b.WriteString("<MntNeto>" + strconv.FormatInt(doc.NetAmount, 10) + "</MntNeto>")
+	}
+	if doc.ExemptAmount > 0 {
+		b.WriteString("<MntExe>" + strconv.FormatInt(doc.ExemptAmount, 10) + "</MntExe>")
+	}
+	if doc.IVA > 0 {
+		if doc.DocType == models.DTEFactura {
+			b.WriteString("<TasaIVA>19</TasaIVA>")
+		}
+		b.WriteString("<IVA>" + strconv.FormatInt(doc.IVA, 10) + "</IVA>")
+	}
+	b.WriteString("<MntTotal>" + strconv.FormatInt(doc.Total, 10) + "</MntTotal></Totales>")
+	b.WriteString("</Encabezado>")
+
+	for i, it := range doc.Items {
+		b.WriteString("<Detalle><NroLinDet>" + strconv.Itoa(i+1) + "</NroLinDet>")
+		if doc.DocType == models.DTEBoletaExenta {
+			b.WriteString("<IndExe>1</IndExe>")
+		}
+		b.WriteString("<NmbItem>" + xmlText(truncate(it.Name, 80)) + "</NmbItem>")
+		b.WriteString("<QtyItem>" + strconv.Itoa(it.Quantity) + "</QtyItem><PrcItem>" + strconv.FormatInt(it.UnitPrice, 10) + "</PrcItem>")
+		b.WriteString("<MontoItem>" + strconv.FormatInt(it.Amount, 10) + "</MontoItem></Detalle>")
+	}
+
+	b.WriteString(ted)
+	b.WriteString("<TmstFirma>" + now.Format("2006-01-02T15:04:05") + "</TmstFirma>")
+	b.WriteString("</Documento></DTE>")
+	return b.String()
+}
+
+// PrepareTaxDocument completa montos y detalle según el tipo de documento.
+// Los items llegan con precios brutos (IVA incluido); en factura se expresan en neto.
+func PrepareTaxDocument(doc *models.TaxDocument) {
+	doc.NetAmount, doc.IVA, doc.ExemptAmount = models.CalculateIVA(doc.Total, doc.DocType)
+	if doc.DocType != models.DTEFactura || len(doc.Items) == 0 {
+		return
+	}
+	// Prorratear el neto entre las líneas; la última absorbe el redondeo
+	var assigned int64
+	for i := range doc.Items {
+		it := &doc.Items[i]
+		if i == len(doc.Items)-1 {
+			it.Amount = doc.NetAmount - assigned
+		} else {
+			it.Amount = it.Amount * doc.NetAmount / doc.Total
+			assigned += it.Amount
+		}
+		if it.Quantity > 0 {
+			it.UnitPrice = it.Amount / int64(it.Quantity)
+		}
+	}
+}
+
+// DTESender envía el documento timbrado al SII (o a un proveedor) y devuelve el track id
+type DTESender interface {
+	Send(doc *models.TaxDocument) (trackID string, err error)
+}
+
+// LocalDTESender - Implementación local: guarda el XML en disco y simula el envío. Útil en desarrollo y certificación.
+type LocalDTESender struct {
+	Dir string
+}
+
+func (s *LocalDTESender) Send(doc *models.TaxDocument) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("T%dF%d.xml", doc.DocType, doc.Folio)
+	if err := os.WriteFile(filepath.Join(s.Dir, name), EncodeLatin1(doc.XML), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("LOCAL-%d-%d", doc.DocType, doc.Folio), nil
+}
+
+// BarcodeEncoder codifica el timbre como PDF417 para imprimirlo. Si no hay uno configurado,
+// la representación impresa muestra los datos del timbre en texto.
+type BarcodeEncoder interface {
+	Encode(data []byte) ([][]bool, error)
+}
+
+// RenderTaxDocumentPDF genera la representación impresa del DTE con el timbre electrónico
+func RenderTaxDocumentPDF(doc *models.TaxDocument, emisor DTEEmisor, barcode BarcodeEncoder) []byte {
+	p := NewPDFDocument()
+	p.AddPage()
+
+	// Emisor
+	p.Text(40, 60, 14, FontBold, emisor.BusinessName)
+	p.Text(40, 78, 9, FontRegular, emisor.Activity)
+	p.Text(40, 92, 9, FontRegular, emisor.Address+", "+emisor.Commune)
+
+	// Recuadro tipo/folio
+	p.Rect(380, 40, 190, 80, 1.5, false, 0)
+	p.TextCenter(475, 62, 11, FontBold, "R.U.T.: "+formatRUT(emisor.RUT))
+	p.TextCenter(475, 82, 9, FontBold, models.DTENames[doc.DocType])
+	p.TextCenter(475, 102, 11, FontBold, "N° "+strconv.FormatInt(doc.Folio, 10))
+	p.TextCenter(475, 135, 8, FontRegular, "S.I.I. - "+strings.ToUpper(emisor.Commune))
+
+	// Receptor
+	y := 160.0
+	p.Text(40, y, 9, FontRegular, "Fecha de emisión: "+doc.IssuedAt.Format("02/01/2006"))
+	if doc.Receptor.RUT != "" && doc.Receptor.RUT != models.ReceptorGenericRUT {
+		y += 14
+		p.Text(40, y, 9, FontRegular, "Señor(es): "+doc.Receptor.BusinessName+"   RUT: "+formatRUT(doc.Receptor.RUT))
+	}
+	if doc.DocType == models.DTEFactura {
+		y += 14
+		p.Text(40, y, 9, FontRegular, "Giro: "+doc.Receptor.Activity)
+		y += 14
+		p.Text(40, y, 9, FontRegular, "Dirección: "+doc.Receptor.Address+", "+doc.Receptor.Commune)
+	}
+
+	// Detalle
+	y += 24
+	p.Rect(40, y, 530, 18, 0, true, 0.9)
+	p.Text(46, y+12, 9, FontBold, "Descripción")
+	p.TextRight(380, y+12, 9, FontBold, "Cant.")
+	p.TextRight(470, y+12, 9, FontBold, "Precio")
+	p.TextRight(564, y+12, 9, FontBold, "Total")
+	y += 18
+	for _, it := range doc.Items {
+		y += 14
+		p.Text(46, y, 9, FontRegular, truncate(it.Name, 60))
+		p.TextRight(380, y, 9, FontRegular, strconv.Itoa(it.Quantity))
+		p.TextRight(470, y, 9, FontRegular, FormatCLP(it.UnitPrice))
+		p.TextRight(564, y, 9, FontRegular, FormatCLP(it.Amount))
+	}
+	y += 10
+	p.Line(40, y, 570, y, 0.5)
+
+	// Totales
+	totals := [][2]string{}
+	if doc.NetAmount > 0 {
+		totals = append(totals, [2]string{"Monto neto", FormatCLP(doc.NetAmount)})
+		totals = append(totals, [2]string{"IVA (19%)", FormatCLP(doc.IVA)})
+	}
+	if doc.ExemptAmount > 0 {
+		totals = append(totals, [2]string{"Monto exento", FormatCLP(doc.ExemptAmount)})
+	}
+	totals = append(totals, [2]string{"Total", FormatCLP(doc.Total)})
+	for i, t := range totals {
+		y += 16
+		font := FontRegular
+		if i == len(totals)-1 {
+			font = FontBold
+		}
+		p.TextRight(470, y, 10, font, t[0])
+		p.TextRight(564, y, 10, font, t[1])
+	}
+
+	// Timbre electrónico
+	y += 40
+	drawn := false
+	if barcode != nil && doc.TED != "" {
+		if m, err := barcode.Encode(EncodeLatin1(doc.TED)); err == nil && len(m) > 0 {
+			moduleW := 230.0 / float64(len(m[0]))
+			p.Matrix(40, y, moduleW, moduleW*3, m)
+			y += float64(len(m)) * moduleW * 3
+			drawn = true
+		}
+	}
+	if !drawn {
+		p.Rect(40, y, 230, 70, 0.5, false, 0)
+		p.Text(46, y+14, 7, FontMono, fmt.Sprintf("TED T%d F%d", doc.DocType, doc.Folio))
+		sig := tedSignature(doc.TED)
+		for i := 0; i < 3 && i*36 < len(sig); i++ {
+			end := (i + 1) * 36
+			if end > len(sig) {
+				end = len(sig)
+			}
+			p.Text(46, y+28+float64(i)*12, 7, FontMono, sig[i*36:end])
+		}
+		y += 70
+	}
+	p.TextCenter(155, y+14, 8, FontBold, "Timbre Electrónico SII")
+	if emisor.Resolution != "" {
+		p.TextCenter(155, y+26, 7, FontRegular, emisor.Resolution)
+	}
+	p.TextCenter(155, y+38, 7, FontRegular, "Verifique documento: www.sii.cl")
+
+	return p.Bytes()
+}
+
+var frmtPattern = regexp.MustCompile(`<FRMT[^>]*>([^<]*)</FRMT>`)
+
+func tedSignature(ted string) string {
+	if m := frmtPattern.FindStringSubmatch(ted); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// formatRUT agrega puntos de miles: 12345678-9 -> 12.345.678-9
+func formatRUT(rut string) string {
+	parts := strings.SplitN(rut, "-", 2)
+	if len(parts) != 2 {
+		return rut
+	}
+	body := parts[0]
+	for i := len(body) - 3; i > 0; i -= 3 {
+		body = body[:i] + "." + body[i:]
+	}
+	return body + "-" + parts[1]
+}
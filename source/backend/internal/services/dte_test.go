@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func itemsNet(items []models.TaxDocumentItem) int64 {
+	var sum int64
+	for _, it := range items {
+		sum += it.Amount
+	}
+	return sum
+}
+
+func TestPrepareTaxDocument_FacturaProratesNet(t *testing.T) {
+	doc := &models.TaxDocument{DocType: models.DTEFactura, Total: 10000, Items: []models.TaxDocumentItem{
+		{Name: "Plan mensual", Quantity: 1, UnitPrice: 3333, Amount: 3333},
+		{Name: "Shaker", Quantity: 2, UnitPrice: 1666, Amount: 3333},
+		{Name: "Proteína", Quantity: 1, UnitPrice: 3334, Amount: 3334},
+	}}
+
+	PrepareTaxDocument(doc)
+
+	if doc.NetAmount != 8403 || doc.IVA != 1597 || doc.ExemptAmount != 0 {
+		t.Fatalf("unexpected totals: net %d, iva %d, exempt %d", doc.NetAmount, doc.IVA, doc.ExemptAmount)
+	}
+	if doc.NetAmount+doc.IVA != doc.Total {
+		t.Fatalf("net %d + iva %d != total %d", doc.NetAmount, doc.IVA, doc.Total)
+	}
+	// Las líneas truncan el prorrateo; la última absorbe la diferencia
+	want := []int64{2800, 2800, 2803}
+	for i, it := range doc.Items {
+		if it.Amount != want[i] {
+			t.Errorf("line %d: amount %d, want %d", i+1, it.Amount, want[i])
+		}
+	}
+	if got := itemsNet(doc.Items); got != doc.NetAmount {
+		t.Fatalf("lines add up to %d, want net %d", got, doc.NetAmount)
+	}
+	if doc.Items[1].UnitPrice != 1400 {
+		t.Errorf("unit price should be net per unit, got %d", doc.Items[1].UnitPrice)
+	}
+}
+
+func TestPrepareTaxDocument_FacturaLinesAlwaysMatchNet(t *testing.T) {
+	for total := int64(3); total <= 30000; total += 97 {
+		a, b := total/3, total/7
+		doc := &models.TaxDocument{DocType: models.DTEFactura, Total: total, Items: []models.TaxDocumentItem{
+			{Quantity: 1, Amount: a}, {Quantity: 3, Amount: b}, {Quantity: 1, Amount: total - a - b},
+		}}
+		PrepareTaxDocument(doc)
+		if doc.NetAmount+doc.IVA != total {
+			t.Fatalf("total %d: net %d + iva %d", total, doc.NetAmount, doc.IVA)
+		}
+		if got := itemsNet(doc.Items); got != doc.NetAmount {
+			t.Fatalf("total %d: lines add up to %d, want net %d", total, got, doc.NetAmount)
+		}
+	}
+}
+
+func TestPrepareTaxDocument_BoletaKeepsGrossLines(t *testing.T) {
+	doc := &models.TaxDocument{DocType: models.DTEBoleta, Total: 45000, Items: []models.TaxDocumentItem{
+		{Name: "Plan mensual", Quantity: 1, UnitPrice: 45000, Amount: 45000},
+	}}
+
+	PrepareTaxDocument(doc)
+
+	if doc.NetAmount != 37815 || doc.IVA != 7185 {
+		t.Fatalf("unexpected split: net %d, iva %d", doc.NetAmount, doc.IVA)
+	}
+	if doc.Items[0].Amount != 45000 || doc.Items[0].UnitPrice != 45000 {
+		t.Fatalf("boleta lines keep gross prices, got %+v", doc.Items[0])
+	}
+}
+
+func TestPrepareTaxDocument_ExentaHasNoIVA(t *testing.T) {
+	doc := &models.TaxDocument{DocType: models.DTEBoletaExenta, Total: 30000, Items: []models.TaxDocumentItem{
+		{Name: "Clase de prueba", Quantity: 1, UnitPrice: 30000, Amount: 30000},
+	}}
+
+	PrepareTaxDocument(doc)
+
+	if doc.IVA != 0 || doc.NetAmount != 0 || doc.ExemptAmount != 30000 {
+		t.Fatalf("exempt document must carry no IVA: net %d, iva %d, exempt %d", doc.NetAmount, doc.IVA, doc.ExemptAmount)
+	}
+	if doc.Items[0].Amount != 30000 {
+		t.Fatalf("exempt lines are not prorated, got %+v", doc.Items[0])
+	}
+}
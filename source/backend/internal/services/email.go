@@ -1,10 +1,12 @@
 package services
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/smtp"
 	"strings"
+	"time"
 
 	"boxmagic/internal/config"
 )
@@ -23,6 +25,36 @@ func (s *EmailService) Send(to, subject, htmlBody string) error {
 		return nil
 	}
 
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
+		s.cfg.SMTPFrom, to, subject, htmlBody)
+	return s.deliver(to, subject, msg)
+}
+
+// SendWithAttachment envía un email HTML con un archivo adjunto (ej. comprobante PDF)
+func (s *EmailService) SendWithAttachment(to, subject, htmlBody, filename, contentType string, data []byte) error {
+	if !s.cfg.EmailEnabled {
+		log.Printf("[email] (disabled) To: %s, Subject: %s, Attachment: %s", to, subject, filename)
+		return nil
+	}
+
+	boundary := fmt.Sprintf("boxmagic-%d", time.Now().UnixNano())
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\n", s.cfg.SMTPFrom, to, subject)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s\r\n", boundary, htmlBody)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: %s; name=%q\r\nContent-Transfer-Encoding: base64\r\nContent-Disposition: attachment; filename=%q\r\n\r\n",
+		boundary, contentType, filename, filename)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return s.deliver(to, subject, b.String())
+}
+
+func (s *EmailService) deliver(to, subject, msg string) error {
 	from := s.cfg.SMTPFrom
 	// Extract email address from "Name <email>" format
 	fromAddr := from
@@ -30,9 +62,6 @@ func (s *EmailService) Send(to, subject, htmlBody string) error {
 		fromAddr = strings.Trim(from[idx:], "<>")
 	}
 
-	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
-		from, to, subject, htmlBody)
-
 	auth := smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPass, s.cfg.SMTPHost)
 	addr := fmt.Sprintf("%s:%d", s.cfg.SMTPHost, s.cfg.SMTPPort)
 
@@ -102,3 +131,126 @@ func (s *EmailService) SendClassCancelled(email, userName, className, date, time
 
 	return s.Send(email, subject, body)
 }
+
+func (s *EmailService) SendTransferApproved(email, userName, planName, endDate string) error {
+	subject := "Transferencia aprobada"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#10b981">Transferencia Aprobada</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Revisamos tu comprobante y tu plan ya está activo:</p>
+		<div style="background:#f4f4f5;padding:15px;border-radius:8px;margin:15px 0">
+			<p style="margin:5px 0"><strong>Plan:</strong> %s</p>
+			<p style="margin:5px 0"><strong>Válido hasta:</strong> %s</p>
+		</div>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, planName, endDate)
+	return s.Send(email, subject, body)
+}
+
+func (s *EmailService) SendTransferRejected(email, userName, planName, reason string) error {
+	subject := "Transferencia rechazada"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#ef4444">Transferencia Rechazada</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>No pudimos validar el comprobante que enviaste:</p>
+		<div style="background:#f4f4f5;padding:15px;border-radius:8px;margin:15px 0">
+			<p style="margin:5px 0"><strong>Plan:</strong> %s</p>
+			<p style="margin:5px 0"><strong>Motivo:</strong> %s</p>
+		</div>
+		<p>Puedes enviar un nuevo comprobante desde la app o acercarte a recepción.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, planName, reason)
+	return s.Send(email, subject, body)
+}
+
+func (s *EmailService) SendEmailVerification(email, userName, link string) error {
+	subject := "Confirma tu email - Box Magic"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#10b981">Confirma tu email</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Para activar tu cuenta confirma tu dirección de email:</p>
+		<p style="margin:20px 0"><a href="%s" style="background:#10b981;color:#fff;padding:12px 20px;border-radius:8px;text-decoration:none">Confirmar email</a></p>
+		<p style="color:#71717a;font-size:14px">Si no creaste una cuenta, ignora este mensaje.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, link)
+	return s.Send(email, subject, body)
+}
+
+func (s *EmailService) SendPasswordReset(email, userName, link string) error {
+	subject := "Recupera tu contraseña - Box Magic"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#10b981">Recuperar contraseña</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Recibimos una solicitud para cambiar tu contraseña. El link es válido por tiempo limitado y se puede usar una sola vez:</p>
+		<p style="margin:20px 0"><a href="%s" style="background:#10b981;color:#fff;padding:12px 20px;border-radius:8px;text-decoration:none">Cambiar contraseña</a></p>
+		<p style="color:#71717a;font-size:14px">Si no fuiste tú, ignora este mensaje; tu contraseña no cambiará.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, link)
+	return s.Send(email, subject, body)
+}
+
+// SendMagicLink envía el link de login sin contraseña
+func (s *EmailService) SendMagicLink(email, userName, link string) error {
+	subject := "Tu link para entrar - Box Magic"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#10b981">Entrar a Box Magic</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Usa este botón para entrar sin contraseña. El link vence en pocos minutos y funciona una sola vez:</p>
+		<p style="margin:20px 0"><a href="%s" style="background:#10b981;color:#fff;padding:12px 20px;border-radius:8px;text-decoration:none">Entrar</a></p>
+		<p style="color:#71717a;font-size:14px">Si no lo pediste, ignora este mensaje.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, link)
+	return s.Send(email, subject, body)
+}
+
+// SendAccountLocked avisa que la cuenta se bloqueó por intentos fallidos de login
+func (s *EmailService) SendAccountLocked(email, userName string, until time.Time) error {
+	subject := "Cuenta bloqueada temporalmente - Box Magic"
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#ef4444">Cuenta bloqueada temporalmente</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Detectamos varios intentos fallidos de inicio de sesión en tu cuenta, por lo que la bloqueamos hasta el <strong>%s</strong>.</p>
+		<p style="color:#71717a;font-size:14px">Si no fuiste tú, te recomendamos cambiar tu contraseña usando "Olvidé mi contraseña"; al hacerlo la cuenta se desbloquea.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, until.Format("02/01/2006 15:04"))
+	return s.Send(email, subject, body)
+}
+
+// SendReceipt envía un comprobante o certificado en PDF
+func (s *EmailService) SendReceipt(email, userName, title, filename string, pdf []byte) error {
+	subject := fmt.Sprintf("%s - Box Magic", title)
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#10b981">%s</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p>Adjuntamos tu documento en formato PDF.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, title, userName)
+
+	return s.SendWithAttachment(email, subject, body, filename, "application/pdf", pdf)
+}
+
+// SendImpersonationNotice avisa al miembro que un administrador entró a su cuenta para darle soporte
+func (s *EmailService) SendImpersonationNotice(email, userName, adminName, reason string, write bool, until time.Time) error {
+	subject := "Un administrador accedió a tu cuenta - Box Magic"
+	access := "solo para consultar tu información"
+	if write {
+		access = "con permiso para hacer cambios (reservas, cancelaciones, etc.)"
+	}
+	body := fmt.Sprintf(`<div style="font-family:sans-serif;max-width:500px;margin:0 auto;padding:20px">
+		<h2 style="color:#f59e0b">Acceso de soporte a tu cuenta</h2>
+		<p>Hola <strong>%s</strong>,</p>
+		<p><strong>%s</strong> entró a tu cuenta %s, hasta el <strong>%s</strong>.</p>
+		<p>Motivo: %s</p>
+		<p style="color:#71717a;font-size:14px">Todas las acciones quedan registradas. Si no pediste ayuda, contacta al box.</p>
+		<hr style="border:none;border-top:1px solid #e4e4e7;margin:20px 0">
+		<p style="color:#a1a1aa;font-size:12px">Box Magic</p>
+	</div>`, userName, adminName, access, until.Format("02/01/2006 15:04"), reason)
+	return s.Send(email, subject, body)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/jwtkeys"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrImpersonationNotFound = errors.New("impersonation session not found")
+	ErrImpersonationReason   = errors.New("reason is required")
+	ErrImpersonationTarget   = errors.New("only active member accounts can be impersonated")
+	ErrImpersonationInactive = errors.New("impersonation ended or expired")
+)
+
+// ImpersonationService - Sesiones de soporte: el admin recibe un access token que actúa como el miembro
+type ImpersonationService struct {
+	repo         *repository.ImpersonationRepository
+	userRepo     repository.UserRepo
+	cfg          *config.Config
+	emailService *EmailService
+}
+
+func NewImpersonationService(repo *repository.ImpersonationRepository, userRepo repository.UserRepo, cfg *config.Config) *ImpersonationService {
+	return &ImpersonationService{repo: repo, userRepo: userRepo, cfg: cfg}
+}
+
+func (s *ImpersonationService) SetEmailService(svc *EmailService) {
+	s.emailService = svc
+}
+
+// Start abre la sesión y firma un token del miembro con el claim "act" del admin. El token dura como máximo
+// IMPERSONATION_TTL, no tiene refresh token y es de solo lectura salvo que se pida escritura.
+func (s *ImpersonationService) Start(adminID int64, req *models.StartImpersonationRequest, client models.ClientInfo) (*models.ImpersonationResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrImpersonationReason
+	}
+	admin, err := s.userRepo.GetByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == admin.ID || user.Role != models.RoleUser || !user.Active {
+		return nil, ErrImpersonationTarget
+	}
+
+	ttl := s.cfg.ImpersonationTTL
+	if d := time.Duration(req.Minutes) * time.Minute; d > 0 && d < ttl {
+		ttl = d
+	}
+	imp := &models.Impersonation{
+		AdminID:   admin.ID,
+		AdminName: admin.Name,
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Reason:    reason,
+		Write:     req.Write,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.repo.Create(imp); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":   user.ID,
+		"email": user.Email,
+		"role":  user.Role,
+		"sid":   0,
+		"ver":   user.TokenVersion,
+		"exp":   imp.ExpiresAt.Unix(),
+		"act":   map[string]interface{}{"sub": admin.ID, "role": admin.Role},
+		"imp":   imp.ID,
+		"imp_w": imp.Write,
+	}
+	if s.cfg.TenantSlug != "" {
+		claims["tid"] = s.cfg.TenantSlug
+	}
+	token, err := jwtkeys.Sign(s.cfg.JWTKeys, s.cfg.JWTSecret, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.emailService != nil {
+		go s.emailService.SendImpersonationNotice(user.Email, user.Name, admin.Name, reason, imp.Write, imp.ExpiresAt)
+	}
+	return &models.ImpersonationResponse{AccessToken: token, Impersonation: imp, User: user}, nil
+}
+
+// End cierra la sesión antes de que venza; el token deja de aceptarse de inmediato
+func (s *ImpersonationService) End(id, adminID int64) (*models.Impersonation, error) {
+	if err := s.repo.End(id, adminID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrImpersonationNotFound
+		}
+		return nil, err
+	}
+	return s.repo.Get(id)
+}
+
+// CheckImpersonation implementa middleware.ImpersonationChecker
+func (s *ImpersonationService) CheckImpersonation(id, actorID, userID int64) error {
+	active, err := s.repo.IsActive(id, actorID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrImpersonationInactive
+	}
+	return nil
+}
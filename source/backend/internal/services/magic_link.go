@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+
+	"boxmagic/internal/models"
+)
+
+var ErrMagicLinkDisabled = errors.New("magic link login is disabled")
+
+func (s *AuthService) magicLinkEnabled() bool {
+	settings, err := s.SecuritySettings()
+	return err == nil && settings.MagicLinkEnabled
+}
+
+// RequestMagicLink envía un link de login de un solo uso. Solo aplica a miembros (el staff entra con
+// contraseña y 2FA) y, como la recuperación de contraseña, no informa si el email existe.
+func (s *AuthService) RequestMagicLink(email string) error {
+	if !s.magicLinkEnabled() {
+		return ErrMagicLinkDisabled
+	}
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.Active || user.Role != models.RoleMember {
+		return nil
+	}
+	token, err := s.issueAuthToken(user.ID, models.TokenMagicLink, s.cfg.MagicLinkTTL)
+	if err != nil {
+		return err
+	}
+	if s.emailService != nil {
+		go s.emailService.SendMagicLink(user.Email, user.Name, s.appLink("/magic-link", token))
+	}
+	return nil
+}
+
+// VerifyMagicLink canjea el link por la misma respuesta que Login (incluido el desafío de 2FA si corresponde)
+func (s *AuthService) VerifyMagicLink(req *models.MagicLinkVerifyRequest) (*models.AuthResponse, error) {
+	if !s.magicLinkEnabled() {
+		return nil, ErrMagicLinkDisabled
+	}
+	userID, err := s.userRepo.ConsumeAuthToken(models.TokenMagicLink, hashToken(req.Token))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || !user.Active || user.Role != models.RoleMember {
+		return nil, ErrInvalidToken
+	}
+	// El link llegó a su email, así que también queda verificado
+	if !user.EmailVerified {
+		s.userRepo.SetEmailVerified(user.ID)
+		user.EmailVerified = true
+	}
+
+	if s.lockoutRepo != nil {
+		s.lockoutRepo.Reset(user.ID)
+	}
+	if challenge, err := s.twoFactorChallenge(user); challenge != nil || err != nil {
+		return challenge, err
+	}
+	return s.startSession(user, req.Client)
+}
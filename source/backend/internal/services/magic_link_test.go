@@ -0,0 +1,178 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+type fakeAuthToken struct {
+	userID    int64
+	purpose   string
+	expiresAt time.Time
+	used      bool
+}
+
+// fakeUsers replica ConsumeAuthToken del repositorio: un solo uso y solo antes de expirar
+type fakeUsers struct {
+	repository.UserRepo
+	users  map[string]*models.User
+	tokens map[string]*fakeAuthToken
+}
+
+func (f *fakeUsers) GetByEmail(email string) (*models.User, error) {
+	if u, ok := f.users[email]; ok {
+		return u, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUsers) GetByID(id int64) (*models.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUsers) SetEmailVerified(userID int64) error { return nil }
+
+func (f *fakeUsers) CreateAuthToken(userID int64, purpose, tokenHash string, expiresAt time.Time) error {
+	f.tokens[tokenHash] = &fakeAuthToken{userID: userID, purpose: purpose, expiresAt: expiresAt}
+	return nil
+}
+
+func (f *fakeUsers) ConsumeAuthToken(purpose, tokenHash string) (int64, error) {
+	t, ok := f.tokens[tokenHash]
+	if !ok || t.purpose != purpose || t.used || !t.expiresAt.After(time.Now()) {
+		return 0, sql.ErrNoRows
+	}
+	t.used = true
+	return t.userID, nil
+}
+
+type fakeSessions struct {
+	repository.SessionRepo
+	nextID int64
+}
+
+func (f *fakeSessions) CreateSession(s *models.Session) error {
+	f.nextID++
+	s.ID = f.nextID
+	return nil
+}
+
+func (f *fakeSessions) SaveRefreshToken(sessionID, userID int64, tokenHash string, expiresAt time.Time) error {
+	return nil
+}
+
+type fakeSettings map[string]string
+
+func (f fakeSettings) Get(key string) (string, error) { return f[key], nil }
+
+func (f fakeSettings) Set(key, value string, updatedBy int64) error {
+	f[key] = value
+	return nil
+}
+
+func newMagicLinkService(enabled bool) (*AuthService, *fakeUsers) {
+	users := &fakeUsers{
+		users: map[string]*models.User{
+			"member@box.cl": {ID: 1, Email: "member@box.cl", Role: models.RoleMember, Active: true},
+			"coach@box.cl":  {ID: 2, Email: "coach@box.cl", Role: models.RoleCoach, Active: true},
+			"owner@box.cl":  {ID: 3, Email: "owner@box.cl", Role: models.RoleOwner, Active: true},
+		},
+		tokens: map[string]*fakeAuthToken{},
+	}
+	cfg := &config.Config{JWTSecret: "test", JWTExpiry: time.Hour, RefreshTokenExpiry: time.Hour, MagicLinkTTL: 15 * time.Minute}
+	s := NewAuthService(users, &fakeSessions{}, cfg)
+	s.SetTwoFactor(nil, fakeSettings{models.SettingMagicLinkEnabled: "false"})
+	if enabled {
+		s.settingsRepo.Set(models.SettingMagicLinkEnabled, "true", 0)
+	}
+	return s, users
+}
+
+func TestMagicLink_DisabledByToggle(t *testing.T) {
+	s, users := newMagicLinkService(false)
+	if err := s.RequestMagicLink("member@box.cl"); err != ErrMagicLinkDisabled {
+		t.Errorf("RequestMagicLink = %v, want ErrMagicLinkDisabled", err)
+	}
+	if len(users.tokens) != 0 {
+		t.Errorf("no token should be issued while disabled, got %d", len(users.tokens))
+	}
+	if _, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: "x"}); err != ErrMagicLinkDisabled {
+		t.Errorf("VerifyMagicLink = %v, want ErrMagicLinkDisabled", err)
+	}
+
+	// Sin repositorio de configuración también queda apagado
+	bare := NewAuthService(users, &fakeSessions{}, &config.Config{})
+	if err := bare.RequestMagicLink("member@box.cl"); err != ErrMagicLinkDisabled {
+		t.Errorf("without settings: RequestMagicLink = %v, want ErrMagicLinkDisabled", err)
+	}
+}
+
+func TestMagicLink_StaffGetsNoLink(t *testing.T) {
+	s, users := newMagicLinkService(true)
+	for _, email := range []string{"coach@box.cl", "owner@box.cl", "unknown@box.cl"} {
+		// Igual que la recuperación de contraseña: no revela si el email existe
+		if err := s.RequestMagicLink(email); err != nil {
+			t.Errorf("%s: RequestMagicLink = %v, want nil", email, err)
+		}
+	}
+	if len(users.tokens) != 0 {
+		t.Errorf("staff and unknown emails should get no token, got %d", len(users.tokens))
+	}
+
+	if err := s.RequestMagicLink("member@box.cl"); err != nil {
+		t.Fatalf("member: RequestMagicLink = %v", err)
+	}
+	if len(users.tokens) != 1 {
+		t.Fatalf("member should get one token, got %d", len(users.tokens))
+	}
+	for _, tok := range users.tokens {
+		if tok.userID != 1 || tok.purpose != models.TokenMagicLink {
+			t.Errorf("token = %+v, want magic link for user 1", tok)
+		}
+	}
+
+	// Un token emitido antes de que el usuario pasara a staff tampoco sirve
+	token, _ := s.issueAuthToken(2, models.TokenMagicLink, time.Minute)
+	if _, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: token}); err != ErrInvalidToken {
+		t.Errorf("staff VerifyMagicLink = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestMagicLink_SingleUse(t *testing.T) {
+	s, _ := newMagicLinkService(true)
+	token, err := s.issueAuthToken(1, models.TokenMagicLink, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: token})
+	if err != nil || resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("first VerifyMagicLink = %+v, %v; want tokens", resp, err)
+	}
+	if _, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: token}); err != ErrInvalidToken {
+		t.Errorf("second VerifyMagicLink = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestMagicLink_Expired(t *testing.T) {
+	s, _ := newMagicLinkService(true)
+	token, _ := s.issueAuthToken(1, models.TokenMagicLink, -time.Second)
+	if _, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: token}); err != ErrInvalidToken {
+		t.Errorf("expired VerifyMagicLink = %v, want ErrInvalidToken", err)
+	}
+
+	// Un token de otro propósito no sirve como magic link
+	other, _ := s.issueAuthToken(1, models.TokenEmailVerify, time.Minute)
+	if _, err := s.VerifyMagicLink(&models.MagicLinkVerifyRequest{Token: other}); err != ErrInvalidToken {
+		t.Errorf("email-verify token VerifyMagicLink = %v, want ErrInvalidToken", err)
+	}
+}
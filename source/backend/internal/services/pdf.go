@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDFDocument es un generador mínimo de PDF (texto, líneas y rectángulos) para comprobantes imprimibles.
+// Usa las fuentes estándar Helvetica/Courier, por lo que no requiere embeber fuentes.
+type PDFDocument struct {
+	Width, Height float64
+	pages         []*bytes.Buffer
+	cur           *bytes.Buffer
+}
+
+// Fuentes disponibles
+const (
+	FontRegular = "F1" // Helvetica
+	FontBold    = "F2" // Helvetica-Bold
+	FontMono    = "F3" // Courier
+)
+
+// NewPDFDocument crea un documento tamaño carta (612x792 pt)
+func NewPDFDocument() *PDFDocument {
+	return &PDFDocument{Width: 612, Height: 792}
+}
+
+func (p *PDFDocument) AddPage() {
+	p.cur = &bytes.Buffer{}
+	p.pages = append(p.pages, p.cur)
+}
+
+// Text escribe texto con origen en (x, y) medido desde la esquina superior izquierda
+func (p *PDFDocument) Text(x, y, size float64, font, s string) {
+	fmt.Fprintf(p.cur, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.Height-y, pdfEscape(s))
+}
+
+// TextRight escribe texto alineado a la derecha en x
+func (p *PDFDocument) TextRight(x, y, size float64, font, s string) {
+	p.Text(x-TextWidth(s, size, font), y, size, font, s)
+}
+
+// TextCenter escribe texto centrado en x
+func (p *PDFDocument) TextCenter(x, y, size float64, font, s string) {
+	p.Text(x-TextWidth(s, size, font)/2, y, size, font, s)
+}
+
+func (p *PDFDocument) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(p.cur, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, p.Height-y1, x2, p.Height-y2)
+}
+
+// Rect dibuja un rectángulo; fill rellena con el gris indicado (0 = negro, 1 = blanco)
+func (p *PDFDocument) Rect(x, y, w, h, lineWidth float64, fill bool, gray float64) {
+	if fill {
+		fmt.Fprintf(p.cur, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, p.Height-y-h, w, h)
+		return
+	}
+	fmt.Fprintf(p.cur, "%.2f w %.2f %.2f %.2f %.2f re S\n", lineWidth, x, p.Height-y-h, w, h)
+}
+
+// Matrix dibuja una matriz de módulos (ej. código de barras 2D) en (x, y) con el tamaño de módulo dado
+func (p *PDFDocument) Matrix(x, y, moduleW, moduleH float64, m [][]bool) {
+	for row, cols := range m {
+		for col, on := range cols {
+			if on {
+				fmt.Fprintf(p.cur, "%.2f %.2f %.2f %.2f re\n", x+float64(col)*moduleW, p.Height-y-float64(row+1)*moduleH, moduleW, moduleH)
+			}
+		}
+	}
+	p.cur.WriteString("f\n")
+}
+
+// Bytes serializa el documento
+func (p *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catálogo, 2 páginas, 3-5 fuentes, luego pares página/contenido
+	nPages := len(p.pages)
+	kids := make([]string, nPages)
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), nPages))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, content := range p.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			p.Width, p.Height, 7+i*2))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape convierte a WinAnsi (Latin-1) y escapa los caracteres especiales de strings PDF
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, c := range EncodeLatin1(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// EncodeLatin1 convierte texto UTF-8 a ISO-8859-1; los caracteres fuera de rango se reemplazan por '?'
+func EncodeLatin1(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < 256 {
+			out = append(out, byte(r))
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// Anchos Helvetica (1/1000 em) de los caracteres más usados; el resto usa un promedio
+var helveticaWidths = map[rune]int{
+	' ': 278, '.': 278, ',': 278, ':': 278, '-': 333, '$': 556, '%': 889, '/': 278, '#': 556, '(': 333, ')': 333,
+	'0': 556, '1': 556, '2': 556, '3': 556, '4': 556, '5': 556, '6': 556, '7': 556, '8': 556, '9': 556,
+	'i': 222, 'l': 222, 'j': 222, 't': 278, 'f': 278, 'r': 333, 'm': 833, 'w': 722, 'I': 278, 'M': 833, 'W': 944,
+}
+
+// TextWidth estima el ancho en puntos de un texto
+func TextWidth(s string, size float64, font string) float64 {
+	if font == FontMono {
+		return float64(len([]rune(s))) * 600 * size / 1000
+	}
+	total := 0
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = 556
+			if r >= 'A' && r <= 'Z' {
+				w = 667
+			}
+		}
+		if font == FontBold && (r == 'i' || r == 'l' || r == 'j') {
+			w = 278
+		}
+		total += w
+	}
+	return float64(total) * size / 1000
+}
+
+// FormatCLP formatea un monto en pesos chilenos (ej. $45.000)
+func FormatCLP(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	s := fmt.Sprintf("%d", amount)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "." + s[i:]
+	}
+	return sign + "$" + s
+}
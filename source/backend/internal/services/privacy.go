@@ -0,0 +1,213 @@
+package services
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrErasureNotFound = errors.New("erasure request not found")
+	ErrErasureOpen     = errors.New("there is already an open erasure request")
+	ErrErasureStaff    = errors.New("staff accounts must be removed by an administrator")
+)
+
+// PrivacyService - Exportación de datos del miembro y eliminación de cuentas con revisión y periodo de gracia
+type PrivacyService struct {
+	privacyRepo repository.PrivacyRepo
+	userRepo    repository.UserRepo
+	uploadDir   string
+	graceDays   int
+}
+
+func NewPrivacyService(privacyRepo repository.PrivacyRepo, userRepo repository.UserRepo, uploadDir string, graceDays int) *PrivacyService {
+	return &PrivacyService{privacyRepo: privacyRepo, userRepo: userRepo, uploadDir: uploadDir, graceDays: graceDays}
+}
+
+// uploadPath resuelve la URL de un archivo subido a su ruta en UploadDir; false si no es un upload propio
+func (s *PrivacyService) uploadPath(url string) (string, bool) {
+	i := strings.LastIndex(url, "/uploads/")
+	if i < 0 {
+		return "", false
+	}
+	name := url[i+len("/uploads/"):]
+	if name == "" || strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", false
+	}
+	return filepath.Join(s.uploadDir, name), true
+}
+
+// Export escribe un ZIP con un JSON por sección (perfil, reservas, resultados, levantamientos, medidas, nutrición, programas personalizados, comentarios)
+// y las fotos subidas por el miembro en photos/
+func (s *PrivacyService) Export(userID int64, w io.Writer) error {
+	data, err := s.privacyRepo.Export(userID)
+	if err != nil {
+		return err
+	}
+	photos, err := s.privacyRepo.PhotoURLs(userID)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for name, raw := range data {
+		f, err := zw.Create(name + ".json")
+		if err != nil {
+			return err
+		}
+		var pretty interface{}
+		if err := json.Unmarshal(raw, &pretty); err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(pretty); err != nil {
+			return err
+		}
+	}
+	for _, url := range photos {
+		path, ok := s.uploadPath(url)
+		if !ok {
+			continue
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			continue // Foto ya borrada del disco
+		}
+		f, err := zw.Create("photos/" + filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(f, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// RequestErasure registra la solicitud del miembro; queda pendiente de revisión del staff
+func (s *PrivacyService) RequestErasure(userID int64, reason string) (*models.ErasureRequest, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != models.RoleUser {
+		return nil, ErrErasureStaff
+	}
+	if _, err := s.privacyRepo.OpenErasure(userID); err == nil {
+		return nil, ErrErasureOpen
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	e := &models.ErasureRequest{UserID: userID, Status: models.ErasurePending, Reason: strings.TrimSpace(reason)}
+	if err := s.privacyRepo.CreateErasure(e); err != nil {
+		return nil, err
+	}
+	return s.privacyRepo.GetErasure(e.ID)
+}
+
+// OpenErasure devuelve la solicitud en curso del miembro
+func (s *PrivacyService) OpenErasure(userID int64) (*models.ErasureRequest, error) {
+	e, err := s.privacyRepo.OpenErasure(userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrErasureNotFound
+	}
+	return e, err
+}
+
+// CancelErasure permite al miembro arrepentirse mientras no haya terminado el periodo de gracia
+func (s *PrivacyService) CancelErasure(userID int64) error {
+	if err := s.privacyRepo.Cancel(userID); err == sql.ErrNoRows {
+		return ErrErasureNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Approve agenda la anonimización al terminar el periodo de gracia (inmediata si es 0)
+func (s *PrivacyService) Approve(id, reviewerID int64, notes string) (*models.ErasureRequest, error) {
+	scheduled := time.Now().AddDate(0, 0, s.graceDays)
+	if err := s.privacyRepo.Review(id, models.ErasureApproved, reviewerID, notes, &scheduled); err == sql.ErrNoRows {
+		return nil, ErrErasureNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if s.graceDays == 0 {
+		s.ProcessDue()
+	}
+	return s.privacyRepo.GetErasure(id)
+}
+
+// Reject cierra la solicitud sin anonimizar (p. ej. deuda pendiente o contrato vigente)
+func (s *PrivacyService) Reject(id, reviewerID int64, notes string) (*models.ErasureRequest, error) {
+	if err := s.privacyRepo.Review(id, models.ErasureRejected, reviewerID, notes, nil); err == sql.ErrNoRows {
+		return nil, ErrErasureNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.privacyRepo.GetErasure(id)
+}
+
+// EraseNow anonimiza la cuenta sin periodo de gracia; reemplaza al borrado físico del usuario
+func (s *PrivacyService) EraseNow(userID, adminID int64) error {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return err
+	}
+	e, err := s.privacyRepo.OpenErasure(userID)
+	if err == sql.ErrNoRows {
+		e = &models.ErasureRequest{UserID: userID, Status: models.ErasurePending, RequestedBy: &adminID}
+		err = s.privacyRepo.CreateErasure(e)
+	}
+	if err != nil {
+		return err
+	}
+	if e.Status == models.ErasurePending {
+		now := time.Now()
+		if err := s.privacyRepo.Review(e.ID, models.ErasureApproved, adminID, "Eliminada por el staff", &now); err != nil {
+			return err
+		}
+	}
+	return s.erase(e)
+}
+
+func (s *PrivacyService) erase(e *models.ErasureRequest) error {
+	photos, err := s.privacyRepo.Anonymize(e.ID, e.UserID)
+	if err != nil {
+		return err
+	}
+	for _, url := range photos {
+		if path, ok := s.uploadPath(url); ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("[PRIVACY] remove %s: %v", path, err)
+			}
+		}
+	}
+	log.Printf("[PRIVACY] user %d anonymized (request %d)", e.UserID, e.ID)
+	return nil
+}
+
+// ProcessDue anonimiza las cuentas cuyo periodo de gracia terminó
+func (s *PrivacyService) ProcessDue() {
+	due, err := s.privacyRepo.DueErasures(time.Now())
+	if err != nil {
+		log.Printf("[PRIVACY] due erasures: %v", err)
+		return
+	}
+	for _, e := range due {
+		if err := s.erase(e); err != nil && err != sql.ErrNoRows {
+			log.Printf("[PRIVACY] erase user %d: %v", e.UserID, err)
+		}
+	}
+}
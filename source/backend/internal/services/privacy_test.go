@@ -0,0 +1,182 @@
+package services
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+// memPrivacy guarda las solicitudes en memoria; Review solo acepta pendientes, como el repositorio
+type memPrivacy struct {
+	repository.PrivacyRepo
+	requests   map[int64]*models.ErasureRequest
+	anonymized []int64
+}
+
+func newMemPrivacy() *memPrivacy {
+	return &memPrivacy{requests: map[int64]*models.ErasureRequest{}}
+}
+
+func (m *memPrivacy) CreateErasure(e *models.ErasureRequest) error {
+	e.ID = int64(len(m.requests) + 1)
+	e.CreatedAt = time.Now()
+	m.requests[e.ID] = e
+	return nil
+}
+
+func (m *memPrivacy) GetErasure(id int64) (*models.ErasureRequest, error) {
+	if e, ok := m.requests[id]; ok {
+		return e, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memPrivacy) OpenErasure(userID int64) (*models.ErasureRequest, error) {
+	for _, e := range m.requests {
+		if e.UserID == userID && (e.Status == models.ErasurePending || e.Status == models.ErasureApproved) {
+			return e, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memPrivacy) Review(id int64, status string, reviewedBy int64, notes string, scheduledFor *time.Time) error {
+	e, ok := m.requests[id]
+	if !ok || e.Status != models.ErasurePending {
+		return sql.ErrNoRows
+	}
+	e.Status, e.ReviewedBy, e.ReviewNotes, e.ScheduledFor = status, &reviewedBy, notes, scheduledFor
+	return nil
+}
+
+func (m *memPrivacy) DueErasures(now time.Time) ([]*models.ErasureRequest, error) {
+	var due []*models.ErasureRequest
+	for _, e := range m.requests {
+		if e.Status == models.ErasureApproved && e.ScheduledFor != nil && !e.ScheduledFor.After(now) {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (m *memPrivacy) Anonymize(requestID, userID int64) ([]string, error) {
+	m.requests[requestID].Status = models.ErasureCompleted
+	m.anonymized = append(m.anonymized, userID)
+	return nil, nil
+}
+
+func newPrivacyService(graceDays int) (*PrivacyService, *memPrivacy) {
+	users := &fakeUsers{users: map[string]*models.User{
+		"member@box.cl": {ID: 1, Email: "member@box.cl", Role: models.RoleMember, Active: true},
+		"coach@box.cl":  {ID: 2, Email: "coach@box.cl", Role: models.RoleCoach, Active: true},
+	}}
+	repo := newMemPrivacy()
+	return NewPrivacyService(repo, users, "/srv/uploads", graceDays), repo
+}
+
+func TestPrivacyService_UploadPath(t *testing.T) {
+	s, _ := newPrivacyService(30)
+	cases := []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{"https://api.box.cl/uploads/photo.jpg", filepath.Join("/srv/uploads", "photo.jpg"), true},
+		{"/uploads/abc-123.png", filepath.Join("/srv/uploads", "abc-123.png"), true},
+		{"/uploads/../etc/passwd", "", false},
+		{"/uploads/..", "", false},
+		{"/uploads/sub/photo.jpg", "", false},
+		{`/uploads/..\secret`, "", false},
+		{"/uploads/", "", false},
+		{"https://cdn.example.com/photo.jpg", "", false},
+	}
+	for _, c := range cases {
+		got, ok := s.uploadPath(c.url)
+		if got != c.want || ok != c.ok {
+			t.Errorf("uploadPath(%q) = %q, %v; want %q, %v", c.url, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestPrivacyService_RequestErasure(t *testing.T) {
+	s, repo := newPrivacyService(30)
+
+	if _, err := s.RequestErasure(2, ""); err != ErrErasureStaff {
+		t.Errorf("staff: RequestErasure = %v, want ErrErasureStaff", err)
+	}
+
+	e, err := s.RequestErasure(1, "  me voy  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != models.ErasurePending || e.Reason != "me voy" {
+		t.Errorf("request = %+v, want pending with trimmed reason", e)
+	}
+
+	if _, err := s.RequestErasure(1, "otra vez"); err != ErrErasureOpen {
+		t.Errorf("duplicate: RequestErasure = %v, want ErrErasureOpen", err)
+	}
+	// Sigue abierta una vez aprobada (dentro del periodo de gracia)
+	repo.requests[e.ID].Status = models.ErasureApproved
+	if _, err := s.RequestErasure(1, ""); err != ErrErasureOpen {
+		t.Errorf("approved: RequestErasure = %v, want ErrErasureOpen", err)
+	}
+	if len(repo.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(repo.requests))
+	}
+
+	// Tras cancelarla puede volver a pedirla
+	repo.requests[e.ID].Status = models.ErasureCancelled
+	if _, err := s.RequestErasure(1, ""); err != nil {
+		t.Errorf("after cancel: RequestErasure = %v", err)
+	}
+}
+
+func TestPrivacyService_ApproveSchedulesGracePeriod(t *testing.T) {
+	s, repo := newPrivacyService(30)
+	e, _ := s.RequestErasure(1, "")
+
+	before := time.Now()
+	approved, err := s.Approve(e.ID, 9, "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != models.ErasureApproved || approved.ScheduledFor == nil {
+		t.Fatalf("approved = %+v, want approved with scheduled_for", approved)
+	}
+	if want := before.AddDate(0, 0, 30); approved.ScheduledFor.Before(want) || approved.ScheduledFor.Sub(want) > time.Minute {
+		t.Errorf("scheduled_for = %v, want ~%v", approved.ScheduledFor, want)
+	}
+	if len(repo.anonymized) != 0 {
+		t.Errorf("anonymized before the grace period: %v", repo.anonymized)
+	}
+
+	// Ya revisada no se puede volver a aprobar
+	if _, err := s.Approve(e.ID, 9, ""); err != ErrErasureNotFound {
+		t.Errorf("second approve = %v, want ErrErasureNotFound", err)
+	}
+
+	// ProcessDue la ejecuta cuando vence el plazo
+	past := time.Now().Add(-time.Minute)
+	repo.requests[e.ID].ScheduledFor = &past
+	s.ProcessDue()
+	if len(repo.anonymized) != 1 || repo.anonymized[0] != 1 {
+		t.Errorf("anonymized = %v, want [1]", repo.anonymized)
+	}
+}
+
+func TestPrivacyService_ApproveWithoutGraceErasesNow(t *testing.T) {
+	s, repo := newPrivacyService(0)
+	e, _ := s.RequestErasure(1, "")
+	got, err := s.Approve(e.ID, 9, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.ErasureCompleted || len(repo.anonymized) != 1 {
+		t.Errorf("status = %s, anonymized = %v; want completed immediately", got.Status, repo.anonymized)
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrTrackNotFound       = errors.New("track not found")
+	ErrTrackInvalid        = errors.New("track name is required")
+	ErrTrackNameConflict   = errors.New("a track with that name already exists")
+	ErrTrackPublishTime    = errors.New("publish_time must be HH:MM and publish_days_before between 0 and 14")
+	ErrTrackDayNotFound    = errors.New("no routine programmed for that date")
+	ErrTrackRoutine        = errors.New("routine not found or inactive")
+	ErrTrackTargetNotFound = errors.New("class or discipline not found")
+	ErrTrackTargetTaken    = errors.New("class or discipline already follows another track")
+	ErrTrackSameWeek       = errors.New("source and target weeks must differ")
+	ErrTrackShiftInvalid   = errors.New("days must be non-zero and the range valid")
+	ErrTrackShiftConflict  = errors.New("shifting would overwrite days outside the range")
+)
+
+// ProgrammingService - Tracks de programación: calendario de rutinas por día, herramientas del coach
+// (copiar semana, correr días, hora de publicación) y asignación automática a los horarios
+type ProgrammingService struct {
+	trackRepo   *repository.ProgrammingRepository
+	routineRepo repository.RoutineRepo
+}
+
+func NewProgrammingService(trackRepo *repository.ProgrammingRepository, routineRepo repository.RoutineRepo) *ProgrammingService {
+	return &ProgrammingService{trackRepo: trackRepo, routineRepo: routineRepo}
+}
+
+// syncFrom - Las asignaciones de días pasados quedan como historial
+func syncFrom() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// SyncSchedules asigna la rutina del track a los horarios desde la fecha dada (p. ej. tras generar la semana)
+func (s *ProgrammingService) SyncSchedules(from time.Time) error {
+	if today := syncFrom(); from.Before(today) {
+		from = today
+	}
+	return s.trackRepo.SyncSchedules(from)
+}
+
+func (s *ProgrammingService) sync() error {
+	return s.trackRepo.SyncSchedules(syncFrom())
+}
+
+func validPublishTime(v string) bool {
+	t, err := time.Parse("15:04", v)
+	return err == nil && t.Format("15:04") == v
+}
+
+func (s *ProgrammingService) Get(id int64) (*models.ProgrammingTrack, error) {
+	t, err := s.trackRepo.Get(id)
+	if err == sql.ErrNoRows {
+		return nil, ErrTrackNotFound
+	}
+	return t, err
+}
+
+func (s *ProgrammingService) apply(t *models.ProgrammingTrack, req *models.TrackRequest) error {
+	t.Name = strings.TrimSpace(req.Name)
+	t.Description = req.Description
+	if t.Name == "" {
+		return ErrTrackInvalid
+	}
+	if req.PublishDaysBefore != nil {
+		t.PublishDaysBefore = *req.PublishDaysBefore
+	}
+	if req.PublishTime != "" {
+		t.PublishTime = req.PublishTime
+	}
+	if t.PublishDaysBefore < 0 || t.PublishDaysBefore > 14 || !validPublishTime(t.PublishTime) {
+		return ErrTrackPublishTime
+	}
+	if taken, err := s.trackRepo.NameTaken(t.Name, t.ID); err != nil {
+		return err
+	} else if taken {
+		return ErrTrackNameConflict
+	}
+	return nil
+}
+
+func (s *ProgrammingService) Create(req *models.TrackRequest, createdBy int64) (*models.ProgrammingTrack, error) {
+	t := &models.ProgrammingTrack{PublishDaysBefore: 1, PublishTime: "20:00", Active: true, CreatedBy: &createdBy,
+		Targets: []*models.TrackTarget{}}
+	if err := s.apply(t, req); err != nil {
+		return nil, err
+	}
+	if err := s.trackRepo.Create(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Update cambia el track; la hora de publicación y la activación se propagan a los horarios ya asignados
+func (s *ProgrammingService) Update(id int64, req *models.TrackRequest) (*models.ProgrammingTrack, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(t, req); err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		t.Active = *req.Active
+	}
+	if err := s.trackRepo.Update(t); err != nil {
+		return nil, err
+	}
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Deactivate desactiva el track: su calendario se conserva pero deja de asignarse a los horarios futuros
+func (s *ProgrammingService) Deactivate(id int64) error {
+	t, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	t.Active = false
+	if err := s.trackRepo.Update(t); err != nil {
+		return err
+	}
+	return s.sync()
+}
+
+// SetTargets reemplaza las clases y disciplinas que siguen el track
+func (s *ProgrammingService) SetTargets(id int64, req *models.TrackTargetsRequest) (*models.ProgrammingTrack, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	check := func(classID, disciplineID *int64) error {
+		if exists, err := s.trackRepo.TargetExists(classID, disciplineID); err != nil {
+			return err
+		} else if !exists {
+			return ErrTrackTargetNotFound
+		}
+		if taken, err := s.trackRepo.TargetTaken(id, classID, disciplineID); err != nil {
+			return err
+		} else if taken {
+			return ErrTrackTargetTaken
+		}
+		return nil
+	}
+	for _, classID := range req.ClassIDs {
+		if err := check(&classID, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, disciplineID := range req.DisciplineIDs {
+		if err := check(nil, &disciplineID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.trackRepo.SetTargets(id, req.ClassIDs, req.DisciplineIDs); err != nil {
+		return nil, err
+	}
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *ProgrammingService) Days(id int64, from, to time.Time) ([]*models.TrackDay, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	return s.trackRepo.Days(id, from, to)
+}
+
+// SetDay programa la rutina del día y la asigna a los horarios de esa fecha
+func (s *ProgrammingService) SetDay(id int64, date time.Time, req *models.TrackDayRequest) (*models.TrackDay, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	routine, err := s.routineRepo.GetByID(req.RoutineID)
+	if err == sql.ErrNoRows || (err == nil && (routine == nil || !routine.Active)) {
+		return nil, ErrTrackRoutine
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &models.TrackDay{TrackID: id, Date: date, RoutineID: req.RoutineID, Notes: req.Notes, PublishAt: req.PublishAt}
+	if err := s.trackRepo.SetDay(d); err != nil {
+		return nil, err
+	}
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+	return s.trackRepo.GetDay(id, date)
+}
+
+func (s *ProgrammingService) DeleteDay(id int64, date time.Time) error {
+	err := s.trackRepo.DeleteDay(id, date)
+	if err == sql.ErrNoRows {
+		return ErrTrackDayNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.sync()
+}
+
+// CopyWeek copia los 7 días que empiezan en from a la semana que empieza en to. Sin overwrite
+// los días ya programados en destino se mantienen. Devuelve cuántos días se copiaron.
+func (s *ProgrammingService) CopyWeek(id int64, from, to time.Time, overwrite bool) (int64, error) {
+	if _, err := s.Get(id); err != nil {
+		return 0, err
+	}
+	offset := int(to.Sub(from).Hours() / 24)
+	if offset == 0 {
+		return 0, ErrTrackSameWeek
+	}
+	n, err := s.trackRepo.CopyDays(id, from, 7, offset, overwrite)
+	if err != nil {
+		return 0, err
+	}
+	return n, s.sync()
+}
+
+// ShiftDays mueve los días entre from y to (nil = hasta el final) days días, p. ej. por un feriado.
+// Falla si pisaría días programados fuera del rango.
+func (s *ProgrammingService) ShiftDays(id int64, from time.Time, to *time.Time, days int) (int, error) {
+	if days == 0 || (to != nil && to.Before(from)) {
+		return 0, ErrTrackShiftInvalid
+	}
+	if _, err := s.Get(id); err != nil {
+		return 0, err
+	}
+	if n, err := s.trackRepo.ShiftConflicts(id, from, to, days); err != nil {
+		return 0, err
+	} else if n > 0 {
+		return 0, ErrTrackShiftConflict
+	}
+	n, err := s.trackRepo.ShiftDays(id, from, to, days)
+	if err != nil {
+		return 0, err
+	}
+	return n, s.sync()
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+)
+
+// ReceiptBranding - Datos del box que encabezan los comprobantes
+type ReceiptBranding struct {
+	Name    string
+	RUT     string
+	Address string
+	Contact string
+}
+
+func BrandingFromConfig(cfg *config.Config) ReceiptBranding {
+	return ReceiptBranding{Name: cfg.GymName, RUT: cfg.DTEEmisorRUT, Address: cfg.GymAddress, Contact: cfg.GymContact}
+}
+
+var paymentMethodLabels = map[string]string{
+	models.PaymentMethodEfectivo:      "Efectivo",
+	models.PaymentMethodDebito:        "Débito",
+	models.PaymentMethodTransferencia: "Transferencia",
+	"cash":                            "Efectivo",
+	"card":                            "Tarjeta",
+	"transfer":                        "Transferencia",
+	models.SalePaymentAccount:         "Cuenta del miembro",
+	models.SalePaymentStoreCredit:     "Saldo a favor",
+}
+
+func paymentMethodLabel(method string) string {
+	if l, ok := paymentMethodLabels[method]; ok {
+		return l
+	}
+	return method
+}
+
+var paymentStatusLabels = map[models.PaymentStatus]string{
+	models.PaymentCompleted: "Pagado",
+	models.PaymentRefunded:  "Reembolsado",
+	models.PaymentPending:   "Pendiente",
+	models.PaymentRejected:  "Rechazado",
+	models.PaymentExpired:   "Expirado",
+	models.PaymentFailed:    "Fallido",
+}
+
+// receiptHeader dibuja el encabezado con los datos del box y el título; devuelve la altura usada
+func receiptHeader(p *PDFDocument, b ReceiptBranding, title, number string) float64 {
+	p.Rect(0, 0, p.Width, 90, 0, true, 0.93)
+	p.Text(40, 42, 20, FontBold, b.Name)
+	sub := b.Address
+	if b.Contact != "" {
+		if sub != "" {
+			sub += "  ·  "
+		}
+		sub += b.Contact
+	}
+	p.Text(40, 62, 9, FontRegular, sub)
+	if b.RUT != "" {
+		p.Text(40, 76, 9, FontRegular, "RUT "+formatRUT(b.RUT))
+	}
+	p.TextRight(572, 42, 14, FontBold, title)
+	if number != "" {
+		p.TextRight(572, 62, 10, FontRegular, number)
+	}
+	return 120
+}
+
+func receiptRow(p *PDFDocument, y float64, label, value string) {
+	p.Text(40, y, 10, FontBold, label)
+	p.Text(180, y, 10, FontRegular, value)
+}
+
+func receiptFooter(p *PDFDocument, b ReceiptBranding, note string) {
+	p.Line(40, 740, 572, 740, 0.5)
+	if note != "" {
+		p.Text(40, 754, 8, FontRegular, note)
+	}
+	p.Text(40, 768, 8, FontRegular, "Emitido por "+b.Name+" el "+time.Now().Format("02/01/2006 15:04"))
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("02/01/2006")
+}
+
+// RenderPaymentReceipt genera el comprobante de pago de un plan (no reemplaza a la boleta/factura)
+func RenderPaymentReceipt(rc *models.PaymentReceipt, b ReceiptBranding) []byte {
+	p := NewPDFDocument()
+	p.AddPage()
+	y := receiptHeader(p, b, "COMPROBANTE DE PAGO", "N° "+rc.ReferenceCode)
+
+	receiptRow(p, y, "Fecha de pago", rc.CreatedAt.Format("02/01/2006"))
+	y += 16
+	receiptRow(p, y, "Estado", paymentStatusLabels[rc.Status])
+	y += 26
+
+	p.Text(40, y, 11, FontBold, "Miembro")
+	y += 18
+	receiptRow(p, y, "Nombre", rc.UserName)
+	y += 16
+	if rc.UserRUT != "" {
+		receiptRow(p, y, "RUT", formatRUT(rc.UserRUT))
+		y += 16
+	}
+	receiptRow(p, y, "Email", rc.UserEmail)
+	y += 26
+
+	p.Text(40, y, 11, FontBold, "Detalle")
+	y += 18
+	receiptRow(p, y, "Plan", rc.PlanName)
+	y += 16
+	receiptRow(p, y, "Periodo", formatDate(rc.StartDate)+" al "+formatDate(rc.EndDate))
+	y += 16
+	receiptRow(p, y, "Medio de pago", paymentMethodLabel(rc.PaymentMethod))
+	y += 16
+	if rc.TaxFolio > 0 {
+		receiptRow(p, y, "Documento SII", fmt.Sprintf("%s N° %d", models.DTENames[rc.TaxDocType], rc.TaxFolio))
+		y += 16
+	}
+
+	// Montos
+	y += 14
+	p.Line(320, y, 572, y, 0.5)
+	y += 18
+	p.Text(320, y, 10, FontRegular, "Precio del plan")
+	p.TextRight(572, y, 10, FontRegular, FormatCLP(rc.PlanPrice))
+	if rc.DiscountAmount > 0 {
+		y += 16
+		p.Text(320, y, 10, FontRegular, "Descuento ("+rc.DiscountCode+")")
+		p.TextRight(572, y, 10, FontRegular, FormatCLP(-rc.DiscountAmount))
+	}
+	y += 20
+	p.Rect(320, y-14, 252, 22, 0, true, 0.9)
+	p.Text(326, y+2, 12, FontBold, "Total pagado")
+	p.TextRight(566, y+2, 12, FontBold, FormatCLP(rc.Amount))
+
+	receiptFooter(p, b, "Comprobante de pago para fines de respaldo. No constituye documento tributario.")
+	return p.Bytes()
+}
+
+// RenderSaleReceipt genera el comprobante de una venta POS
+func RenderSaleReceipt(s *models.Sale, b ReceiptBranding) []byte {
+	p := NewPDFDocument()
+	p.AddPage()
+	y := receiptHeader(p, b, "COMPROBANTE DE VENTA", "N° V-"+strconv.FormatInt(s.ID, 10))
+
+	receiptRow(p, y, "Fecha", s.CreatedAt.Format("02/01/2006 15:04"))
+	y += 16
+	if s.UserName != "" {
+		receiptRow(p, y, "Cliente", s.UserName)
+		y += 16
+	}
+	receiptRow(p, y, "Medio de pago", paymentMethodLabel(s.PaymentMethod))
+	y += 16
+	if s.TaxDocument != nil {
+		receiptRow(p, y, "Documento SII", fmt.Sprintf("%s N° %d", models.DTENames[s.TaxDocument.DocType], s.TaxDocument.Folio))
+		y += 16
+	}
+
+	y += 16
+	p.Rect(40, y, 532, 18, 0, true, 0.9)
+	p.Text(46, y+12, 9, FontBold, "Producto")
+	p.TextRight(380, y+12, 9, FontBold, "Cant.")
+	p.TextRight(470, y+12, 9, FontBold, "Precio")
+	p.TextRight(566, y+12, 9, FontBold, "Total")
+	y += 18
+	for _, it := range s.Items {
+		y += 14
+		name := it.ProductName
+		if it.GiftCard {
+			name += " (gift card)"
+		}
+		p.Text(46, y, 9, FontRegular, truncate(name, 60))
+		p.TextRight(380, y, 9, FontRegular, strconv.Itoa(it.Quantity))
+		p.TextRight(470, y, 9, FontRegular, FormatCLP(it.UnitPrice))
+		p.TextRight(566, y, 9, FontRegular, FormatCLP(it.UnitPrice*int64(it.Quantity)))
+	}
+	y += 10
+	p.Line(40, y, 572, y, 0.5)
+	y += 20
+	p.TextRight(470, y, 12, FontBold, "Total")
+	p.TextRight(566, y, 12, FontBold, FormatCLP(s.Total))
+
+	receiptFooter(p, b, "Comprobante de venta para fines de respaldo. No constituye documento tributario.")
+	return p.Bytes()
+}
+
+// RenderYearlyStatement genera el certificado anual con todos los pagos del miembro
+func RenderYearlyStatement(st *models.YearlyStatement, b ReceiptBranding) []byte {
+	p := NewPDFDocument()
+	title := "CERTIFICADO ANUAL " + strconv.Itoa(st.Year)
+	header := func() float64 {
+		p.AddPage()
+		return receiptHeader(p, b, title, st.UserName)
+	}
+	tableHeader := func(y float64) float64 {
+		p.Rect(40, y, 532, 18, 0, true, 0.9)
+		p.Text(46, y+12, 9, FontBold, "Fecha")
+		p.Text(106, y+12, 9, FontBold, "Plan")
+		p.Text(256, y+12, 9, FontBold, "Periodo")
+		p.Text(386, y+12, 9, FontBold, "Medio")
+		p.Text(466, y+12, 9, FontBold, "Estado")
+		p.TextRight(566, y+12, 9, FontBold, "Monto")
+		return y + 18
+	}
+
+	y := header()
+	receiptRow(p, y, "Miembro", st.UserName)
+	y += 16
+	if st.UserRUT != "" {
+		receiptRow(p, y, "RUT", formatRUT(st.UserRUT))
+		y += 16
+	}
+	receiptRow(p, y, "Email", st.UserEmail)
+	y += 16
+	receiptRow(p, y, "Periodo", fmt.Sprintf("01/01/%d al 31/12/%d", st.Year, st.Year))
+	y += 24
+
+	y = tableHeader(y)
+	if len(st.Payments) == 0 {
+		y += 16
+		p.Text(46, y, 9, FontRegular, "Sin pagos registrados en el periodo.")
+	}
+	for _, rc := range st.Payments {
+		if y > 700 {
+			receiptFooter(p, b, "")
+			y = tableHeader(header())
+		}
+		y += 14
+		p.Text(46, y, 9, FontRegular, rc.CreatedAt.Format("02/01/2006"))
+		p.Text(106, y, 9, FontRegular, truncate(rc.PlanName, 26))
+		p.Text(256, y, 8, FontRegular, formatDate(rc.StartDate)+" - "+formatDate(rc.EndDate))
+		p.Text(386, y, 9, FontRegular, paymentMethodLabel(rc.PaymentMethod))
+		p.Text(466, y, 9, FontRegular, paymentStatusLabels[rc.Status])
+		p.TextRight(566, y, 9, FontRegular, FormatCLP(rc.Amount))
+	}
+	y += 10
+	p.Line(40, y, 572, y, 0.5)
+	y += 20
+	p.TextRight(470, y, 12, FontBold, "Total pagado")
+	p.TextRight(566, y, 12, FontBold, FormatCLP(st.Total))
+
+	receiptFooter(p, b, "Los pagos reembolsados se listan como referencia y no suman al total.")
+	return p.Bytes()
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+var (
+	startxrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefEntryRe = regexp.MustCompile(`(\d{10}) 00000 n `)
+	pageCountRe = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+)
+
+// checkPDF valida encabezado, trailer y tabla xref; devuelve la cantidad de páginas
+func checkPDF(t *testing.T, data []byte) int {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header: %q", data[:min(len(data), 16)])
+	}
+	m := startxrefRe.FindSubmatch(data)
+	if m == nil {
+		t.Fatalf("missing startxref/%%%%EOF trailer: %q", data[max(0, len(data)-64):])
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+	for i, e := range xrefEntryRe.FindAllSubmatch(data[xref:], -1) {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Fatalf("xref entry %d points to %q, want %q", i+1, data[off:off+len(want)], want)
+		}
+	}
+	count := pageCountRe.FindSubmatch(data)
+	if count == nil {
+		t.Fatal("missing page tree")
+	}
+	pages, _ := strconv.Atoi(string(count[1]))
+	return pages
+}
+
+func testReceipt(id int64) *models.PaymentReceipt {
+	paid := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	rc := &models.PaymentReceipt{PlanPrice: 45000, StartDate: &paid}
+	rc.ID = id
+	rc.ReferenceCode = models.PaymentReference(id)
+	rc.Amount = 45000
+	rc.Status = models.PaymentCompleted
+	rc.PaymentMethod = models.PaymentMethodTransferencia
+	rc.CreatedAt = paid
+	rc.UserName = "Ana Pérez (Box)"
+	rc.PlanName = "Plan mensual"
+	return rc
+}
+
+func TestRenderPaymentReceipt_SequentialNumbers(t *testing.T) {
+	branding := ReceiptBranding{Name: "Box Norte", RUT: "76.123.456-7"}
+	for id := int64(41); id <= 43; id++ {
+		pdf := RenderPaymentReceipt(testReceipt(id), branding)
+		if pages := checkPDF(t, pdf); pages != 1 {
+			t.Fatalf("receipt %d: expected 1 page, got %d", id, pages)
+		}
+		// "N°" se escribe en WinAnsi (° = 0xB0)
+		if want := fmt.Sprintf("N\xb0 BM-%d", id); !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("receipt %d does not show number %q", id, want)
+		}
+		if next := fmt.Sprintf("BM-%d)", id+1); bytes.Contains(pdf, []byte(next)) {
+			t.Errorf("receipt %d shows the next number %q", id, next)
+		}
+	}
+	if !bytes.Contains(RenderPaymentReceipt(testReceipt(41), ReceiptBranding{}), []byte("Ana P\xe9rez \\(Box\\)")) {
+		t.Error("member name must be Latin-1 encoded with escaped parentheses")
+	}
+}
+
+func TestRenderPaymentReceipt_ShowsTaxFolio(t *testing.T) {
+	rc := testReceipt(41)
+	rc.TaxDocType, rc.TaxFolio = models.DTEBoleta, 1502
+
+	pdf := RenderPaymentReceipt(rc, ReceiptBranding{})
+
+	checkPDF(t, pdf)
+	if !bytes.Contains(pdf, []byte(fmt.Sprintf("N\xb0 %d", 1502))) {
+		t.Error("receipt does not show the SII folio")
+	}
+}
+
+func TestRenderSaleReceipt_ValidPDF(t *testing.T) {
+	sale := &models.Sale{ID: 9, Total: 7000, PaymentMethod: "cash", CreatedAt: time.Now(), Items: []models.SaleItem{
+		{ProductName: "Agua", Quantity: 2, UnitPrice: 1000},
+		{ProductName: "Barra proteica", Quantity: 1, UnitPrice: 5000},
+	}}
+
+	pdf := RenderSaleReceipt(sale, ReceiptBranding{Name: "Box Norte"})
+
+	checkPDF(t, pdf)
+	if !bytes.Contains(pdf, []byte("N\xb0 V-9")) {
+		t.Error("sale receipt does not show its number")
+	}
+}
+
+func TestRenderYearlyStatement_PaginatesLongYears(t *testing.T) {
+	st := &models.YearlyStatement{UserName: "Ana", Year: 2025}
+	for i := int64(1); i <= 80; i++ {
+		st.Payments = append(st.Payments, testReceipt(i))
+		st.Total += 45000
+	}
+
+	pages := checkPDF(t, RenderYearlyStatement(st, ReceiptBranding{}))
+
+	if pages < 2 {
+		t.Fatalf("80 payments should span several pages, got %d", pages)
+	}
+	if empty := checkPDF(t, RenderYearlyStatement(&models.YearlyStatement{Year: 2025}, ReceiptBranding{})); empty != 1 {
+		t.Fatalf("empty statement: expected 1 page, got %d", empty)
+	}
+}
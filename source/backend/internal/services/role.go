@@ -0,0 +1,208 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrRoleBuiltIn       = errors.New("built-in roles cannot be modified")
+	ErrRoleInvalidName   = errors.New("role name must be lowercase letters, digits or underscores")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrRoleLabelRequired = errors.New("label is required")
+)
+
+// roleCacheTTL acota cuánto tarda en verse un cambio de rol hecho desde otra instancia
+const roleCacheTTL = time.Minute
+
+// RoleService resuelve los permisos de cada rol; cachea los roles personalizados en memoria
+type RoleService struct {
+	roleRepo *repository.RoleRepository
+
+	mu       sync.RWMutex
+	custom   map[models.Role]map[string]bool
+	loadedAt time.Time
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository) *RoleService {
+	return &RoleService{roleRepo: roleRepo}
+}
+
+func permissionSet(perms []string) map[string]bool {
+	set := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
+
+// Permissions implementa middleware.PermissionResolver; ok=false si el rol no existe
+func (s *RoleService) Permissions(role models.Role) (map[string]bool, bool) {
+	if def, ok := models.BuiltinRoles[role]; ok {
+		return permissionSet(def.Permissions), true
+	}
+
+	s.mu.RLock()
+	fresh := s.custom != nil && time.Since(s.loadedAt) < roleCacheTTL
+	perms, ok := s.custom[role]
+	s.mu.RUnlock()
+	if fresh {
+		return perms, ok
+	}
+
+	if err := s.reload(); err != nil {
+		// Si la BD falla se usa lo último cargado
+		return perms, ok
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	perms, ok = s.custom[role]
+	return perms, ok
+}
+
+func (s *RoleService) reload() error {
+	roles, err := s.roleRepo.List()
+	if err != nil {
+		return err
+	}
+	custom := make(map[models.Role]map[string]bool, len(roles))
+	for _, r := range roles {
+		custom[r.Name] = permissionSet(r.Permissions)
+	}
+	s.mu.Lock()
+	s.custom = custom
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RoleService) invalidate() {
+	s.mu.Lock()
+	s.custom = nil
+	s.mu.Unlock()
+}
+
+// List devuelve los roles integrados seguidos de los personalizados
+func (s *RoleService) List() ([]*models.RoleDef, error) {
+	var roles []*models.RoleDef
+	for _, def := range models.BuiltinRoles {
+		roles = append(roles, def)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	custom, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return append(roles, custom...), nil
+}
+
+func (s *RoleService) Get(name models.Role) (*models.RoleDef, error) {
+	if def, ok := models.BuiltinRoles[name]; ok {
+		return def, nil
+	}
+	role, err := s.roleRepo.Get(name)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+func normalizePermissions(perms []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, p := range perms {
+		p = strings.TrimSpace(p)
+		if _, ok := models.AllPermissions[p]; !ok {
+			return nil, ErrUnknownPermission
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *RoleService) Create(req *models.RoleRequest) (*models.RoleDef, error) {
+	name := models.Role(strings.ToLower(strings.TrimSpace(req.Name)))
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return nil, ErrRoleExists
+	}
+	if !models.ValidRoleName(string(name)) {
+		return nil, ErrRoleInvalidName
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return nil, ErrRoleLabelRequired
+	}
+	perms, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.roleRepo.Get(name); err == nil {
+		return nil, ErrRoleExists
+	}
+
+	role := &models.RoleDef{Name: name, Label: strings.TrimSpace(req.Label), Description: req.Description, Permissions: perms, Staff: true}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return role, nil
+}
+
+func (s *RoleService) Update(name models.Role, req *models.RoleRequest) (*models.RoleDef, error) {
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return nil, ErrRoleBuiltIn
+	}
+	role, err := s.roleRepo.Get(name)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.Label != "" {
+		role.Label = strings.TrimSpace(req.Label)
+	}
+	role.Description = req.Description
+	if req.Permissions != nil {
+		if role.Permissions, err = normalizePermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return role, nil
+}
+
+func (s *RoleService) Delete(name models.Role) error {
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return ErrRoleBuiltIn
+	}
+	if _, err := s.roleRepo.Get(name); err == sql.ErrNoRows {
+		return ErrRoleNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := s.roleRepo.Delete(name); err == sql.ErrNoRows {
+		return ErrRoleInUse
+	} else if err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
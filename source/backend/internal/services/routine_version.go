@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+
+	"boxmagic/internal/models"
+)
+
+// DiffRoutineVersions compara dos versiones campo a campo; los textos del WOD se comparan por líneas
+func DiffRoutineVersions(from, to *models.RoutineVersion) *models.RoutineDiff {
+	d := &models.RoutineDiff{RoutineID: to.RoutineID, From: from.Version, To: to.Version, Changes: []*models.FieldChange{}}
+	text := func(field, a, b string) {
+		if a != b {
+			d.Changes = append(d.Changes, &models.FieldChange{Field: field, From: a, To: b, Lines: DiffLines(a, b)})
+		}
+	}
+	value := func(field string, a, b interface{}) {
+		if a != b {
+			d.Changes = append(d.Changes, &models.FieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	value("name", from.Name, to.Name)
+	value("type", from.Type, to.Type)
+	text("description", from.Description, to.Description)
+	text("content", from.Content, to.Content)
+	text("content_scaled", from.ContentScaled, to.ContentScaled)
+	text("content_beginner", from.ContentBeginner, to.ContentBeginner)
+	value("duration", from.Duration, to.Duration)
+	value("difficulty", from.Difficulty, to.Difficulty)
+
+	var fromBenchmark, toBenchmark interface{}
+	if from.BenchmarkID != nil {
+		fromBenchmark = *from.BenchmarkID
+	}
+	if to.BenchmarkID != nil {
+		toBenchmark = *to.BenchmarkID
+	}
+	value("benchmark_id", fromBenchmark, toBenchmark)
+
+	// Los bloques se comparan completos; el detalle legible ya está en el diff de content
+	fromBlocks, _ := json.Marshal(from.Blocks)
+	toBlocks, _ := json.Marshal(to.Blocks)
+	if string(fromBlocks) != string(toBlocks) {
+		d.Changes = append(d.Changes, &models.FieldChange{Field: "blocks", From: from.Blocks, To: to.Blocks})
+	}
+	return d
+}
+
+// DiffLines calcula el diff por líneas (subsecuencia común más larga) entre dos textos
+func DiffLines(a, b string) []models.DiffLine {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// lcs[i][j] = largo de la subsecuencia común de x[i:] e y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []models.DiffLine
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, models.DiffLine{Op: " ", Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, models.DiffLine{Op: "-", Text: x[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: "+", Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, models.DiffLine{Op: "-", Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, models.DiffLine{Op: "+", Text: y[j]})
+	}
+	return lines
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want []string // op + texto
+	}{
+		{"identical", "21-15-9\nThrusters", "21-15-9\nThrusters", []string{" 21-15-9", " Thrusters"}},
+		{"changed line", "21-15-9\nThrusters 43kg\nPull-ups", "21-15-9\nThrusters 35kg\nPull-ups",
+			[]string{" 21-15-9", "-Thrusters 43kg", "+Thrusters 35kg", " Pull-ups"}},
+		{"appended", "AMRAP 12", "AMRAP 12\n10 Burpees", []string{" AMRAP 12", "+10 Burpees"}},
+		{"removed", "5 rounds\n400m run\n15 OHS", "5 rounds\n15 OHS", []string{" 5 rounds", "-400m run", " 15 OHS"}},
+		{"from empty", "", "EMOM 10", []string{"-", "+EMOM 10"}},
+		{"reordered", "A\nB\nC", "C\nA\nB", []string{"+C", " A", " B", "-C"}},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, l := range DiffLines(c.a, c.b) {
+			got = append(got, l.Op+l.Text)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: DiffLines = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDiffLines_RebuildsBothTexts(t *testing.T) {
+	a := "For time\n50 DU\n40 KBS\n30 box jumps\n20 T2B"
+	b := "For time\n60 DU\n40 KBS\n20 T2B\n10 MU"
+	var from, to []string
+	for _, l := range DiffLines(a, b) {
+		if l.Op != "+" {
+			from = append(from, l.Text)
+		}
+		if l.Op != "-" {
+			to = append(to, l.Text)
+		}
+	}
+	if got := strings.Join(from, "\n"); got != a {
+		t.Errorf("old side = %q, want %q", got, a)
+	}
+	if got := strings.Join(to, "\n"); got != b {
+		t.Errorf("new side = %q, want %q", got, b)
+	}
+}
+
+func TestDiffRoutineVersions_OnlyChangedFields(t *testing.T) {
+	from := &models.RoutineVersion{RoutineID: 5, Version: 1, Name: "Fran", Type: "wod", Content: "21-15-9", Duration: 10}
+	to := &models.RoutineVersion{RoutineID: 5, Version: 2, Name: "Fran", Type: "wod", Content: "21-15-9\nThrusters", Duration: 12}
+
+	d := DiffRoutineVersions(from, to)
+	if d.From != 1 || d.To != 2 || len(d.Changes) != 2 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+	if d.Changes[0].Field != "content" || len(d.Changes[0].Lines) != 2 || d.Changes[1].Field != "duration" {
+		t.Fatalf("unexpected changes: %+v %+v", d.Changes[0], d.Changes[1])
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrLiftInvalid      = errors.New("reps must be between 1 and 100 and load must be positive")
+	ErrLiftInvalidRPE   = errors.New("rpe must be between 1 and 10")
+	ErrLiftInvalidDate  = errors.New("invalid logged_on date")
+	ErrNoOneRM          = errors.New("no lifts logged for this movement")
+	ErrPercentsRequired = errors.New("at least one percentage between 1 and 150 is required")
+)
+
+// maxEstimateReps - Sobre esta cantidad de repeticiones las fórmulas dejan de ser fiables para el 1RM de referencia
+const maxEstimateReps = 12
+
+// maxLiftHistory - Levantamientos que se consideran para los récords del miembro
+const maxLiftHistory = 5000
+
+// StrengthService - Registro de fuerza, 1RM estimado, tablas de rep-max y calculadora de porcentajes
+type StrengthService struct {
+	strengthRepo *repository.StrengthRepository
+	movementRepo *repository.MovementRepository
+	routineRepo  repository.RoutineRepo
+}
+
+func NewStrengthService(strengthRepo *repository.StrengthRepository, movementRepo *repository.MovementRepository, routineRepo repository.RoutineRepo) *StrengthService {
+	return &StrengthService{strengthRepo: strengthRepo, movementRepo: movementRepo, routineRepo: routineRepo}
+}
+
+// Epley1RM - carga × (1 + reps/30)
+func Epley1RM(load float64, reps int) float64 {
+	if reps <= 1 {
+		return load
+	}
+	return round2(load * (1 + float64(reps)/30))
+}
+
+// Brzycki1RM - carga × 36 / (37 − reps); 0 cuando la fórmula no aplica
+func Brzycki1RM(load float64, reps int) float64 {
+	if reps <= 1 {
+		return load
+	}
+	if reps >= 37 {
+		return 0
+	}
+	return round2(load * 36 / float64(37-reps))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// RoundLoad redondea la carga al incremento de discos disponible (p. ej. 2.5 kg)
+func RoundLoad(v, increment float64) float64 {
+	if increment <= 0 {
+		return round2(v)
+	}
+	return round2(math.Round(v/increment) * increment)
+}
+
+func (s *StrengthService) Log(userID int64, req *models.LogLiftRequest) (*models.LiftLog, error) {
+	if req.Reps < 1 || req.Reps > 100 || req.LoadKg <= 0 || req.LoadKg > 1000 {
+		return nil, ErrLiftInvalid
+	}
+	if req.RPE != nil && (*req.RPE < 1 || *req.RPE > 10) {
+		return nil, ErrLiftInvalidRPE
+	}
+	m, err := s.movementRepo.GetByID(req.MovementID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMovementNotFound
+	}
+	loggedOn := time.Now()
+	if req.LoggedOn != "" {
+		if loggedOn, err = time.Parse("2006-01-02", req.LoggedOn); err != nil {
+			return nil, ErrLiftInvalidDate
+		}
+	}
+	sets := req.Sets
+	if sets < 1 {
+		sets = 1
+	}
+	l := &models.LiftLog{
+		UserID:       userID,
+		MovementID:   m.ID,
+		MovementName: m.Name,
+		RoutineID:    req.RoutineID,
+		Sets:         sets,
+		Reps:         req.Reps,
+		LoadKg:       req.LoadKg,
+		RPE:          req.RPE,
+		Notes:        strings.TrimSpace(req.Notes),
+		LoggedOn:     loggedOn,
+		Estimated1RM: Epley1RM(req.LoadKg, req.Reps),
+		Brzycki1RM:   Brzycki1RM(req.LoadKg, req.Reps),
+	}
+	if err := s.strengthRepo.Create(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// History devuelve los levantamientos con ambas estimaciones de 1RM
+func (s *StrengthService) History(userID, movementID int64, limit int) ([]*models.LiftLog, error) {
+	list, err := s.strengthRepo.List(userID, movementID, limit)
+	for _, l := range list {
+		l.Brzycki1RM = Brzycki1RM(l.LoadKg, l.Reps)
+	}
+	return list, err
+}
+
+// Summary arma los récords por movimiento. El nRM es la mejor carga levantada con al menos n repeticiones;
+// el 1RM de referencia es el mejor estimado (Epley) de series de hasta 12 repeticiones, "actual" si viene de un single.
+func (s *StrengthService) Summary(userID, movementID int64) ([]*models.LiftSummary, error) {
+	lifts, err := s.strengthRepo.List(userID, movementID, maxLiftHistory)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeLifts(lifts), nil
+}
+
+func summarizeLifts(lifts []*models.LiftLog) []*models.LiftSummary {
+	byMovement := map[int64]*models.LiftSummary{}
+	var list []*models.LiftSummary
+	for _, l := range lifts {
+		sum, ok := byMovement[l.MovementID]
+		if !ok {
+			sum = &models.LiftSummary{MovementID: l.MovementID, MovementName: l.MovementName, LastLoggedOn: l.LoggedOn}
+			for _, n := range models.RepMaxReps {
+				sum.RepMaxes = append(sum.RepMaxes, &models.RepMax{Reps: n})
+			}
+			byMovement[l.MovementID] = sum
+			list = append(list, sum)
+		}
+		sum.Lifts++
+		for _, rm := range sum.RepMaxes {
+			if l.Reps >= rm.Reps && l.LoadKg > rm.LoadKg {
+				rm.LoadKg, rm.LoggedOn, rm.LiftID = l.LoadKg, l.LoggedOn, l.ID
+			}
+		}
+		if l.Estimated1RM > sum.Estimated1RM {
+			sum.Estimated1RM = l.Estimated1RM
+		}
+		if l.Reps <= maxEstimateReps && l.Estimated1RM > sum.OneRM {
+			sum.OneRM = l.Estimated1RM
+			sum.OneRMSource = models.OneRMEstimated
+			if l.Reps == 1 {
+				sum.OneRMSource = models.OneRMActual
+			}
+		}
+	}
+	for _, sum := range list {
+		if sum.OneRM == 0 {
+			sum.OneRM, sum.OneRMSource = sum.Estimated1RM, models.OneRMEstimated
+		}
+		// Rep-max sin levantamientos de esas repeticiones
+		kept := sum.RepMaxes[:0]
+		for _, rm := range sum.RepMaxes {
+			if rm.LiftID != 0 {
+				kept = append(kept, rm)
+			}
+		}
+		sum.RepMaxes = kept
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MovementName < list[j].MovementName })
+	return list
+}
+
+func (s *StrengthService) oneRM(userID, movementID int64) (*models.LiftSummary, error) {
+	list, err := s.Summary(userID, movementID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNoOneRM
+	}
+	return list[0], nil
+}
+
+// Percentages calcula las cargas objetivo de cada porcentaje sobre el 1RM de referencia del miembro
+func (s *StrengthService) Percentages(userID, movementID int64, percents []float64, increment float64) (*models.PercentageResponse, error) {
+	if len(percents) == 0 {
+		return nil, ErrPercentsRequired
+	}
+	for _, p := range percents {
+		if p < 1 || p > 150 {
+			return nil, ErrPercentsRequired
+		}
+	}
+	sum, err := s.oneRM(userID, movementID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.PercentageResponse{MovementID: sum.MovementID, MovementName: sum.MovementName, OneRM: sum.OneRM, OneRMSource: sum.OneRMSource}
+	for _, p := range percents {
+		resp.Targets = append(resp.Targets, &models.PercentageTarget{Percent: p, LoadKg: RoundLoad(sum.OneRM*p/100, increment)})
+	}
+	return resp, nil
+}
+
+// RoutineTargets devuelve la carga del miembro para cada línea de la rutina prescrita en % del 1RM
+// RoutineReleaseAt - Hora de publicación de la rutina si los miembros aún no pueden verla (nil si ya es visible)
+func (s *StrengthService) RoutineReleaseAt(routineID int64) (*time.Time, error) {
+	return s.routineRepo.ReleaseAt(routineID)
+}
+
+func (s *StrengthService) RoutineTargets(userID, routineID int64, level string, increment float64) ([]*models.RoutineTarget, error) {
+	blocks, err := s.routineRepo.GetBlocks(routineID)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := s.Summary(userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	oneRMs := map[int64]float64{}
+	for _, sum := range summaries {
+		oneRMs[sum.MovementID] = sum.OneRM
+	}
+	targets := []*models.RoutineTarget{}
+	for _, b := range blocks {
+		for _, l := range b.Lines {
+			p := l.At(level)
+			if l.MovementID == nil || p.LoadPct <= 0 {
+				continue
+			}
+			t := &models.RoutineTarget{BlockID: b.ID, LineID: l.ID, MovementID: *l.MovementID, MovementName: l.Name,
+				Reps: p.Reps, Percent: p.LoadPct, OneRM: oneRMs[*l.MovementID]}
+			if t.OneRM > 0 {
+				t.LoadKg = RoundLoad(t.OneRM*p.LoadPct/100, increment)
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
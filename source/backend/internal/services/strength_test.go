@@ -0,0 +1,146 @@
+package services
+
+import (
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func TestEpley1RM(t *testing.T) {
+	cases := []struct {
+		load float64
+		reps int
+		want float64
+	}{
+		{100, 1, 100},
+		{100, 0, 100},
+		{100, 5, 116.67},
+		{100, 10, 133.33},
+		{110, 3, 121},
+		{60, 30, 120},
+	}
+	for _, c := range cases {
+		if got := Epley1RM(c.load, c.reps); got != c.want {
+			t.Errorf("Epley1RM(%v, %d) = %v, want %v", c.load, c.reps, got, c.want)
+		}
+	}
+}
+
+func TestBrzycki1RM(t *testing.T) {
+	cases := []struct {
+		load float64
+		reps int
+		want float64
+	}{
+		{100, 1, 100},
+		{100, 5, 112.5},
+		{100, 10, 133.33},
+		{100, 36, 3600},
+		{100, 37, 0}, // La fórmula no aplica desde 37 repeticiones
+		{100, 50, 0},
+	}
+	for _, c := range cases {
+		if got := Brzycki1RM(c.load, c.reps); got != c.want {
+			t.Errorf("Brzycki1RM(%v, %d) = %v, want %v", c.load, c.reps, got, c.want)
+		}
+	}
+}
+
+func TestRoundLoad(t *testing.T) {
+	cases := []struct {
+		v, increment, want float64
+	}{
+		{101.3, 2.5, 102.5},
+		{101.2, 2.5, 100},
+		{83.75, 2.5, 85}, // A medio camino se redondea hacia arriba
+		{47.3, 1, 47},
+		{71.1, 0.5, 71},
+		{83.333, 0, 83.33}, // Sin incremento solo se redondea a 2 decimales
+		{47.3, -1, 47.3},
+	}
+	for _, c := range cases {
+		if got := RoundLoad(c.v, c.increment); got != c.want {
+			t.Errorf("RoundLoad(%v, %v) = %v, want %v", c.v, c.increment, got, c.want)
+		}
+	}
+}
+
+func lift(id, movementID int64, name string, reps int, load float64) *models.LiftLog {
+	return &models.LiftLog{ID: id, MovementID: movementID, MovementName: name, Reps: reps, LoadKg: load,
+		Estimated1RM: Epley1RM(load, reps)}
+}
+
+func TestSummarizeLifts_OneRMSource(t *testing.T) {
+	cases := []struct {
+		name       string
+		lifts      []*models.LiftLog
+		wantOneRM  float64
+		wantSource string
+		wantBest   float64 // Mejor estimado de cualquier serie
+	}{
+		{"heavier single is actual", []*models.LiftLog{lift(1, 1, "Deadlift", 1, 180), lift(2, 1, "Deadlift", 5, 150)},
+			180, models.OneRMActual, 180},
+		{"estimate beats single", []*models.LiftLog{lift(1, 1, "Back Squat", 1, 115), lift(2, 1, "Back Squat", 3, 110)},
+			121, models.OneRMEstimated, 121},
+		{"twelve reps still count", []*models.LiftLog{lift(1, 1, "Press", 12, 50), lift(2, 1, "Press", 1, 60)},
+			70, models.OneRMEstimated, 70},
+		{"high-rep sets ignored", []*models.LiftLog{lift(1, 1, "Front Squat", 15, 90), lift(2, 1, "Front Squat", 5, 100)},
+			116.67, models.OneRMEstimated, 135},
+		{"only high-rep sets fall back to best estimate", []*models.LiftLog{lift(1, 1, "Thruster", 20, 40)},
+			66.67, models.OneRMEstimated, 66.67},
+	}
+	for _, c := range cases {
+		list := summarizeLifts(c.lifts)
+		if len(list) != 1 {
+			t.Fatalf("%s: expected one summary, got %d", c.name, len(list))
+		}
+		sum := list[0]
+		if sum.OneRM != c.wantOneRM || sum.OneRMSource != c.wantSource || sum.Estimated1RM != c.wantBest {
+			t.Errorf("%s: 1RM %v (%s), best %v; want %v (%s), best %v", c.name,
+				sum.OneRM, sum.OneRMSource, sum.Estimated1RM, c.wantOneRM, c.wantSource, c.wantBest)
+		}
+		if sum.Lifts != len(c.lifts) {
+			t.Errorf("%s: counted %d lifts, want %d", c.name, sum.Lifts, len(c.lifts))
+		}
+	}
+}
+
+func TestSummarizeLifts_RepMaxes(t *testing.T) {
+	lifts := []*models.LiftLog{
+		lift(1, 1, "Back Squat", 5, 100),
+		lift(2, 1, "Back Squat", 1, 115),
+		lift(3, 1, "Back Squat", 3, 110),
+		lift(4, 1, "Back Squat", 8, 95), // Un 8RM también cuenta como 5RM, pero con menos carga
+		lift(5, 2, "Bench Press", 3, 80),
+	}
+	list := summarizeLifts(lifts)
+	if len(list) != 2 || list[0].MovementName != "Back Squat" || list[1].MovementName != "Bench Press" {
+		t.Fatalf("expected summaries sorted by movement name, got %+v", list)
+	}
+
+	want := map[int]struct {
+		load float64
+		id   int64
+	}{1: {115, 2}, 3: {110, 3}, 5: {100, 1}}
+	squat := list[0]
+	if len(squat.RepMaxes) != len(want) {
+		t.Fatalf("expected %d rep-maxes, got %d", len(want), len(squat.RepMaxes))
+	}
+	for _, rm := range squat.RepMaxes {
+		if w := want[rm.Reps]; rm.LoadKg != w.load || rm.LiftID != w.id {
+			t.Errorf("%dRM = %v (lift %d), want %v (lift %d)", rm.Reps, rm.LoadKg, rm.LiftID, w.load, w.id)
+		}
+	}
+
+	// Sin series de 5 o más repeticiones no hay 5RM; el triple sí cuenta como 1RM
+	bench := list[1]
+	if len(bench.RepMaxes) != 2 || bench.RepMaxes[0].Reps != 1 || bench.RepMaxes[0].LoadKg != 80 || bench.RepMaxes[1].Reps != 3 {
+		t.Fatalf("unexpected bench rep-maxes: %+v %+v", bench.RepMaxes[0], bench.RepMaxes)
+	}
+}
+
+func TestSummarizeLifts_Empty(t *testing.T) {
+	if list := summarizeLifts(nil); len(list) != 0 {
+		t.Fatalf("expected no summaries, got %d", len(list))
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrDTEDisabled        = errors.New("electronic tax documents are not configured")
+	ErrInvalidDocType     = errors.New("invalid tax document type")
+	ErrReceptorIncomplete = errors.New("factura requires receptor rut, business name, activity, address and commune")
+	ErrSourceNotFound     = errors.New("payment or sale not found")
+	ErrSourceNotCompleted = errors.New("payment is not completed")
+	ErrNothingToInvoice   = errors.New("sale has no taxable items")
+	ErrCAFEmisorMismatch  = errors.New("CAF belongs to a different emisor RUT")
+	ErrUnsupportedCAFType = errors.New("CAF document type is not supported")
+)
+
+// TaxService emite documentos tributarios electrónicos (boleta/factura) para pagos y ventas
+type TaxService struct {
+	repo      *repository.TaxRepository
+	emisor    DTEEmisor
+	autoIssue bool
+	sender    DTESender
+	barcode   BarcodeEncoder
+}
+
+func NewTaxService(repo *repository.TaxRepository, cfg *config.Config) *TaxService {
+	return &TaxService{
+		repo:      repo,
+		emisor:    EmisorFromConfig(cfg),
+		autoIssue: cfg.DTEAutoIssue,
+		sender:    &LocalDTESender{Dir: cfg.DTEOutputDir},
+	}
+}
+
+// SetSender reemplaza el envío local por una integración real (SII o proveedor)
+func (s *TaxService) SetSender(sender DTESender) {
+	s.sender = sender
+}
+
+func (s *TaxService) SetBarcodeEncoder(enc BarcodeEncoder) {
+	s.barcode = enc
+}
+
+// Enabled indica si hay datos de emisor configurados
+func (s *TaxService) Enabled() bool {
+	return s.emisor.RUT != ""
+}
+
+// AutoIssue indica si se emite boleta automáticamente al completar pagos y ventas
+func (s *TaxService) AutoIssue() bool {
+	return s.Enabled() && s.autoIssue
+}
+
+// ImportCAF valida y guarda un archivo CAF descargado desde el SII
+func (s *TaxService) ImportCAF(data []byte) (*models.CAF, error) {
+	parsed, err := ParseCAF(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := models.DTENames[parsed.DocType]; !ok {
+		return nil, ErrUnsupportedCAFType
+	}
+	if s.emisor.RUT != "" && parsed.EmisorRUT != s.emisor.RUT {
+		return nil, ErrCAFEmisorMismatch
+	}
+	caf := &models.CAF{
+		DocType:      parsed.DocType,
+		FolioFrom:    parsed.FolioFrom,
+		FolioTo:      parsed.FolioTo,
+		AuthorizedAt: parsed.AuthorizedAt,
+	}
+	if err := s.repo.CreateCAF(caf, parsed.RawCAF, parsed.PrivateKey); err != nil {
+		return nil, err
+	}
+	return caf, nil
+}
+
+func (s *TaxService) GetBillingProfile(userID int64) (*models.TaxReceptor, error) {
+	return s.repo.GetBillingProfile(userID)
+}
+
+// SaveBillingProfile guarda los datos de facturación capturados en el perfil o al pagar
+func (s *TaxService) SaveBillingProfile(userID int64, rec *models.TaxReceptor) error {
+	rec.RUT = models.NormalizeRUT(rec.RUT)
+	return s.repo.SaveBillingProfile(userID, rec)
+}
+
+// resolveReceptor usa el receptor indicado o el perfil de facturación del miembro
+func (s *TaxService) resolveReceptor(docType int, userID *int64, receptor *models.TaxReceptor) (models.TaxReceptor, error) {
+	var rec models.TaxReceptor
+	switch {
+	case receptor != nil:
+		rec = *receptor
+		rec.RUT = models.NormalizeRUT(rec.RUT)
+		if userID != nil && rec.RUT != "" {
+			_ = s.repo.SaveBillingProfile(*userID, &rec)
+		}
+	case userID != nil:
+		if profile, err := s.repo.GetBillingProfile(*userID); err == nil {
+			rec = *profile
+		}
+	}
+
+	if docType == models.DTEFactura {
+		if !rec.Complete() {
+			return rec, ErrReceptorIncomplete
+		}
+		return rec, nil
+	}
+	if rec.RUT == "" {
+		rec.RUT = models.ReceptorGenericRUT
+	}
+	return rec, nil
+}
+
+// IssueForPayment emite el documento de un pago completado (mensualidad/plan)
+func (s *TaxService) IssueForPayment(paymentID int64, docType int, receptor *models.TaxReceptor) (*models.TaxDocument, error) {
+	p, err := s.repo.PaymentSource(paymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSourceNotFound
+		}
+		return nil, err
+	}
+	if p.Status != models.PaymentCompleted {
+		return nil, ErrSourceNotCompleted
+	}
+	userID := p.UserID
+	doc := &models.TaxDocument{
+		SourceType: models.TaxSourcePayment,
+		SourceID:   p.ID,
+		UserID:     &userID,
+		Total:      p.Amount,
+		Items:      []models.TaxDocumentItem{{Name: "Plan " + p.PlanName, Quantity: 1, UnitPrice: p.Amount, Amount: p.Amount}},
+	}
+	return s.issue(doc, docType, receptor)
+}
+
+// IssueForSale emite el documento de una venta POS. Las gift cards no se incluyen: tributan al canjearse.
+func (s *TaxService) IssueForSale(saleID int64, docType int, receptor *models.TaxReceptor) (*models.TaxDocument, error) {
+	sale, err := s.repo.SaleSource(saleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSourceNotFound
+		}
+		return nil, err
+	}
+	doc := &models.TaxDocument{SourceType: models.TaxSourceSale, SourceID: sale.ID, UserID: sale.UserID}
+	for _, it := range sale.Items {
+		if it.GiftCard {
+			continue
+		}
+		amount := it.UnitPrice * int64(it.Quantity)
+		doc.Items = append(doc.Items, models.TaxDocumentItem{Name: it.ProductName, Quantity: it.Quantity, UnitPrice: it.UnitPrice, Amount: amount})
+		doc.Total += amount
+	}
+	if doc.Total <= 0 {
+		return nil, ErrNothingToInvoice
+	}
+	return s.issue(doc, docType, receptor)
+}
+
+func (s *TaxService) issue(doc *models.TaxDocument, docType int, receptor *models.TaxReceptor) (*models.TaxDocument, error) {
+	if !s.Enabled() {
+		return nil, ErrDTEDisabled
+	}
+	if docType == 0 {
+		docType = models.DTEBoleta
+	}
+	if _, ok := models.DTENames[docType]; !ok {
+		return nil, ErrInvalidDocType
+	}
+
+	rec, err := s.resolveReceptor(docType, doc.UserID, receptor)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	doc.DocType = docType
+	doc.Receptor = rec
+	doc.Status = models.TaxDocIssued
+	doc.IssuedAt = now
+	PrepareTaxDocument(doc)
+
+	err = s.repo.IssueDocument(doc, func(rawCAF, privateKey string) error {
+		ted, err := BuildTED(doc, s.emisor.RUT, rawCAF, privateKey, now)
+		if err != nil {
+			return fmt.Errorf("stamp document: %w", err)
+		}
+		doc.TED = ted
+		doc.XML = BuildDTEXML(doc, s.emisor, ted, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.Send(doc)
+	return doc, nil
+}
+
+// Send envía (o reenvía) el documento y registra el resultado
+func (s *TaxService) Send(doc *models.TaxDocument) error {
+	trackID, err := s.sender.Send(doc)
+	if err != nil {
+		doc.Status = models.TaxDocError
+		doc.SendError = err.Error()
+		_ = s.repo.UpdateSendResult(doc.ID, doc.Status, "", doc.SendError)
+		return err
+	}
+	doc.Status = models.TaxDocSent
+	doc.TrackID = trackID
+	doc.SendError = ""
+	return s.repo.UpdateSendResult(doc.ID, doc.Status, trackID, "")
+}
+
+// PDF genera la representación impresa con timbre
+func (s *TaxService) PDF(doc *models.TaxDocument) []byte {
+	return RenderTaxDocumentPDF(doc, s.emisor, s.barcode)
+}
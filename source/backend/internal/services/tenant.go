@@ -0,0 +1,222 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"boxmagic/internal/config"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrTenantInvalidSlug = errors.New("slug must be 3-40 lowercase letters, digits or dashes and not reserved")
+	ErrTenantExists      = errors.New("tenant already exists")
+	ErrTenantNotFound    = errors.New("tenant not found")
+	ErrTenantSuspended   = errors.New("tenant suspended")
+	ErrTenantNameMissing = errors.New("name is required")
+	ErrTenantOwner       = errors.New("owner email, name and a password of at least 8 characters are required")
+)
+
+// TenantService - Alta y configuración de tenants (super-admin) y acceso a sus bases
+type TenantService struct {
+	tenantRepo *repository.TenantRepository
+	db         *sql.DB
+	cfg        *config.Config
+	onChange   func(slug string)
+}
+
+func NewTenantService(tenantRepo *repository.TenantRepository, db *sql.DB, cfg *config.Config) *TenantService {
+	return &TenantService{tenantRepo: tenantRepo, db: db, cfg: cfg}
+}
+
+// SetOnChange avisa cuando cambia la configuración o el estado de un tenant (para reconstruir su API)
+func (s *TenantService) SetOnChange(fn func(slug string)) {
+	s.onChange = fn
+}
+
+func (s *TenantService) List() ([]*models.Tenant, error) {
+	return s.tenantRepo.List()
+}
+
+func (s *TenantService) Get(slug string) (*models.Tenant, error) {
+	t, err := s.tenantRepo.GetBySlug(strings.ToLower(slug))
+	if err == sql.ErrNoRows {
+		return nil, ErrTenantNotFound
+	}
+	return t, err
+}
+
+// Open abre el pool acotado al schema del tenant
+func (s *TenantService) Open(t *models.Tenant) (*sql.DB, error) {
+	return repository.NewTenantDB(s.cfg.DatabaseURL, t.Schema)
+}
+
+// MigrateAll aplica las migraciones en el schema de cada tenant (al iniciar, como en single-tenant)
+func (s *TenantService) MigrateAll() error {
+	tenants, err := s.tenantRepo.List()
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		if err := s.migrate(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TenantService) migrate(t *models.Tenant) error {
+	if err := repository.CreateSchema(s.db, t.Schema); err != nil {
+		return err
+	}
+	tdb, err := s.Open(t)
+	if err != nil {
+		return err
+	}
+	defer tdb.Close()
+	if err := repository.Migrate(tdb); err != nil {
+		return err
+	}
+	// Catálogo estándar; solo se carga si el del tenant está vacío
+	return repository.SeedBenchmarks(tdb)
+}
+
+// Provision registra el tenant, crea y migra su schema y la cuenta del dueño
+func (s *TenantService) Provision(req *models.CreateTenantRequest) (*models.Tenant, error) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !models.ValidTenantSlug(slug) {
+		return nil, ErrTenantInvalidSlug
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, ErrTenantNameMissing
+	}
+	if req.OwnerEmail == "" || req.OwnerName == "" || len(req.OwnerPassword) < models.MinPasswordLength {
+		return nil, ErrTenantOwner
+	}
+	if _, err := s.tenantRepo.GetBySlug(slug); err == nil {
+		return nil, ErrTenantExists
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.OwnerPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &models.Tenant{Slug: slug, Name: strings.TrimSpace(req.Name), Schema: models.TenantSchema(slug), Active: true, Config: req.Config}
+	if err := s.tenantRepo.Create(t); err != nil {
+		return nil, err
+	}
+	// El registro reserva el slug; si el alta queda a medias se deshace para que pueda reintentarse
+	if err := s.setup(t, req, string(hash)); err != nil {
+		s.discard(t)
+		return nil, err
+	}
+	log.Printf("[TENANT] provisioned %s (schema %s)", t.Slug, t.Schema)
+	return t, nil
+}
+
+// setup crea y migra el schema del tenant y la cuenta del dueño
+func (s *TenantService) setup(t *models.Tenant, req *models.CreateTenantRequest, hash string) error {
+	if err := s.migrate(t); err != nil {
+		return err
+	}
+
+	tdb, err := s.Open(t)
+	if err != nil {
+		return err
+	}
+	defer tdb.Close()
+	userRepo := repository.NewUserRepository(tdb)
+	owner := &models.User{
+		Email:        strings.ToLower(strings.TrimSpace(req.OwnerEmail)),
+		PasswordHash: hash,
+		Name:         req.OwnerName,
+		Role:         models.RoleOwner,
+		Active:       true,
+	}
+	if err := userRepo.Create(owner); err != nil {
+		return err
+	}
+	return userRepo.SetEmailVerified(owner.ID)
+}
+
+// discard borra el schema y el registro de un alta fallida
+func (s *TenantService) discard(t *models.Tenant) {
+	if err := repository.DropSchema(s.db, t.Schema); err != nil {
+		log.Printf("[TENANT] drop schema %s after failed provisioning: %v", t.Schema, err)
+	}
+	if err := s.tenantRepo.Delete(t.ID); err != nil {
+		log.Printf("[TENANT] delete %s after failed provisioning: %v", t.Slug, err)
+	}
+}
+
+func (s *TenantService) Update(slug string, req *models.UpdateTenantRequest) (*models.Tenant, error) {
+	t, err := s.Get(slug)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return nil, ErrTenantNameMissing
+		}
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Active != nil {
+		t.Active = *req.Active
+	}
+	if req.Config != nil {
+		t.Config = *req.Config
+	}
+	if err := s.tenantRepo.Update(t); err != nil {
+		return nil, err
+	}
+	if s.onChange != nil {
+		s.onChange(t.Slug)
+	}
+	return t, nil
+}
+
+// TenantConfig arma la config del tenant: copia de la global con sus valores propios
+func TenantConfig(base *config.Config, t *models.Tenant) *config.Config {
+	cfg := *base
+	cfg.TenantSlug = t.Slug
+	cfg.GymName = t.Name
+
+	c := t.Config
+	if c.GymName != nil {
+		cfg.GymName = *c.GymName
+	}
+	if c.GymAddress != nil {
+		cfg.GymAddress = *c.GymAddress
+	}
+	if c.GymContact != nil {
+		cfg.GymContact = *c.GymContact
+	}
+	if c.AppURL != nil {
+		cfg.AppURL = *c.AppURL
+	}
+	if c.SMTPFrom != nil {
+		cfg.SMTPFrom = *c.SMTPFrom
+	}
+	if c.InvitationClassPrice != nil {
+		cfg.InvitationClassPrice = *c.InvitationClassPrice
+	}
+	if c.BeforeClassPhotoPrice != nil {
+		cfg.BeforeClassPhotoPrice = *c.BeforeClassPhotoPrice
+	}
+	if c.BookingWindowDays != nil {
+		cfg.BookingWindowDays = *c.BookingWindowDays
+	}
+	if c.BookingCutoffHours != nil {
+		cfg.BookingCutoffHours = *c.BookingCutoffHours
+	}
+	if c.PendingPaymentExpiryDays != nil {
+		cfg.PendingPaymentExpiryDays = *c.PendingPaymentExpiryDays
+	}
+	return &cfg
+}
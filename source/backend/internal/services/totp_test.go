@@ -0,0 +1,127 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"boxmagic/internal/models"
+)
+
+// Secreto de los vectores del RFC 6238 (SHA1): "12345678901234567890" en base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// El RFC publica 8 dígitos; con 6 son los últimos seis
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		got, err := totpCode(rfc6238Secret, c.unix/totpPeriod)
+		if err != nil || got != c.want {
+			t.Errorf("T=%d: totpCode = %q, %v; want %q", c.unix, got, err, c.want)
+		}
+		if step, ok := matchTOTP(rfc6238Secret, c.want, time.Unix(c.unix, 0)); !ok || step != c.unix/totpPeriod {
+			t.Errorf("T=%d: matchTOTP(%q) = %d, %v", c.unix, c.want, step, ok)
+		}
+	}
+}
+
+func TestTOTPCode_LowercaseSecretAndBadSecret(t *testing.T) {
+	if got, _ := totpCode(strings.ToLower(rfc6238Secret), 1); got != "287082" {
+		t.Errorf("lowercase secret should decode, got %q", got)
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestMatchTOTP_SkewWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, _ := totpCode(rfc6238Secret, current+offset)
+		step, ok := matchTOTP(rfc6238Secret, code, now)
+		inWindow := offset >= -totpSkew && offset <= totpSkew
+		if ok != inWindow {
+			t.Errorf("offset %d: accepted=%v, want %v", offset, ok, inWindow)
+		}
+		if ok && step != current+offset {
+			t.Errorf("offset %d: matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestMatchTOTP_Format(t *testing.T) {
+	now := time.Unix(59, 0)
+	for code, want := range map[string]bool{
+		"287082":    true,
+		" 287 082 ": true,
+		"28708":     false,
+		"2870820":   false,
+		"":          false,
+	} {
+		if _, ok := matchTOTP(rfc6238Secret, code, now); ok != want {
+			t.Errorf("matchTOTP(%q) = %v, want %v", code, ok, want)
+		}
+	}
+}
+
+// fakeSteps replica UseStep: solo acepta pasos posteriores al último usado
+type fakeSteps struct {
+	last map[int64]int64
+}
+
+func (f *fakeSteps) UseStep(userID, step int64) (bool, error) {
+	if last, ok := f.last[userID]; ok && last >= step {
+		return false, nil
+	}
+	f.last[userID] = step
+	return true, nil
+}
+
+func TestVerifyTOTP_RejectsReplay(t *testing.T) {
+	steps := &fakeSteps{last: map[int64]int64{}}
+	tf := &models.TwoFactor{UserID: 7, Secret: rfc6238Secret}
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	code, _ := totpCode(rfc6238Secret, current)
+	if err := verifyTOTP(steps, tf, code, now); err != nil {
+		t.Fatalf("first use should pass: %v", err)
+	}
+	if err := verifyTOTP(steps, tf, code, now); err != ErrInvalid2FACode {
+		t.Fatalf("replayed code must be rejected, got %v", err)
+	}
+	// Un código anterior aún dentro de la tolerancia tampoco sirve después de usar uno más nuevo
+	previous, _ := totpCode(rfc6238Secret, current-1)
+	if err := verifyTOTP(steps, tf, previous, now); err != ErrInvalid2FACode {
+		t.Fatalf("older step must be rejected after a newer one, got %v", err)
+	}
+	// Otro usuario con el mismo secreto no se ve afectado
+	if err := verifyTOTP(steps, &models.TwoFactor{UserID: 8, Secret: rfc6238Secret}, code, now); err != nil {
+		t.Fatalf("other user should pass: %v", err)
+	}
+	next, _ := totpCode(rfc6238Secret, current+1)
+	if err := verifyTOTP(steps, tf, next, now.Add(totpPeriod*time.Second)); err != nil {
+		t.Fatalf("next step should pass: %v", err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Box Magic", "ana@example.com", rfc6238Secret)
+	for _, part := range []string{"otpauth://totp/Box%20Magic:ana@example.com?", "secret=" + rfc6238Secret, "issuer=Box+Magic", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %q missing %q", uri, part)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"boxmagic/internal/jwtkeys"
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrInvalid2FACode       = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotStarted  = errors.New("two-factor setup not started")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required for staff accounts")
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication not configured")
+)
+
+// mfaChallengeTTL - Tiempo para ingresar el código tras la contraseña
+const mfaChallengeTTL = 5 * time.Minute
+
+// SetTwoFactor activa 2FA (TOTP) y la política de 2FA obligatorio para el staff
+func (s *AuthService) SetTwoFactor(repo *repository.TwoFactorRepository, settingsRepo repository.SettingsRepo) {
+	s.twoFactor = repo
+	s.settingsRepo = settingsRepo
+}
+
+// SecuritySettings devuelve las políticas de seguridad vigentes
+func (s *AuthService) SecuritySettings() (*models.SecuritySettings, error) {
+	settings := &models.SecuritySettings{}
+	if s.settingsRepo == nil {
+		return settings, nil
+	}
+	for key, dst := range map[string]*bool{
+		models.SettingRequire2FAStaff:  &settings.Require2FAStaff,
+		models.SettingMagicLinkEnabled: &settings.MagicLinkEnabled,
+	} {
+		v, err := s.settingsRepo.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		*dst = v == "true"
+	}
+	return settings, nil
+}
+
+func (s *AuthService) UpdateSecuritySettings(req *models.SecuritySettingsRequest, updatedBy int64) (*models.SecuritySettings, error) {
+	if s.settingsRepo == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	for key, v := range map[string]*bool{
+		models.SettingRequire2FAStaff:  req.Require2FAStaff,
+		models.SettingMagicLinkEnabled: req.MagicLinkEnabled,
+	} {
+		if v == nil {
+			continue
+		}
+		if err := s.settingsRepo.Set(key, strconv.FormatBool(*v), updatedBy); err != nil {
+			return nil, err
+		}
+	}
+	return s.SecuritySettings()
+}
+
+// twoFactorRequired indica si la política obliga al usuario a usar 2FA (todo rol distinto de miembro es staff)
+func (s *AuthService) twoFactorRequired(user *models.User) bool {
+	if user.Role == models.RoleMember {
+		return false
+	}
+	settings, err := s.SecuritySettings()
+	return err == nil && settings.Require2FAStaff
+}
+
+// twoFactorChallenge devuelve el desafío cuando la cuenta tiene 2FA o debe activarlo; nil si puede entrar directo
+func (s *AuthService) twoFactorChallenge(user *models.User) (*models.AuthResponse, error) {
+	if s.twoFactor == nil {
+		return nil, nil
+	}
+	tf, err := s.twoFactor.Get(user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	enabled := tf != nil && tf.EnabledAt != nil
+	if !enabled && !s.twoFactorRequired(user) {
+		return nil, nil
+	}
+
+	// Token de corta vida sin rol: el middleware Auth lo rechaza como token de acceso
+	claims := jwt.MapClaims{
+		"sub": user.ID,
+		"typ": "mfa",
+		"ver": user.TokenVersion,
+		"exp": time.Now().Add(mfaChallengeTTL).Unix(),
+	}
+	if s.cfg.TenantSlug != "" {
+		claims["tid"] = s.cfg.TenantSlug
+	}
+	token, err := jwtkeys.Sign(s.cfg.JWTKeys, s.cfg.JWTSecret, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthResponse{MFARequired: true, MFASetupRequired: !enabled, MFAToken: token}, nil
+}
+
+// challengeUser valida el token del desafío y devuelve su usuario
+func (s *AuthService) challengeUser(mfaToken string) (*models.User, error) {
+	token, err := jwt.Parse(mfaToken, jwtkeys.Keyfunc(s.cfg.JWTKeys, s.cfg.JWTSecret))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	sub, ok := claims["sub"].(float64)
+	if tid, _ := claims["tid"].(string); !ok || claims["typ"] != "mfa" || tid != s.cfg.TenantSlug {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.userRepo.GetByID(int64(sub))
+	if err != nil || !user.Active {
+		return nil, ErrInvalidToken
+	}
+	// Un cambio de contraseña o de rol invalida los desafíos emitidos antes
+	if ver, _ := claims["ver"].(float64); int(ver) != user.TokenVersion {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+// BeginTwoFactorLoginSetup genera el secreto para el staff que debe activar 2FA durante el login
+func (s *AuthService) BeginTwoFactorLoginSetup(mfaToken string) (*models.TwoFactorSetup, error) {
+	user, err := s.challengeUser(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginSetup(user)
+}
+
+// VerifyTwoFactorLogin completa el login con un código TOTP o de recuperación. Si la activación estaba
+// pendiente (2FA obligatorio), el código la confirma y la respuesta incluye los códigos de recuperación.
+func (s *AuthService) VerifyTwoFactorLogin(req *models.TwoFactorLoginRequest) (*models.AuthResponse, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	user, err := s.challengeUser(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if s.lockoutRepo != nil {
+		if l, err := s.lockoutRepo.Get(user.ID); err == nil && l.LockedUntil != nil && l.LockedUntil.After(time.Now()) {
+			return nil, &AccountLockedError{Until: *l.LockedUntil}
+		}
+	}
+
+	tf, err := s.twoFactor.Get(user.ID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	switch {
+	case tf.EnabledAt == nil:
+		if err := s.checkTOTP(tf, req.Code); err != nil {
+			return nil, s.twoFactorFailure(user, err)
+		}
+		if recoveryCodes, err = s.enable(user.ID); err != nil {
+			return nil, err
+		}
+	case req.RecoveryCode != "":
+		ok, err := s.twoFactor.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, s.twoFactorFailure(user, ErrInvalid2FACode)
+		}
+	default:
+		if err := s.checkTOTP(tf, req.Code); err != nil {
+			return nil, s.twoFactorFailure(user, err)
+		}
+	}
+
+	if s.lockoutRepo != nil {
+		s.lockoutRepo.Reset(user.ID)
+	}
+	resp, err := s.startSession(user, req.Client)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+// twoFactorFailure cuenta los códigos erróneos igual que las contraseñas erróneas
+func (s *AuthService) twoFactorFailure(user *models.User, err error) error {
+	if err != ErrInvalid2FACode {
+		return err
+	}
+	if lockErr := s.recordLoginFailure(user); lockErr != ErrInvalidCredentials {
+		return lockErr
+	}
+	return ErrInvalid2FACode
+}
+
+// checkTOTP valida el código y marca su paso como usado para que no se pueda repetir
+func (s *AuthService) checkTOTP(tf *models.TwoFactor, code string) error {
+	return verifyTOTP(s.twoFactor, tf, code, time.Now())
+}
+
+// totpSteps registra el último paso TOTP usado por cada usuario (repository.TwoFactorRepository)
+type totpSteps interface {
+	UseStep(userID, step int64) (bool, error)
+}
+
+func verifyTOTP(steps totpSteps, tf *models.TwoFactor, code string, now time.Time) error {
+	step, ok := matchTOTP(tf.Secret, code, now)
+	if !ok {
+		return ErrInvalid2FACode
+	}
+	fresh, err := steps.UseStep(tf.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalid2FACode
+	}
+	return nil
+}
+
+func (s *AuthService) beginSetup(user *models.User) (*models.TwoFactorSetup, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	tf, err := s.twoFactor.Get(user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret := newTOTPSecret()
+	if err := s.twoFactor.SavePending(user.ID, secret); err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.cfg.GymName, user.Email, secret),
+	}, nil
+}
+
+// enable activa 2FA y devuelve los códigos de recuperación en claro (solo se muestran esta vez)
+func (s *AuthService) enable(userID int64) ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	if err := s.twoFactor.Enable(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// enabledTwoFactor devuelve la configuración activa o ErrTwoFactorNotEnabled
+func (s *AuthService) enabledTwoFactor(userID int64) (*models.TwoFactor, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	tf, err := s.twoFactor.Get(userID)
+	if err == sql.ErrNoRows || (err == nil && tf.EnabledAt == nil) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return tf, err
+}
+
+func (s *AuthService) TwoFactorStatus(userID int64) (*models.TwoFactorStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatus{Required: s.twoFactorRequired(user)}
+	tf, err := s.enabledTwoFactor(userID)
+	if err == ErrTwoFactorNotEnabled || err == ErrTwoFactorUnavailable {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled = true
+	status.EnabledAt = tf.EnabledAt
+	status.RecoveryCodesLeft, _ = s.twoFactor.CountRecoveryCodes(userID)
+	return status, nil
+}
+
+// BeginTwoFactorSetup genera un secreto pendiente para el usuario autenticado
+func (s *AuthService) BeginTwoFactorSetup(userID int64) (*models.TwoFactorSetup, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginSetup(user)
+}
+
+// EnableTwoFactor confirma la activación con el primer código de la app
+func (s *AuthService) EnableTwoFactor(userID int64, code string) ([]string, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorUnavailable
+	}
+	tf, err := s.twoFactor.Get(userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if err := s.checkTOTP(tf, code); err != nil {
+		return nil, err
+	}
+	return s.enable(userID)
+}
+
+// DisableTwoFactor desactiva 2FA con un código válido, salvo que la política lo exija
+func (s *AuthService) DisableTwoFactor(userID int64, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if s.twoFactorRequired(user) {
+		return ErrTwoFactorRequired
+	}
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkTOTP(tf, code); err != nil {
+		return err
+	}
+	return s.twoFactor.Delete(userID)
+}
+
+// RegenerateRecoveryCodes invalida los códigos anteriores y entrega nuevos
+func (s *AuthService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	tf, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTOTP(tf, code); err != nil {
+		return nil, err
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := s.twoFactor.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTwoFactor - Un administrador quita el 2FA de otro usuario (p. ej. perdió el teléfono) y cierra sus sesiones
+func (s *AuthService) ResetTwoFactor(userID int64) error {
+	if _, err := s.enabledTwoFactor(userID); err != nil {
+		return err
+	}
+	if err := s.twoFactor.Delete(userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllSessions(userID, models.SessionRevokedAdmin)
+}
+
+// newRecoveryCodes genera códigos "xxxx-xxxx" y sus hashes
+func newRecoveryCodes() (codes, hashes []string) {
+	for i := 0; i < models.RecoveryCodeCount; i++ {
+		raw := generateRandomToken()[:8]
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrWaiverNotFound         = errors.New("waiver document not found")
+	ErrWaiverInvalidType      = errors.New("invalid waiver type")
+	ErrWaiverInvalidAppliesTo = errors.New("applies_to must be all, minors or adults")
+	ErrWaiverContentMissing   = errors.New("title and body are required")
+	ErrWaiverNotApplicable    = errors.New("this document does not apply to the member")
+	ErrWaiverNotAccepted      = errors.New("the document must be accepted")
+	ErrWaiverSignerMissing    = errors.New("signer_name is required")
+	ErrWaiverGuardianMissing  = errors.New("minors must be signed by a guardian: guardian_name and guardian_rut are required")
+	ErrWaiverGuardianSigner   = errors.New("signer_name must match guardian_name")
+)
+
+// WaiverService - Documentos de exención vigentes según la edad del miembro y sus firmas
+type WaiverService struct {
+	waiverRepo *repository.WaiverRepository
+	userRepo   repository.UserRepo
+}
+
+func NewWaiverService(waiverRepo *repository.WaiverRepository, userRepo repository.UserRepo) *WaiverService {
+	return &WaiverService{waiverRepo: waiverRepo, userRepo: userRepo}
+}
+
+// IsMinor indica si a la fecha el miembro no cumple AdultAge; sin fecha de nacimiento se lo trata como adulto
+func IsMinor(birthDate *time.Time, now time.Time) bool {
+	if birthDate == nil {
+		return false
+	}
+	return birthDate.AddDate(models.AdultAge, 0, 0).After(now)
+}
+
+func waiverApplies(d *models.WaiverDocument, minor bool) bool {
+	switch d.AppliesTo {
+	case models.WaiverAppliesMinors:
+		return minor
+	case models.WaiverAppliesAdults:
+		return !minor
+	}
+	return true
+}
+
+// documentHash identifica el texto exacto firmado
+func documentHash(d *models.WaiverDocument) string {
+	sum := sha256.Sum256([]byte(d.Title + "\n" + d.Body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Publish crea una nueva versión del tipo; los miembros deberán firmarla de nuevo
+func (s *WaiverService) Publish(req *models.WaiverDocumentRequest, createdBy int64) (*models.WaiverDocument, error) {
+	if !models.ValidWaiverTypes[req.Type] {
+		return nil, ErrWaiverInvalidType
+	}
+	if req.AppliesTo == "" {
+		req.AppliesTo = models.WaiverAppliesAll
+		if req.Type == models.WaiverMinorConsent {
+			req.AppliesTo = models.WaiverAppliesMinors
+		}
+	}
+	switch req.AppliesTo {
+	case models.WaiverAppliesAll, models.WaiverAppliesMinors, models.WaiverAppliesAdults:
+	default:
+		return nil, ErrWaiverInvalidAppliesTo
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || strings.TrimSpace(req.Body) == "" {
+		return nil, ErrWaiverContentMissing
+	}
+	d := &models.WaiverDocument{
+		Type:      req.Type,
+		Title:     req.Title,
+		Body:      req.Body,
+		Required:  req.Required,
+		AppliesTo: req.AppliesTo,
+		CreatedBy: &createdBy,
+	}
+	if err := s.waiverRepo.Publish(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// MemberStatus arma el estado de firmas del miembro con los documentos vigentes que le aplican
+func (s *WaiverService) MemberStatus(userID int64) (*models.MemberWaivers, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.waiverRepo.ListDocuments(false)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := s.waiverRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	signed := map[int64]*models.Authorization{}
+	for _, a := range signatures {
+		if a.DocumentID != nil {
+			signed[*a.DocumentID] = a
+		}
+	}
+
+	m := &models.MemberWaivers{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+		Minor:     IsMinor(user.BirthDate, time.Now()),
+		Waivers:   []*models.WaiverStatus{},
+	}
+	for _, d := range docs {
+		if !waiverApplies(d, m.Minor) {
+			continue
+		}
+		st := &models.WaiverStatus{Document: d, Signature: signed[d.ID]}
+		st.Signed = st.Signature != nil
+		if d.Required && !st.Signed {
+			m.PendingRequired++
+		}
+		m.Waivers = append(m.Waivers, st)
+	}
+	m.CanBook = m.PendingRequired == 0
+	return m, nil
+}
+
+// CanBook indica si el miembro firmó todos los documentos obligatorios vigentes
+func (s *WaiverService) CanBook(userID int64) (bool, error) {
+	m, err := s.MemberStatus(userID)
+	if err != nil {
+		return false, err
+	}
+	return m.CanBook, nil
+}
+
+// Sign registra la firma del miembro (o de su apoderado si es menor) sobre la versión vigente del documento
+func (s *WaiverService) Sign(userID, documentID int64, req *models.SignWaiverRequest, client models.ClientInfo) (*models.Authorization, error) {
+	d, err := s.waiverRepo.GetDocument(documentID)
+	if err == sql.ErrNoRows || (err == nil && !d.Active) {
+		return nil, ErrWaiverNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	minor := IsMinor(user.BirthDate, time.Now())
+	if !waiverApplies(d, minor) {
+		return nil, ErrWaiverNotApplicable
+	}
+	if !req.Accept {
+		return nil, ErrWaiverNotAccepted
+	}
+	req.SignerName = strings.TrimSpace(req.SignerName)
+	if req.SignerName == "" {
+		return nil, ErrWaiverSignerMissing
+	}
+
+	a := &models.Authorization{
+		UserID:       userID,
+		DocumentType: d.Type,
+		DocumentID:   &d.ID,
+		SignerName:   req.SignerName,
+		IP:           client.IP,
+		UserAgent:    client.UserAgent,
+		DocumentHash: documentHash(d),
+	}
+	if minor {
+		a.GuardianName = strings.TrimSpace(req.GuardianName)
+		a.GuardianRut = models.NormalizeRUT(req.GuardianRut)
+		if a.GuardianName == "" || a.GuardianRut == "" {
+			return nil, ErrWaiverGuardianMissing
+		}
+		if !strings.EqualFold(a.GuardianName, a.SignerName) {
+			return nil, ErrWaiverGuardianSigner
+		}
+	}
+	if err := s.waiverRepo.Sign(a); err != nil {
+		return nil, err
+	}
+	a.DocumentVersion = d.Version
+	a.DocumentTitle = d.Title
+	return a, nil
+}
+
+// Pending lista los miembros activos que aún no firman documentos obligatorios
+func (s *WaiverService) Pending() ([]*models.MemberWaivers, error) {
+	return s.waiverRepo.PendingRequired(models.AdultAge)
+}
+
+// RenderSignedWaiver genera la copia firmada: el texto tal como se firmó y la evidencia de la firma
+func RenderSignedWaiver(sw *models.SignedWaiver, b ReceiptBranding) []byte {
+	p := NewPDFDocument()
+	p.AddPage()
+	number := ""
+	if sw.DocumentVersion > 0 {
+		number = fmt.Sprintf("Versión %d", sw.DocumentVersion)
+	}
+	y := receiptHeader(p, b, "DOCUMENTO FIRMADO", number)
+
+	title := sw.DocumentTitle
+	if title == "" {
+		title = sw.DocumentType
+	}
+	p.Text(40, y, 13, FontBold, title)
+	y += 22
+	for _, line := range wrapText(sw.Body, 532, 9, FontRegular) {
+		if y > 700 {
+			receiptFooter(p, b, "")
+			p.AddPage()
+			y = 60
+		}
+		p.Text(40, y, 9, FontRegular, line)
+		y += 12
+	}
+
+	if y > 600 {
+		receiptFooter(p, b, "")
+		p.AddPage()
+		y = 60
+	}
+	y += 16
+	p.Line(40, y, 572, y, 0.5)
+	y += 20
+	p.Text(40, y, 11, FontBold, "Firma")
+	y += 18
+	receiptRow(p, y, "Miembro", sw.UserName)
+	y += 16
+	if sw.UserRUT != "" {
+		receiptRow(p, y, "RUT", formatRUT(sw.UserRUT))
+		y += 16
+	}
+	if sw.GuardianName != "" {
+		receiptRow(p, y, "Apoderado", sw.GuardianName+"  (RUT "+formatRUT(sw.GuardianRut)+")")
+		y += 16
+	}
+	receiptRow(p, y, "Firmado por", sw.SignerName)
+	y += 16
+	receiptRow(p, y, "Fecha", sw.SignedAt.Format("02/01/2006 15:04:05 MST"))
+	y += 16
+	if sw.IP != "" {
+		receiptRow(p, y, "IP", sw.IP)
+		y += 16
+	}
+	if sw.DocumentHash != "" {
+		receiptRow(p, y, "SHA-256", "")
+		p.Text(180, y, 7, FontMono, sw.DocumentHash)
+	}
+
+	receiptFooter(p, b, "Firma electrónica simple: nombre tipeado por el firmante al aceptar el documento.")
+	return p.Bytes()
+}
+
+// wrapText corta el texto en líneas que caben en width, respetando los saltos de párrafo
+func wrapText(s string, width, size float64, font string) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		words := strings.Fields(para)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if TextWidth(line+" "+w, size, font) > width {
+				lines = append(lines, line)
+				line = w
+				continue
+			}
+			line += " " + w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
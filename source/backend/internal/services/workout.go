@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"boxmagic/internal/models"
+	"boxmagic/internal/repository"
+)
+
+var (
+	ErrWorkoutInvalidKind   = errors.New("invalid block kind")
+	ErrWorkoutInvalidFormat = errors.New("invalid block format")
+	ErrWorkoutLineMissing   = errors.New("each line needs a movement or a name")
+	ErrMovementNotFound     = errors.New("movement not found")
+)
+
+var blockLabels = map[string]string{
+	models.BlockWarmup:   "CALENTAMIENTO",
+	models.BlockStrength: "FUERZA",
+	models.BlockSkill:    "TÉCNICA",
+	models.BlockMetcon:   "METCON",
+	models.BlockCooldown: "VUELTA A LA CALMA",
+}
+
+var blockFormats = map[string]bool{
+	"":                    true,
+	models.FormatAMRAP:    true,
+	models.FormatEMOM:     true,
+	models.FormatForTime:  true,
+	models.FormatTabata:   true,
+	models.FormatSetsReps: true,
+}
+
+// WorkoutService - Valida rutinas estructuradas y genera su texto para la TV y clientes antiguos
+type WorkoutService struct {
+	movementRepo *repository.MovementRepository
+}
+
+func NewWorkoutService(movementRepo *repository.MovementRepository) *WorkoutService {
+	return &WorkoutService{movementRepo: movementRepo}
+}
+
+// Prepare valida los bloques, completa el nombre de las líneas con el de la biblioteca de movimientos
+// y reescribe Content, ContentScaled y ContentBeginner de la rutina
+func (s *WorkoutService) Prepare(routine *models.Routine) error {
+	for _, b := range routine.Blocks {
+		if _, ok := blockLabels[b.Kind]; !ok {
+			return ErrWorkoutInvalidKind
+		}
+		if !blockFormats[b.Format] {
+			return ErrWorkoutInvalidFormat
+		}
+		if b.Lines == nil {
+			b.Lines = []*models.WorkoutLine{}
+		}
+		for _, l := range b.Lines {
+			l.Name = strings.TrimSpace(l.Name)
+			if l.MovementID != nil {
+				m, err := s.movementRepo.GetByID(*l.MovementID)
+				if err != nil {
+					return err
+				}
+				if m == nil {
+					return ErrMovementNotFound
+				}
+				l.Name = m.Name
+			}
+			if l.Name == "" {
+				return ErrWorkoutLineMissing
+			}
+		}
+	}
+	routine.Content = RenderWorkout(routine.Blocks, models.LevelRx)
+	routine.ContentScaled, routine.ContentBeginner = "", ""
+	if hasLevel(routine.Blocks, models.LevelScaled) {
+		routine.ContentScaled = RenderWorkout(routine.Blocks, models.LevelScaled)
+	}
+	if hasLevel(routine.Blocks, models.LevelBeginner) {
+		routine.ContentBeginner = RenderWorkout(routine.Blocks, models.LevelBeginner)
+	}
+	return nil
+}
+
+// hasLevel indica si alguna línea tiene una prescripción propia para el nivel
+func hasLevel(blocks []*models.WorkoutBlock, level string) bool {
+	for _, b := range blocks {
+		for _, l := range b.Lines {
+			if l.Scaled != nil || (level == models.LevelBeginner && l.Beginner != nil) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RenderWorkout arma el texto de la rutina para un nivel, un bloque por párrafo
+func RenderWorkout(blocks []*models.WorkoutBlock, level string) string {
+	var parts []string
+	for _, b := range blocks {
+		var sb strings.Builder
+		sb.WriteString(blockLabels[b.Kind])
+		if b.Title != "" {
+			sb.WriteString(" - " + b.Title)
+		}
+		if h := formatHeader(b); h != "" {
+			sb.WriteString("\n" + h)
+		}
+		sets := 0
+		if b.Format == models.FormatSetsReps {
+			sets = b.Sets
+		}
+		for _, l := range b.Lines {
+			sb.WriteString("\n" + formatLine(l, l.At(level), sets))
+		}
+		if b.Notes != "" {
+			sb.WriteString("\n" + b.Notes)
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func formatHeader(b *models.WorkoutBlock) string {
+	switch b.Format {
+	case models.FormatAMRAP:
+		return fmt.Sprintf("AMRAP %d min", b.Minutes)
+	case models.FormatEMOM:
+		if b.IntervalSeconds > 0 && b.IntervalSeconds != 60 {
+			return fmt.Sprintf("Cada %s durante %d min", formatClock(b.IntervalSeconds), b.Minutes)
+		}
+		return fmt.Sprintf("EMOM %d min", b.Minutes)
+	case models.FormatForTime:
+		h := "For time"
+		if b.Rounds > 1 {
+			h = fmt.Sprintf("%d rondas for time", b.Rounds)
+		}
+		if b.Minutes > 0 {
+			h += fmt.Sprintf(" (time cap %d min)", b.Minutes)
+		}
+		return h
+	case models.FormatTabata:
+		rounds := b.Rounds
+		if rounds == 0 {
+			rounds = 8
+		}
+		return fmt.Sprintf("Tabata %d x 20s/10s", rounds)
+	}
+	if b.Rounds > 1 {
+		return fmt.Sprintf("%d rondas", b.Rounds)
+	}
+	return ""
+}
+
+// formatLine - "5 x 5 Back Squat @ 75%", "400 m Run", "15 cal Row", "21-15-9 Thruster @ 43 kg"
+func formatLine(l *models.WorkoutLine, p models.Prescription, sets int) string {
+	qty := p.Reps
+	switch {
+	case p.Distance > 0:
+		qty = fmt.Sprintf("%d m", p.Distance)
+	case p.Calories > 0:
+		qty = fmt.Sprintf("%d cal", p.Calories)
+	case p.Seconds > 0:
+		qty = formatClock(p.Seconds)
+	}
+	if sets > 0 && qty != "" {
+		qty = fmt.Sprintf("%d x %s", sets, qty)
+	}
+	line := strings.TrimSpace(qty + " " + l.Name)
+	switch {
+	case p.LoadKg > 0:
+		line += " @ " + strconv.FormatFloat(p.LoadKg, 'f', -1, 64) + " kg"
+	case p.LoadPct > 0:
+		line += " @ " + strconv.FormatFloat(p.LoadPct, 'f', -1, 64) + "%"
+	}
+	if l.Notes != "" {
+		line += " (" + l.Notes + ")"
+	}
+	return line
+}
+
+// formatClock - 45 → "45s", 90 → "1:30", 120 → "2:00"
+func formatClock(seconds int) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
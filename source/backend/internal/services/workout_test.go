@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+
+	"boxmagic/internal/models"
+)
+
+func workoutLine(name string, rx models.Prescription) *models.WorkoutLine {
+	return &models.WorkoutLine{Name: name, Rx: rx}
+}
+
+func TestRenderWorkout_Formats(t *testing.T) {
+	cases := []struct {
+		name  string
+		block *models.WorkoutBlock
+		want  string
+	}{
+		{
+			name: "amrap",
+			block: &models.WorkoutBlock{Kind: models.BlockMetcon, Format: models.FormatAMRAP, Minutes: 12, Lines: []*models.WorkoutLine{
+				workoutLine("Pull-up", models.Prescription{Reps: "5"}),
+				workoutLine("Push-up", models.Prescription{Reps: "10"}),
+				workoutLine("Air Squat", models.Prescription{Reps: "15"}),
+			}},
+			want: "METCON\nAMRAP 12 min\n5 Pull-up\n10 Push-up\n15 Air Squat",
+		},
+		{
+			name: "emom with title",
+			block: &models.WorkoutBlock{Kind: models.BlockMetcon, Title: "Complejo", Format: models.FormatEMOM, Minutes: 10, Lines: []*models.WorkoutLine{
+				workoutLine("Power Clean", models.Prescription{Reps: "3", LoadKg: 60}),
+			}},
+			want: "METCON - Complejo\nEMOM 10 min\n3 Power Clean @ 60 kg",
+		},
+		{
+			name: "emom custom interval",
+			block: &models.WorkoutBlock{Kind: models.BlockSkill, Format: models.FormatEMOM, Minutes: 15, IntervalSeconds: 90, Lines: []*models.WorkoutLine{
+				workoutLine("Handstand Walk", models.Prescription{Distance: 10}),
+			}},
+			want: "TÉCNICA\nCada 1:30 durante 15 min\n10 m Handstand Walk",
+		},
+		{
+			name: "for time with rounds and cap",
+			block: &models.WorkoutBlock{Kind: models.BlockMetcon, Format: models.FormatForTime, Rounds: 3, Minutes: 20, Lines: []*models.WorkoutLine{
+				workoutLine("Run", models.Prescription{Distance: 400}),
+				workoutLine("Row", models.Prescription{Calories: 15}),
+				workoutLine("Thruster", models.Prescription{Reps: "21-15-9", LoadKg: 42.5}),
+			}},
+			want: "METCON\n3 rondas for time (time cap 20 min)\n400 m Run\n15 cal Row\n21-15-9 Thruster @ 42.5 kg",
+		},
+		{
+			name: "for time single round",
+			block: &models.WorkoutBlock{Kind: models.BlockMetcon, Format: models.FormatForTime, Lines: []*models.WorkoutLine{
+				workoutLine("Burpee", models.Prescription{Reps: "100"}),
+			}},
+			want: "METCON\nFor time\n100 Burpee",
+		},
+		{
+			name: "tabata default rounds",
+			block: &models.WorkoutBlock{Kind: models.BlockMetcon, Format: models.FormatTabata, Lines: []*models.WorkoutLine{
+				workoutLine("Plank", models.Prescription{Seconds: 45}),
+			}},
+			want: "METCON\nTabata 8 x 20s/10s\n45s Plank",
+		},
+		{
+			name: "sets and reps",
+			block: &models.WorkoutBlock{Kind: models.BlockStrength, Format: models.FormatSetsReps, Sets: 5, Lines: []*models.WorkoutLine{
+				{Name: "Back Squat", Rx: models.Prescription{Reps: "5", LoadPct: 75}, Notes: "pausa abajo"},
+			}},
+			want: "FUERZA\n5 x 5 Back Squat @ 75% (pausa abajo)",
+		},
+		{
+			name: "free rounds with notes",
+			block: &models.WorkoutBlock{Kind: models.BlockWarmup, Rounds: 2, Notes: "Movilidad libre", Lines: []*models.WorkoutLine{
+				workoutLine("Descanso", models.Prescription{Seconds: 90}),
+			}},
+			want: "CALENTAMIENTO\n2 rondas\n1:30 Descanso\nMovilidad libre",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := RenderWorkout([]*models.WorkoutBlock{c.block}, models.LevelRx); got != c.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRenderWorkout_Levels(t *testing.T) {
+	blocks := []*models.WorkoutBlock{
+		{Kind: models.BlockStrength, Format: models.FormatSetsReps, Sets: 3, Lines: []*models.WorkoutLine{
+			workoutLine("Deadlift", models.Prescription{Reps: "5", LoadPct: 80}),
+		}},
+		{Kind: models.BlockMetcon, Format: models.FormatAMRAP, Minutes: 8, Lines: []*models.WorkoutLine{
+			{Name: "Thruster", Rx: models.Prescription{Reps: "10", LoadKg: 43}, Scaled: &models.Prescription{Reps: "10", LoadKg: 30}},
+			{Name: "Pull-up", Rx: models.Prescription{Reps: "10"}, Scaled: &models.Prescription{Reps: "6"}, Beginner: &models.Prescription{Reps: "10"}},
+		}},
+	}
+	cases := []struct {
+		level string
+		want  string
+	}{
+		{models.LevelRx, "FUERZA\n3 x 5 Deadlift @ 80%\n\nMETCON\nAMRAP 8 min\n10 Thruster @ 43 kg\n10 Pull-up"},
+		{models.LevelScaled, "FUERZA\n3 x 5 Deadlift @ 80%\n\nMETCON\nAMRAP 8 min\n10 Thruster @ 30 kg\n6 Pull-up"},
+		// Thruster no tiene prescripción beginner y toma la de scaled; Pull-up usa la propia
+		{models.LevelBeginner, "FUERZA\n3 x 5 Deadlift @ 80%\n\nMETCON\nAMRAP 8 min\n10 Thruster @ 30 kg\n10 Pull-up"},
+	}
+	for _, c := range cases {
+		if got := RenderWorkout(blocks, c.level); got != c.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", c.level, got, c.want)
+		}
+	}
+}
+
+func TestWorkoutService_Prepare(t *testing.T) {
+	s := NewWorkoutService(nil)
+
+	routine := &models.Routine{Blocks: []*models.WorkoutBlock{
+		{Kind: models.BlockMetcon, Format: models.FormatForTime, Lines: []*models.WorkoutLine{workoutLine("  Burpee ", models.Prescription{Reps: "50"})}},
+	}}
+	if err := s.Prepare(routine); err != nil {
+		t.Fatal(err)
+	}
+	if routine.Content != "METCON\nFor time\n50 Burpee" {
+		t.Errorf("content = %q", routine.Content)
+	}
+	if routine.ContentScaled != "" || routine.ContentBeginner != "" {
+		t.Errorf("no scaled lines: scaled = %q, beginner = %q; want empty", routine.ContentScaled, routine.ContentBeginner)
+	}
+
+	invalid := []struct {
+		block *models.WorkoutBlock
+		want  error
+	}{
+		{&models.WorkoutBlock{Kind: "cardio"}, ErrWorkoutInvalidKind},
+		{&models.WorkoutBlock{Kind: models.BlockMetcon, Format: "chipper"}, ErrWorkoutInvalidFormat},
+		{&models.WorkoutBlock{Kind: models.BlockMetcon, Lines: []*models.WorkoutLine{workoutLine("  ", models.Prescription{Reps: "5"})}}, ErrWorkoutLineMissing},
+	}
+	for _, c := range invalid {
+		if err := s.Prepare(&models.Routine{Blocks: []*models.WorkoutBlock{c.block}}); err != c.want {
+			t.Errorf("Prepare(%+v) = %v, want %v", c.block, err, c.want)
+		}
+	}
+}